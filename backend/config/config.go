@@ -5,20 +5,66 @@ import (
 	"os"
 	"strconv"
 	"strings"
+	"time"
 )
 
+// ContactView is a named preset of fields and includes for the contact list
+type ContactView struct {
+	Fields   []string
+	Includes []string
+}
+
 type Config struct {
-	DBPath             string
-	ReminderTime       string
-	FrontendURL        string
-	Port               string
-	TrustedProxies     []string
-	UseSendgrid        bool
-	SendgridToEmail    string
-	SendgridTemplateID string
-	SendgridAPIKey     string
-	JWTSecretKey       string
-	JWTExpiryHours     int
+	DBDriver               string
+	DBPath                 string
+	DatabaseURL            string
+	ReminderTime           string
+	DailyReminders         bool
+	WeeklyDigest           bool
+	DigestTime             string
+	Port                   string
+	TrustedProxies         []string
+	CORSAllowedOrigins     []string // Origins of browser apps like the frontend which may call the API
+	CORSAllowAllOrigins    bool     // Allow any origin, only meant for local development
+	CORSAllowedMethods     []string
+	CORSAllowedHeaders     []string
+	CORSAllowCredentials   bool
+	EmailProvider          string
+	EmailFrom              string
+	EmailTo                string
+	SMTPHost               string
+	SMTPPort               string
+	SMTPUsername           string
+	SMTPPassword           string
+	SendgridAPIKey         string
+	RemembranceMails       bool
+	JWTSecretKey           string
+	AdminUsername          string
+	AdminEmail             string
+	AdminPassword          string
+	AllowRegistration      bool
+	JWTExpiryHours         int
+	Timezone               *time.Location
+	ContactViews           map[string]ContactView
+	SearchFields           []string
+	OverdueGraceDays       int
+	ReminderLeadDays       int
+	LeapDayBirthdays       string // Day Feb 29 birthdays are celebrated on in other years, "feb28" or "mar1"
+	HTMLSanitization       string
+	ContactSummaryTemplate string
+	RateLimitPerMinute     int
+	RateLimitBurst         int
+	AuthRateLimitPerMinute int
+	MaxPhotoSizeMB         int
+	MaxAttachmentSizeMB    int
+	DefaultPageLimit       int               // Contacts per page if the request does not set a limit
+	MaxPageLimit           int               // Larger limits requested for contact lists are clamped to this
+	CircleCaseFolding      bool              // Store circles in lower case, so circles differing only in case are the same
+	RelationshipInverses   map[string]string // Inverse of each relationship type by its lowercase name
+	BackupDir              string            // Directory of the scheduled backups, empty to disable them
+	BackupIntervalHours    int
+	BackupRetention        int  // Number of scheduled backups kept, older ones are deleted
+	WebhookPrivateTargets  bool // Allow webhooks to loopback, private and link-local addresses
 }
 
 func LoadConfig() *Config {
@@ -30,22 +76,187 @@ func LoadConfig() *Config {
 		jwtExpiryHours = defaultJWTExpiry
 	}
 
+	overdueGraceDays, err := strconv.Atoi(getEnv("OVERDUE_GRACE_DAYS", "0"))
+	if err != nil || overdueGraceDays < 0 {
+		log.Println("WARN: Invalid overdue grace days set. Please provide a positive integer value.")
+		overdueGraceDays = 0
+	}
+
+	reminderLeadDays, err := strconv.Atoi(getEnv("REMINDER_LEAD_DAYS", "0"))
+	if err != nil || reminderLeadDays < 0 {
+		log.Println("WARN: Invalid reminder lead days set. Please provide a positive integer value.")
+		reminderLeadDays = 0
+	}
+
+	rateLimitPerMinute, err := strconv.Atoi(getEnv("RATE_LIMIT_PER_MINUTE", "120"))
+	if err != nil || rateLimitPerMinute < 0 {
+		log.Println("WARN: Invalid rate limit set. Please provide a positive integer value or 0 to disable it.")
+		rateLimitPerMinute = 120
+	}
+
+	rateLimitBurst, err := strconv.Atoi(getEnv("RATE_LIMIT_BURST", "30"))
+	if err != nil || rateLimitBurst < 1 {
+		log.Println("WARN: Invalid rate limit burst set. Please provide a positive integer value.")
+		rateLimitBurst = 30
+	}
+
+	authRateLimitPerMinute, err := strconv.Atoi(getEnv("AUTH_RATE_LIMIT_PER_MINUTE", "10"))
+	if err != nil || authRateLimitPerMinute < 0 {
+		log.Println("WARN: Invalid login rate limit set. Please provide a positive integer value or 0 to disable it.")
+		authRateLimitPerMinute = 10
+	}
+
+	maxPhotoSizeMB, err := strconv.Atoi(getEnv("PROFILE_PHOTO_MAX_SIZE_MB", "10"))
+	if err != nil || maxPhotoSizeMB < 1 {
+		log.Println("WARN: Invalid maximum profile photo size set. Please provide a positive integer value.")
+		maxPhotoSizeMB = 10
+	}
+
+	maxAttachmentSizeMB, err := strconv.Atoi(getEnv("ATTACHMENT_MAX_SIZE_MB", "25"))
+	if err != nil || maxAttachmentSizeMB < 1 {
+		log.Println("WARN: Invalid maximum attachment size set. Please provide a positive integer value.")
+		maxAttachmentSizeMB = 25
+	}
+
+	maxPageLimit, err := strconv.Atoi(getEnv("MAX_PAGE_LIMIT", "100"))
+	if err != nil || maxPageLimit < 1 {
+		log.Println("WARN: Invalid maximum page limit set. Please provide a positive integer value.")
+		maxPageLimit = 100
+	}
+
+	defaultPageLimit, err := strconv.Atoi(getEnv("DEFAULT_PAGE_LIMIT", "25"))
+	if err != nil || defaultPageLimit < 1 {
+		log.Println("WARN: Invalid default page limit set. Please provide a positive integer value.")
+		defaultPageLimit = 25
+	}
+	if defaultPageLimit > maxPageLimit {
+		log.Println("WARN: Default page limit exceeds the maximum page limit. Using the maximum instead.")
+		defaultPageLimit = maxPageLimit
+	}
+
+	leapDayBirthdays := getEnv("LEAP_DAY_BIRTHDAYS", "feb28")
+	if leapDayBirthdays != "feb28" && leapDayBirthdays != "mar1" {
+		log.Println("WARN: Invalid leap day birthdays set. Please provide 'feb28' or 'mar1'.")
+		leapDayBirthdays = "feb28"
+	}
+
+	htmlSanitization := getEnv("HTML_SANITIZATION", "safe")
+	if htmlSanitization != "safe" && htmlSanitization != "strict" {
+		log.Println("WARN: Invalid HTML sanitization set. Please provide 'safe' or 'strict'.")
+		htmlSanitization = "safe"
+	}
+
+	// Installations configured for Sendgrid before the provider became selectable keep using it
+	defaultEmailProvider := "smtp"
+	if _, exists := os.LookupEnv("SENDGRID_API_KEY"); exists {
+		defaultEmailProvider = "sendgrid"
+	}
+	emailProvider := getEnv("EMAIL_PROVIDER", defaultEmailProvider)
+	if emailProvider != "smtp" && emailProvider != "sendgrid" {
+		log.Println("WARN: Invalid email provider set. Please provide 'smtp' or 'sendgrid'.")
+		emailProvider = "smtp"
+	}
+	// Mails are sent as plain text now, the templates of earlier versions are not used anymore
+	for _, name := range []string{"SENDGRID_TEMPLATE_ID", "SENDGRID_BIRTHDAY_TEMPLATE_ID", "SENDGRID_REMEMBRANCE_TEMPLATE_ID"} {
+		if os.Getenv(name) != "" {
+			log.Printf("WARN: The template of %s is not used since mails are sent as plain text. Set EMAIL_FROM to your verified Sendgrid sender.", name)
+		}
+	}
+
+	backupIntervalHours, err := strconv.Atoi(getEnv("BACKUP_INTERVAL_HOURS", "24"))
+	if err != nil || backupIntervalHours < 1 {
+		log.Println("WARN: Invalid backup interval set. Please provide a positive integer value.")
+		backupIntervalHours = 24
+	}
+
+	backupRetention, err := strconv.Atoi(getEnv("BACKUP_RETENTION", "7"))
+	if err != nil || backupRetention < 1 {
+		log.Println("WARN: Invalid backup retention set. Please provide a positive integer value.")
+		backupRetention = 7
+	}
+
+	dbDriver := getEnv("DB_DRIVER", "sqlite")
+	if dbDriver != "sqlite" && dbDriver != "postgres" {
+		log.Println("WARN: Invalid database driver set. Please provide 'sqlite' or 'postgres'.")
+		dbDriver = "sqlite"
+	}
+
+	// FRONTEND_URL was the only CORS setting before, a wildcard now has to be enabled explicitly
+	corsAllowedOrigins := []string{}
+	for _, origin := range splitList(getEnv("CORS_ALLOWED_ORIGINS", getEnv("FRONTEND_URL", ""))) {
+		if origin == "*" {
+			log.Println("WARN: Wildcard in the allowed CORS origins is ignored. Set CORS_ALLOW_ALL_ORIGINS for local development instead.")
+			continue
+		}
+		corsAllowedOrigins = append(corsAllowedOrigins, strings.TrimSuffix(origin, "/"))
+	}
+	corsAllowAllOrigins := getEnv("CORS_ALLOW_ALL_ORIGINS", "false") == "true"
+	if corsAllowAllOrigins {
+		log.Println("WARN: Requests from all origins are allowed. Only use CORS_ALLOW_ALL_ORIGINS for local development.")
+	}
+
+	// The scheduler and all calculations of today use this timezone, SCHEDULER_TIMEZONE and TZ are accepted as well
+	timezone, err := time.LoadLocation(getEnv("TIMEZONE", getEnv("SCHEDULER_TIMEZONE", getEnv("TZ", "UTC"))))
+	if err != nil {
+		log.Println("WARN: Invalid timezone set. Falling back to UTC.")
+		timezone = time.UTC
+	}
+
 	cfg := &Config{
-		DBPath:             getEnv("SQLITE_DB_PATH", "perema.db"),
-		ReminderTime:       getEnv("REMINDER_TIME", "12:00"),
-		FrontendURL:        getEnv("FRONTEND_URL", "*"),
-		Port:               getEnv("PORT", "8080"),
-		UseSendgrid:        true,
-		SendgridAPIKey:     getEnv("SENDGRID_API_KEY", ""),
-		SendgridTemplateID: getEnv("SENDGRID_BIRTHDAY_TEMPLATE_ID", ""),
-		SendgridToEmail:    getEnv("SENDGRID_TO_EMAIL", ""),
-		JWTSecretKey:       getEnv("JWT_SECRET_KEY", ""),
-		JWTExpiryHours:     jwtExpiryHours,
-		TrustedProxies:     getProxies(getEnv("TRUSTED_PROXIES", "")),
+		DBDriver:               dbDriver,
+		DBPath:                 getEnv("SQLITE_DB_PATH", "perema.db"),
+		DatabaseURL:            getEnv("DATABASE_URL", ""),
+		ReminderTime:           getEnv("REMINDER_TIME", "12:00"),
+		DailyReminders:         getEnv("DAILY_REMINDERS", "true") == "true",
+		WeeklyDigest:           getEnv("WEEKLY_DIGEST", "false") == "true",
+		DigestTime:             getEnv("DIGEST_TIME", "08:00"),
+		Port:                   getEnv("PORT", "8080"),
+		EmailProvider:          emailProvider,
+		EmailFrom:              getEnv("EMAIL_FROM", ""),
+		EmailTo:                getEnv("EMAIL_TO", getEnv("SENDGRID_TO_EMAIL", "")),
+		SMTPHost:               getEnv("SMTP_HOST", ""),
+		SMTPPort:               getEnv("SMTP_PORT", "587"),
+		SMTPUsername:           getEnv("SMTP_USER", ""),
+		SMTPPassword:           getEnv("SMTP_PASSWORD", ""),
+		SendgridAPIKey:         getEnv("SENDGRID_API_KEY", ""),
+		RemembranceMails:       getEnv("REMEMBRANCE_MAILS", strconv.FormatBool(os.Getenv("SENDGRID_REMEMBRANCE_TEMPLATE_ID") != "")) == "true",
+		JWTSecretKey:           getEnv("JWT_SECRET_KEY", ""),
+		AdminUsername:          getEnv("ADMIN_USERNAME", "admin"),
+		AdminEmail:             getEnv("ADMIN_EMAIL", ""),
+		AdminPassword:          getEnv("ADMIN_PASSWORD", ""),
+		AllowRegistration:      getEnv("ALLOW_REGISTRATION", "false") == "true",
+		JWTExpiryHours:         jwtExpiryHours,
+		TrustedProxies:         getProxies(getEnv("TRUSTED_PROXIES", "")),
+		CORSAllowedOrigins:     corsAllowedOrigins,
+		CORSAllowAllOrigins:    corsAllowAllOrigins,
+		CORSAllowedMethods:     splitList(getEnv("CORS_ALLOWED_METHODS", "GET,POST,PUT,PATCH,DELETE,OPTIONS")),
+		CORSAllowedHeaders:     splitList(getEnv("CORS_ALLOWED_HEADERS", "Origin,Content-Type,Authorization,If-None-Match")),
+		CORSAllowCredentials:   getEnv("CORS_ALLOW_CREDENTIALS", "true") == "true",
+		Timezone:               timezone,
+		ContactViews:           getContactViews(getEnv("CONTACT_VIEWS", "")),
+		SearchFields:           splitList(getEnv("SEARCH_FIELDS", "firstname,lastname,nickname")),
+		OverdueGraceDays:       overdueGraceDays,
+		ReminderLeadDays:       reminderLeadDays,
+		LeapDayBirthdays:       leapDayBirthdays,
+		HTMLSanitization:       htmlSanitization,
+		ContactSummaryTemplate: getEnv("CONTACT_SUMMARY_TEMPLATE", "{circles} in {address}, works at {work}, last seen {last_seen}, birthday {birthday}"),
+		RateLimitPerMinute:     rateLimitPerMinute,
+		RateLimitBurst:         rateLimitBurst,
+		AuthRateLimitPerMinute: authRateLimitPerMinute,
+		MaxPhotoSizeMB:         maxPhotoSizeMB,
+		MaxAttachmentSizeMB:    maxAttachmentSizeMB,
+		DefaultPageLimit:       defaultPageLimit,
+		MaxPageLimit:           maxPageLimit,
+		CircleCaseFolding:      getEnv("CIRCLE_CASE_FOLDING", "false") == "true",
+		WebhookPrivateTargets:  getEnv("WEBHOOK_ALLOW_PRIVATE_TARGETS", "false") == "true",
+		RelationshipInverses:   getRelationshipInverses(getEnv("RELATIONSHIP_INVERSES", defaultRelationshipInverses)),
+		BackupDir:              getEnv("BACKUP_DIR", ""),
+		BackupIntervalHours:    backupIntervalHours,
+		BackupRetention:        backupRetention,
 	}
 
-	if cfg.SendgridAPIKey == "" || cfg.SendgridTemplateID == "" || cfg.SendgridToEmail == "" {
-		cfg.UseSendgrid = false
+	if cfg.JWTSecretKey == "" {
+		log.Println("WARN: No JWT secret key set. Logins and all protected routes will be rejected.")
 	}
 
 	return cfg
@@ -69,3 +280,67 @@ func getProxies(proxies string) []string {
 	}
 	return proxyList
 }
+
+// getContactViews parses presets in the format "name=field1,field2|include1,include2;name2=..."
+// on top of the default card and detail views
+func getContactViews(views string) map[string]ContactView {
+	contactViews := map[string]ContactView{
+		"card": {
+			Fields:   []string{"ID", "firstname", "lastname", "nickname", "circles"},
+			Includes: []string{},
+		},
+		"detail": {
+			Fields:   []string{"ID", "firstname", "lastname", "nickname", "gender", "email", "phone", "birthday", "address", "circles"},
+			Includes: []string{"relationships", "reminders"},
+		},
+	}
+
+	for _, view := range strings.Split(views, ";") {
+		name, definition, found := strings.Cut(view, "=")
+		name = strings.TrimSpace(name)
+		if !found || name == "" {
+			continue
+		}
+		fields, includes, _ := strings.Cut(definition, "|")
+		contactViews[name] = ContactView{
+			Fields:   splitList(fields),
+			Includes: splitList(includes),
+		}
+	}
+	return contactViews
+}
+
+// Relationship types in English and German which are created in reverse on the related contact
+const defaultRelationshipInverses = "Parent=Child,Mother=Child,Father=Child,Sibling=Sibling,Partner=Partner,Spouse=Spouse,Friend=Friend," +
+	"Elternteil=Kind,Geschwister=Geschwister,Freund=Freund"
+
+// getRelationshipInverses parses pairs in the format "Parent=Child,Sibling=Sibling", each pair applies in both
+// directions. If a type is listed several times, its first pair is used, e.g. Child stays the inverse of Parent
+// with "Parent=Child,Mother=Child".
+func getRelationshipInverses(pairs string) map[string]string {
+	inverses := map[string]string{}
+	for _, pair := range splitList(pairs) {
+		from, to, found := strings.Cut(pair, "=")
+		from, to = strings.TrimSpace(from), strings.TrimSpace(to)
+		if !found || from == "" || to == "" {
+			log.Printf("WARN: Invalid relationship inverse %q, use the format Parent=Child.", pair)
+			continue
+		}
+		for _, direction := range [][2]string{{from, to}, {to, from}} {
+			if _, exists := inverses[strings.ToLower(direction[0])]; !exists {
+				inverses[strings.ToLower(direction[0])] = direction[1]
+			}
+		}
+	}
+	return inverses
+}
+
+func splitList(list string) []string {
+	items := []string{}
+	for _, item := range strings.Split(list, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -1,29 +1,58 @@
 package controllers
 
 import (
-	"log"
+	"fmt"
 	"net/http"
+	"perema/config"
+	"perema/middleware"
 	"perema/models"
-	"strconv"
+	"perema/services"
+	"slices"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
 )
 
-func CreateActivity(c *gin.Context) {
+func CreateActivity(c *gin.Context, cfg *config.Config) {
+	createActivity(c, cfg, nil)
+}
+
+// CreateContactActivity creates an activity with the contact of the URL, further contacts can be given as contact_ids
+func CreateContactActivity(c *gin.Context, cfg *config.Config) {
+	db := c.MustGet("db").(*gorm.DB)
+
+	var contact models.Contact
+	if err := db.Scopes(ownContacts(c)).First(&contact, c.Param("id")).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Contact not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	createActivity(c, cfg, &contact)
+}
+
+// createActivity creates an activity from the request body, together with the given contact if it is not nil
+func createActivity(c *gin.Context, cfg *config.Config, contact *models.Contact) {
 	var requestBody struct {
-		Title       string    `json:"title"`
-		Date        time.Time `json:"date"`
-		Description string    `json:"description"`
-		Location    string    `json:"location"`
-		ContactIDs  []uint    `json:"contact_ids"` // Accept an array of contact IDs for many-to-many association
+		Title        string    `json:"title"`
+		Date         time.Time `json:"date"`
+		Description  string    `json:"description"`
+		Location     string    `json:"location"`
+		ActivityType string    `json:"activity_type"`
+		ContactIDs   []uint    `json:"contact_ids"` // Accept an array of contact IDs for many-to-many association
 	}
 
 	// Bind the incoming JSON to the requestBody
 	if err := c.ShouldBindJSON(&requestBody); err != nil {
-		log.Println("Error binding JSON for create activity:", err)
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		middleware.Logger(c).Warn("Invalid activity", "error", err)
+		respondBindingError(c, err)
+		return
+	}
+	if !bindActivityType(c, &requestBody.ActivityType) || !bindActivityDate(c, requestBody.Date) {
 		return
 	}
 
@@ -31,24 +60,29 @@ func CreateActivity(c *gin.Context) {
 	db := c.MustGet("db").(*gorm.DB)
 	var contacts []models.Contact
 	if len(requestBody.ContactIDs) > 0 {
-		if err := db.Where("id IN ?", requestBody.ContactIDs).Find(&contacts).Error; err != nil {
-			log.Println("Error finding contacts with IDs:", requestBody.ContactIDs, err)
+		if err := db.Scopes(ownContacts(c)).Where("id IN ?", requestBody.ContactIDs).Find(&contacts).Error; err != nil || len(contacts) == 0 {
+			middleware.Logger(c).Warn("Contacts of activity not found", "contact_ids", requestBody.ContactIDs, "error", err)
 			c.JSON(http.StatusNotFound, gin.H{"error": "One or more contacts not found"})
 			return
 		}
 	}
+	if contact != nil && !slices.ContainsFunc(contacts, func(other models.Contact) bool { return other.ID == contact.ID }) {
+		contacts = append(contacts, *contact)
+	}
 
 	// Create a new activity without the associations initially
 	activity := models.Activity{
-		Title:       requestBody.Title,
-		Date:        requestBody.Date,
-		Description: requestBody.Description,
-		Location:    requestBody.Location,
+		Title:        requestBody.Title,
+		Date:         requestBody.Date,
+		Description:  services.SanitizeHTML(requestBody.Description, cfg.HTMLSanitization),
+		Location:     requestBody.Location,
+		ActivityType: requestBody.ActivityType,
+		UserID:       currentUserID(c),
 	}
 
 	// Save the new activity to the database
 	if err := db.Create(&activity).Error; err != nil {
-		log.Println("Error saving activity to the database:", err)
+		middleware.Logger(c).Error("Failed to save activity", "error", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save activity"})
 		return
 	}
@@ -56,21 +90,85 @@ func CreateActivity(c *gin.Context) {
 	// Update the activity's contacts association
 	if len(contacts) > 0 {
 		if err := db.Model(&activity).Association("Contacts").Append(contacts); err != nil {
-			log.Println("Error creating relationship between activity and contacts:", err)
+			middleware.Logger(c).Error("Failed to associate contacts with activity", "activity_id", activity.ID, "error", err)
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to associate contacts with activity"})
 			return
 		}
 	}
 
 	// Respond with success
-	c.JSON(http.StatusOK, gin.H{"message": "Activity created successfully", "activity": activity})
+	respondCreated(c, fmt.Sprintf("/activities/%d", activity.ID), gin.H{"message": "Activity created successfully", "activity": activity})
+}
+
+// bindActivityType defaults a missing activity type to other and responds with an error for unknown types
+func bindActivityType(c *gin.Context, activityType *string) bool {
+	if *activityType == "" {
+		*activityType = models.ActivityOther
+	}
+	if !models.ValidActivityType(*activityType) {
+		respondFieldErrors(c, map[string]fieldError{
+			"activity_type": {Rule: "activity_type", Message: models.InvalidActivityTypeMessage(), Value: *activityType},
+		})
+		return false
+	}
+	return true
+}
+
+// bindActivityDate responds with an error and returns false if the date of an activity is missing
+func bindActivityDate(c *gin.Context, date time.Time) bool {
+	if date.IsZero() {
+		respondFieldErrors(c, map[string]fieldError{
+			"date": {Rule: "required", Message: "This field is required", Value: nil},
+		})
+		return false
+	}
+	return true
+}
+
+// activityFilters returns a scope filtering activities by the type and the inclusive from and to dates of the query.
+// It responds with an error and returns false for invalid filters.
+func activityFilters(c *gin.Context) (func(*gorm.DB) *gorm.DB, bool) {
+	activityType := c.Query("type")
+	if activityType != "" && !models.ValidActivityType(activityType) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": models.InvalidActivityTypeMessage()})
+		return nil, false
+	}
+
+	var fromDate, toDate time.Time
+	if from := c.Query("from"); from != "" {
+		var err error
+		if fromDate, err = time.Parse(models.DateFormat, from); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid from date, use YYYY-MM-DD"})
+			return nil, false
+		}
+	}
+	if to := c.Query("to"); to != "" {
+		var err error
+		if toDate, err = time.Parse(models.DateFormat, to); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid to date, use YYYY-MM-DD"})
+			return nil, false
+		}
+	}
+
+	return func(db *gorm.DB) *gorm.DB {
+		if activityType != "" {
+			db = db.Where("activities.activity_type = ?", activityType)
+		}
+		if !fromDate.IsZero() {
+			db = db.Where("activities.date >= ?", fromDate)
+		}
+		if !toDate.IsZero() {
+			db = db.Where("activities.date < ?", toDate.AddDate(0, 0, 1))
+		}
+		return db
+	}, true
 }
 
 func GetActivity(c *gin.Context) {
 	id := c.Param("id")
 	var activity models.Activity
 	db := c.MustGet("db").(*gorm.DB)
-	if err := db.First(&activity, id).Error; err != nil {
+	if err := db.Scopes(ownedBy(c, "activities")).First(&activity, id).Error; err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Activity not found"})
 		return
 	}
@@ -78,40 +176,34 @@ func GetActivity(c *gin.Context) {
 	c.JSON(http.StatusOK, activity)
 }
 
-func GetActivities(c *gin.Context) {
+func GetActivities(c *gin.Context, cfg *config.Config) {
 	db := c.MustGet("db").(*gorm.DB).Debug()
 
 	// Get pagination parameters from query
-	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
-	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "25"))
-
-	if page < 1 {
-		page = 1
-	}
-	if limit < 1 {
-		limit = 25
-	}
-
-	// Calculate offset
-	offset := (page - 1) * limit
+	page, limit, offset := paginationParams(c, cfg)
 
 	includeContacts := c.DefaultQuery("include", "") == "contacts"
 
+	filters, ok := activityFilters(c)
+	if !ok {
+		return
+	}
+
 	var activities []models.Activity
 	var total int64
 
-	// Get the total count of activities
-	db.Model(&models.Activity{}).Count(&total)
+	// Get the total count of matching activities
+	db.Model(&models.Activity{}).Scopes(ownedBy(c, "activities"), filters).Count(&total)
 
 	// Build the query with optional preloading and ordering by date in descending order
 	query := db.Model(&models.Activity{}).
+		Scopes(ownedBy(c, "activities"), filters).
 		Order("date DESC").
 		Limit(limit).
 		Offset(offset)
 
 	if includeContacts {
 		query = query.Preload("Contacts")
-		log.Println("Preloading contacts")
 	}
 
 	// Execute the query
@@ -121,37 +213,40 @@ func GetActivities(c *gin.Context) {
 	}
 
 	// Include pagination metadata in response
-	c.JSON(http.StatusOK, gin.H{
-		"activities": activities,
-		"total":      total,
-		"page":       page,
-		"limit":      limit,
-	})
+	c.JSON(http.StatusOK, paginatedResponse("activities", activities, total, page, limit))
 }
 
-func UpdateActivity(c *gin.Context) {
+func UpdateActivity(c *gin.Context, cfg *config.Config) {
 	id := c.Param("id")
 	db := c.MustGet("db").(*gorm.DB)
 
 	var activity models.Activity
-	if err := db.First(&activity, id).Error; err != nil {
+	if err := db.Scopes(ownedBy(c, "activities")).First(&activity, id).Error; err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Activity not found"})
 		return
 	}
 
 	var updatedActivity models.Activity
 	if err := c.ShouldBindJSON(&updatedActivity); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondBindingError(c, err)
+		return
+	}
+	if !bindActivityType(c, &updatedActivity.ActivityType) || !bindActivityDate(c, updatedActivity.Date) {
 		return
 	}
 
 	// Updateable fields
 	activity.Title = updatedActivity.Title
-	activity.Description = updatedActivity.Description
+	activity.Description = services.SanitizeHTML(updatedActivity.Description, cfg.HTMLSanitization)
 	activity.Location = updatedActivity.Location
 	activity.Date = updatedActivity.Date
+	activity.ActivityType = updatedActivity.ActivityType
 
-	db.Save(&activity)
+	if err := db.Save(&activity).Error; err != nil {
+		middleware.Logger(c).Error("Failed to update activity", "activity_id", activity.ID, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update activity"})
+		return
+	}
 
 	c.JSON(http.StatusOK, activity)
 }
@@ -159,6 +254,10 @@ func UpdateActivity(c *gin.Context) {
 func DeleteActivity(c *gin.Context) {
 	id := c.Param("id")
 	db := c.MustGet("db").(*gorm.DB)
+	if err := db.Scopes(ownedBy(c, "activities")).First(&models.Activity{}, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Activity not found"})
+		return
+	}
 	if err := db.Delete(&models.Activity{}, id).Error; err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Activity not found"})
 		return
@@ -174,11 +273,16 @@ func GetActivitiesForContact(c *gin.Context) {
 	// Get the database instance from the context
 	db := c.MustGet("db").(*gorm.DB)
 
+	filters, ok := activityFilters(c)
+	if !ok {
+		return
+	}
+
 	// Initialize a variable to store the contact
 	var contact models.Contact
 
 	// Find the contact and preload associated activities
-	if err := db.Preload("Activities").First(&contact, contactID).Error; err != nil {
+	if err := db.Scopes(ownContacts(c)).Preload("Activities", filters).First(&contact, contactID).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
 			// If no contact found, return a 404 error
 			c.JSON(http.StatusNotFound, gin.H{"error": "Contact not found"})
@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"perema/config"
 	"perema/models"
 	"strconv"
 
@@ -25,7 +26,7 @@ func setupRouter() (*gorm.DB, *gin.Engine) {
 		panic("failed to connect database")
 	}
 
-	db.AutoMigrate(&models.Contact{}, &models.Activity{}, &models.Note{}, models.Relationship{}, models.Reminder{}, models.User{})
+	db.AutoMigrate(&models.Contact{}, &models.Activity{}, &models.Note{}, models.Relationship{}, models.Reminder{}, models.User{}, models.NoteAttachment{}, models.APIKey{}, models.AuditLog{}, models.ContactMethod{}, models.ContactAttachment{}, models.Webhook{}, models.WebhookDeadLetter{}, models.CustomDate{})
 
 	router := gin.Default()
 	router.Use(func(c *gin.Context) {
@@ -39,7 +40,9 @@ func setupRouter() (*gorm.DB, *gin.Engine) {
 func TestCreateActivity(t *testing.T) {
 	db, router := setupRouter()
 
-	router.POST("/activities", CreateActivity)
+	router.POST("/activities", func(c *gin.Context) {
+		CreateActivity(c, &config.Config{})
+	})
 
 	contacts := []models.Contact{
 		{
@@ -70,13 +73,79 @@ func TestCreateActivity(t *testing.T) {
 	w := httptest.NewRecorder()
 	router.ServeHTTP(w, req)
 
-	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, http.StatusCreated, w.Code)
 
 	var responseBody map[string]any
 	json.Unmarshal(w.Body.Bytes(), &responseBody)
 	assert.Equal(t, "Activity created successfully", responseBody["message"])
 }
 
+func TestCreateContactActivity(t *testing.T) {
+	db, router := setupRouter()
+
+	router.POST("/contacts/:id/activities", func(c *gin.Context) {
+		CreateContactActivity(c, &config.Config{})
+	})
+	router.PUT("/activities/:id", func(c *gin.Context) {
+		UpdateActivity(c, &config.Config{})
+	})
+
+	alice := models.Contact{Firstname: "Alice"}
+	bob := models.Contact{Firstname: "Bob"}
+	other := models.Contact{Firstname: "Mallory", UserID: 2}
+	db.Create(&alice)
+	db.Create(&bob)
+	db.Create(&other)
+
+	send := func(method, url string, body map[string]any) (int, models.Activity) {
+		jsonValue, _ := json.Marshal(body)
+		req, _ := http.NewRequest(method, url, bytes.NewBuffer(jsonValue))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		var responseBody struct {
+			Activity models.Activity `json:"activity"`
+		}
+		json.Unmarshal(w.Body.Bytes(), &responseBody)
+		return w.Code, responseBody.Activity
+	}
+	url := "/contacts/" + strconv.Itoa(int(alice.ID)) + "/activities"
+
+	// The contact of the URL is added to the contacts of the body once
+	code, activity := send("POST", url, map[string]any{"title": "Lunch", "date": time.Now(), "activity_type": "meeting", "contact_ids": []uint{alice.ID, bob.ID}})
+	assert.Equal(t, http.StatusCreated, code)
+	var stored models.Activity
+	db.Preload("Contacts").First(&stored, activity.ID)
+	assert.Len(t, stored.Contacts, 2)
+	assert.Equal(t, models.ActivityMeeting, stored.ActivityType)
+
+	code, activity = send("POST", url, map[string]any{"title": "Call", "date": time.Now()})
+	assert.Equal(t, http.StatusCreated, code)
+	var call models.Activity
+	db.Preload("Contacts").First(&call, activity.ID)
+	assert.Len(t, call.Contacts, 1)
+	assert.Equal(t, alice.ID, call.Contacts[0].ID)
+
+	// Missing and foreign contacts are not found
+	code, _ = send("POST", "/contacts/9999/activities", map[string]any{"title": "Call", "date": time.Now()})
+	assert.Equal(t, http.StatusNotFound, code)
+	code, _ = send("POST", "/contacts/"+strconv.Itoa(int(other.ID))+"/activities", map[string]any{"title": "Call", "date": time.Now()})
+	assert.Equal(t, http.StatusNotFound, code)
+
+	// The date is required and has to be a valid date
+	code, _ = send("POST", url, map[string]any{"title": "Call"})
+	assert.Equal(t, http.StatusBadRequest, code)
+	code, _ = send("POST", url, map[string]any{"title": "Call", "date": "2024-02-30T10:00:00Z"})
+	assert.Equal(t, http.StatusBadRequest, code)
+	code, _ = send("PUT", "/activities/"+strconv.Itoa(int(activity.ID)), map[string]any{"title": "Call"})
+	assert.Equal(t, http.StatusBadRequest, code)
+
+	var count int64
+	db.Model(&models.Activity{}).Count(&count)
+	assert.Equal(t, int64(2), count)
+}
+
 func TestGetActivitiesForContact(t *testing.T) {
 	db, router := setupRouter()
 
@@ -124,7 +193,9 @@ func TestGetActivitiesForContact(t *testing.T) {
 func TestGetActivities(t *testing.T) {
 	db, router := setupRouter()
 
-	router.GET("/activities", GetActivities)
+	router.GET("/activities", func(c *gin.Context) {
+		GetActivities(c, &config.Config{DefaultPageLimit: 25, MaxPageLimit: 100})
+	})
 
 	// Create some activities
 	activity1 := models.Activity{
@@ -154,6 +225,76 @@ func TestGetActivities(t *testing.T) {
 	assert.Len(t, responseBody["activities"], 2) // Should return both activities
 }
 
+func TestActivityTypes(t *testing.T) {
+	db, router := setupRouter()
+
+	router.POST("/activities", func(c *gin.Context) {
+		CreateActivity(c, &config.Config{})
+	})
+	router.GET("/activities", func(c *gin.Context) {
+		GetActivities(c, &config.Config{DefaultPageLimit: 25, MaxPageLimit: 100})
+	})
+	router.GET("/contacts/:id/activities", GetActivitiesForContact)
+
+	contact := models.Contact{Firstname: "John"}
+	db.Create(&contact)
+
+	post := func(body string) (int, map[string]any) {
+		req, _ := http.NewRequest("POST", "/activities", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		var responseBody map[string]any
+		json.Unmarshal(w.Body.Bytes(), &responseBody)
+		return w.Code, responseBody
+	}
+	get := func(url string) (int, []models.Activity) {
+		req, _ := http.NewRequest("GET", url, nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		var responseBody struct {
+			Activities []models.Activity `json:"activities"`
+		}
+		json.Unmarshal(w.Body.Bytes(), &responseBody)
+		return w.Code, responseBody.Activities
+	}
+
+	contactIDs := `"contact_ids": [` + strconv.Itoa(int(contact.ID)) + `]`
+	code, _ := post(`{"title": "Phone call", "activity_type": "call", "date": "2024-03-01T10:00:00Z", ` + contactIDs + `}`)
+	assert.Equal(t, http.StatusCreated, code)
+	code, _ = post(`{"title": "Dinner", "activity_type": "meeting", "date": "2024-03-05T19:00:00Z", ` + contactIDs + `}`)
+	assert.Equal(t, http.StatusCreated, code)
+	code, responseBody := post(`{"title": "Walk", "date": "2024-04-01T10:00:00Z"}`)
+	assert.Equal(t, http.StatusCreated, code)
+	assert.Equal(t, models.ActivityOther, responseBody["activity"].(map[string]any)["activity_type"])
+
+	// Unknown types are rejected with the list of valid types
+	code, responseBody = post(`{"title": "Party", "activity_type": "party"}`)
+	assert.Equal(t, http.StatusBadRequest, code)
+	field := responseBody["fields"].(map[string]any)["activity_type"].(map[string]any)
+	assert.Equal(t, "Invalid activity type, use one of call, meeting, message, gift, other", field["message"])
+
+	_, activities := get("/activities?type=call")
+	assert.Len(t, activities, 1)
+	assert.Equal(t, "Phone call", activities[0].Title)
+
+	_, activities = get("/activities?from=2024-03-05&to=2024-04-01")
+	assert.Len(t, activities, 2)
+
+	_, activities = get("/contacts/" + strconv.Itoa(int(contact.ID)) + "/activities?type=meeting")
+	assert.Len(t, activities, 1)
+	assert.Equal(t, "Dinner", activities[0].Title)
+
+	code, _ = get("/activities?type=party")
+	assert.Equal(t, http.StatusBadRequest, code)
+	code, _ = get("/contacts/" + strconv.Itoa(int(contact.ID)) + "/activities?type=party")
+	assert.Equal(t, http.StatusBadRequest, code)
+	code, _ = get("/activities?from=yesterday")
+	assert.Equal(t, http.StatusBadRequest, code)
+}
+
 func TestGetActivity(t *testing.T) {
 	db, router := setupRouter()
 
@@ -183,7 +324,9 @@ func TestGetActivity(t *testing.T) {
 func TestUpdateActivity(t *testing.T) {
 	db, router := setupRouter()
 
-	router.PUT("/activities/:id", UpdateActivity)
+	router.PUT("/activities/:id", func(c *gin.Context) {
+		UpdateActivity(c, &config.Config{})
+	})
 
 	// Create an activity
 	activity := models.Activity{
@@ -0,0 +1,106 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"perema/config"
+	"perema/middleware"
+	"perema/services"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// Maximum size of an uploaded database backup
+const maxRestoreSize = 1 << 30 // 1 GB
+
+// requireSQLite responds with an error and returns false if the database is not SQLite
+func requireSQLite(c *gin.Context, cfg *config.Config) bool {
+	if cfg.DBDriver != services.DriverSQLite {
+		c.JSON(http.StatusNotImplemented, gin.H{"error": "Backups are only supported for SQLite, use pg_dump for PostgreSQL"})
+		return false
+	}
+	return true
+}
+
+// GetBackup returns a consistent copy of the SQLite database as download. The server keeps running while
+// the copy is created.
+func GetBackup(c *gin.Context, cfg *config.Config) {
+	if !requireSQLite(c, cfg) {
+		return
+	}
+	db := c.MustGet("db").(*gorm.DB)
+
+	dir, err := os.MkdirTemp("", "perema-backup-")
+	if err != nil {
+		middleware.Logger(c).Error("Failed to create backup directory", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create backup"})
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "perema.db")
+	if err := services.BackupSQLite(db, path); err != nil {
+		middleware.Logger(c).Error("Failed to create backup", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create backup"})
+		return
+	}
+
+	c.FileAttachment(path, services.BackupFilename(time.Now()))
+}
+
+// RestoreBackup validates an uploaded SQLite backup and stages it to replace the database on the next start
+// of the server. Open connections keep using the current database until then, so the restore never
+// swaps the database under running requests.
+func RestoreBackup(c *gin.Context, cfg *config.Config) {
+	if !requireSQLite(c, cfg) {
+		return
+	}
+
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxRestoreSize+1<<20)
+	file, err := c.FormFile("file")
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) || (err == nil && file.Size > maxRestoreSize) {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "File is too large"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "No file uploaded"})
+		return
+	}
+
+	pending := services.PendingRestorePath(cfg.DBPath)
+	upload := pending + ".upload"
+	defer os.Remove(upload)
+	if err := c.SaveUploadedFile(file, upload); err != nil {
+		middleware.Logger(c).Error("Failed to save backup", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save backup"})
+		return
+	}
+
+	if err := services.ValidateSQLiteBackup(upload); err != nil {
+		if errors.Is(err, services.ErrInvalidBackup) {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+			return
+		}
+		middleware.Logger(c).Error("Failed to validate backup", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to validate backup"})
+		return
+	}
+	if err := os.Rename(upload, pending); err != nil {
+		middleware.Logger(c).Error("Failed to stage backup", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save backup"})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"message":          "Backup validated, it replaces the database on the next restart of the server",
+		"restart_required": true,
+		"downtime": "The server has to be restarted to apply the backup and is unavailable while it restarts. " +
+			"Changes made after the backup was created are lost, including those made until the restart. " +
+			"The current database is kept next to it with the suffix .before-restore and the time of the restore.",
+	})
+}
@@ -0,0 +1,73 @@
+package controllers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"perema/config"
+	"perema/middleware"
+	"perema/models"
+	"perema/services"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBackupAndRestore(t *testing.T) {
+	db, router := setupRouter()
+	cfg := &config.Config{DBDriver: services.DriverSQLite, DBPath: filepath.Join(t.TempDir(), "perema.db"), AdminEmail: "admin@example.com"}
+
+	admin := models.User{Username: "admin", Email: "Admin@example.com"}
+	user := models.User{Username: "jane", Email: "jane@example.com"}
+	db.Create(&admin)
+	db.Create(&user)
+	db.Create(&models.Contact{Firstname: "Alice"})
+
+	userID := admin.ID
+	router.Use(func(c *gin.Context) {
+		c.Set("user_id", userID)
+		c.Next()
+	}, middleware.AdminOnly(cfg))
+	router.GET("/admin/backup", func(c *gin.Context) { GetBackup(c, cfg) })
+	router.POST("/admin/restore", func(c *gin.Context) { RestoreBackup(c, cfg) })
+
+	// The backup is a complete SQLite database
+	req, _ := http.NewRequest("GET", "/admin/backup", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.True(t, strings.HasPrefix(w.Header().Get("Content-Disposition"), `attachment; filename="perema-backup-`))
+	assert.True(t, strings.HasPrefix(w.Body.String(), "SQLite format 3\x00"))
+	backup := w.Body.Bytes()
+
+	// Only valid backups are staged, the database itself is replaced on the next start
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, newFormFileRequest("/admin/restore", "file", "backup.db", []byte("garbage")))
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	assert.NoFileExists(t, services.PendingRestorePath(cfg.DBPath))
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, newFormFileRequest("/admin/restore", "file", "backup.db", backup))
+	assert.Equal(t, http.StatusAccepted, w.Code)
+	assert.Contains(t, w.Body.String(), "restart_required")
+	staged, _ := os.ReadFile(services.PendingRestorePath(cfg.DBPath))
+	assert.Equal(t, backup, staged)
+
+	// Other users are not allowed to access backups
+	userID = user.ID
+	req, _ = http.NewRequest("GET", "/admin/backup", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	// Backups of PostgreSQL are left to its own tools
+	userID = admin.ID
+	cfg.DBDriver = services.DriverPostgres
+	req, _ = http.NewRequest("GET", "/admin/backup", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNotImplemented, w.Code)
+}
@@ -0,0 +1,70 @@
+package controllers
+
+import (
+	"net/http"
+	"perema/models"
+	"perema/services"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// CreateAPIKey creates an API key for the authenticated user. The key is only returned in this response.
+func CreateAPIKey(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+
+	var apiKey models.APIKey
+	if err := c.ShouldBindJSON(&apiKey); err != nil {
+		respondBindingError(c, err)
+		return
+	}
+
+	key, hash, err := services.GenerateAPIKey()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate API key"})
+		return
+	}
+	apiKey = models.APIKey{
+		UserID:  currentUserID(c),
+		Name:    apiKey.Name,
+		Hint:    key[len(key)-4:],
+		KeyHash: hash,
+	}
+
+	if err := db.Create(&apiKey).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save API key"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"message": "API key created, it will not be shown again", "api_key": apiKey, "key": key})
+}
+
+// GetAPIKeys lists the API keys of the authenticated user
+func GetAPIKeys(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+
+	apiKeys := []models.APIKey{}
+	if err := db.Scopes(ownedBy(c, "api_keys")).Order("created_at DESC").Find(&apiKeys).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve API keys"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"api_keys": apiKeys})
+}
+
+// RevokeAPIKey deletes an API key, so it can no longer be used
+func RevokeAPIKey(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+
+	var apiKey models.APIKey
+	if err := db.Scopes(ownedBy(c, "api_keys")).First(&apiKey, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "API key not found"})
+		return
+	}
+	if err := db.Delete(&apiKey).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke API key"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "API key revoked"})
+}
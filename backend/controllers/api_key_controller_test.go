@@ -0,0 +1,64 @@
+package controllers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"perema/models"
+	"perema/services"
+	"strconv"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAPIKeys(t *testing.T) {
+	db, router := setupRouter()
+	router.Use(func(c *gin.Context) {
+		c.Set("user_id", uint(1))
+		c.Next()
+	})
+	router.POST("/api-keys", CreateAPIKey)
+	router.GET("/api-keys", GetAPIKeys)
+	router.DELETE("/api-keys/:id", RevokeAPIKey)
+
+	send := func(method, url, body string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(method, url, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	assert.Equal(t, http.StatusBadRequest, send("POST", "/api-keys", `{}`).Code)
+
+	// The key is returned once and only its hash is stored
+	w := send("POST", "/api-keys", `{"name": "Cron"}`)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	var created struct {
+		APIKey models.APIKey `json:"api_key"`
+		Key    string        `json:"key"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &created)
+	var stored models.APIKey
+	db.First(&stored, created.APIKey.ID)
+	assert.Equal(t, services.HashAPIKey(created.Key), stored.KeyHash)
+	assert.Equal(t, uint(1), stored.UserID)
+	assert.NotContains(t, send("GET", "/api-keys", "").Body.String(), created.Key)
+
+	// Keys of other users can neither be listed nor revoked
+	other := models.APIKey{UserID: 2, Name: "Other", KeyHash: "hash"}
+	db.Create(&other)
+	var list struct {
+		APIKeys []models.APIKey `json:"api_keys"`
+	}
+	json.Unmarshal(send("GET", "/api-keys", "").Body.Bytes(), &list)
+	assert.Len(t, list.APIKeys, 1)
+	assert.Equal(t, http.StatusNotFound, send("DELETE", "/api-keys/"+strconv.Itoa(int(other.ID)), "").Code)
+
+	assert.Equal(t, http.StatusOK, send("DELETE", "/api-keys/"+strconv.Itoa(int(created.APIKey.ID)), "").Code)
+	_, err := services.AuthenticateAPIKey(db, created.Key)
+	assert.Error(t, err)
+}
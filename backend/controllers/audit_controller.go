@@ -0,0 +1,68 @@
+package controllers
+
+import (
+	"net/http"
+	"perema/config"
+	"perema/middleware"
+	"perema/models"
+	"perema/services"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// recordAudit writes the changes between before and after to the audit log of the current user and
+// notifies the webhooks of changed contacts. A failure is only logged, the change itself has already been saved.
+func recordAudit(c *gin.Context, db *gorm.DB, entry models.AuditLog, before, after any) {
+	entry.UserID = currentUserID(c)
+	if err := services.RecordAudit(db, entry, before, after); err != nil {
+		middleware.Logger(c).Error("Failed to record audit log", "entity_type", entry.EntityType, "entity_id", entry.EntityID, "error", err)
+	}
+
+	if event, ok := contactWebhookEvents[entry.Action]; ok && entry.EntityType == models.AuditEntityContact {
+		if after == nil {
+			after = before
+		}
+		dispatchWebhook(c, event, after)
+	}
+}
+
+// contactAudit returns the audit entry for an action on the contact
+func contactAudit(contact models.Contact, action string) models.AuditLog {
+	return models.AuditLog{ContactID: contact.ID, EntityType: models.AuditEntityContact, EntityID: contact.ID, Action: action}
+}
+
+// reminderAudit returns the audit entry for an action on the reminder, listed in the history of its contact
+func reminderAudit(reminder models.Reminder, action string) models.AuditLog {
+	entry := models.AuditLog{EntityType: models.AuditEntityReminder, EntityID: reminder.ID, Action: action}
+	if reminder.ContactID != nil {
+		entry.ContactID = *reminder.ContactID
+	}
+	return entry
+}
+
+// GetContactHistory lists the changes of a contact and its reminders, newest first
+func GetContactHistory(c *gin.Context, cfg *config.Config) {
+	db := c.MustGet("db").(*gorm.DB)
+
+	var contact models.Contact
+	if err := db.Scopes(ownContacts(c)).First(&contact, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Contact not found"})
+		return
+	}
+
+	page, limit, offset := paginationParams(c, cfg)
+
+	query := db.Model(&models.AuditLog{}).Scopes(ownedBy(c, "audit_logs")).Where("contact_id = ?", contact.ID)
+
+	var total int64
+	query.Count(&total)
+
+	var entries []models.AuditLog
+	if err := query.Order("created_at DESC").Order("id DESC").Limit(limit).Offset(offset).Find(&entries).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve history"})
+		return
+	}
+
+	c.JSON(http.StatusOK, paginatedResponse("history", entries, total, page, limit))
+}
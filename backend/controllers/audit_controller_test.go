@@ -0,0 +1,73 @@
+package controllers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"perema/config"
+	"perema/models"
+	"strconv"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetContactHistory(t *testing.T) {
+	db, router := setupRouter()
+
+	router.POST("/contacts", CreateContact)
+	router.PATCH("/contacts/:id", PatchContact)
+	router.POST("/contacts/:id/reminders", CreateReminder)
+	router.DELETE("/reminders/:id", DeleteReminder)
+	router.GET("/contacts/:id/history", func(c *gin.Context) {
+		GetContactHistory(c, &config.Config{DefaultPageLimit: 25, MaxPageLimit: 100})
+	})
+
+	send := func(method, url, body string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(method, url, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	assert.Equal(t, http.StatusCreated, send("POST", "/contacts", `{"firstname": "Alice", "lastname": "Johnson"}`).Code)
+	var contact models.Contact
+	db.First(&contact)
+	url := "/contacts/" + strconv.Itoa(int(contact.ID))
+
+	assert.Equal(t, http.StatusOK, send("PATCH", url, `{"lastname": "Smith"}`).Code)
+	assert.Equal(t, http.StatusOK, send("PATCH", url, `{"lastname": "Smith"}`).Code) // Changes nothing
+	assert.Equal(t, http.StatusCreated, send("POST", url+"/reminders", `{"message": "Call", "remind_at": "2030-01-01T09:00:00Z", "recurrence": "once"}`).Code)
+	var reminder models.Reminder
+	db.First(&reminder)
+	assert.Equal(t, http.StatusOK, send("DELETE", "/reminders/"+strconv.Itoa(int(reminder.ID)), "").Code)
+
+	w := send("GET", url+"/history", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	var response struct {
+		History []models.AuditLog `json:"history"`
+		Total   int64             `json:"total"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &response)
+	assert.Equal(t, int64(4), response.Total)
+
+	// Newest first
+	assert.Equal(t, models.AuditEntityReminder, response.History[0].EntityType)
+	assert.Equal(t, models.AuditDelete, response.History[0].Action)
+	assert.Equal(t, models.AuditCreate, response.History[1].Action)
+
+	update := response.History[2]
+	assert.Equal(t, models.AuditEntityContact, update.EntityType)
+	assert.Equal(t, models.AuditUpdate, update.Action)
+	assert.Equal(t, map[string]models.AuditChange{"lastname": {Old: "Johnson", New: "Smith"}}, update.Changes)
+
+	create := response.History[3]
+	assert.Equal(t, models.AuditCreate, create.Action)
+	assert.Equal(t, "Alice", create.Changes["firstname"].New)
+	assert.NotContains(t, create.Changes, "nickname")
+
+	assert.Equal(t, http.StatusNotFound, send("GET", "/contacts/999/history", "").Code)
+}
@@ -0,0 +1,257 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"perema/config"
+	"perema/middleware"
+	"perema/models"
+	"perema/services"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// Format version of contact bundles, increased on incompatible changes
+const contactBundleVersion = 1
+
+// Maximum size of an imported contact bundle
+const maxBundleImportSize = 10 << 20 // 10 MB
+
+// contactBundle is a self-contained export of a contact with its notes, activities, relationships,
+// reminders, contact methods and custom dates, used for backups and transfers between instances
+type contactBundle struct {
+	Version    int            `json:"version"`
+	ExportedAt time.Time      `json:"exported_at"`
+	Contact    models.Contact `json:"contact"`
+}
+
+// ExportContactBundle returns a contact with all of its records as JSON file
+func ExportContactBundle(c *gin.Context) {
+	id := c.Param("id")
+	var contact models.Contact
+	db := c.MustGet("db").(*gorm.DB)
+	if err := db.Scopes(ownContacts(c)).
+		Preload("Notes", func(db *gorm.DB) *gorm.DB { return db.Order("date, id") }).
+		Preload("Activities", func(db *gorm.DB) *gorm.DB { return db.Order("date, id") }).
+		Preload("Relationships", func(db *gorm.DB) *gorm.DB { return db.Order("id") }).
+		Preload("Reminders", func(db *gorm.DB) *gorm.DB { return db.Order("remind_at, id") }).
+		Preload("ContactMethods", func(db *gorm.DB) *gorm.DB { return db.Order("type, is_primary DESC, id") }).
+		Preload("CustomDates", func(db *gorm.DB) *gorm.DB { return db.Order("id") }).
+		First(&contact, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Contact not found"})
+		return
+	}
+
+	// Photos are files of this instance and cannot be transferred with the bundle
+	contact.Photo = ""
+	contact.PhotoThumbnail = ""
+
+	c.Header("Content-Disposition", `attachment; filename="`+contactFilename(contact, ".json")+`"`)
+	c.JSON(http.StatusOK, contactBundle{Version: contactBundleVersion, ExportedAt: time.Now().UTC(), Contact: contact})
+}
+
+// ImportContactBundle creates a new contact with all records of an exported bundle. The IDs of the bundle
+// are discarded and fresh ones are generated. Relationships are kept without their link to other contacts,
+// activities are only linked to the new contact.
+func ImportContactBundle(c *gin.Context, cfg *config.Config) {
+	db := c.MustGet("db").(*gorm.DB)
+
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxBundleImportSize)
+	var bundle contactBundle
+	if err := c.ShouldBindJSON(&bundle); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "File is too large"})
+			return
+		}
+		respondBindingError(c, err)
+		return
+	}
+	if bundle.Version != contactBundleVersion {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Unsupported bundle version " + strconv.Itoa(bundle.Version)})
+		return
+	}
+	if fieldErrors := bundleFieldErrors(bundle.Contact); len(fieldErrors) > 0 {
+		respondFieldErrors(c, fieldErrors)
+		return
+	}
+
+	imported := bundle.Contact
+	contact := models.Contact{
+		Firstname:            imported.Firstname,
+		Lastname:             imported.Lastname,
+		Nickname:             imported.Nickname,
+		Email:                imported.Email,
+		Phone:                imported.Phone,
+		Birthday:             imported.Birthday,
+		Deceased:             imported.Deceased,
+		DeceasedDate:         imported.DeceasedDate,
+		Favorite:             imported.Favorite,
+		Address:              imported.Address,
+		HowWeMet:             imported.HowWeMet,
+		FoodPreference:       imported.FoodPreference,
+		WorkInformation:      imported.WorkInformation,
+		ContactInformation:   imported.ContactInformation,
+		Circles:              normalizeNames(imported.Circles),
+		Tags:                 normalizeNames(imported.Tags),
+		ContactFrequencyDays: imported.ContactFrequencyDays,
+		ReminderLeadDays:     imported.ReminderLeadDays,
+		UserID:               currentUserID(c),
+	}
+	contact.Gender, _ = models.NormalizeGender(imported.Gender)
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&contact).Error; err != nil {
+			return err
+		}
+
+		for _, note := range imported.Notes {
+			if err := tx.Create(&models.Note{
+				Content:   services.SanitizeHTML(note.Content, cfg.HTMLSanitization),
+				Date:      note.Date,
+				Tags:      normalizeNames(note.Tags),
+				ContactID: &contact.ID,
+				UserID:    contact.UserID,
+			}).Error; err != nil {
+				return err
+			}
+		}
+
+		for _, activity := range imported.Activities {
+			created := models.Activity{
+				Title:        activity.Title,
+				Description:  services.SanitizeHTML(activity.Description, cfg.HTMLSanitization),
+				Location:     activity.Location,
+				ActivityType: activity.ActivityType,
+				Date:         activity.Date,
+				UserID:       contact.UserID,
+			}
+			if created.ActivityType == "" {
+				created.ActivityType = models.ActivityOther
+			}
+			if err := tx.Create(&created).Error; err != nil {
+				return err
+			}
+			if err := tx.Model(&created).Association("Contacts").Append(&contact); err != nil {
+				return err
+			}
+		}
+
+		for _, relationship := range imported.Relationships {
+			if err := tx.Create(&models.Relationship{
+				Name:      relationship.Name,
+				Type:      relationship.Type,
+				Gender:    relationship.Gender,
+				Birthday:  relationship.Birthday,
+				ContactID: contact.ID,
+			}).Error; err != nil {
+				return err
+			}
+		}
+
+		for _, reminder := range imported.Reminders {
+			created := models.Reminder{
+				Message:               reminder.Message,
+				ByMail:                reminder.ByMail,
+				RemindAt:              reminder.RemindAt,
+				Recurrence:            reminder.Recurrence,
+				RecurrenceInterval:    reminder.RecurrenceInterval,
+				ReocurrFromCompletion: reminder.ReocurrFromCompletion,
+				LastSent:              reminder.LastSent,
+				Completed:             reminder.Completed,
+				Status:                reminder.Status,
+				ContactID:             &contact.ID,
+			}
+			created.ScheduleNextDue()
+			if err := tx.Create(&created).Error; err != nil {
+				return err
+			}
+		}
+
+		for _, date := range imported.CustomDates {
+			if err := tx.Create(&models.CustomDate{
+				ContactID:        contact.ID,
+				Label:            strings.TrimSpace(date.Label),
+				Date:             date.Date,
+				Recurring:        date.Recurring,
+				ReminderLeadDays: date.ReminderLeadDays,
+			}).Error; err != nil {
+				return err
+			}
+		}
+
+		// The primary methods of the bundle replace the email and phone of the contact,
+		// which are only turned into methods for types the bundle has no methods of
+		importedTypes := map[string]bool{}
+		for _, method := range imported.ContactMethods {
+			if err := tx.Create(&models.ContactMethod{
+				ContactID: contact.ID,
+				Type:      method.Type,
+				Label:     method.Label,
+				Value:     method.Value,
+				IsPrimary: method.IsPrimary,
+			}).Error; err != nil {
+				return err
+			}
+			importedTypes[method.Type] = true
+		}
+		for methodType := range importedTypes {
+			if err := services.EnsurePrimaryContactMethod(tx, contact.ID, methodType); err != nil {
+				return err
+			}
+		}
+		if err := tx.First(&contact, contact.ID).Error; err != nil {
+			return err
+		}
+		return services.SyncLegacyContactMethods(tx, contact)
+	})
+	if err != nil {
+		middleware.Logger(c).Error("Failed to import contact bundle", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to import contact"})
+		return
+	}
+	recordAudit(c, db, contactAudit(contact, models.AuditCreate), nil, contact)
+
+	respondCreated(c, fmt.Sprintf("/contacts/%d", contact.ID), gin.H{"message": "Contact imported successfully", "contact": contact})
+}
+
+// bundleFieldErrors validates the contact of a bundle and its records, the fields of the records are
+// reported with their position like reminders[0].recurrence
+func bundleFieldErrors(contact models.Contact) map[string]fieldError {
+	fields := contactFieldErrors(contact)
+	for i, reminder := range contact.Reminders {
+		prefix := "reminders[" + strconv.Itoa(i) + "]."
+		if reminder.Message == "" {
+			fields[prefix+"message"] = fieldError{Rule: "required", Message: "This field is required", Value: reminder.Message}
+		}
+		for field, fieldErr := range reminderFieldErrors(reminder) {
+			fields[prefix+field] = fieldErr
+		}
+	}
+	for i, activity := range contact.Activities {
+		if activity.ActivityType != "" && !models.ValidActivityType(activity.ActivityType) {
+			fields["activities["+strconv.Itoa(i)+"].activity_type"] = fieldError{Rule: "oneof", Message: models.InvalidActivityTypeMessage(), Value: activity.ActivityType}
+		}
+	}
+	for i, method := range contact.ContactMethods {
+		prefix := "contact_methods[" + strconv.Itoa(i) + "]."
+		if method.Type != models.ContactMethodEmail && method.Type != models.ContactMethodPhone {
+			fields[prefix+"type"] = fieldError{Rule: "oneof", Message: "Must be one of email phone", Value: method.Type}
+			continue
+		}
+		for field, message := range method.Validate() {
+			fields[prefix+field] = fieldError{Rule: field, Message: message, Value: method.Value}
+		}
+	}
+	for i, date := range contact.CustomDates {
+		for field, fieldErr := range customDateFieldErrors(date) {
+			fields["custom_dates["+strconv.Itoa(i)+"]."+field] = fieldErr
+		}
+	}
+	return fields
+}
@@ -0,0 +1,115 @@
+package controllers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"perema/config"
+	"perema/models"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestContactBundleRoundTrip(t *testing.T) {
+	db, router := setupRouter()
+	router.GET("/contacts/:id/export", ExportContactBundle)
+	router.POST("/contacts/import", func(c *gin.Context) {
+		ImportContactBundle(c, &config.Config{})
+	})
+
+	alice := models.Contact{Firstname: "Alice", Lastname: "Doe", Email: "alice@example.com", Tags: []string{"VIP"},
+		Birthday: &models.Date{Time: time.Date(1990, 5, 4, 0, 0, 0, 0, time.UTC), Valid: true}, Photo: "uploads/alice.jpg"}
+	bob := models.Contact{Firstname: "Bob"}
+	db.Create(&alice)
+	db.Create(&bob)
+	db.Create(&models.ContactMethod{ContactID: alice.ID, Type: "email", Label: "home", Value: "alice@example.com", IsPrimary: true})
+	db.Create(&models.ContactMethod{ContactID: alice.ID, Type: "email", Label: "work", Value: "alice@work.example.com"})
+	db.Create(&models.Note{Content: "Likes tea", Date: time.Now(), ContactID: &alice.ID})
+	db.Create(&models.Relationship{Name: "Bob", Type: "Friend", ContactID: alice.ID, RelatedContactID: &bob.ID})
+	db.Create(&models.Reminder{Message: "Call Alice", RemindAt: time.Now().AddDate(0, 0, 1), Recurrence: "Monthly", ContactID: &alice.ID})
+	db.Create(&models.Activity{Title: "Lunch", Date: time.Now(), ActivityType: models.ActivityMeeting, Contacts: []models.Contact{alice, bob}})
+
+	req, _ := http.NewRequest("GET", "/contacts/"+strconv.Itoa(int(alice.ID))+"/export", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, `attachment; filename="alice_doe.json"`, w.Header().Get("Content-Disposition"))
+
+	var bundle contactBundle
+	json.Unmarshal(w.Body.Bytes(), &bundle)
+	assert.Equal(t, contactBundleVersion, bundle.Version)
+	assert.Empty(t, bundle.Contact.Photo)
+	assert.Len(t, bundle.Contact.Notes, 1)
+	assert.Len(t, bundle.Contact.Activities, 1)
+	assert.Len(t, bundle.Contact.ContactMethods, 2)
+
+	importBundle := func(body []byte) (int, models.Contact) {
+		req, _ := http.NewRequest("POST", "/contacts/import", bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		var responseBody struct {
+			Contact models.Contact `json:"contact"`
+		}
+		json.Unmarshal(w.Body.Bytes(), &responseBody)
+		return w.Code, responseBody.Contact
+	}
+
+	// The exported bundle is imported as a new contact with fresh IDs
+	code, imported := importBundle(w.Body.Bytes())
+	assert.Equal(t, http.StatusCreated, code)
+	assert.NotEqual(t, alice.ID, imported.ID)
+	assert.NotEqual(t, bob.ID, imported.ID)
+	assert.Equal(t, "Alice", imported.Firstname)
+	assert.Equal(t, "alice@example.com", imported.Email)
+	assert.Equal(t, []string{"VIP"}, imported.Tags)
+
+	var stored models.Contact
+	db.Preload("Notes").Preload("Activities.Contacts").Preload("Relationships").Preload("Reminders").Preload("ContactMethods").First(&stored, imported.ID)
+	assert.Len(t, stored.Notes, 1)
+	assert.Equal(t, "Likes tea", stored.Notes[0].Content)
+	assert.Len(t, stored.Reminders, 1)
+	assert.NotNil(t, stored.Reminders[0].NextDue)
+	assert.Len(t, stored.ContactMethods, 2)
+
+	// Links to other contacts of the exporting instance are dropped
+	assert.Len(t, stored.Relationships, 1)
+	assert.Equal(t, "Bob", stored.Relationships[0].Name)
+	assert.Nil(t, stored.Relationships[0].RelatedContactID)
+	assert.Len(t, stored.Activities, 1)
+	assert.Len(t, stored.Activities[0].Contacts, 1)
+	assert.Equal(t, imported.ID, stored.Activities[0].Contacts[0].ID)
+
+	// The original contact keeps all of its records
+	var original models.Contact
+	db.Preload("Notes").Preload("ContactMethods").First(&original, alice.ID)
+	assert.Len(t, original.Notes, 1)
+	assert.Len(t, original.ContactMethods, 2)
+
+	// Other versions and invalid records are rejected without creating anything
+	var contacts int64
+	db.Model(&models.Contact{}).Count(&contacts)
+	code, _ = importBundle([]byte(`{"version": 2, "contact": {"firstname": "Carol"}}`))
+	assert.Equal(t, http.StatusBadRequest, code)
+	code, _ = importBundle([]byte(`{"version": 1, "contact": {"firstname": "Carol", "reminders": [{"message": "Hi", "recurrence": "Sometimes"}]}}`))
+	assert.Equal(t, http.StatusBadRequest, code)
+	code, _ = importBundle([]byte(`{"version": 1, "contact": {"firstname": "Carol", "contact_methods": [{"type": "email", "value": "nope"}]}}`))
+	assert.Equal(t, http.StatusBadRequest, code)
+	var after int64
+	db.Model(&models.Contact{}).Count(&after)
+	assert.Equal(t, contacts, after)
+
+	// Contacts of other users cannot be exported
+	other := models.Contact{Firstname: "Mallory", UserID: 2}
+	db.Create(&other)
+	req, _ = http.NewRequest("GET", "/contacts/"+strconv.Itoa(int(other.ID))+"/export", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
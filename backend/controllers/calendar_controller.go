@@ -0,0 +1,85 @@
+package controllers
+
+import (
+	"net/http"
+	"perema/config"
+	"perema/middleware"
+	"perema/models"
+	"perema/services"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// Path of the calendar feed below the API, the token is passed as query parameter
+const calendarFeedPath = "/api/calendar.ics"
+
+// CreateCalendarToken generates a new token for the calendar feed of the authenticated user and returns the
+// URL to subscribe to. A previous token stops working. The token is only returned in this response.
+func CreateCalendarToken(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+
+	token, hash, err := services.GenerateCalendarToken()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate calendar token"})
+		return
+	}
+	if err := db.Model(&models.User{}).Where("id = ?", currentUserID(c)).Update("calendar_token_hash", hash).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save calendar token"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Calendar token created, it will not be shown again",
+		"token":   token,
+		"url":     calendarFeedPath + "?token=" + token,
+	})
+}
+
+// RevokeCalendarToken disables the calendar feed of the authenticated user
+func RevokeCalendarToken(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+
+	if err := db.Model(&models.User{}).Where("id = ?", currentUserID(c)).Update("calendar_token_hash", "").Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke calendar token"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Calendar token revoked"})
+}
+
+// GetCalendarFeed returns the birthdays of the living contacts and the open reminders of a user as iCalendar
+// feed. Calendar apps cannot send an Authorization header, so the user is identified by the token in the URL.
+func GetCalendarFeed(c *gin.Context, cfg *config.Config) {
+	db := c.MustGet("db").(*gorm.DB)
+
+	token := c.Query("token")
+	var user models.User
+	if token == "" || db.Where("calendar_token_hash = ?", services.HashAPIKey(token)).First(&user).Error != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid calendar token"})
+		return
+	}
+
+	var contacts []models.Contact
+	if err := db.Select("id", "firstname", "lastname", "birthday").
+		Where("user_id = ? AND birthday IS NOT NULL AND deceased = ?", user.ID, false).
+		Order("id").Find(&contacts).Error; err != nil {
+		middleware.Logger(c).Error("Failed to retrieve birthdays for calendar", "user_id", user.ID, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve birthdays"})
+		return
+	}
+
+	var reminders []models.Reminder
+	if err := db.Preload("Contact").
+		Where("contact_id IN (SELECT id FROM contacts WHERE user_id = ? AND deleted_at IS NULL)", user.ID).
+		Where("completed = ? AND status <> ?", false, models.ReminderDone).
+		Order("remind_at, id").Find(&reminders).Error; err != nil {
+		middleware.Logger(c).Error("Failed to retrieve reminders for calendar", "user_id", user.ID, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve reminders"})
+		return
+	}
+
+	c.Header("Content-Disposition", `inline; filename="perema.ics"`)
+	c.Data(http.StatusOK, "text/calendar; charset=utf-8", []byte(services.CalendarFeed(contacts, reminders, time.Now(), cfg.Timezone)))
+}
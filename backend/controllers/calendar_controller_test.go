@@ -0,0 +1,81 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"perema/config"
+	"perema/models"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCalendarFeed(t *testing.T) {
+	db, router := setupRouter()
+	router.GET("/api/calendar.ics", func(c *gin.Context) {
+		GetCalendarFeed(c, &config.Config{Timezone: time.UTC})
+	})
+	authenticated := router.Group("/")
+	authenticated.Use(func(c *gin.Context) {
+		c.Set("user_id", uint(1))
+		c.Next()
+	})
+	authenticated.POST("/calendar/token", CreateCalendarToken)
+	authenticated.DELETE("/calendar/token", RevokeCalendarToken)
+
+	send := func(method, url string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(method, url, nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	db.Create(&models.User{Username: "alice", Email: "alice@example.com"})
+	db.Create(&models.User{Username: "bob", Email: "bob@example.com"})
+	alice := models.Contact{Firstname: "Alice", UserID: 1, Birthday: &models.Date{Time: time.Date(1990, 5, 4, 0, 0, 0, 0, time.UTC), Valid: true}}
+	deceased := models.Contact{Firstname: "Dora", UserID: 1, Deceased: true, Birthday: &models.Date{Time: time.Date(1930, 1, 2, 0, 0, 0, 0, time.UTC), Valid: true}}
+	mallory := models.Contact{Firstname: "Mallory", UserID: 2, Birthday: &models.Date{Time: time.Date(1985, 7, 8, 0, 0, 0, 0, time.UTC), Valid: true}}
+	db.Create(&alice)
+	db.Create(&deceased)
+	db.Create(&mallory)
+	db.Create(&models.Reminder{Message: "Call Alice", RemindAt: time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC), Recurrence: "once", ContactID: &alice.ID})
+	db.Create(&models.Reminder{Message: "Done already", RemindAt: time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC), Recurrence: "once", ContactID: &alice.ID, Completed: true, Status: models.ReminderDone})
+	db.Create(&models.Reminder{Message: "Call Mallory", RemindAt: time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC), Recurrence: "once", ContactID: &mallory.ID})
+
+	// The feed is disabled until a token is created
+	assert.Equal(t, http.StatusUnauthorized, send("GET", "/api/calendar.ics").Code)
+	assert.Equal(t, http.StatusUnauthorized, send("GET", "/api/calendar.ics?token=").Code)
+
+	w := send("POST", "/calendar/token")
+	assert.Equal(t, http.StatusCreated, w.Code)
+	var created struct {
+		Token string `json:"token"`
+		URL   string `json:"url"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &created)
+	assert.NotEmpty(t, created.Token)
+	assert.Equal(t, "/api/calendar.ics?token="+url.QueryEscape(created.Token), created.URL)
+
+	w = send("GET", created.URL)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "text/calendar; charset=utf-8", w.Header().Get("Content-Type"))
+	feed := w.Body.String()
+	assert.Contains(t, feed, "SUMMARY:Birthday of Alice\r\n")
+	assert.Contains(t, feed, "SUMMARY:Call Alice\r\n")
+	assert.NotContains(t, feed, "Dora")
+	assert.NotContains(t, feed, "Done already")
+	assert.NotContains(t, feed, "Mallory")
+
+	// A new token replaces the previous one
+	previous := created.URL
+	json.Unmarshal(send("POST", "/calendar/token").Body.Bytes(), &created)
+	assert.Equal(t, http.StatusUnauthorized, send("GET", previous).Code)
+	assert.Equal(t, http.StatusOK, send("GET", created.URL).Code)
+
+	assert.Equal(t, http.StatusOK, send("DELETE", "/calendar/token").Code)
+	assert.Equal(t, http.StatusUnauthorized, send("GET", created.URL).Code)
+}
@@ -0,0 +1,380 @@
+package controllers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"io"
+	"net/http"
+	"path"
+	"perema/middleware"
+	"perema/models"
+	"perema/services"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// A single address book of all contacts of the user is served below the principal
+const (
+	cardDAVPrincipalPath   = "/carddav/"
+	cardDAVAddressBookPath = "/carddav/contacts/"
+)
+
+// Maximum size of a card sent by a client
+const maxCardSize = 1 << 20 // 1 MB
+
+// XML namespace of CardDAV (RFC 6352)
+const nsCardDAV = "urn:ietf:params:xml:ns:carddav"
+
+type davMultistatus struct {
+	XMLName   xml.Name      `xml:"DAV: multistatus"`
+	Responses []davResponse `xml:"response"`
+}
+
+type davResponse struct {
+	Href     string       `xml:"DAV: href"`
+	Propstat *davPropstat `xml:"DAV: propstat,omitempty"`
+	Status   string       `xml:"DAV: status,omitempty"` // Only set for resources which were not found
+}
+
+type davPropstat struct {
+	Prop   davProp `xml:"DAV: prop"`
+	Status string  `xml:"DAV: status"`
+}
+
+// davProp holds the properties of the principal, the address book and the cards, unset ones are left out
+type davProp struct {
+	ResourceType         *davResourceType       `xml:"DAV: resourcetype,omitempty"`
+	DisplayName          string                 `xml:"DAV: displayname,omitempty"`
+	CurrentUserPrincipal *davHref               `xml:"DAV: current-user-principal,omitempty"`
+	AddressBookHomeSet   *davHref               `xml:"urn:ietf:params:xml:ns:carddav addressbook-home-set,omitempty"`
+	SupportedReportSet   *davSupportedReportSet `xml:"DAV: supported-report-set,omitempty"`
+	CTag                 string                 `xml:"http://calendarserver.org/ns/ getctag,omitempty"`
+	ETag                 string                 `xml:"DAV: getetag,omitempty"`
+	ContentType          string                 `xml:"DAV: getcontenttype,omitempty"`
+	AddressData          string                 `xml:"urn:ietf:params:xml:ns:carddav address-data,omitempty"`
+}
+
+type davResourceType struct {
+	Collection  *struct{} `xml:"DAV: collection"`
+	AddressBook *struct{} `xml:"urn:ietf:params:xml:ns:carddav addressbook"`
+}
+
+type davHref struct {
+	Href string `xml:"DAV: href"`
+}
+
+type davSupportedReportSet struct {
+	Reports []davSupportedReport `xml:"DAV: supported-report"`
+}
+
+type davSupportedReport struct {
+	Report davReport `xml:"DAV: report"`
+}
+
+type davReport struct {
+	MultiGet *struct{} `xml:"urn:ietf:params:xml:ns:carddav addressbook-multiget"`
+	Query    *struct{} `xml:"urn:ietf:params:xml:ns:carddav addressbook-query"`
+}
+
+// cardDAVReportRequest is the body of a REPORT request, only multiget lists the requested cards
+type cardDAVReportRequest struct {
+	XMLName xml.Name
+	Hrefs   []string `xml:"DAV: href"`
+}
+
+// CardDAVOptions announces the supported methods and the address book capability
+func CardDAVOptions(c *gin.Context) {
+	c.Header("DAV", "1, 3, addressbook")
+	c.Header("Allow", "OPTIONS, GET, PUT, DELETE, PROPFIND, REPORT")
+	c.Status(http.StatusOK)
+}
+
+// CardDAVWellKnown redirects clients looking for the CardDAV server to the principal (RFC 6764)
+func CardDAVWellKnown(c *gin.Context) {
+	c.Redirect(http.StatusMovedPermanently, cardDAVPrincipalPath)
+}
+
+// PropfindCardDAVPrincipal describes the principal of the user, which is also the home of its address book
+func PropfindCardDAVPrincipal(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+
+	responses := []davResponse{davOK(cardDAVPrincipalPath, davProp{
+		ResourceType:         &davResourceType{Collection: &struct{}{}},
+		CurrentUserPrincipal: &davHref{Href: cardDAVPrincipalPath},
+		AddressBookHomeSet:   &davHref{Href: cardDAVPrincipalPath},
+	})}
+	if c.GetHeader("Depth") != "0" {
+		contacts, ok := cardDAVContacts(c, db)
+		if !ok {
+			return
+		}
+		responses = append(responses, davOK(cardDAVAddressBookPath, addressBookProp(contacts)))
+	}
+
+	respondMultistatus(c, responses)
+}
+
+// PropfindCardDAVAddressBook describes the address book and with depth 1 lists the ETags of all cards
+func PropfindCardDAVAddressBook(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+
+	contacts, ok := cardDAVContacts(c, db)
+	if !ok {
+		return
+	}
+
+	responses := []davResponse{davOK(cardDAVAddressBookPath, addressBookProp(contacts))}
+	if c.GetHeader("Depth") != "0" {
+		for _, contact := range contacts {
+			responses = append(responses, davOK(cardPath(contact), davProp{
+				ResourceType: &davResourceType{},
+				ETag:         cardETag(contact),
+				ContentType:  mimeVCard + "; charset=utf-8",
+			}))
+		}
+	}
+
+	respondMultistatus(c, responses)
+}
+
+// ReportCardDAVAddressBook returns the requested cards for addressbook-multiget and all cards for
+// addressbook-query, filters of the query are not supported and ignored
+func ReportCardDAVAddressBook(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+
+	var report cardDAVReportRequest
+	if err := xml.NewDecoder(io.LimitReader(c.Request.Body, maxCardSize)).Decode(&report); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid report"})
+		return
+	}
+	if report.XMLName.Space != nsCardDAV || (report.XMLName.Local != "addressbook-multiget" && report.XMLName.Local != "addressbook-query") {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Unsupported report"})
+		return
+	}
+
+	contacts, ok := cardDAVContacts(c, db)
+	if !ok {
+		return
+	}
+
+	responses := []davResponse{}
+	if report.XMLName.Local == "addressbook-query" {
+		for _, contact := range contacts {
+			responses = append(responses, cardResponse(contact))
+		}
+		respondMultistatus(c, responses)
+		return
+	}
+
+	byPath := map[string]models.Contact{}
+	for _, contact := range contacts {
+		byPath[cardPath(contact)] = contact
+	}
+	for _, href := range report.Hrefs {
+		if contact, found := byPath[path.Clean(strings.TrimSpace(href))]; found {
+			responses = append(responses, cardResponse(contact))
+		} else {
+			responses = append(responses, davResponse{Href: href, Status: "HTTP/1.1 404 Not Found"})
+		}
+	}
+	respondMultistatus(c, responses)
+}
+
+// GetCard returns a contact as vCard
+func GetCard(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+
+	contact, found := findCard(c, db)
+	if !found {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Card not found"})
+		return
+	}
+
+	c.Header("ETag", cardETag(contact))
+	c.Data(http.StatusOK, mimeVCard+"; charset=utf-8", []byte(services.ContactToVCard(contact)))
+}
+
+// PutCard updates the contact of a card or creates a new contact. Only the fields of the vCard mapping are
+// changed, all other fields of the contact are kept. New cards are stored under the ID of the created contact
+// regardless of the name chosen by the client, which picks up the new name on its next sync.
+func PutCard(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+
+	data, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, maxCardSize))
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "Card is too large"})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read card"})
+		return
+	}
+	cards := services.ParseVCards(string(data))
+	if len(cards) != 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Request must contain exactly one vCard"})
+		return
+	}
+	if cards[0].Err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": cards[0].Err.Error()})
+		return
+	}
+	card := cards[0].Contact
+	if fieldErrors := contactFieldErrors(card); len(fieldErrors) > 0 {
+		respondFieldErrors(c, fieldErrors)
+		return
+	}
+
+	contact, found := findCard(c, db)
+
+	// Clients send preconditions to avoid overwriting changes made by other clients
+	ifMatch, ifNoneMatch := c.GetHeader("If-Match"), c.GetHeader("If-None-Match")
+	if (found && ifNoneMatch == "*") || (ifMatch != "" && (!found || (ifMatch != "*" && ifMatch != cardETag(contact)))) {
+		c.JSON(http.StatusPreconditionFailed, gin.H{"error": "Card was changed in the meantime"})
+		return
+	}
+
+	if !found {
+		card.UserID = currentUserID(c)
+		if err := db.Create(&card).Error; err != nil {
+			middleware.Logger(c).Error("Failed to create contact from card", "error", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create contact"})
+			return
+		}
+		if err := services.SyncLegacyContactMethods(db, card); err != nil {
+			middleware.Logger(c).Error("Failed to save contact methods", "contact_id", card.ID, "error", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create contact"})
+			return
+		}
+		recordAudit(c, db, contactAudit(card, models.AuditCreate), nil, card)
+
+		c.Header("Location", cardPath(card))
+		c.Status(http.StatusCreated)
+		return
+	}
+
+	before := contact
+	contact.Firstname = card.Firstname
+	contact.Lastname = card.Lastname
+	contact.Nickname = card.Nickname
+	contact.Email = card.Email
+	contact.Phone = card.Phone
+	contact.Birthday = card.Birthday
+	contact.Address = card.Address
+	contact.Circles = card.Circles
+	contact.Version++
+
+	// Select writes cleared fields as well, the vCard is the complete state of the mapped fields
+	columns := append([]string{"firstname", "lastname", "nickname", "email", "phone", "birthday", "circles", "version"}, models.AddressColumns...)
+	if err := db.Model(&contact).Select(columns).Updates(&contact).Error; err != nil {
+		middleware.Logger(c).Error("Failed to update contact from card", "contact_id", contact.ID, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update contact"})
+		return
+	}
+	if err := services.SyncLegacyContactMethods(db, contact); err != nil {
+		middleware.Logger(c).Error("Failed to save contact methods", "contact_id", contact.ID, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update contact"})
+		return
+	}
+	recordAudit(c, db, contactAudit(contact, models.AuditUpdate), before, contact)
+
+	c.Status(http.StatusNoContent)
+}
+
+// DeleteCard moves the contact of a card to the trash
+func DeleteCard(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+
+	contact, found := findCard(c, db)
+	if !found {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Card not found"})
+		return
+	}
+	if ifMatch := c.GetHeader("If-Match"); ifMatch != "" && ifMatch != "*" && ifMatch != cardETag(contact) {
+		c.JSON(http.StatusPreconditionFailed, gin.H{"error": "Card was changed in the meantime"})
+		return
+	}
+
+	if err := trashContact(db, contact); err != nil {
+		middleware.Logger(c).Error("Failed to delete contact", "contact_id", contact.ID, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete contact"})
+		return
+	}
+	recordAudit(c, db, contactAudit(contact, models.AuditDelete), contact, nil)
+
+	c.Status(http.StatusNoContent)
+}
+
+// findCard returns the contact of the card named like 42.vcf in the URL
+func findCard(c *gin.Context, db *gorm.DB) (models.Contact, bool) {
+	var contact models.Contact
+	id, err := strconv.ParseUint(strings.TrimSuffix(c.Param("card"), ".vcf"), 10, 64)
+	if err != nil {
+		return contact, false
+	}
+	return contact, db.Scopes(ownContacts(c)).First(&contact, id).Error == nil
+}
+
+// cardDAVContacts loads all contacts of the user, responding with an error if that fails
+func cardDAVContacts(c *gin.Context, db *gorm.DB) ([]models.Contact, bool) {
+	var contacts []models.Contact
+	if err := db.Scopes(ownContacts(c)).Order("id").Find(&contacts).Error; err != nil {
+		middleware.Logger(c).Error("Failed to retrieve contacts for CardDAV", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve contacts"})
+		return nil, false
+	}
+	return contacts, true
+}
+
+func addressBookProp(contacts []models.Contact) davProp {
+	return davProp{
+		ResourceType: &davResourceType{Collection: &struct{}{}, AddressBook: &struct{}{}},
+		DisplayName:  "perema",
+		SupportedReportSet: &davSupportedReportSet{Reports: []davSupportedReport{
+			{Report: davReport{MultiGet: &struct{}{}}},
+			{Report: davReport{Query: &struct{}{}}},
+		}},
+		CTag: addressBookCTag(contacts),
+	}
+}
+
+func cardResponse(contact models.Contact) davResponse {
+	return davOK(cardPath(contact), davProp{ETag: cardETag(contact), AddressData: services.ContactToVCard(contact)})
+}
+
+func davOK(href string, prop davProp) davResponse {
+	return davResponse{Href: href, Propstat: &davPropstat{Prop: prop, Status: "HTTP/1.1 200 OK"}}
+}
+
+func respondMultistatus(c *gin.Context, responses []davResponse) {
+	body, err := xml.Marshal(davMultistatus{Responses: responses})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to encode response"})
+		return
+	}
+	c.Data(http.StatusMultiStatus, "application/xml; charset=utf-8", append([]byte(xml.Header), body...))
+}
+
+func cardPath(contact models.Contact) string {
+	return cardDAVAddressBookPath + strconv.Itoa(int(contact.ID)) + ".vcf"
+}
+
+// cardETag is derived from the vCard, so it changes exactly when the card seen by clients changes
+func cardETag(contact models.Contact) string {
+	hash := sha256.Sum256([]byte(services.ContactToVCard(contact)))
+	return `"` + hex.EncodeToString(hash[:8]) + `"`
+}
+
+// addressBookCTag changes whenever a card is added, changed or removed, so clients can skip unchanged address books
+func addressBookCTag(contacts []models.Contact) string {
+	hash := sha256.New()
+	for _, contact := range contacts {
+		hash.Write([]byte(cardPath(contact) + cardETag(contact)))
+	}
+	return hex.EncodeToString(hash.Sum(nil)[:8])
+}
@@ -0,0 +1,111 @@
+package controllers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"perema/middleware"
+	"perema/models"
+	"perema/services"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCardDAV(t *testing.T) {
+	db, router := setupRouter()
+	carddav := router.Group("/carddav")
+	carddav.Use(middleware.CardDAVAuth())
+	carddav.Handle("PROPFIND", "/", PropfindCardDAVPrincipal)
+	carddav.Handle("PROPFIND", "/contacts/", PropfindCardDAVAddressBook)
+	carddav.Handle("REPORT", "/contacts/", ReportCardDAVAddressBook)
+	carddav.GET("/contacts/:card", GetCard)
+	carddav.PUT("/contacts/:card", PutCard)
+	carddav.DELETE("/contacts/:card", DeleteCard)
+
+	key, hash, _ := services.GenerateAPIKey()
+	db.Create(&models.APIKey{UserID: 1, Name: "Phone", KeyHash: hash})
+
+	send := func(method, url, body string, headers map[string]string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(method, url, strings.NewReader(body))
+		req.SetBasicAuth("alice", key)
+		for name, value := range headers {
+			req.Header.Set(name, value)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	alice := models.Contact{Firstname: "Alice", Lastname: "Doe", Email: "alice@example.com", HowWeMet: "School", UserID: 1}
+	mallory := models.Contact{Firstname: "Mallory", UserID: 2}
+	db.Create(&alice)
+	db.Create(&mallory)
+	alicePath := "/carddav/contacts/" + strconv.Itoa(int(alice.ID)) + ".vcf"
+	malloryPath := "/carddav/contacts/" + strconv.Itoa(int(mallory.ID)) + ".vcf"
+
+	// Clients are challenged for basic authentication with an API key
+	req, _ := http.NewRequest("PROPFIND", "/carddav/", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Contains(t, w.Header().Get("WWW-Authenticate"), "Basic")
+
+	w = send("PROPFIND", "/carddav/", "", map[string]string{"Depth": "0"})
+	assert.Equal(t, http.StatusMultiStatus, w.Code)
+	assert.Contains(t, w.Body.String(), "addressbook-home-set")
+
+	// The address book lists the cards of the user only
+	w = send("PROPFIND", "/carddav/contacts/", "", map[string]string{"Depth": "1"})
+	assert.Equal(t, http.StatusMultiStatus, w.Code)
+	assert.Contains(t, w.Body.String(), "getctag")
+	assert.Contains(t, w.Body.String(), "<href xmlns=\"DAV:\">"+alicePath+"</href>")
+	assert.NotContains(t, w.Body.String(), malloryPath)
+	ctag := w.Body.String()
+
+	multiget := `<?xml version="1.0"?><C:addressbook-multiget xmlns:D="DAV:" xmlns:C="urn:ietf:params:xml:ns:carddav">
+		<D:prop><D:getetag/><C:address-data/></D:prop><D:href>` + alicePath + `</D:href><D:href>` + malloryPath + `</D:href></C:addressbook-multiget>`
+	w = send("REPORT", "/carddav/contacts/", multiget, nil)
+	assert.Equal(t, http.StatusMultiStatus, w.Code)
+	assert.Contains(t, w.Body.String(), "FN:Alice Doe")
+	assert.NotContains(t, w.Body.String(), "Mallory")
+	assert.Contains(t, w.Body.String(), "404 Not Found")
+
+	w = send("GET", alicePath, "", nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "UID:perema-contact-"+strconv.Itoa(int(alice.ID)))
+	etag := w.Header().Get("ETag")
+	assert.NotEmpty(t, etag)
+	assert.Equal(t, http.StatusNotFound, send("GET", malloryPath, "", nil).Code)
+
+	// Changes of the mapped fields are saved, other fields are kept
+	card := "BEGIN:VCARD\r\nVERSION:3.0\r\nN:Smith;Alice;;;\r\nEMAIL:alice@smith.example.com\r\nCATEGORIES:Friends\r\nEND:VCARD\r\n"
+	assert.Equal(t, http.StatusPreconditionFailed, send("PUT", alicePath, card, map[string]string{"If-Match": `"outdated"`}).Code)
+	assert.Equal(t, http.StatusNoContent, send("PUT", alicePath, card, map[string]string{"If-Match": etag}).Code)
+	var updated models.Contact
+	db.First(&updated, alice.ID)
+	assert.Equal(t, "Smith", updated.Lastname)
+	assert.Equal(t, "alice@smith.example.com", updated.Email)
+	assert.Equal(t, []string{"Friends"}, updated.Circles)
+	assert.Equal(t, "School", updated.HowWeMet)
+	assert.NotEqual(t, etag, send("GET", alicePath, "", nil).Header().Get("ETag"))
+	assert.NotEqual(t, ctag, send("PROPFIND", "/carddav/contacts/", "", map[string]string{"Depth": "1"}).Body.String())
+
+	// Cards of other users cannot be overwritten, a new contact is created instead
+	w = send("PUT", "/carddav/contacts/new-card.vcf", "BEGIN:VCARD\r\nVERSION:3.0\r\nFN:Bob Miller\r\nEND:VCARD\r\n", map[string]string{"If-None-Match": "*"})
+	assert.Equal(t, http.StatusCreated, w.Code)
+	var bob models.Contact
+	db.Where("firstname = ?", "Bob").First(&bob)
+	assert.Equal(t, uint(1), bob.UserID)
+	assert.Equal(t, "/carddav/contacts/"+strconv.Itoa(int(bob.ID))+".vcf", w.Header().Get("Location"))
+	assert.Equal(t, http.StatusBadRequest, send("PUT", "/carddav/contacts/x.vcf", "BEGIN:VCARD\r\nEND:VCARD\r\n", nil).Code)
+
+	// Deleted cards are moved to the trash
+	assert.Equal(t, http.StatusNoContent, send("DELETE", alicePath, "", nil).Code)
+	assert.Equal(t, http.StatusNotFound, send("GET", alicePath, "", nil).Code)
+	var trashed models.Contact
+	assert.NoError(t, db.Unscoped().First(&trashed, alice.ID).Error)
+	assert.True(t, trashed.DeletedAt.Valid)
+	assert.Equal(t, http.StatusNotFound, send("DELETE", malloryPath, "", nil).Code)
+}
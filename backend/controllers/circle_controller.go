@@ -0,0 +1,337 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"perema/middleware"
+	"perema/models"
+	"perema/services"
+	"slices"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+var errCirclePlanChanged = errors.New("circle merge plan changed")
+
+// circleMergePlan describes the effect of merging one circle into another
+type circleMergePlan struct {
+	From               string `json:"from"`
+	To                 string `json:"to"`
+	AffectedCount      int    `json:"affected_count"`
+	AffectedContactIDs []uint `json:"affected_contact_ids"` // Contacts currently in the source circle
+	NoOpContactIDs     []uint `json:"noop_contact_ids"`     // Contacts already in both circles, only the source is dropped
+	NoOp               bool   `json:"noop"`                 // True if the merge would not change anything
+}
+
+type circleMergeRequest struct {
+	From       string `json:"from" binding:"required"`
+	To         string `json:"to" binding:"required"`
+	ContactIDs []uint `json:"contact_ids"` // Affected contact IDs as returned by the preview
+}
+
+// planCircleMerge computes which contacts of the user are affected by merging circle "from" into circle "to"
+func planCircleMerge(db *gorm.DB, userID uint, from, to string) (circleMergePlan, error) {
+	plan := circleMergePlan{From: from, To: to, AffectedContactIDs: []uint{}, NoOpContactIDs: []uint{}}
+
+	var contacts []models.Contact
+	if err := db.Select("ID", "Circles").
+		Where("user_id = ?", userID).
+		Where(services.Dialect(db).JSONContains("contacts.circles"), from).
+		Order("id").
+		Find(&contacts).Error; err != nil {
+		return plan, err
+	}
+
+	for _, contact := range contacts {
+		plan.AffectedContactIDs = append(plan.AffectedContactIDs, contact.ID)
+		if slices.Contains(contact.Circles, to) {
+			plan.NoOpContactIDs = append(plan.NoOpContactIDs, contact.ID)
+		}
+	}
+	plan.AffectedCount = len(plan.AffectedContactIDs)
+	plan.NoOp = from == to || plan.AffectedCount == 0
+
+	return plan, nil
+}
+
+// renameCircle replaces the circle "from" with "to" in all contacts of the user using the JSON functions of the database.
+// Each circle is kept only once, so renaming into an existing circle merges both. Returns the number of changed contacts.
+func renameCircle(db *gorm.DB, userID uint, from, to string) (int64, error) {
+	if from == to {
+		return 0, nil
+	}
+
+	dialect := services.Dialect(db)
+	result := db.Model(&models.Contact{}).
+		Where("user_id = ?", userID).
+		Where(dialect.JSONContains("contacts.circles"), from).
+		Update("circles", gorm.Expr(`(SELECT `+dialect.JSONGroupArray("value")+` FROM (
+			SELECT CASE WHEN json_each.value = ? THEN ? ELSE json_each.value END AS value, MIN(json_each.key) AS position
+			FROM `+dialect.JSONEach("contacts.circles")+` GROUP BY 1 ORDER BY position) AS elements)`, from, to))
+	return result.RowsAffected, result.Error
+}
+
+// removeCircle drops the circle from all contacts of the user. Returns the number of changed contacts.
+func removeCircle(db *gorm.DB, userID uint, name string) (int64, error) {
+	dialect := services.Dialect(db)
+	result := db.Model(&models.Contact{}).
+		Where("user_id = ?", userID).
+		Where(dialect.JSONContains("contacts.circles"), name).
+		Update("circles", gorm.Expr(`(SELECT `+dialect.JSONGroupArray("value")+` FROM (
+			SELECT json_each.value AS value FROM `+dialect.JSONEach("contacts.circles")+` WHERE json_each.value <> ? ORDER BY json_each.key) AS elements)`, name))
+	return result.RowsAffected, result.Error
+}
+
+func bindCircleMergeRequest(c *gin.Context) (circleMergeRequest, bool) {
+	var request circleMergeRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return request, false
+	}
+	request.From = strings.TrimSpace(request.From)
+	request.To = strings.TrimSpace(request.To)
+	if request.From == "" || request.To == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Circle names must not be empty"})
+		return request, false
+	}
+	return request, true
+}
+
+// PreviewCircleMerge reports which contacts would be affected by merging two circles without changing anything
+func PreviewCircleMerge(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+
+	request, ok := bindCircleMergeRequest(c)
+	if !ok {
+		return
+	}
+
+	plan, err := planCircleMerge(db, currentUserID(c), request.From, request.To)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to plan circle merge"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"plan": plan})
+}
+
+// MergeCircles merges one circle into another across all contacts.
+// If contact_ids from a previous preview are sent, the merge is only executed if the plan is still the same.
+func MergeCircles(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+
+	request, ok := bindCircleMergeRequest(c)
+	if !ok {
+		return
+	}
+
+	var plan circleMergePlan
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		plan, err = planCircleMerge(tx, currentUserID(c), request.From, request.To)
+		if err != nil {
+			return err
+		}
+
+		if request.ContactIDs != nil && !slices.Equal(sortedIDs(request.ContactIDs), plan.AffectedContactIDs) {
+			return errCirclePlanChanged
+		}
+
+		if plan.NoOp {
+			return nil
+		}
+
+		_, err = renameCircle(tx, currentUserID(c), request.From, request.To)
+		return err
+	})
+
+	if errors.Is(err, errCirclePlanChanged) {
+		c.JSON(http.StatusConflict, gin.H{"error": "Circle membership changed since the preview", "plan": plan})
+		return
+	}
+	if err != nil {
+		middleware.Logger(c).Error("Failed to merge circles", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to merge circles"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Circles merged successfully", "plan": plan})
+}
+
+func sortedIDs(ids []uint) []uint {
+	sorted := slices.Clone(ids)
+	slices.Sort(sorted)
+	return slices.Compact(sorted)
+}
+
+// circleRenameResult is the outcome of a single mapping of a batch rename
+type circleRenameResult struct {
+	From          string `json:"from"`
+	To            string `json:"to"`
+	AffectedCount int64  `json:"affected_count"`
+}
+
+// RenameCircles applies a map of old to new circle names across all contacts in a single transaction.
+// Mappings are applied in alphabetical order of the old names.
+func RenameCircles(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+
+	var mappings map[string]string
+	if err := c.ShouldBindJSON(&mappings); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if len(mappings) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "No circle mappings given"})
+		return
+	}
+
+	froms := make([]string, 0, len(mappings))
+	for from, to := range mappings {
+		if strings.TrimSpace(from) == "" || strings.TrimSpace(to) == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Circle names must not be empty"})
+			return
+		}
+		froms = append(froms, from)
+	}
+	slices.Sort(froms)
+
+	results := make([]circleRenameResult, 0, len(froms))
+	err := db.Transaction(func(tx *gorm.DB) error {
+		for _, from := range froms {
+			to := strings.TrimSpace(mappings[from])
+			affected, err := renameCircle(tx, currentUserID(c), from, to)
+			if err != nil {
+				return err
+			}
+			results = append(results, circleRenameResult{From: from, To: to, AffectedCount: affected})
+		}
+		return nil
+	})
+	if err != nil {
+		middleware.Logger(c).Error("Failed to rename circles", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to rename circles"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Circles renamed successfully", "results": results})
+}
+
+// RenameCircle renames a single circle across all contacts
+func RenameCircle(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+
+	var request struct {
+		Name string `json:"name" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	name := strings.TrimSpace(request.Name)
+	if name == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Circle names must not be empty"})
+		return
+	}
+
+	var affected int64
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		affected, err = renameCircle(tx, currentUserID(c), c.Param("name"), name)
+		return err
+	})
+	if err != nil {
+		middleware.Logger(c).Error("Failed to rename circle", "circle", c.Param("name"), "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to rename circle"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Circle renamed successfully", "affected_count": affected})
+}
+
+// DeleteCircle removes a circle from all contacts
+func DeleteCircle(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+
+	var affected int64
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		affected, err = removeCircle(tx, currentUserID(c), c.Param("name"))
+		return err
+	})
+	if err != nil {
+		middleware.Logger(c).Error("Failed to delete circle", "circle", c.Param("name"), "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete circle"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Circle deleted", "affected_count": affected})
+}
+
+// BulkUpdateCircles adds and removes circles for several contacts in a single transaction.
+// Unknown IDs are reported as failed without affecting the others.
+func BulkUpdateCircles(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+
+	var request struct {
+		bulkContactsRequest
+		Add    []string `json:"add"`
+		Remove []string `json:"remove"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		respondBindingError(c, err)
+		return
+	}
+	add, remove := normalizeNames(request.Add), normalizeNames(request.Remove)
+	if len(add) == 0 && len(remove) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "No circles to add or remove"})
+		return
+	}
+	for _, circle := range add {
+		if slices.Contains(remove, circle) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Circle " + circle + " cannot be added and removed at once"})
+			return
+		}
+	}
+
+	contacts, results, err := findBulkContacts(c, db, request.IDs)
+	var updated []models.Contact
+	if err == nil {
+		err = db.Transaction(func(tx *gorm.DB) error {
+			for _, contact := range contacts {
+				circles := slices.DeleteFunc(slices.Clone(contact.Circles), func(circle string) bool {
+					return slices.Contains(remove, circle)
+				})
+				for _, circle := range add {
+					if !slices.Contains(circles, circle) {
+						circles = append(circles, circle)
+					}
+				}
+				if slices.Equal(circles, contact.Circles) {
+					continue
+				}
+
+				changed := contact
+				changed.Circles = circles
+				changed.Version++
+				if err := tx.Model(&changed).Select("Circles", "Version").Updates(&changed).Error; err != nil {
+					return err
+				}
+				updated = append(updated, changed)
+			}
+			return nil
+		})
+	}
+	if err != nil {
+		middleware.Logger(c).Error("Failed to update circles", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update circles"})
+		return
+	}
+	for _, contact := range updated {
+		recordAudit(c, db, contactAudit(contact, models.AuditUpdate), contacts[contact.ID], contact)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"results": results, "updated": len(updated)})
+}
@@ -0,0 +1,303 @@
+package controllers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"perema/config"
+	"perema/models"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPreviewCircleMerge(t *testing.T) {
+	db, router := setupRouter()
+	router.POST("/circles/merge/preview", PreviewCircleMerge)
+
+	contacts := []models.Contact{
+		{Firstname: "Alice", Circles: []string{"friends"}},
+		{Firstname: "Bob", Circles: []string{"friends", "Friends"}},
+		{Firstname: "Carol", Circles: []string{"Friends", "Work"}},
+	}
+	for i := range contacts {
+		db.Create(&contacts[i])
+	}
+
+	jsonValue, _ := json.Marshal(map[string]string{"from": "friends", "to": "Friends"})
+	req, _ := http.NewRequest("POST", "/circles/merge/preview", bytes.NewBuffer(jsonValue))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var responseBody struct {
+		Plan circleMergePlan `json:"plan"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &responseBody)
+	assert.Equal(t, 2, responseBody.Plan.AffectedCount)
+	assert.Equal(t, []uint{contacts[0].ID, contacts[1].ID}, responseBody.Plan.AffectedContactIDs)
+	assert.Equal(t, []uint{contacts[1].ID}, responseBody.Plan.NoOpContactIDs)
+	assert.False(t, responseBody.Plan.NoOp)
+
+	// Nothing must have changed
+	var bob models.Contact
+	db.First(&bob, contacts[1].ID)
+	assert.Equal(t, []string{"friends", "Friends"}, bob.Circles)
+}
+
+func TestMergeCircles(t *testing.T) {
+	db, router := setupRouter()
+	router.POST("/circles/merge", MergeCircles)
+
+	contacts := []models.Contact{
+		{Firstname: "Alice", Circles: []string{"friends"}},
+		{Firstname: "Bob", Circles: []string{"friends", "Friends"}},
+		{Firstname: "Carol", Circles: []string{"Work"}},
+	}
+	for i := range contacts {
+		db.Create(&contacts[i])
+	}
+
+	// A stale preview must be rejected
+	jsonValue, _ := json.Marshal(map[string]any{"from": "friends", "to": "Friends", "contact_ids": []uint{contacts[0].ID}})
+	req, _ := http.NewRequest("POST", "/circles/merge", bytes.NewBuffer(jsonValue))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	// Committing the current plan merges the circles
+	jsonValue, _ = json.Marshal(map[string]any{"from": "friends", "to": "Friends", "contact_ids": []uint{contacts[1].ID, contacts[0].ID}})
+	req, _ = http.NewRequest("POST", "/circles/merge", bytes.NewBuffer(jsonValue))
+	req.Header.Set("Content-Type", "application/json")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var alice, bob, carol models.Contact
+	db.First(&alice, contacts[0].ID)
+	db.First(&bob, contacts[1].ID)
+	db.First(&carol, contacts[2].ID)
+	assert.Equal(t, []string{"Friends"}, alice.Circles)
+	assert.Equal(t, []string{"Friends"}, bob.Circles)
+	assert.Equal(t, []string{"Work"}, carol.Circles)
+}
+
+func TestRenameCircles(t *testing.T) {
+	db, router := setupRouter()
+	router.POST("/circles/rename", RenameCircles)
+
+	contacts := []models.Contact{
+		{Firstname: "Alice", Circles: []string{"frnds", "wrk"}},
+		{Firstname: "Bob", Circles: []string{"Work", "wrk", "Family"}},
+		{Firstname: "Carol", Circles: []string{"Family"}},
+	}
+	for i := range contacts {
+		db.Create(&contacts[i])
+	}
+
+	jsonValue, _ := json.Marshal(map[string]string{"frnds": "Friends", "wrk": "Work"})
+	req, _ := http.NewRequest("POST", "/circles/rename", bytes.NewBuffer(jsonValue))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var responseBody struct {
+		Results []circleRenameResult `json:"results"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &responseBody)
+	assert.Equal(t, []circleRenameResult{
+		{From: "frnds", To: "Friends", AffectedCount: 1},
+		{From: "wrk", To: "Work", AffectedCount: 2},
+	}, responseBody.Results)
+
+	var alice, bob, carol models.Contact
+	db.First(&alice, contacts[0].ID)
+	db.First(&bob, contacts[1].ID)
+	db.First(&carol, contacts[2].ID)
+	assert.Equal(t, []string{"Friends", "Work"}, alice.Circles)
+	assert.Equal(t, []string{"Work", "Family"}, bob.Circles) // Merged on collision without duplicate
+	assert.Equal(t, []string{"Family"}, carol.Circles)
+}
+
+func TestRenameAndDeleteCircle(t *testing.T) {
+	db, router := setupRouter()
+	router.PUT("/circles/:name", RenameCircle)
+	router.DELETE("/circles/:name", DeleteCircle)
+
+	contacts := []models.Contact{
+		{Firstname: "Alice", Circles: []string{"Collegues", "Sports"}},
+		{Firstname: "Bob", Circles: []string{"Colleagues", "Collegues"}},
+		{Firstname: "Carol", Circles: []string{"Family"}},
+	}
+	for i := range contacts {
+		db.Create(&contacts[i])
+	}
+
+	var responseBody struct {
+		AffectedCount int64 `json:"affected_count"`
+	}
+
+	jsonValue, _ := json.Marshal(map[string]string{"name": "Colleagues"})
+	req, _ := http.NewRequest("PUT", "/circles/Collegues", bytes.NewBuffer(jsonValue))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	json.Unmarshal(w.Body.Bytes(), &responseBody)
+	assert.Equal(t, int64(2), responseBody.AffectedCount)
+
+	var alice, bob models.Contact
+	db.First(&alice, contacts[0].ID)
+	db.First(&bob, contacts[1].ID)
+	assert.Equal(t, []string{"Colleagues", "Sports"}, alice.Circles)
+	assert.Equal(t, []string{"Colleagues"}, bob.Circles)
+
+	req, _ = http.NewRequest("DELETE", "/circles/Colleagues", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	json.Unmarshal(w.Body.Bytes(), &responseBody)
+	assert.Equal(t, int64(2), responseBody.AffectedCount)
+
+	db.First(&alice, contacts[0].ID)
+	db.First(&bob, contacts[1].ID)
+	assert.Equal(t, []string{"Sports"}, alice.Circles)
+	assert.Equal(t, []string{}, bob.Circles)
+
+	// An empty new name is rejected
+	jsonValue, _ = json.Marshal(map[string]string{"name": " "})
+	req, _ = http.NewRequest("PUT", "/circles/Family", bytes.NewBuffer(jsonValue))
+	req.Header.Set("Content-Type", "application/json")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestGetCircleContacts(t *testing.T) {
+	db, router := setupRouter()
+	router.GET("/circles/:name/contacts", func(c *gin.Context) {
+		GetCircleContacts(c, &config.Config{DefaultPageLimit: 25, MaxPageLimit: 100})
+	})
+
+	contacts := []models.Contact{
+		{Firstname: "Alice", Circles: []string{"Friends"}},
+		{Firstname: "Bob", Circles: []string{"Close Friends"}},
+		{Firstname: "Carol", Circles: []string{"Work", "Friends"}},
+		{Firstname: "Dave", Circles: []string{"Friends"}},
+	}
+	for i := range contacts {
+		db.Create(&contacts[i])
+	}
+	db.Create(&models.Note{Content: "Hello", Date: time.Now(), ContactID: &contacts[0].ID})
+
+	var responseBody struct {
+		Contacts []map[string]any `json:"contacts"`
+		Total    int64            `json:"total"`
+	}
+
+	// "Close Friends" does not match "Friends"
+	req, _ := http.NewRequest("GET", "/circles/Friends/contacts?limit=2&fields=ID,firstname&includes=notes", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	json.Unmarshal(w.Body.Bytes(), &responseBody)
+	assert.Equal(t, int64(3), responseBody.Total)
+	assert.Len(t, responseBody.Contacts, 2)
+	assert.Equal(t, "Alice", responseBody.Contacts[0]["firstname"])
+	assert.Len(t, responseBody.Contacts[0]["notes"], 1)
+	assert.NotContains(t, responseBody.Contacts[0], "circles")
+
+	req, _ = http.NewRequest("GET", "/circles/Close%20Friends/contacts", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	responseBody.Contacts = nil
+	json.Unmarshal(w.Body.Bytes(), &responseBody)
+	assert.Equal(t, int64(1), responseBody.Total)
+	assert.Equal(t, "Bob", responseBody.Contacts[0]["firstname"])
+}
+
+func TestBulkUpdateCircles(t *testing.T) {
+	db, router := setupRouter()
+	router.POST("/contacts/bulk/circles", BulkUpdateCircles)
+
+	contacts := []models.Contact{
+		{Firstname: "Alice", Circles: []string{"Friends", "School"}},
+		{Firstname: "Bob", Circles: []string{"Work"}},
+		{Firstname: "Carol", Circles: []string{"Family"}},
+		{Firstname: "Dave", Circles: []string{"School"}, UserID: 2},
+	}
+	for i := range contacts {
+		db.Create(&contacts[i])
+	}
+
+	send := func(body map[string]any) (int, []bulkResult, int) {
+		jsonValue, _ := json.Marshal(body)
+		req, _ := http.NewRequest("POST", "/contacts/bulk/circles", bytes.NewBuffer(jsonValue))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		var responseBody struct {
+			Results []bulkResult `json:"results"`
+			Updated int          `json:"updated"`
+		}
+		json.Unmarshal(w.Body.Bytes(), &responseBody)
+		return w.Code, responseBody.Results, responseBody.Updated
+	}
+
+	code, results, updated := send(map[string]any{
+		"ids":    []uint{contacts[1].ID, contacts[0].ID, contacts[3].ID, 999},
+		"add":    []string{" Work ", "Book club"},
+		"remove": []string{"School"},
+	})
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, 2, updated)
+	assert.Equal(t, []bulkResult{
+		{ID: contacts[0].ID, Success: true},
+		{ID: contacts[1].ID, Success: true},
+		{ID: contacts[3].ID, Error: "Contact not found"},
+		{ID: 999, Error: "Contact not found"},
+	}, results)
+
+	var alice, bob, carol, dave models.Contact
+	db.First(&alice, contacts[0].ID)
+	db.First(&bob, contacts[1].ID)
+	db.First(&carol, contacts[2].ID)
+	db.First(&dave, contacts[3].ID)
+	assert.Equal(t, []string{"Friends", "Work", "Book club"}, alice.Circles)
+	assert.Equal(t, uint(2), alice.Version)
+	assert.Equal(t, []string{"Work", "Book club"}, bob.Circles)
+	assert.Equal(t, []string{"Family"}, carol.Circles)
+	assert.Equal(t, []string{"School"}, dave.Circles)
+
+	var history int64
+	db.Model(&models.AuditLog{}).Where("contact_id = ?", alice.ID).Count(&history)
+	assert.Equal(t, int64(1), history)
+
+	// Contacts which already have the circles are not changed
+	code, _, updated = send(map[string]any{"ids": []uint{alice.ID, bob.ID}, "add": []string{"Work"}})
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, 0, updated)
+
+	code, _, _ = send(map[string]any{"ids": []uint{alice.ID}})
+	assert.Equal(t, http.StatusBadRequest, code)
+	code, _, _ = send(map[string]any{"ids": []uint{alice.ID}, "add": []string{"Work"}, "remove": []string{"Work"}})
+	assert.Equal(t, http.StatusBadRequest, code)
+	code, _, _ = send(map[string]any{"ids": []uint{}, "add": []string{"Work"}})
+	assert.Equal(t, http.StatusBadRequest, code)
+}
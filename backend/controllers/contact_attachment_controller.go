@@ -0,0 +1,159 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"perema/config"
+	"perema/middleware"
+	"perema/models"
+	"time"
+
+	"github.com/gabriel-vasile/mimetype"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// AddAttachmentToContact stores an uploaded file and attaches it to the contact
+func AddAttachmentToContact(c *gin.Context, cfg *config.Config) {
+	db := c.MustGet("db").(*gorm.DB)
+
+	var contact models.Contact
+	if err := db.Scopes(ownContacts(c)).First(&contact, c.Param("id")).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Contact not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	// Stop reading oversized uploads early instead of buffering them to disk, leaving room for the form fields
+	maxSize := int64(cfg.MaxAttachmentSizeMB) << 20
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxSize+1<<20)
+	file, err := c.FormFile("file")
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) || (err == nil && file.Size > maxSize) {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "File is too large"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "No file uploaded"})
+		return
+	}
+
+	src, err := file.Open()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read file"})
+		return
+	}
+	defer src.Close()
+
+	// Detect the content type from the file content instead of trusting the client
+	detected, err := mimetype.DetectReader(src)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read file"})
+		return
+	}
+
+	uploadDir := attachmentDir()
+	if err := os.MkdirAll(uploadDir, os.ModePerm); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create upload directory"})
+		return
+	}
+
+	attachment := models.ContactAttachment{
+		ContactID:   contact.ID,
+		Filename:    filepath.Base(file.Filename),
+		StoredName:  uuid.New().String() + "_attachment" + detected.Extension(),
+		ContentType: detected.String(),
+		Size:        file.Size,
+		UploadedAt:  time.Now(),
+	}
+	if err := c.SaveUploadedFile(file, filepath.Join(uploadDir, attachment.StoredName)); err != nil {
+		middleware.Logger(c).Error("Failed to save attachment", "contact_id", contact.ID, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save file"})
+		return
+	}
+
+	if err := db.Create(&attachment).Error; err != nil {
+		os.Remove(filepath.Join(uploadDir, attachment.StoredName))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save attachment"})
+		return
+	}
+
+	respondCreated(c, fmt.Sprintf("/contacts/%d/attachments/%d", attachment.ContactID, attachment.ID), gin.H{"message": "Attachment added successfully", "attachment": attachment})
+}
+
+// GetContactAttachments lists the files attached to a contact, newest first
+func GetContactAttachments(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+
+	var contact models.Contact
+	if err := db.Scopes(ownContacts(c)).First(&contact, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Contact not found"})
+		return
+	}
+
+	attachments := []models.ContactAttachment{}
+	if err := db.Where("contact_id = ?", contact.ID).Order("uploaded_at DESC, id DESC").Find(&attachments).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve attachments"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"attachments": attachments})
+}
+
+// GetContactAttachment serves an attached file as download with its original name
+func GetContactAttachment(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+
+	var attachment models.ContactAttachment
+	if err := db.Where("contact_id = ?", c.Param("id")).Scopes(ownedThroughContact(c, "contact_id")).First(&attachment, c.Param("aid")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Attachment not found"})
+		return
+	}
+
+	file, err := os.Open(filepath.Join(attachmentDir(), attachment.StoredName))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "File not found"})
+		return
+	}
+	defer file.Close()
+
+	// Documents may contain HTML or scripts, so they are always downloaded instead of shown in the browser
+	c.Header("Content-Type", attachment.ContentType)
+	c.Header("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": attachment.Filename}))
+	c.Header("X-Content-Type-Options", "nosniff")
+	http.ServeContent(c.Writer, c.Request, attachment.Filename, attachment.UpdatedAt, file)
+}
+
+// DeleteContactAttachment removes an attachment and its file
+func DeleteContactAttachment(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+
+	var attachment models.ContactAttachment
+	if err := db.Where("contact_id = ?", c.Param("id")).Scopes(ownedThroughContact(c, "contact_id")).First(&attachment, c.Param("aid")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Attachment not found"})
+		return
+	}
+
+	if err := db.Unscoped().Delete(&attachment).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete attachment"})
+		return
+	}
+	removeContactAttachmentFile(attachment)
+
+	c.JSON(http.StatusOK, gin.H{"message": "Attachment deleted"})
+}
+
+func removeContactAttachmentFile(attachment models.ContactAttachment) {
+	if err := os.Remove(filepath.Join(attachmentDir(), attachment.StoredName)); err != nil && !os.IsNotExist(err) {
+		slog.Error("Failed to remove attachment file", "contact_id", attachment.ContactID, "file", attachment.StoredName, "error", err)
+	}
+}
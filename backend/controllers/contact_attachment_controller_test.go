@@ -0,0 +1,109 @@
+package controllers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"perema/config"
+	"perema/models"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestContactAttachments(t *testing.T) {
+	db, router := setupRouter()
+	cfg := &config.Config{MaxAttachmentSizeMB: 1, Timezone: time.UTC}
+	router.POST("/contacts/:id/attachments", func(c *gin.Context) {
+		AddAttachmentToContact(c, cfg)
+	})
+	router.GET("/contacts/:id/attachments", GetContactAttachments)
+	router.GET("/contacts/:id/attachments/:aid", GetContactAttachment)
+	router.DELETE("/contacts/:id/attachments/:aid", DeleteContactAttachment)
+	router.GET("/contacts/:id", func(c *gin.Context) {
+		GetContact(c, cfg)
+	})
+	dir := t.TempDir()
+	t.Setenv("ATTACHMENT_DIR", dir)
+
+	contact := models.Contact{Firstname: "Alice"}
+	db.Create(&contact)
+	url := "/contacts/" + strconv.Itoa(int(contact.ID)) + "/attachments"
+
+	content := []byte("%PDF-1.4\n%âãÏÓ\n1 0 obj\n<<>>\nendobj\n")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, newUploadRequest(url, "contract.pdf", content))
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	var created struct {
+		Attachment models.ContactAttachment `json:"attachment"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &created)
+	assert.Equal(t, "contract.pdf", created.Attachment.Filename)
+	assert.Equal(t, "application/pdf", created.Attachment.ContentType)
+	assert.Equal(t, int64(len(content)), created.Attachment.Size)
+	assert.False(t, created.Attachment.UploadedAt.IsZero())
+
+	var stored models.ContactAttachment
+	db.First(&stored, created.Attachment.ID)
+	assert.True(t, isGeneratedUpload(stored.StoredName))
+	assert.FileExists(t, filepath.Join(dir, stored.StoredName))
+
+	// Files above the limit are rejected
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, newUploadRequest(url, "large.bin", bytes.Repeat([]byte{1}, 2<<20)))
+	assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+
+	w = httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", url, nil)
+	router.ServeHTTP(w, req)
+	var list struct {
+		Attachments []models.ContactAttachment `json:"attachments"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &list)
+	assert.Len(t, list.Attachments, 1)
+
+	// The attachments are part of the contact
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/contacts/"+strconv.Itoa(int(contact.ID)), nil)
+	router.ServeHTTP(w, req)
+	var loaded models.Contact
+	json.Unmarshal(w.Body.Bytes(), &loaded)
+	assert.Len(t, loaded.Attachments, 1)
+
+	// The file is downloaded with its original name
+	attachmentURL := url + "/" + strconv.Itoa(int(stored.ID))
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", attachmentURL, nil)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, content, w.Body.Bytes())
+	assert.Equal(t, "application/pdf", w.Header().Get("Content-Type"))
+	assert.Equal(t, "attachment; filename=contract.pdf", w.Header().Get("Content-Disposition"))
+
+	// Attachments of other users are not found
+	other := models.Contact{Firstname: "Mallory", UserID: 2}
+	db.Create(&other)
+	otherAttachment := models.ContactAttachment{ContactID: other.ID, Filename: "secret.pdf", StoredName: "secret_attachment.pdf"}
+	db.Create(&otherAttachment)
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/contacts/"+strconv.Itoa(int(other.ID))+"/attachments/"+strconv.Itoa(int(otherAttachment.ID)), nil)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("DELETE", attachmentURL, nil)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	_, err := os.Stat(filepath.Join(dir, stored.StoredName))
+	assert.True(t, os.IsNotExist(err))
+	var count int64
+	db.Unscoped().Model(&models.ContactAttachment{}).Where("contact_id = ?", contact.ID).Count(&count)
+	assert.Zero(t, count)
+}
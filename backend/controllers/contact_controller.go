@@ -1,61 +1,225 @@
 package controllers
 
 import (
-	"log"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"net/http"
+	"perema/config"
+	"perema/middleware"
 	"perema/models"
+	"perema/services"
 	"slices"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
+// Fields which may be used for searching contacts
+var searchableFields = []string{"firstname", "lastname", "nickname", "email", "phone", "address", "work_information", "contact_information", "how_we_met"}
+
+// Columns the contact list may be sorted by, they are used in the ORDER BY clause and must not come from the request
+var sortableContactColumns = []string{"lastname", "firstname", "birthday", "created_at", "updated_at"}
+
+// Fields searched if neither the request nor the configuration specify any
+var defaultSearchFields = []string{"firstname", "lastname", "nickname"}
+
+// contactColumns returns the database columns of a contact field, the address is stored in several columns
+func contactColumns(field string) []string {
+	switch field {
+	case "address":
+		return models.AddressColumns
+	case "CreatedAt":
+		return []string{"created_at"}
+	case "UpdatedAt":
+		return []string{"updated_at"}
+	}
+	return []string{field}
+}
+
+// searchCondition builds a parameterized OR condition matching the search term in any of the given fields.
+// Fields not contained in searchableFields are ignored.
+func searchCondition(dialect services.SQLDialect, fields []string, searchTerm string) clause.Expr {
+	var conditions []string
+	var params []any
+	for _, field := range fields {
+		if slices.Contains(searchableFields, field) {
+			for _, column := range contactColumns(field) {
+				conditions = append(conditions, column+" "+dialect.Like()+" ?")
+				params = append(params, "%"+searchTerm+"%")
+			}
+		}
+	}
+
+	if len(conditions) == 0 {
+		return searchCondition(dialect, defaultSearchFields, searchTerm)
+	}
+
+	return gorm.Expr("("+strings.Join(conditions, " OR ")+")", params...)
+}
+
+// CreateContact creates a contact of the authenticated user
+//
+//	@Summary	Create a contact
+//	@Tags		contacts
+//	@Accept		json
+//	@Produce	json
+//	@Param		contact	body		models.Contact	true	"Contact to create"
+//	@Success	201		{object}	object{message=string,contact=models.Contact}
+//	@Header		201		{string}	Location	"URL of the created contact"
+//	@Failure	400		{object}	validationErrorResponse
+//	@Failure	500		{object}	errorResponse
+//	@Security	bearerauth
+//	@Router		/contacts [post]
 func CreateContact(c *gin.Context) {
 	// Save to the database
 	db := c.MustGet("db").(*gorm.DB)
 
 	var contact models.Contact
 	if err := c.ShouldBindJSON(&contact); err != nil {
-		log.Println("Error binding JSON for create contact:", err)
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		middleware.Logger(c).Warn("Invalid contact", "error", err)
+		respondBindingError(c, err)
+		return
+	}
+	if fieldErrors := contactFieldErrors(contact); len(fieldErrors) > 0 {
+		respondFieldErrors(c, fieldErrors)
 		return
 	}
+	contact.Gender, _ = models.NormalizeGender(contact.Gender)
+	contact.Tags = normalizeNames(contact.Tags)
 
 	// Save the new contact to the database
+	contact.UserID = currentUserID(c)
 	if err := db.Create(&contact).Error; err != nil {
-		log.Println("Error saving to database:", err)
+		middleware.Logger(c).Error("Failed to save contact", "error", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save contact"})
 		return
 	}
+	if err := services.SyncLegacyContactMethods(db, contact); err != nil {
+		middleware.Logger(c).Error("Failed to save contact methods", "contact_id", contact.ID, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save contact"})
+		return
+	}
+	recordAudit(c, db, contactAudit(contact, models.AuditCreate), nil, contact)
+
+	respondCreated(c, fmt.Sprintf("/contacts/%d", contact.ID), gin.H{"message": "Contact created successfully", "contact": contact})
+}
+
+// contactFieldErrors validates the gender, the format of the email and phone and the reminder lead days of a contact
+func contactFieldErrors(contact models.Contact) map[string]fieldError {
+	fields := map[string]fieldError{}
+	values := map[string]any{"gender": contact.Gender, "email": contact.Email, "phone": contact.Phone, "reminder_lead_days": contact.ReminderLeadDays}
+	for field, message := range contact.Validate() {
+		fields[field] = fieldError{Rule: field, Message: message, Value: values[field]}
+	}
+	return fields
+}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Contact created successfully", "contact": contact})
+// GetContacts lists the contacts of the authenticated user page by page
+//
+//	@Summary	List contacts
+//	@Tags		contacts
+//	@Produce	json
+//	@Param		page			query		int			false	"Page number"	default(1)
+//	@Param		limit			query		int			false	"Contacts per page, up to the configured maximum"
+//	@Param		fields			query		string		false	"Comma separated fields to return"
+//	@Param		includes		query		string		false	"Comma separated relations to include: notes, activities, relationships, reminders"
+//	@Param		view			query		string		false	"Named view of fields and includes from the configuration"
+//	@Param		search			query		string		false	"Search term"
+//	@Param		search_fields	query		string		false	"Comma separated fields to search in"
+//	@Param		sort			query		string		false	"Sort column, favorites come first without it"	Enums(lastname, firstname, birthday, created_at, updated_at)
+//	@Param		order			query		string		false	"Sort order"									Enums(asc, desc)	default(asc)
+//	@Param		cadence			query		string		false	"Health of the contact frequency goal"			Enums(on_track, slipping, overdue)
+//	@Param		favorites		query		bool		false	"Only favorite contacts"
+//	@Param		city			query		string		false	"City of the address, case insensitive"
+//	@Param		circle			query		string		false	"Circle the contact is in"
+//	@Param		tag				query		[]string	false	"Tags the contact needs all of"	collectionFormat(multi)
+//	@Param		If-None-Match	header		string		false	"ETag of a previous response"
+//	@Success	200				{object}	contactPage
+//	@Header		200				{string}	ETag						"Tag of the response for conditional requests"
+//	@Header		200				{string}	X-Pagination-Limit-Clamped	"Set if the requested limit exceeded the maximum"
+//	@Success	304				"Not modified since the ETag"
+//	@Failure	400				{object}	errorResponse
+//	@Failure	500				{object}	errorResponse
+//	@Security	bearerauth
+//	@Router		/contacts [get]
+func GetContacts(c *gin.Context, cfg *config.Config) {
+	listContacts(c, cfg)
 }
 
-func GetContacts(c *gin.Context) {
+// GetCircleContacts lists the contacts of a circle. The circle is matched exactly against the elements of the
+// circles array, all other parameters are the same as for GetContacts.
+//
+//	@Summary	List the contacts of a circle
+//	@Tags		contacts
+//	@Produce	json
+//	@Param		name			path		string		true	"Circle"
+//	@Param		page			query		int			false	"Page number"	default(1)
+//	@Param		limit			query		int			false	"Contacts per page, up to the configured maximum"
+//	@Param		fields			query		string		false	"Comma separated fields to return"
+//	@Param		includes		query		string		false	"Comma separated relations to include: notes, activities, relationships, reminders"
+//	@Param		view			query		string		false	"Named view of fields and includes from the configuration"
+//	@Param		search			query		string		false	"Search term"
+//	@Param		search_fields	query		string		false	"Comma separated fields to search in"
+//	@Param		sort			query		string		false	"Sort column, favorites come first without it"	Enums(lastname, firstname, birthday, created_at, updated_at)
+//	@Param		order			query		string		false	"Sort order"									Enums(asc, desc)	default(asc)
+//	@Param		cadence			query		string		false	"Health of the contact frequency goal"			Enums(on_track, slipping, overdue)
+//	@Param		favorites		query		bool		false	"Only favorite contacts"
+//	@Param		city			query		string		false	"City of the address, case insensitive"
+//	@Param		circle			query		string		false	"Circle the contact is in"
+//	@Param		tag				query		[]string	false	"Tags the contact needs all of"	collectionFormat(multi)
+//	@Param		If-None-Match	header		string		false	"ETag of a previous response"
+//	@Success	200				{object}	contactPage
+//	@Header		200				{string}	ETag						"Tag of the response for conditional requests"
+//	@Header		200				{string}	X-Pagination-Limit-Clamped	"Set if the requested limit exceeded the maximum"
+//	@Success	304				"Not modified since the ETag"
+//	@Failure	400				{object}	errorResponse
+//	@Failure	500				{object}	errorResponse
+//	@Security	bearerauth
+//	@Router		/circles/{name}/contacts [get]
+func GetCircleContacts(c *gin.Context, cfg *config.Config) {
+	circle := c.Param("name")
+	listContacts(c, cfg, func(db *gorm.DB) *gorm.DB {
+		return db.Where(services.Dialect(db).JSONContains("contacts.circles"), circle)
+	})
+}
+
+// listContacts responds with a page of contacts filtered by the query parameters and the given scopes
+func listContacts(c *gin.Context, cfg *config.Config, scopes ...func(*gorm.DB) *gorm.DB) {
 	db := c.MustGet("db").(*gorm.DB)
 
-	// Get pagination parameters
-	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
-	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "25"))
-	if page < 1 {
-		page = 1
-	}
-	if limit < 1 || limit > 100 {
-		limit = 25
+	page, limit, offset := paginationParams(c, cfg)
+
+	// A named view expands to a preset of fields and includes, otherwise the explicit parameters are used
+	fields := c.Query("fields")
+	includes := c.Query("includes")
+	if viewName := c.Query("view"); viewName != "" {
+		view, exists := cfg.ContactViews[viewName]
+		if !exists {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown view"})
+			return
+		}
+		fields = strings.Join(view.Fields, ",")
+		includes = strings.Join(view.Includes, ",")
 	}
-	offset := (page - 1) * limit
 
 	// Define allowed fields and parse requested fields with validation
-	allowedFields := []string{"ID", "firstname", "lastname", "nickname", "gender", "email", "phone", "birthday", "address", "how_we_met", "food_preference", "work_information", "contact_information", "circles"}
+	allowedFields := []string{"ID", "firstname", "lastname", "nickname", "gender", "email", "phone", "birthday", "deceased", "deceased_date", "favorite", "address", "how_we_met", "food_preference", "work_information", "contact_information", "circles", "tags", "contact_frequency_days", "reminder_lead_days", "CreatedAt", "UpdatedAt"}
 	var selectedFields []string
-	fields := c.Query("fields")
 	if fields != "" {
 		for _, field := range strings.Split(fields, ",") {
-			if slices.Contains(allowedFields, field) { // Validate field
-				selectedFields = append(selectedFields, field)
+			// Only allowed fields may reach the SELECT clause
+			if !slices.Contains(allowedFields, field) {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid field: " + field, "allowed": allowedFields})
+				return
 			}
+			selectedFields = append(selectedFields, field)
 		}
 	} else {
 		selectedFields = allowedFields // Use all allowed fields if none are specified
@@ -68,7 +232,6 @@ func GetContacts(c *gin.Context) {
 		"relationships": false,
 		"reminders":     false,
 	}
-	includes := c.Query("includes")
 	for _, rel := range strings.Split(includes, ",") {
 		if _, exists := relationshipMap[rel]; exists {
 			relationshipMap[rel] = true
@@ -76,119 +239,1558 @@ func GetContacts(c *gin.Context) {
 	}
 
 	var contacts []models.Contact
-	query := db.Model(&models.Contact{}).Limit(limit).Offset(offset)
+	query := db.Model(&models.Contact{}).Scopes(ownContacts(c)).Scopes(scopes...)
 
-	if len(selectedFields) > 0 {
-		query = query.Select(selectedFields)
+	// Determine the fields to search in, either from the request or the configuration
+	searchFields := cfg.SearchFields
+	if param := c.Query("search_fields"); param != "" {
+		searchFields = strings.Split(param, ",")
+		for _, field := range searchFields {
+			if !slices.Contains(searchableFields, field) {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid search field: " + field, "allowed": searchableFields})
+				return
+			}
+		}
 	}
 
 	// Apply search filter using parameterization
 	if searchTerm := c.Query("search"); searchTerm != "" {
-		searchTermParam := "%" + searchTerm + "%"
-		query = query.Where("firstname LIKE ? OR lastname LIKE ? OR nickname LIKE ?", searchTermParam, searchTermParam, searchTermParam)
+		query = query.Where(searchCondition(services.Dialect(db), searchFields, searchTerm))
+	}
+
+	sortColumn := c.DefaultQuery("sort", "lastname")
+	if !slices.Contains(sortableContactColumns, sortColumn) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid sort column: " + sortColumn, "allowed": sortableContactColumns})
+		return
+	}
+	order := c.DefaultQuery("order", "asc")
+	if order != "asc" && order != "desc" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid order, use asc or desc"})
+		return
+	}
+
+	if cadence := c.Query("cadence"); cadence != "" {
+		if !slices.Contains(cadenceHealthStates, cadence) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid cadence", "allowed": cadenceHealthStates})
+			return
+		}
+		query = query.Where(cadenceHealthSQL(services.Dialect(db), time.Now().In(cfg.Timezone))+" = ?", cadence)
+	}
+
+	if c.Query("favorites") == "true" {
+		query = query.Where("contacts.favorite = ?", true)
+	}
+
+	if city := c.Query("city"); city != "" {
+		query = query.Where("LOWER(address_city) = LOWER(?)", city)
 	}
 
 	if circle := c.Query("circle"); circle != "" {
-		query = query.Where("circles LIKE ?", "%"+circle+"%") // Using parameterization
+		query = query.Where("circles "+services.Dialect(db).Like()+" ?", "%"+circle+"%") // Using parameterization
+	}
+
+	// Tags are matched exactly, a contact needs all of the requested tags
+	for _, tag := range c.QueryArray("tag") {
+		query = query.Where(services.Dialect(db).JSONContains("contacts.tags"), tag)
+	}
+
+	// The filtered query is shared by the count and the page of contacts
+	query = query.Session(&gorm.Session{})
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve contacts"})
+		return
+	}
+
+	// Favorites are pinned to the top unless a sort is requested, the ID breaks ties so that pages do not overlap
+	pageQuery := query
+	if c.Query("sort") == "" {
+		pageQuery = pageQuery.Order("contacts.favorite DESC")
+	}
+	orderColumn := "contacts." + sortColumn
+	if sortColumn == "lastname" || sortColumn == "firstname" {
+		orderColumn = "LOWER(" + orderColumn + ")"
+	}
+	pageQuery = pageQuery.Order(orderColumn + " " + order).Order("contacts.id").Limit(limit).Offset(offset)
+	if len(selectedFields) > 0 {
+		var columns []string
+		for _, field := range selectedFields {
+			columns = append(columns, contactColumns(field)...)
+		}
+		pageQuery = pageQuery.Select(columns)
 	}
 
 	// Preload requested relationships
 	for rel, include := range relationshipMap {
 		if include {
-			query = query.Preload(rel)
+			pageQuery = pageQuery.Preload(strings.ToUpper(rel[:1]) + rel[1:]) // Association names are capitalized
 		}
 	}
 
 	// Execute query
-	if err := query.Find(&contacts).Error; err != nil {
+	if err := pageQuery.Find(&contacts).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve contacts"})
 		return
 	}
 
+	// Only return the requested fields and includes if a selection was made
+	var response any = contacts
+	if fields != "" {
+		for rel, include := range relationshipMap {
+			if include {
+				selectedFields = append(selectedFields, rel)
+			}
+		}
+		filtered, err := filterJSONFields(contacts, selectedFields)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve contacts"})
+			return
+		}
+		response = filtered
+	}
+
+	// Respond with contacts and pagination metadata, clients polling the list get 304 while it is unchanged
+	respondJSONWithETag(c, paginatedResponse("contacts", response, total, page, limit))
+}
+
+// filterJSONFields converts the given items into JSON objects only containing the given keys
+func filterJSONFields[T any](items []T, keys []string) ([]map[string]any, error) {
+	filtered := make([]map[string]any, 0, len(items))
+	for _, item := range items {
+		encoded, err := json.Marshal(item)
+		if err != nil {
+			return nil, err
+		}
+		var fields map[string]any
+		if err := json.Unmarshal(encoded, &fields); err != nil {
+			return nil, err
+		}
+		for key := range fields {
+			if !slices.Contains(keys, key) {
+				delete(fields, key)
+			}
+		}
+		filtered = append(filtered, fields)
+	}
+	return filtered, nil
+}
+
+// Possible states of the contact frequency goal
+const (
+	CadenceOnTrack  = "on_track"
+	CadenceSlipping = "slipping"
+	CadenceOverdue  = "overdue"
+)
+
+var cadenceHealthStates = []string{CadenceOnTrack, CadenceSlipping, CadenceOverdue}
+
+// lastContactedSQL selects the date of the latest activity or note of a contact
+func lastContactedSQL(dialect services.SQLDialect) string {
+	return lastContactedSubquery(dialect.Greatest("COALESCE(last_activity, last_note)", "COALESCE(last_note, last_activity)"))
+}
+
+func lastContactedSubquery(column string) string {
+	lastActivity := `(SELECT MAX(activities.date) FROM activities
+		JOIN activity_contacts ON activity_contacts.activity_id = activities.id
+		WHERE activity_contacts.contact_id = contacts.id AND activities.deleted_at IS NULL)`
+	lastNote := `(SELECT MAX(notes.date) FROM notes WHERE notes.contact_id = contacts.id AND notes.deleted_at IS NULL)`
+	return strings.NewReplacer("last_activity", lastActivity, "last_note", lastNote).Replace(column)
+}
+
+// GetStaleContacts returns the contacts without any activity or note within the last days (default 90),
+// starting with the longest silence. Contacts which were never contacted come first.
+//
+//	@Summary	List contacts without recent activity or note
+//	@Tags		contacts
+//	@Produce	json
+//	@Param		days	query		int	false	"Days without activity or note"	default(90)
+//	@Param		page	query		int	false	"Page number"					default(1)
+//	@Param		limit	query		int	false	"Contacts per page, up to the configured maximum"
+//	@Success	200		{object}	contactPage
+//	@Failure	400		{object}	errorResponse
+//	@Failure	500		{object}	errorResponse
+//	@Security	bearerauth
+//	@Router		/contacts/stale [get]
+func GetStaleContacts(c *gin.Context, cfg *config.Config) {
+	db := c.MustGet("db").(*gorm.DB)
+
+	days, err := strconv.Atoi(c.DefaultQuery("days", "90"))
+	if err != nil || days < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid number of days"})
+		return
+	}
+
+	page, limit, offset := paginationParams(c, cfg)
+
+	now := time.Now().In(cfg.Timezone)
+	cutoff := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC).AddDate(0, 0, -days)
+	dialect := services.Dialect(db)
+	lastContacted := dialect.Date(lastContactedSQL(dialect))
+
+	query := db.Model(&models.Contact{}).
+		Scopes(ownContacts(c)).
+		Where(lastContacted+" IS NULL OR "+lastContacted+" < ?", cutoff.Format(models.DateFormat)).
+		Session(&gorm.Session{})
+
 	var total int64
-	countQuery := db.Model(&models.Contact{})
-	countQuery.Count(&total)
+	if err := query.Count(&total).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve contacts"})
+		return
+	}
 
-	// Respond with contacts and pagination metadata
-	c.JSON(http.StatusOK, gin.H{
-		"contacts": contacts,
-		"total":    total,
-		"page":     page,
-		"limit":    limit,
-	})
+	var rows []struct {
+		ID            uint
+		LastContacted *string
+	}
+	if err := query.Select("contacts.id AS id, " + lastContacted + " AS last_contacted").
+		Order(lastContacted + " IS NOT NULL, last_contacted ASC, contacts.id").
+		Limit(limit).Offset(offset).
+		Scan(&rows).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve contacts"})
+		return
+	}
+
+	ids := make([]uint, len(rows))
+	for i, row := range rows {
+		ids[i] = row.ID
+	}
+	var contacts []models.Contact
+	if err := db.Find(&contacts, ids).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve contacts"})
+		return
+	}
+	contactsByID := map[uint]models.Contact{}
+	for _, contact := range contacts {
+		contactsByID[contact.ID] = contact
+	}
+
+	staleContacts := []models.Contact{}
+	for _, row := range rows {
+		contact := contactsByID[row.ID]
+		if row.LastContacted != nil {
+			if date, err := time.Parse(models.DateFormat, *row.LastContacted); err == nil {
+				contact.LastContacted = &models.Date{Time: date, Valid: true}
+			}
+		}
+		staleContacts = append(staleContacts, contact)
+	}
+
+	c.JSON(http.StatusOK, paginatedResponse("contacts", staleContacts, total, page, limit))
+}
+
+// cadenceHealthSQL returns an SQL expression evaluating the contact frequency goal on the day of now in its
+// timezone, which should be the configured one.
+// Contacts are overdue after the goal has passed, slipping in the last quarter of it and on track otherwise.
+// Contacts without a goal evaluate to NULL.
+func cadenceHealthSQL(dialect services.SQLDialect, now time.Time) string {
+	lastContacted := lastContactedSQL(dialect)
+	daysSince := dialect.DaysSince(now.Format(models.DateFormat), lastContacted)
+
+	return fmt.Sprintf(`(CASE
+		WHEN contacts.contact_frequency_days <= 0 THEN NULL
+		WHEN %[1]s IS NULL THEN '%[3]s'
+		WHEN %[2]s > contacts.contact_frequency_days THEN '%[3]s'
+		WHEN %[2]s * 4 > contacts.contact_frequency_days * 3 THEN '%[4]s'
+		ELSE '%[5]s' END)`, lastContacted, daysSince, CadenceOverdue, CadenceSlipping, CadenceOnTrack)
 }
 
-func GetContact(c *gin.Context) {
+// GetContact returns a contact with its notes, activities, relationships, reminders, contact methods,
+// attachments and custom dates as JSON, vCard or CSV depending on the Accept header
+//
+//	@Summary	Get a contact
+//	@Tags		contacts
+//	@Produce	json,text/vcard,text/csv
+//	@Param		id				path		int		true	"Contact ID"
+//	@Param		If-None-Match	header		string	false	"ETag of a previous response"
+//	@Success	200				{object}	models.Contact
+//	@Header		200				{string}	ETag	"Tag of the response for conditional requests"
+//	@Success	304				"Not modified since the ETag"
+//	@Failure	404				{object}	errorResponse
+//	@Failure	406				{object}	errorResponse
+//	@Failure	500				{object}	errorResponse
+//	@Security	bearerauth
+//	@Router		/contacts/{id} [get]
+func GetContact(c *gin.Context, cfg *config.Config) {
 	id := c.Param("id")
 	var contact models.Contact
 	db := c.MustGet("db").(*gorm.DB)
-	if err := db.Preload("Notes").Preload("Activities").Preload("Relationships").Preload("Reminders").First(&contact, id).Error; err != nil {
+	if err := db.Scopes(ownContacts(c)).Preload("Notes").Preload("Activities").Preload("Relationships").Preload("Reminders").
+		Preload("ContactMethods", func(db *gorm.DB) *gorm.DB { return db.Order("type, is_primary DESC, id") }).
+		Preload("Attachments", func(db *gorm.DB) *gorm.DB { return db.Order("uploaded_at DESC, id DESC") }).
+		Preload("CustomDates", func(db *gorm.DB) *gorm.DB { return db.Order("id") }).
+		First(&contact, id).Error; err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Contact not found"})
 		return
 	}
-	c.JSON(http.StatusOK, contact)
+
+	if contact.ContactFrequencyDays > 0 {
+		if err := db.Model(&models.Contact{}).Select(cadenceHealthSQL(services.Dialect(db), time.Now().In(cfg.Timezone))).Where("id = ?", contact.ID).Scan(&contact.CadenceHealth).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to compute cadence health"})
+			return
+		}
+	}
+
+	contact.Summary = services.ContactSummary(contact, cfg.ContactSummaryTemplate, time.Now().In(cfg.Timezone), cfg.LeapDayBirthdays)
+	if lastContacted, ok := services.LastSeen(contact); ok {
+		contact.LastContacted = &models.Date{Time: lastContacted, Valid: true}
+	}
+
+	// Serve the representation requested by the Accept header, JSON by default
+	switch c.NegotiateFormat(gin.MIMEJSON, mimeVCard, "text/x-vcard", mimeCSV) {
+	case gin.MIMEJSON:
+		respondJSONWithETag(c, contact)
+	case mimeVCard, "text/x-vcard":
+		writeVCard(c, contactFilename(contact, ".vcf"), []models.Contact{contact})
+	case mimeCSV:
+		writeContactsCSV(c, contactFilename(contact, ".csv"), []models.Contact{contact})
+	default:
+		c.JSON(http.StatusNotAcceptable, gin.H{"error": "Unsupported format, use application/json, text/vcard or text/csv"})
+	}
 }
 
-func UpdateContact(c *gin.Context) {
+// ExportContactVCard returns a single contact as vCard file
+//
+//	@Summary	Export a contact as vCard
+//	@Tags		contacts
+//	@Produce	text/vcard
+//	@Param		id	path		int		true	"Contact ID"
+//	@Success	200	{string}	string	"vCard file"
+//	@Failure	404	{object}	errorResponse
+//	@Security	bearerauth
+//	@Router		/contacts/{id}/vcard [get]
+func ExportContactVCard(c *gin.Context) {
 	id := c.Param("id")
-	db := c.MustGet("db").(*gorm.DB)
-
 	var contact models.Contact
-	if err := db.First(&contact, id).Error; err != nil {
+	db := c.MustGet("db").(*gorm.DB)
+	if err := db.Scopes(ownContacts(c)).First(&contact, id).Error; err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Contact not found"})
 		return
 	}
 
-	var updatedContact models.Contact
-	if err := c.ShouldBindJSON(&updatedContact); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	writeVCard(c, contactFilename(contact, ".vcf"), []models.Contact{contact})
+}
+
+// ExportContactsVCard returns all contacts concatenated into a single vCard file
+//
+//	@Summary	Export all contacts as vCard
+//	@Tags		contacts
+//	@Produce	text/vcard
+//	@Success	200	{string}	string	"vCard file"
+//	@Failure	500	{object}	errorResponse
+//	@Security	bearerauth
+//	@Router		/contacts/vcard [get]
+func ExportContactsVCard(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+
+	var contacts []models.Contact
+	if err := db.Scopes(ownContacts(c)).Order("LOWER(lastname), LOWER(firstname)").Find(&contacts).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve contacts"})
 		return
 	}
 
-	// Updateable fields
-	contact.Firstname = updatedContact.Firstname
-	contact.Lastname = updatedContact.Lastname
-	contact.Nickname = updatedContact.Nickname
-	contact.Gender = updatedContact.Gender
-	contact.Email = updatedContact.Email
-	contact.Phone = updatedContact.Phone
-	contact.Birthday = updatedContact.Birthday
-	contact.Address = updatedContact.Address
-	contact.HowWeMet = updatedContact.HowWeMet
-	contact.FoodPreference = updatedContact.FoodPreference
-	contact.WorkInformation = updatedContact.WorkInformation
-	contact.ContactInformation = updatedContact.ContactInformation
-	contact.Circles = updatedContact.Circles
+	writeVCard(c, "contacts.vcf", contacts)
+}
 
-	db.Updates(&contact)
+// Maximum size of an imported vCard file
+const maxVCardImportSize = 5 << 20 // 5 MB
 
-	c.JSON(http.StatusOK, contact)
+type vCardImportError struct {
+	Card  int    `json:"card"` // Position of the card in the file, starting at 1
+	Name  string `json:"name,omitempty"`
+	Error string `json:"error"`
 }
 
-func DeleteContact(c *gin.Context) {
-	id := c.Param("id")
+type vCardImportSummary struct {
+	Created int                `json:"created"`
+	Skipped int                `json:"skipped"` // Cards matching an existing contact by name and email
+	Failed  int                `json:"failed"`
+	Errors  []vCardImportError `json:"errors"`
+}
+
+// ImportContactsVCard creates contacts from an uploaded vCard file.
+// The import is all or nothing: if a single card is malformed, no contact is created.
+//
+//	@Summary	Import contacts from a vCard file
+//	@Tags		contacts
+//	@Accept		multipart/form-data
+//	@Produce	json
+//	@Param		request	body		fileUpload	true	"vCard file of at most 5 MB"
+//	@Success	200		{object}	object{message=string,summary=vCardImportSummary}
+//	@Failure	400		{object}	errorResponse
+//	@Failure	413		{object}	errorResponse
+//	@Failure	422		{object}	object{error=string,summary=vCardImportSummary}
+//	@Failure	500		{object}	errorResponse
+//	@Security	bearerauth
+//	@Router		/contacts/import/vcard [post]
+func ImportContactsVCard(c *gin.Context) {
 	db := c.MustGet("db").(*gorm.DB)
-	if err := db.Delete(&models.Contact{}, id).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Contact not found"})
+
+	file, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "No file uploaded"})
+		return
+	}
+	if file.Size > maxVCardImportSize {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "File is too large"})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Contact deleted"})
+	src, err := file.Open()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read file"})
+		return
+	}
+	defer src.Close()
+
+	data, err := io.ReadAll(src)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read file"})
+		return
+	}
+
+	cards := services.ParseVCards(string(data))
+	if len(cards) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "No vCards found in file"})
+		return
+	}
+
+	summary := vCardImportSummary{Errors: []vCardImportError{}}
+	for i, card := range cards {
+		if card.Err != nil {
+			summary.Failed++
+			summary.Errors = append(summary.Errors, vCardImportError{
+				Card:  i + 1,
+				Name:  strings.TrimSpace(card.Contact.Firstname + " " + card.Contact.Lastname),
+				Error: card.Err.Error(),
+			})
+		}
+	}
+	if summary.Failed > 0 {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "Import contains invalid cards, no contacts were created", "summary": summary})
+		return
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		for _, card := range cards {
+			contact := card.Contact
+
+			var existing int64
+			if err := tx.Model(&models.Contact{}).
+				Scopes(ownContacts(c)).
+				Where("LOWER(firstname) = LOWER(?) AND LOWER(lastname) = LOWER(?) AND LOWER(email) = LOWER(?)", contact.Firstname, contact.Lastname, contact.Email).
+				Count(&existing).Error; err != nil {
+				return err
+			}
+			if existing > 0 {
+				summary.Skipped++
+				continue
+			}
+
+			contact.UserID = currentUserID(c)
+			if err := tx.Create(&contact).Error; err != nil {
+				return err
+			}
+			if err := services.SyncLegacyContactMethods(tx, contact); err != nil {
+				return err
+			}
+			summary.Created++
+		}
+		return nil
+	})
+	if err != nil {
+		middleware.Logger(c).Error("Failed to import contacts", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to import contacts"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Contacts imported successfully", "summary": summary})
 }
 
-// GetCircles returns all unique circles associated with contacts.
-func GetCircles(c *gin.Context) {
+// Maximum size of an imported CSV file
+const maxCSVImportSize = 5 << 20 // 5 MB
+
+type csvImportRow struct {
+	Line      int    `json:"line"`
+	ContactID uint   `json:"contact_id,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+// ImportContactsCSV creates contacts from an uploaded CSV file with a header row.
+// Valid rows are created in a single transaction, invalid rows are reported with their line number.
+//
+//	@Summary	Import contacts from a CSV file
+//	@Tags		contacts
+//	@Accept		multipart/form-data
+//	@Produce	json
+//	@Param		request	body		fileUpload	true	"CSV file of at most 5 MB with a header row"
+//	@Success	200		{object}	object{message=string,created=[]csvImportRow,failed=[]csvImportRow}
+//	@Failure	400		{object}	errorResponse
+//	@Failure	413		{object}	errorResponse
+//	@Failure	500		{object}	errorResponse
+//	@Security	bearerauth
+//	@Router		/contacts/import/csv [post]
+func ImportContactsCSV(c *gin.Context) {
 	db := c.MustGet("db").(*gorm.DB)
-	var circleNames []string
 
-	// Raw SQL query to extract unique circle names
-	err := db.Raw(`SELECT DISTINCT json_each.value AS circle
-	               FROM contacts, json_each(contacts.circles)`).Scan(&circleNames).Error
+	file, err := c.FormFile("file")
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve circles"})
+		c.JSON(http.StatusBadRequest, gin.H{"error": "No file uploaded"})
+		return
+	}
+	if file.Size > maxCSVImportSize {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "File is too large"})
 		return
 	}
 
-	// Return the list of unique circle names
-	c.JSON(http.StatusOK, circleNames)
+	src, err := file.Open()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read file"})
+		return
+	}
+	defer src.Close()
+
+	reader := csv.NewReader(src)
+	reader.FieldsPerRecord = -1
+	header, err := reader.Read()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read header row"})
+		return
+	}
+	header[0] = strings.TrimPrefix(header[0], "\ufeff") // Byte order mark written by spreadsheet applications
+	if !slices.ContainsFunc(header, func(column string) bool { return strings.EqualFold(strings.TrimSpace(column), "firstname") }) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Header row must contain a firstname column"})
+		return
+	}
+
+	type csvRow struct {
+		line    int
+		contact models.Contact
+	}
+	var rows []csvRow
+	failed := []csvImportRow{}
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			failed = append(failed, csvImportRow{Line: parseErr.Line, Error: parseErr.Err.Error()})
+			continue
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read file"})
+			return
+		}
+
+		line, _ := reader.FieldPos(0)
+		contact, err := services.ContactFromCSVRecord(header, record)
+		if err != nil {
+			failed = append(failed, csvImportRow{Line: line, Error: err.Error()})
+			continue
+		}
+		rows = append(rows, csvRow{line: line, contact: contact})
+	}
+
+	created := []csvImportRow{}
+	err = db.Transaction(func(tx *gorm.DB) error {
+		for _, row := range rows {
+			row.contact.UserID = currentUserID(c)
+			if err := tx.Create(&row.contact).Error; err != nil {
+				return err
+			}
+			if err := services.SyncLegacyContactMethods(tx, row.contact); err != nil {
+				return err
+			}
+			created = append(created, csvImportRow{Line: row.line, ContactID: row.contact.ID})
+		}
+		return nil
+	})
+	if err != nil {
+		middleware.Logger(c).Error("Failed to import contacts", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to import contacts"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Contacts imported", "created": created, "failed": failed})
+}
+
+const (
+	mimeVCard = "text/vcard"
+	mimeCSV   = "text/csv"
+)
+
+// contactFilename builds a download filename from the contact name only using safe characters
+func contactFilename(contact models.Contact, extension string) string {
+	name := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9', r == '-':
+			return r
+		case r >= 'A' && r <= 'Z':
+			return r + ('a' - 'A')
+		case r == ' ' || r == '_':
+			return '_'
+		}
+		return -1
+	}, strings.TrimSpace(contact.Firstname+" "+contact.Lastname))
+
+	if name == "" {
+		name = "contact_" + strconv.FormatUint(uint64(contact.ID), 10)
+	}
+	return name + extension
+}
+
+func writeVCard(c *gin.Context, filename string, contacts []models.Contact) {
+	var vcards strings.Builder
+	for _, contact := range contacts {
+		vcards.WriteString(services.ContactToVCard(contact))
+	}
+
+	c.Header("Content-Disposition", `attachment; filename="`+filename+`"`)
+	c.Data(http.StatusOK, mimeVCard+"; charset=utf-8", []byte(vcards.String()))
+}
+
+// ExportContactsCSV streams all contacts as CSV in batches. The columns can be limited with the fields parameter
+// like in GetContacts and are always written in the order of services.ContactCSVColumns.
+//
+//	@Summary	Export all contacts as CSV
+//	@Tags		contacts
+//	@Produce	text/csv
+//	@Param		fields	query		string	false	"Comma separated columns to export"
+//	@Success	200		{string}	string	"CSV file"
+//	@Failure	400		{object}	errorResponse
+//	@Security	bearerauth
+//	@Router		/contacts/export/csv [get]
+func ExportContactsCSV(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+
+	columns := services.ContactCSVColumns
+	if fields := c.Query("fields"); fields != "" {
+		requested := strings.Split(fields, ",")
+		columns = slices.DeleteFunc(slices.Clone(columns), func(column string) bool {
+			return !slices.Contains(requested, column)
+		})
+		if len(columns) == 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "No valid fields requested"})
+			return
+		}
+	}
+
+	c.Header("Content-Disposition", `attachment; filename="contacts.csv"`)
+	c.Header("Content-Type", mimeCSV+"; charset=utf-8")
+	c.Status(http.StatusOK)
+
+	writer := csv.NewWriter(c.Writer)
+	writer.Write(columns)
+
+	var contacts []models.Contact
+	err := db.Scopes(ownContacts(c)).Order("id").FindInBatches(&contacts, 100, func(tx *gorm.DB, batch int) error {
+		for _, contact := range contacts {
+			writer.Write(services.ContactCSVRecord(contact, columns))
+		}
+		writer.Flush()
+		return writer.Error()
+	}).Error
+	if err != nil {
+		// The status has already been sent, so the export can only be aborted
+		middleware.Logger(c).Error("Failed to export contacts", "error", err)
+		c.Abort()
+		return
+	}
+	writer.Flush()
+}
+
+func writeContactsCSV(c *gin.Context, filename string, contacts []models.Contact) {
+	c.Header("Content-Disposition", `attachment; filename="`+filename+`"`)
+	c.Header("Content-Type", mimeCSV+"; charset=utf-8")
+	c.Status(http.StatusOK)
+
+	writer := csv.NewWriter(c.Writer)
+	writer.Write(services.ContactCSVColumns)
+	for _, contact := range contacts {
+		writer.Write(services.ContactCSVRecord(contact, services.ContactCSVColumns))
+	}
+	writer.Flush()
+}
+
+// Columns UpdateContact replaces. They are selected explicitly, as updating from the struct would skip false,
+// 0 and empty values and those could never be cleared.
+var updatableContactColumns = append([]string{"Version", "Firstname", "Lastname", "Nickname", "Gender", "Email", "Phone",
+	"Birthday", "Deceased", "DeceasedDate", "HowWeMet", "FoodPreference", "WorkInformation", "ContactInformation",
+	"Circles", "Tags", "ContactFrequencyDays", "ReminderLeadDays"}, models.AddressColumns...)
+
+// UpdateContact replaces the fields of a contact. The version of the edited contact is required and the update
+// is rejected with the current contact if it is outdated.
+//
+//	@Summary	Update a contact
+//	@Tags		contacts
+//	@Accept		json
+//	@Produce	json
+//	@Param		id		path		int				true	"Contact ID"
+//	@Param		contact	body		models.Contact	true	"Contact with the version it is based on"
+//	@Success	200		{object}	models.Contact
+//	@Failure	400		{object}	validationErrorResponse
+//	@Failure	404		{object}	errorResponse
+//	@Failure	409		{object}	object{error=string,contact=models.Contact}
+//	@Failure	500		{object}	errorResponse
+//	@Security	bearerauth
+//	@Router		/contacts/{id} [put]
+func UpdateContact(c *gin.Context) {
+	id := c.Param("id")
+	db := c.MustGet("db").(*gorm.DB)
+
+	var contact models.Contact
+	if err := db.Scopes(ownContacts(c)).First(&contact, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Contact not found"})
+		return
+	}
+
+	var updatedContact models.Contact
+	if err := c.ShouldBindJSON(&updatedContact); err != nil {
+		respondBindingError(c, err)
+		return
+	}
+	if fieldErrors := contactFieldErrors(updatedContact); len(fieldErrors) > 0 {
+		respondFieldErrors(c, fieldErrors)
+		return
+	}
+	if updatedContact.Version == 0 {
+		respondFieldErrors(c, map[string]fieldError{
+			"version": {Rule: "required", Message: "Version of the edited contact is required"},
+		})
+		return
+	}
+
+	// The client has to edit the latest version, otherwise it would overwrite changes made in the meantime
+	if updatedContact.Version != contact.Version {
+		respondContactConflict(c, contact)
+		return
+	}
+
+	before := contact
+
+	// Updateable fields
+	contact.Firstname = updatedContact.Firstname
+	contact.Lastname = updatedContact.Lastname
+	contact.Nickname = updatedContact.Nickname
+	contact.Gender, _ = models.NormalizeGender(updatedContact.Gender)
+	contact.Email = updatedContact.Email
+	contact.Phone = updatedContact.Phone
+	contact.Birthday = updatedContact.Birthday
+	contact.Deceased = updatedContact.Deceased
+	contact.DeceasedDate = updatedContact.DeceasedDate
+	contact.Address = updatedContact.Address
+	contact.HowWeMet = updatedContact.HowWeMet
+	contact.FoodPreference = updatedContact.FoodPreference
+	contact.WorkInformation = updatedContact.WorkInformation
+	contact.ContactInformation = updatedContact.ContactInformation
+	contact.Circles = updatedContact.Circles
+	contact.Tags = normalizeNames(updatedContact.Tags)
+	contact.ContactFrequencyDays = updatedContact.ContactFrequencyDays
+	contact.ReminderLeadDays = updatedContact.ReminderLeadDays
+	contact.Version++
+
+	// Only update if no other request changed the contact since it was loaded
+	result := db.Model(&contact).Where("version = ?", updatedContact.Version).Select(updatableContactColumns).Updates(&contact)
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update contact"})
+		return
+	}
+	if result.RowsAffected == 0 {
+		var current models.Contact
+		db.First(&current, contact.ID)
+		respondContactConflict(c, current)
+		return
+	}
+	if err := services.SyncLegacyContactMethods(db, contact); err != nil {
+		middleware.Logger(c).Error("Failed to save contact methods", "contact_id", contact.ID, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update contact"})
+		return
+	}
+	recordAudit(c, db, contactAudit(contact, models.AuditUpdate), before, contact)
+
+	c.JSON(http.StatusOK, contact)
+}
+
+// respondContactConflict rejects an update based on an outdated version with the current state of the contact
+func respondContactConflict(c *gin.Context, current models.Contact) {
+	c.JSON(http.StatusConflict, gin.H{"error": "Contact was changed in the meantime", "contact": current})
+}
+
+// patchableContactFields maps the JSON names of the fields which PatchContact may change to the struct fields.
+// The address is not listed as it spans several columns.
+var patchableContactFields = map[string]string{
+	"firstname":              "Firstname",
+	"lastname":               "Lastname",
+	"nickname":               "Nickname",
+	"gender":                 "Gender",
+	"email":                  "Email",
+	"phone":                  "Phone",
+	"birthday":               "Birthday",
+	"deceased":               "Deceased",
+	"deceased_date":          "DeceasedDate",
+	"favorite":               "Favorite",
+	"how_we_met":             "HowWeMet",
+	"food_preference":        "FoodPreference",
+	"work_information":       "WorkInformation",
+	"contact_information":    "ContactInformation",
+	"circles":                "Circles",
+	"tags":                   "Tags",
+	"contact_frequency_days": "ContactFrequencyDays",
+	"reminder_lead_days":     "ReminderLeadDays",
+}
+
+// PatchContact changes only the fields present in the request body, so an empty string clears a field while
+// an omitted field is kept. The version is optional, if it is sent the update is rejected when it is outdated.
+//
+//	@Summary	Change some fields of a contact
+//	@Tags		contacts
+//	@Accept		json
+//	@Produce	json
+//	@Param		id		path		int		true	"Contact ID"
+//	@Param		contact	body		object	true	"Fields of the contact to change, optionally with its version"
+//	@Success	200		{object}	models.Contact
+//	@Failure	400		{object}	validationErrorResponse
+//	@Failure	404		{object}	errorResponse
+//	@Failure	409		{object}	object{error=string,contact=models.Contact}
+//	@Failure	500		{object}	errorResponse
+//	@Security	bearerauth
+//	@Router		/contacts/{id} [patch]
+func PatchContact(c *gin.Context) {
+	id := c.Param("id")
+	db := c.MustGet("db").(*gorm.DB)
+
+	var contact models.Contact
+	if err := db.Scopes(ownContacts(c)).First(&contact, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Contact not found"})
+		return
+	}
+
+	body, err := c.GetRawData()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+	var provided map[string]interface{}
+	if err := json.Unmarshal(body, &provided); err != nil {
+		respondBindingError(c, err)
+		return
+	}
+
+	columns := []string{"Version"}
+	fieldErrors := map[string]fieldError{}
+	for key, value := range provided {
+		if key == "address" {
+			columns = append(columns, models.AddressColumns...)
+		} else if field, ok := patchableContactFields[key]; ok {
+			columns = append(columns, field)
+		} else if key != "version" {
+			fieldErrors[key] = fieldError{Rule: "unknown", Message: "This field cannot be changed", Value: value}
+		}
+	}
+	if len(fieldErrors) > 0 {
+		respondFieldErrors(c, fieldErrors)
+		return
+	}
+
+	// Decoding onto the stored contact replaces exactly the provided fields and checks their types
+	version := contact.Version
+	patched := contact
+	if err := json.Unmarshal(body, &patched); err != nil {
+		respondBindingError(c, err)
+		return
+	}
+	if fieldErrors := contactFieldErrors(patched); len(fieldErrors) > 0 {
+		respondFieldErrors(c, fieldErrors)
+		return
+	}
+	patched.Gender, _ = models.NormalizeGender(patched.Gender)
+	patched.Tags = normalizeNames(patched.Tags)
+	if _, ok := provided["version"]; ok && patched.Version != version {
+		respondContactConflict(c, contact)
+		return
+	}
+	patched.Version = version + 1
+
+	result := db.Model(&patched).Where("version = ?", version).Select(columns).Updates(&patched)
+	if result.Error != nil {
+		middleware.Logger(c).Error("Failed to update contact", "contact_id", contact.ID, "error", result.Error)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update contact"})
+		return
+	}
+	if result.RowsAffected == 0 {
+		var current models.Contact
+		db.First(&current, contact.ID)
+		respondContactConflict(c, current)
+		return
+	}
+	if err := services.SyncLegacyContactMethods(db, patched); err != nil {
+		middleware.Logger(c).Error("Failed to save contact methods", "contact_id", contact.ID, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update contact"})
+		return
+	}
+	recordAudit(c, db, contactAudit(contact, models.AuditUpdate), contact, patched)
+
+	c.JSON(http.StatusOK, patched)
+}
+
+// ToggleFavorite pins the contact to the top of the contact list or unpins it again
+//
+//	@Summary	Pin or unpin a contact
+//	@Tags		contacts
+//	@Produce	json
+//	@Param		id	path		int	true	"Contact ID"
+//	@Success	200	{object}	models.Contact
+//	@Failure	404	{object}	errorResponse
+//	@Failure	500	{object}	errorResponse
+//	@Security	bearerauth
+//	@Router		/contacts/{id}/favorite [post]
+func ToggleFavorite(c *gin.Context) {
+	id := c.Param("id")
+	db := c.MustGet("db").(*gorm.DB)
+
+	var contact models.Contact
+	if err := db.Scopes(ownContacts(c)).First(&contact, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Contact not found"})
+		return
+	}
+
+	toggled := contact
+	toggled.Favorite = !contact.Favorite
+	toggled.Version++
+	if err := db.Model(&toggled).Select("Favorite", "Version").Updates(&toggled).Error; err != nil {
+		middleware.Logger(c).Error("Failed to update favorite", "contact_id", contact.ID, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update contact"})
+		return
+	}
+	recordAudit(c, db, contactAudit(contact, models.AuditUpdate), contact, toggled)
+
+	c.JSON(http.StatusOK, toggled)
+}
+
+// GetGenders returns the genders a contact can have, e.g. for a dropdown
+//
+//	@Summary	List the genders of contacts
+//	@Tags		contacts
+//	@Produce	json
+//	@Success	200	{object}	object{genders=[]string}
+//	@Security	bearerauth
+//	@Router		/genders [get]
+func GetGenders(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"genders": models.Genders})
+}
+
+// GetUpcomingBirthdays returns the contacts whose birthday or custom date is within the next days (default 30),
+// soonest first
+//
+//	@Summary	List upcoming birthdays and custom dates
+//	@Tags		contacts
+//	@Produce	json
+//	@Param		days	query		int	false	"Days to look ahead, at most 366"	default(30)
+//	@Success	200		{object}	object{birthdays=[]services.UpcomingBirthday,custom_dates=[]services.UpcomingCustomDate}
+//	@Failure	400		{object}	errorResponse
+//	@Failure	500		{object}	errorResponse
+//	@Security	bearerauth
+//	@Router		/contacts/birthdays/upcoming [get]
+func GetUpcomingBirthdays(c *gin.Context, cfg *config.Config) {
+	db := c.MustGet("db").(*gorm.DB)
+
+	days, err := strconv.Atoi(c.DefaultQuery("days", "30"))
+	if err != nil || days < 0 || days > 366 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid number of days"})
+		return
+	}
+
+	var contacts []models.Contact
+	if err := db.Select("ID", "Firstname", "Lastname", "Nickname", "Birthday", "Deceased", "Photo", "PhotoThumbnail").
+		Scopes(ownContacts(c)).
+		Where("birthday IS NOT NULL").Find(&contacts).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve contacts"})
+		return
+	}
+
+	// Custom dates like anniversaries are listed alongside, also only for living contacts
+	var dates []models.CustomDate
+	if err := db.Scopes(ownedThroughContact(c, "custom_dates.contact_id")).
+		InnerJoins("Contact", db.Select("ID", "Firstname", "Lastname", "Nickname", "Photo", "PhotoThumbnail")).
+		Where(`"Contact".deceased = ?`, false).
+		Find(&dates).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve custom dates"})
+		return
+	}
+
+	now := time.Now().In(cfg.Timezone)
+	c.JSON(http.StatusOK, gin.H{
+		"birthdays":    services.UpcomingBirthdays(contacts, now, days, cfg.LeapDayBirthdays),
+		"custom_dates": services.UpcomingCustomDates(dates, now, days, cfg.LeapDayBirthdays),
+	})
+}
+
+// Maximum number of contacts returned by the recent contacts feed
+const maxRecentContacts = 50
+
+// GetRecentContacts returns the most recently created (type=created, default) or modified (type=updated)
+// contacts, newest first. The timestamps are part of every contact.
+//
+//	@Summary	List recently created or modified contacts
+//	@Tags		contacts
+//	@Produce	json
+//	@Param		type	query		string	false	"Timestamp to sort by"				Enums(created, updated)	default(created)
+//	@Param		limit	query		int		false	"Number of contacts, at most 50"	default(10)
+//	@Success	200		{object}	object{contacts=[]models.Contact}
+//	@Failure	400		{object}	errorResponse
+//	@Failure	500		{object}	errorResponse
+//	@Security	bearerauth
+//	@Router		/contacts/recent [get]
+func GetRecentContacts(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+
+	column := map[string]string{"created": "created_at", "updated": "updated_at"}[c.DefaultQuery("type", "created")]
+	if column == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid type, use created or updated"})
+		return
+	}
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "10"))
+	if err != nil || limit < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid limit"})
+		return
+	}
+	limit = min(limit, maxRecentContacts)
+
+	var contacts []models.Contact
+	if err := db.Select("ID", "Firstname", "Lastname", "Nickname", "Photo", "PhotoThumbnail", "CreatedAt", "UpdatedAt").
+		Scopes(ownContacts(c)).
+		Order(column + " DESC").Order("id DESC").
+		Limit(limit).Find(&contacts).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve contacts"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"contacts": contacts})
+}
+
+// GetDuplicateContacts returns clusters of contacts which are likely the same person
+//
+//	@Summary	List likely duplicate contacts
+//	@Tags		contacts
+//	@Produce	json
+//	@Success	200	{object}	object{clusters=[]services.DuplicateCluster}
+//	@Failure	500	{object}	errorResponse
+//	@Security	bearerauth
+//	@Router		/contacts/duplicates [get]
+func GetDuplicateContacts(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+
+	var contacts []models.Contact
+	if err := db.Select("ID", "Firstname", "Lastname", "Nickname", "Email", "Phone").Scopes(ownContacts(c)).Order("id").Find(&contacts).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve contacts"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"clusters": services.FindDuplicateContacts(contacts)})
+}
+
+// MergeContact merges the contact given as source_id into the contact of the URL. Notes, activities, reminders
+// and relationships are moved over, blank fields are filled from the source and the source is deleted afterwards.
+//
+//	@Summary	Merge another contact into a contact
+//	@Tags		contacts
+//	@Accept		json
+//	@Produce	json
+//	@Param		id		path		int							true	"Contact ID of the target"
+//	@Param		request	body		object{source_id=integer}	true	"Contact to merge and delete"
+//	@Success	200		{object}	object{message=string,contact=models.Contact}
+//	@Failure	400		{object}	validationErrorResponse
+//	@Failure	404		{object}	errorResponse
+//	@Failure	500		{object}	errorResponse
+//	@Security	bearerauth
+//	@Router		/contacts/{id}/merge [post]
+func MergeContact(c *gin.Context) {
+	id := c.Param("id")
+	db := c.MustGet("db").(*gorm.DB)
+
+	var request struct {
+		SourceID uint `json:"source_id" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		respondBindingError(c, err)
+		return
+	}
+
+	var target, source models.Contact
+	if err := db.Scopes(ownContacts(c)).First(&target, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Contact not found"})
+		return
+	}
+	if err := db.Scopes(ownContacts(c)).First(&source, request.SourceID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Source contact not found"})
+		return
+	}
+	if source.ID == target.ID {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "A contact cannot be merged into itself"})
+		return
+	}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		moves := []struct {
+			model  any
+			column string
+		}{
+			{&models.Note{}, "contact_id"},
+			{&models.Reminder{}, "contact_id"},
+			{&models.Relationship{}, "contact_id"},
+			{&models.Relationship{}, "related_contact_id"},
+			{&models.ContactAttachment{}, "contact_id"},
+			{&models.CustomDate{}, "contact_id"},
+		}
+		for _, move := range moves {
+			if err := tx.Unscoped().Model(move.model).Where(move.column+" = ?", source.ID).Update(move.column, target.ID).Error; err != nil {
+				return err
+			}
+		}
+
+		// The primary methods of the target stay primary, contacts saved before they had
+		// contact methods get their existing email and phone first
+		for _, contact := range []models.Contact{target, source} {
+			if err := services.SyncLegacyContactMethods(tx, contact); err != nil {
+				return err
+			}
+		}
+		if err := tx.Unscoped().Model(&models.ContactMethod{}).Where("contact_id = ?", source.ID).
+			Updates(map[string]any{"contact_id": target.ID, "is_primary": false}).Error; err != nil {
+			return err
+		}
+
+		// Relationships between both contacts would now point to the contact itself
+		if err := tx.Unscoped().Where("contact_id = ? AND related_contact_id = ?", target.ID, target.ID).Delete(&models.Relationship{}).Error; err != nil {
+			return err
+		}
+
+		// Shared activities are linked only once
+		if err := tx.Exec(`UPDATE activity_contacts SET contact_id = ? WHERE contact_id = ?
+			AND activity_id NOT IN (SELECT activity_id FROM activity_contacts WHERE contact_id = ?)`, target.ID, source.ID, target.ID).Error; err != nil {
+			return err
+		}
+		if err := tx.Exec("DELETE FROM activity_contacts WHERE contact_id = ?", source.ID).Error; err != nil {
+			return err
+		}
+
+		before := target
+		before.Circles = slices.Clone(target.Circles)
+		before.Tags = slices.Clone(target.Tags)
+		target.FillBlanks(source)
+		target.Version++
+		if err := tx.Save(&target).Error; err != nil {
+			return err
+		}
+		if err := tx.Delete(&source).Error; err != nil {
+			return err
+		}
+		for _, methodType := range []string{models.ContactMethodEmail, models.ContactMethodPhone} {
+			if err := services.EnsurePrimaryContactMethod(tx, target.ID, methodType); err != nil {
+				return err
+			}
+		}
+		recordAudit(c, tx, contactAudit(target, models.AuditUpdate), before, target)
+		recordAudit(c, tx, contactAudit(source, models.AuditDelete), source, nil)
+		return nil
+	})
+	if err != nil {
+		middleware.Logger(c).Error("Failed to merge contacts", "contact_id", target.ID, "source_contact_id", source.ID, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to merge contacts"})
+		return
+	}
+
+	if err := db.Preload("Notes").Preload("Activities").Preload("Relationships").Preload("Reminders").First(&target, target.ID).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve merged contact"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Contacts merged successfully", "contact": target})
+}
+
+// DeleteContact moves a contact to the trash together with its notes, reminders, relationships and
+// the activities only shared with this contact. Relationships of other contacts pointing to it are trashed as well.
+//
+//	@Summary	Move a contact to the trash
+//	@Tags		contacts
+//	@Produce	json
+//	@Param		id	path		int	true	"Contact ID"
+//	@Success	200	{object}	object{message=string}
+//	@Failure	404	{object}	errorResponse
+//	@Failure	500	{object}	errorResponse
+//	@Security	bearerauth
+//	@Router		/contacts/{id} [delete]
+func DeleteContact(c *gin.Context) {
+	id := c.Param("id")
+	db := c.MustGet("db").(*gorm.DB)
+
+	var contact models.Contact
+	if err := db.Scopes(ownContacts(c)).First(&contact, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Contact not found"})
+		return
+	}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		return trashContact(tx, contact)
+	})
+	if err != nil {
+		middleware.Logger(c).Error("Failed to delete contact", "contact_id", contact.ID, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete contact"})
+		return
+	}
+	recordAudit(c, db, contactAudit(contact, models.AuditDelete), contact, nil)
+
+	c.JSON(http.StatusOK, gin.H{"message": "Contact deleted"})
+}
+
+// trashContact soft-deletes the contact together with its related rows
+func trashContact(tx *gorm.DB, contact models.Contact) error {
+	// All rows share the same deletion time so that a restore can bring back exactly these rows
+	deletedAt := time.Now()
+	for _, related := range contactRelatedRows(contact.ID) {
+		if err := tx.Model(related.model).Where(related.query, related.args...).Update("deleted_at", deletedAt).Error; err != nil {
+			return err
+		}
+	}
+	return tx.Model(&contact).Update("deleted_at", deletedAt).Error
+}
+
+// bulkContactsRequest selects the contacts of a bulk operation
+type bulkContactsRequest struct {
+	IDs []uint `json:"ids" binding:"required,min=1,max=500"`
+}
+
+// bulkResult is the outcome of a bulk operation for a single contact
+type bulkResult struct {
+	ID      uint   `json:"id"`
+	Success bool   `json:"success"`
+	Error   string `json:"error,omitempty"`
+}
+
+// findBulkContacts loads the requested contacts of the user by their ID. Each ID is listed once in the results,
+// which already report the IDs without a contact as failed.
+func findBulkContacts(c *gin.Context, db *gorm.DB, ids []uint) (map[uint]models.Contact, []bulkResult, error) {
+	var contacts []models.Contact
+	if err := db.Scopes(ownContacts(c)).Where("id IN ?", ids).Find(&contacts).Error; err != nil {
+		return nil, nil, err
+	}
+	found := make(map[uint]models.Contact, len(contacts))
+	for _, contact := range contacts {
+		found[contact.ID] = contact
+	}
+
+	ids = sortedIDs(ids)
+	results := make([]bulkResult, len(ids))
+	for i, id := range ids {
+		results[i] = bulkResult{ID: id, Success: true}
+		if _, exists := found[id]; !exists {
+			results[i] = bulkResult{ID: id, Error: "Contact not found"}
+		}
+	}
+	return found, results, nil
+}
+
+// BulkDeleteContacts moves several contacts to the trash in a single transaction. Unknown IDs are reported
+// as failed without affecting the others.
+//
+//	@Summary	Move several contacts to the trash
+//	@Tags		contacts
+//	@Accept		json
+//	@Produce	json
+//	@Param		request	body		bulkContactsRequest	true	"Contacts to delete"
+//	@Success	200		{object}	object{results=[]bulkResult,deleted=int}
+//	@Failure	400		{object}	validationErrorResponse
+//	@Failure	500		{object}	errorResponse
+//	@Security	bearerauth
+//	@Router		/contacts/bulk/delete [post]
+func BulkDeleteContacts(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+
+	var request bulkContactsRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		respondBindingError(c, err)
+		return
+	}
+
+	contacts, results, err := findBulkContacts(c, db, request.IDs)
+	if err == nil {
+		err = db.Transaction(func(tx *gorm.DB) error {
+			for _, contact := range contacts {
+				if err := trashContact(tx, contact); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	}
+	if err != nil {
+		middleware.Logger(c).Error("Failed to delete contacts", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete contacts"})
+		return
+	}
+	for _, contact := range contacts {
+		recordAudit(c, db, contactAudit(contact, models.AuditDelete), contact, nil)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"results": results, "deleted": len(contacts)})
+}
+
+// contactRelatedRow selects rows of a model belonging to a contact
+type contactRelatedRow struct {
+	model any
+	query string
+	args  []any
+}
+
+// contactRelatedRows returns the rows belonging to a contact, which are deleted and restored together with it
+func contactRelatedRows(contactID uint) []contactRelatedRow {
+	return []contactRelatedRow{
+		{&models.Note{}, "contact_id = ?", []any{contactID}},
+		{&models.Reminder{}, "contact_id = ?", []any{contactID}},
+		{&models.ContactMethod{}, "contact_id = ?", []any{contactID}},
+		{&models.ContactAttachment{}, "contact_id = ?", []any{contactID}},
+		{&models.CustomDate{}, "contact_id = ?", []any{contactID}},
+		{&models.Relationship{}, "contact_id = ? OR related_contact_id = ?", []any{contactID, contactID}},
+		// Activities shared with other contacts are kept for them
+		{&models.Activity{}, "id IN (SELECT activity_id FROM activity_contacts WHERE contact_id = ?) AND id NOT IN (SELECT activity_id FROM activity_contacts WHERE contact_id <> ?)", []any{contactID, contactID}},
+	}
+}
+
+// GetTrashedContacts lists soft-deleted contacts, most recently deleted first
+//
+//	@Summary	List contacts in the trash
+//	@Tags		contacts
+//	@Produce	json
+//	@Param		page	query		int	false	"Page number"					default(1)
+//	@Param		limit	query		int	false	"Items per page, up to the configured maximum"
+//	@Success	200		{object}	contactPage
+//	@Failure	500		{object}	errorResponse
+//	@Security	bearerauth
+//	@Router		/contacts/trash [get]
+func GetTrashedContacts(c *gin.Context, cfg *config.Config) {
+	db := c.MustGet("db").(*gorm.DB)
+
+	page, limit, offset := paginationParams(c, cfg)
+
+	query := db.Unscoped().Model(&models.Contact{}).Scopes(ownContacts(c)).Where("deleted_at IS NOT NULL")
+
+	var total int64
+	query.Count(&total)
+
+	var contacts []models.Contact
+	if err := query.Order("deleted_at DESC").Limit(limit).Offset(offset).Find(&contacts).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve trashed contacts"})
+		return
+	}
+
+	c.JSON(http.StatusOK, paginatedResponse("contacts", contacts, total, page, limit))
+}
+
+// RestoreContact moves a soft-deleted contact and the rows deleted together with it out of the trash
+//
+//	@Summary	Restore a contact from the trash
+//	@Tags		contacts
+//	@Produce	json
+//	@Param		id	path		int	true	"Contact ID"
+//	@Success	200	{object}	object{message=string,contact=models.Contact}
+//	@Failure	404	{object}	errorResponse
+//	@Failure	500	{object}	errorResponse
+//	@Security	bearerauth
+//	@Router		/contacts/{id}/restore [post]
+func RestoreContact(c *gin.Context) {
+	id := c.Param("id")
+	db := c.MustGet("db").(*gorm.DB)
+
+	var contact models.Contact
+	if err := db.Unscoped().Scopes(ownContacts(c)).Where("deleted_at IS NOT NULL").First(&contact, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Contact not found in trash"})
+		return
+	}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		for _, related := range contactRelatedRows(contact.ID) {
+			if err := tx.Unscoped().Model(related.model).Where(related.query, related.args...).Where("deleted_at = ?", contact.DeletedAt).Update("deleted_at", nil).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Unscoped().Model(&contact).Update("deleted_at", nil).Error
+	})
+	if err != nil {
+		middleware.Logger(c).Error("Failed to restore contact", "contact_id", contact.ID, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to restore contact"})
+		return
+	}
+	contact.DeletedAt = gorm.DeletedAt{}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Contact restored", "contact": contact})
+}
+
+// DeleteContactPermanently removes a contact and all its related rows from the database, whether it is in the trash or not
+//
+//	@Summary	Delete a contact permanently
+//	@Tags		contacts
+//	@Produce	json
+//	@Param		id	path		int	true	"Contact ID"
+//	@Success	200	{object}	object{message=string}
+//	@Failure	404	{object}	errorResponse
+//	@Failure	500	{object}	errorResponse
+//	@Security	bearerauth
+//	@Router		/contacts/{id}/permanent [delete]
+func DeleteContactPermanently(c *gin.Context) {
+	id := c.Param("id")
+	db := c.MustGet("db").(*gorm.DB)
+
+	var contact models.Contact
+	if err := db.Unscoped().Scopes(ownContacts(c)).First(&contact, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Contact not found"})
+		return
+	}
+
+	var attachments []models.NoteAttachment
+	var contactAttachments []models.ContactAttachment
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("note_id IN (SELECT id FROM notes WHERE contact_id = ?)", contact.ID).Find(&attachments).Error; err != nil {
+			return err
+		}
+		// Attachments of trashed contacts are in the trash as well
+		if err := tx.Unscoped().Where("contact_id = ?", contact.ID).Find(&contactAttachments).Error; err != nil {
+			return err
+		}
+		if err := tx.Unscoped().Where("id IN ?", attachmentIDs(attachments)).Delete(&models.NoteAttachment{}).Error; err != nil {
+			return err
+		}
+		for _, related := range contactRelatedRows(contact.ID) {
+			if err := tx.Unscoped().Where(related.query, related.args...).Delete(related.model).Error; err != nil {
+				return err
+			}
+		}
+		if err := tx.Exec("DELETE FROM activity_contacts WHERE contact_id = ?", contact.ID).Error; err != nil {
+			return err
+		}
+		// Nothing of the contact is kept, including its history
+		if err := tx.Where("contact_id = ?", contact.ID).Delete(&models.AuditLog{}).Error; err != nil {
+			return err
+		}
+		return tx.Unscoped().Delete(&contact).Error
+	})
+	if err != nil {
+		middleware.Logger(c).Error("Failed to delete contact permanently", "contact_id", contact.ID, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete contact"})
+		return
+	}
+
+	// Files are only removed once the rows are gone for good
+	for _, attachment := range attachments {
+		removeAttachmentFile(attachment)
+	}
+	for _, attachment := range contactAttachments {
+		removeContactAttachmentFile(attachment)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Contact permanently deleted"})
+}
+
+// GetCircles returns all unique circles associated with contacts.
+// With counts=true the circles are returned with their number of contacts, largest circles first.
+//
+//	@Summary	List the circles of contacts
+//	@Tags		contacts
+//	@Produce	json
+//	@Param		counts	query		bool	false	"Return circleCount objects with the number of contacts instead of names"
+//	@Success	200		{array}		string
+//	@Failure	500		{object}	errorResponse
+//	@Security	bearerauth
+//	@Router		/contacts/circles [get]
+func GetCircles(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+	if c.Query("counts") == "true" {
+		getCircleCounts(c, db)
+		return
+	}
+
+	circleNames := []string{}
+	var err error
+
+	if db.Dialector.Name() == services.DriverSQLite {
+		// Raw SQL query to extract unique circle names
+		err = db.Raw(`SELECT DISTINCT json_each.value AS circle
+		              FROM contacts, json_each(contacts.circles)
+		              WHERE contacts.deleted_at IS NULL AND contacts.user_id = ? AND json_each.type = 'text'`, currentUserID(c)).Scan(&circleNames).Error
+	} else {
+		// Other databases have no portable JSON functions, so the circles are collected in Go
+		var contacts []models.Contact
+		err = db.Scopes(ownContacts(c)).Select("Circles").Find(&contacts).Error
+		circleNames = distinctCircles(contacts)
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve circles"})
+		return
+	}
+
+	// Return the list of unique circle names
+	c.JSON(http.StatusOK, circleNames)
+}
+
+// circleCount is a circle with the number of contacts in it
+type circleCount struct {
+	Name  string `json:"name"`
+	Count int64  `json:"count"`
+}
+
+// getCircleCounts responds with all circles and their number of contacts, sorted by count and then by name
+func getCircleCounts(c *gin.Context, db *gorm.DB) {
+	counts := []circleCount{}
+	err := db.Raw(`SELECT json_each.value AS name, COUNT(DISTINCT contacts.id) AS count
+	               FROM contacts, `+services.Dialect(db).JSONEach("contacts.circles")+`
+	               WHERE contacts.deleted_at IS NULL AND contacts.user_id = ?
+	               GROUP BY json_each.value
+	               ORDER BY count DESC, name`, currentUserID(c)).Scan(&counts).Error
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve circles"})
+		return
+	}
+
+	c.JSON(http.StatusOK, counts)
+}
+
+// distinctCircles returns each circle of the contacts once, in the order they first appear
+func distinctCircles(contacts []models.Contact) []string {
+	circles := []string{}
+	seen := map[string]bool{}
+	for _, contact := range contacts {
+		for _, circle := range contact.Circles {
+			if !seen[circle] {
+				seen[circle] = true
+				circles = append(circles, circle)
+			}
+		}
+	}
+	return circles
+}
+
+// GetContactTags returns all unique tags of the contacts, the tags of notes are separate
+//
+//	@Summary	List the tags of contacts
+//	@Tags		contacts
+//	@Produce	json
+//	@Success	200	{array}		string
+//	@Failure	500	{object}	errorResponse
+//	@Security	bearerauth
+//	@Router		/contacts/tags [get]
+func GetContactTags(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+	tags := []string{}
+
+	err := db.Raw(`SELECT DISTINCT json_each.value AS tag
+	               FROM contacts, `+services.Dialect(db).JSONEach("contacts.tags")+`
+	               WHERE contacts.deleted_at IS NULL AND contacts.user_id = ?
+	               ORDER BY tag`, currentUserID(c)).Scan(&tags).Error
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve tags"})
+		return
+	}
+
+	c.JSON(http.StatusOK, tags)
 }
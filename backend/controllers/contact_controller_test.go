@@ -2,20 +2,29 @@ package controllers
 
 import (
 	"bytes"
+	"encoding/csv"
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"perema/config"
 	"perema/models"
+	"perema/services"
 	"strconv"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
 )
 
 func TestGetContacts(t *testing.T) {
 	db, router := setupRouter()
 
-	router.GET("/contacts", GetContacts)
+	router.GET("/contacts", func(c *gin.Context) {
+		GetContacts(c, &config.Config{DefaultPageLimit: 25, MaxPageLimit: 100})
+	})
 
 	// Create some contacts
 	contacts := []models.Contact{
@@ -81,10 +90,407 @@ func TestGetContacts(t *testing.T) {
 	assert.Equal(t, float64(2), responseBody["limit"]) // Limit per page
 }
 
+func TestGetContactsFilteredTotal(t *testing.T) {
+	db, router := setupRouter()
+
+	router.GET("/contacts", func(c *gin.Context) {
+		GetContacts(c, &config.Config{DefaultPageLimit: 25, MaxPageLimit: 100})
+	})
+
+	contacts := []models.Contact{
+		{Firstname: "Alice", Lastname: "Johnson", Circles: []string{"Friends"}, Tags: []string{"VIP"}},
+		{Firstname: "Alina", Lastname: "Smith", Circles: []string{"Friends", "Work"}},
+		{Firstname: "Bob", Lastname: "Alison", Circles: []string{"Work"}, Tags: []string{"VIP"}},
+		{Firstname: "Carol", Lastname: "Williams", Circles: []string{"Family"}},
+		{Firstname: "David", Lastname: "Brown"},
+	}
+	for _, contact := range contacts {
+		db.Create(&contact)
+	}
+
+	// The total counts all matching contacts, not only the page and not all contacts
+	for query, expected := range map[string]int64{
+		"search=ali":              3,
+		"circle=Friends":          2,
+		"circle=Work&search=ali":  2,
+		"tag=VIP":                 2,
+		"tag=VIP&circle=Friends":  1,
+		"search=nobody":           0,
+		"circle=Family&tag=other": 0,
+	} {
+		req, _ := http.NewRequest("GET", "/contacts?limit=1&"+query, nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		var responseBody struct {
+			Contacts   []models.Contact `json:"contacts"`
+			Total      int64            `json:"total"`
+			TotalPages int              `json:"total_pages"`
+		}
+		json.Unmarshal(w.Body.Bytes(), &responseBody)
+		assert.Equal(t, expected, responseBody.Total, query)
+		assert.Equal(t, int(expected), responseBody.TotalPages, query)
+		assert.Len(t, responseBody.Contacts, min(int(expected), 1), query)
+	}
+}
+
+func TestGetContactsView(t *testing.T) {
+	db, router := setupRouter()
+
+	cfg := &config.Config{DefaultPageLimit: 25, MaxPageLimit: 100, ContactViews: map[string]config.ContactView{
+		"card": {Fields: []string{"ID", "firstname", "lastname", "circles"}, Includes: []string{"reminders"}},
+	}}
+	router.GET("/contacts", func(c *gin.Context) {
+		GetContacts(c, cfg)
+	})
+
+	contact := models.Contact{Firstname: "Alice", Lastname: "Johnson", Email: "alice@example.com", Circles: []string{"Friends"}}
+	db.Create(&contact)
+	db.Create(&models.Reminder{Message: "Call Alice", RemindAt: time.Now(), Recurrence: "Once", ContactID: &contact.ID})
+
+	// The card view only returns the configured columns and includes
+	req, _ := http.NewRequest("GET", "/contacts?view=card", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var responseBody struct {
+		Contacts []map[string]any `json:"contacts"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &responseBody)
+	assert.Len(t, responseBody.Contacts, 1)
+
+	var keys []string
+	for key := range responseBody.Contacts[0] {
+		keys = append(keys, key)
+	}
+	assert.ElementsMatch(t, []string{"ID", "firstname", "lastname", "circles", "reminders"}, keys)
+	assert.Equal(t, "Alice", responseBody.Contacts[0]["firstname"])
+
+	// Explicit fields are used if no view is given
+	req, _ = http.NewRequest("GET", "/contacts?fields=firstname,email", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	responseBody.Contacts = nil
+	json.Unmarshal(w.Body.Bytes(), &responseBody)
+	assert.Len(t, responseBody.Contacts[0], 2)
+	assert.Equal(t, "alice@example.com", responseBody.Contacts[0]["email"])
+
+	// The timestamps are returned when selected and by default
+	for _, url := range []string{"/contacts?fields=firstname,CreatedAt,UpdatedAt", "/contacts"} {
+		req, _ = http.NewRequest("GET", url, nil)
+		w = httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		var timestamps struct {
+			Contacts []models.Contact `json:"contacts"`
+		}
+		json.Unmarshal(w.Body.Bytes(), &timestamps)
+		assert.WithinDuration(t, contact.CreatedAt, timestamps.Contacts[0].CreatedAt, time.Second, url)
+		assert.WithinDuration(t, contact.UpdatedAt, timestamps.Contacts[0].UpdatedAt, time.Second, url)
+	}
+
+	// Unknown fields are rejected instead of being passed to the query
+	for _, fields := range []string{"firstname)%3BDROP", "firstname,photo", "firstname%2C%20lastname"} {
+		req, _ = http.NewRequest("GET", "/contacts?fields="+fields, nil)
+		w = httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusBadRequest, w.Code, fields)
+	}
+	var count int64
+	db.Model(&models.Contact{}).Count(&count)
+	assert.Equal(t, int64(1), count)
+
+	// Unknown views are rejected
+	req, _ = http.NewRequest("GET", "/contacts?view=unknown", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestContactAddress(t *testing.T) {
+	db, router := setupRouter()
+
+	cfg := &config.Config{DefaultPageLimit: 25, MaxPageLimit: 100}
+	router.GET("/contacts", func(c *gin.Context) {
+		GetContacts(c, cfg)
+	})
+	router.POST("/contacts", CreateContact)
+	router.PATCH("/contacts/:id", PatchContact)
+
+	send := func(method, url, body string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(method, url, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	// Addresses are accepted structured and, as before, as a plain string which becomes the street
+	assert.Equal(t, http.StatusCreated, send("POST", "/contacts", `{"firstname": "Alice", "address": {"street": "Main Street 1", "postal_code": "10115", "city": "Berlin", "country": "Germany"}}`).Code)
+	assert.Equal(t, http.StatusCreated, send("POST", "/contacts", `{"firstname": "Bob", "address": "Station Road 2"}`).Code)
+
+	var bob models.Contact
+	db.Where("firstname = ?", "Bob").First(&bob)
+	assert.Equal(t, models.Address{Street: "Station Road 2"}, bob.Address)
+
+	// Patching a part of the address keeps the other parts
+	assert.Equal(t, http.StatusOK, send("PATCH", "/contacts/"+strconv.Itoa(int(bob.ID)), `{"address": {"city": "munich"}}`).Code)
+	db.First(&bob, bob.ID)
+	assert.Equal(t, models.Address{Street: "Station Road 2", City: "munich"}, bob.Address)
+
+	var responseBody struct {
+		Contacts []models.Contact `json:"contacts"`
+	}
+	w := send("GET", "/contacts?city=berlin", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	json.Unmarshal(w.Body.Bytes(), &responseBody)
+	assert.Len(t, responseBody.Contacts, 1)
+	assert.Equal(t, "Alice", responseBody.Contacts[0].Firstname)
+	assert.Equal(t, "Main Street 1, 10115 Berlin, Germany", responseBody.Contacts[0].Address.String())
+
+	// All parts of the address are searched
+	responseBody.Contacts = nil
+	json.Unmarshal(send("GET", "/contacts?search=munich&search_fields=address", "").Body.Bytes(), &responseBody)
+	assert.Len(t, responseBody.Contacts, 1)
+	assert.Equal(t, "Bob", responseBody.Contacts[0].Firstname)
+
+	// Selecting the address field returns the whole address
+	var selected struct {
+		Contacts []map[string]any `json:"contacts"`
+	}
+	json.Unmarshal(send("GET", "/contacts?fields=firstname,address&city=Berlin", "").Body.Bytes(), &selected)
+	assert.Equal(t, "Germany", selected.Contacts[0]["address"].(map[string]any)["country"])
+}
+
+func TestContactTags(t *testing.T) {
+	db, router := setupRouter()
+
+	cfg := &config.Config{DefaultPageLimit: 25, MaxPageLimit: 100}
+	router.GET("/contacts", func(c *gin.Context) {
+		GetContacts(c, cfg)
+	})
+	router.GET("/contacts/tags", GetContactTags)
+	router.POST("/contacts", CreateContact)
+
+	send := func(method, url, body string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(method, url, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	// Tags are trimmed and kept once
+	assert.Equal(t, http.StatusCreated, send("POST", "/contacts", `{"firstname": "Alice", "tags": ["VIP", " owes me money", "VIP", ""]}`).Code)
+	assert.Equal(t, http.StatusCreated, send("POST", "/contacts", `{"firstname": "Bob", "tags": ["VIP"]}`).Code)
+	assert.Equal(t, http.StatusCreated, send("POST", "/contacts", `{"firstname": "Carol", "tags": ["VIPs"], "circles": ["VIP"]}`).Code)
+
+	var alice models.Contact
+	db.Where("firstname = ?", "Alice").First(&alice)
+	assert.Equal(t, []string{"VIP", "owes me money"}, alice.Tags)
+
+	names := func(url string) []string {
+		var responseBody struct {
+			Contacts []models.Contact `json:"contacts"`
+		}
+		w := send("GET", url, "")
+		assert.Equal(t, http.StatusOK, w.Code)
+		json.Unmarshal(w.Body.Bytes(), &responseBody)
+		names := []string{}
+		for _, contact := range responseBody.Contacts {
+			names = append(names, contact.Firstname)
+		}
+		return names
+	}
+
+	// Tags are matched exactly and separately from circles, several tags must all match
+	assert.ElementsMatch(t, []string{"Alice", "Bob"}, names("/contacts?tag=VIP"))
+	assert.Equal(t, []string{"Alice"}, names("/contacts?tag=VIP&tag=owes+me+money"))
+	assert.Empty(t, names("/contacts?tag=VIP&tag=VIPs"))
+
+	var tags []string
+	json.Unmarshal(send("GET", "/contacts/tags", "").Body.Bytes(), &tags)
+	assert.Equal(t, []string{"VIP", "VIPs", "owes me money"}, tags)
+}
+
+func TestGetContactsSorting(t *testing.T) {
+	db, router := setupRouter()
+
+	cfg := &config.Config{DefaultPageLimit: 25, MaxPageLimit: 100}
+	router.GET("/contacts", func(c *gin.Context) {
+		GetContacts(c, cfg)
+	})
+
+	db.Create(&models.Contact{Firstname: "Carol", Lastname: "Williams", Birthday: &models.Date{Time: time.Date(1985, 2, 1, 0, 0, 0, 0, time.UTC), Valid: true}})
+	db.Create(&models.Contact{Firstname: "Alice", Lastname: "Smith", Birthday: &models.Date{Time: time.Date(1990, 5, 4, 0, 0, 0, 0, time.UTC), Valid: true}})
+	db.Create(&models.Contact{Firstname: "Bob", Lastname: "Smith", Birthday: &models.Date{Time: time.Date(1970, 12, 24, 0, 0, 0, 0, time.UTC), Valid: true}})
+
+	list := func(url string) (int, []string) {
+		req, _ := http.NewRequest("GET", url, nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		var responseBody struct {
+			Contacts []models.Contact `json:"contacts"`
+		}
+		json.Unmarshal(w.Body.Bytes(), &responseBody)
+		names := []string{}
+		for _, contact := range responseBody.Contacts {
+			names = append(names, contact.Firstname)
+		}
+		return w.Code, names
+	}
+
+	// Contacts are sorted by last name by default, equal names keep their creation order
+	code, names := list("/contacts")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, []string{"Alice", "Bob", "Carol"}, names)
+
+	_, names = list("/contacts?sort=birthday&order=desc")
+	assert.Equal(t, []string{"Alice", "Carol", "Bob"}, names)
+
+	_, names = list("/contacts?sort=firstname&order=desc")
+	assert.Equal(t, []string{"Carol", "Bob", "Alice"}, names)
+
+	// The sort is applied before pagination
+	_, names = list("/contacts?sort=created_at&order=desc&limit=2&page=2")
+	assert.Equal(t, []string{"Carol"}, names)
+
+	// Recently changed contacts come first when sorting by the modification time
+	db.Model(&models.Contact{}).Where("firstname = ?", "Alice").Update("updated_at", time.Now().Add(time.Hour))
+	_, names = list("/contacts?sort=updated_at&order=desc")
+	assert.Equal(t, []string{"Alice", "Bob", "Carol"}, names)
+
+	// Names are sorted ignoring the case
+	db.Create(&models.Contact{Firstname: "dave", Lastname: "miller"})
+	_, names = list("/contacts?sort=lastname")
+	assert.Equal(t, []string{"dave", "Alice", "Bob", "Carol"}, names)
+
+	code, _ = list("/contacts?sort=email")
+	assert.Equal(t, http.StatusBadRequest, code)
+	code, _ = list("/contacts?sort=lastname%3B+DROP+TABLE+contacts")
+	assert.Equal(t, http.StatusBadRequest, code)
+	code, _ = list("/contacts?order=sideways")
+	assert.Equal(t, http.StatusBadRequest, code)
+}
+
+func TestContactFavorites(t *testing.T) {
+	db, router := setupRouter()
+
+	cfg := &config.Config{DefaultPageLimit: 25, MaxPageLimit: 100}
+	router.GET("/contacts", func(c *gin.Context) {
+		GetContacts(c, cfg)
+	})
+	router.POST("/contacts/:id/favorite", ToggleFavorite)
+
+	alice := models.Contact{Firstname: "Alice", Lastname: "Adams"}
+	bob := models.Contact{Firstname: "Bob", Lastname: "Brown"}
+	carol := models.Contact{Firstname: "Carol", Lastname: "Clark"}
+	mallory := models.Contact{Firstname: "Mallory", UserID: 2}
+	db.Create(&alice)
+	db.Create(&bob)
+	db.Create(&carol)
+	db.Create(&mallory)
+
+	toggle := func(id uint) (int, models.Contact) {
+		req, _ := http.NewRequest("POST", "/contacts/"+strconv.Itoa(int(id))+"/favorite", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		var contact models.Contact
+		json.Unmarshal(w.Body.Bytes(), &contact)
+		return w.Code, contact
+	}
+	list := func(url string) []string {
+		req, _ := http.NewRequest("GET", url, nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		var responseBody struct {
+			Contacts []models.Contact `json:"contacts"`
+		}
+		json.Unmarshal(w.Body.Bytes(), &responseBody)
+		names := []string{}
+		for _, contact := range responseBody.Contacts {
+			names = append(names, contact.Firstname)
+		}
+		return names
+	}
+
+	code, toggled := toggle(carol.ID)
+	assert.Equal(t, http.StatusOK, code)
+	assert.True(t, toggled.Favorite)
+	assert.Equal(t, carol.Version+1, toggled.Version)
+	toggle(bob.ID)
+	code, _ = toggle(mallory.ID)
+	assert.Equal(t, http.StatusNotFound, code)
+
+	// Favorites come first in the default order, an explicit sort ignores them
+	assert.Equal(t, []string{"Bob", "Carol", "Alice"}, list("/contacts"))
+	assert.Equal(t, []string{"Alice", "Bob", "Carol"}, list("/contacts?sort=lastname"))
+	assert.Equal(t, []string{"Bob", "Carol"}, list("/contacts?favorites=true"))
+
+	// Toggling again unpins the contact
+	_, toggled = toggle(bob.ID)
+	assert.False(t, toggled.Favorite)
+	var stored models.Contact
+	db.First(&stored, bob.ID)
+	assert.False(t, stored.Favorite)
+	assert.Equal(t, []string{"Carol"}, list("/contacts?favorites=true"))
+}
+
+func TestGetContactsSearchFields(t *testing.T) {
+	db, router := setupRouter()
+
+	cfg := &config.Config{DefaultPageLimit: 25, MaxPageLimit: 100, SearchFields: []string{"firstname", "lastname", "nickname"}}
+	router.GET("/contacts", func(c *gin.Context) {
+		GetContacts(c, cfg)
+	})
+
+	db.Create(&models.Contact{Firstname: "Alice", Lastname: "Johnson", Email: "alice@acme.com"})
+	db.Create(&models.Contact{Firstname: "Bob", Lastname: "Acme", Email: "bob@example.com"})
+	db.Create(&models.Contact{Firstname: "Carol", Lastname: "Williams", Email: "carol@example.com"})
+
+	var responseBody struct {
+		Contacts []models.Contact `json:"contacts"`
+	}
+
+	// By default only the name fields are searched
+	req, _ := http.NewRequest("GET", "/contacts?search=acme", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	json.Unmarshal(w.Body.Bytes(), &responseBody)
+	assert.Len(t, responseBody.Contacts, 1)
+	assert.Equal(t, "Bob", responseBody.Contacts[0].Firstname)
+
+	// Including the email field also matches Alice
+	req, _ = http.NewRequest("GET", "/contacts?search=acme&search_fields=lastname,email", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	json.Unmarshal(w.Body.Bytes(), &responseBody)
+	assert.Len(t, responseBody.Contacts, 2)
+
+	// Fields outside of the whitelist are rejected
+	req, _ = http.NewRequest("GET", "/contacts?search=acme&search_fields=email,photo", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
 func TestGetContact(t *testing.T) {
 	db, router := setupRouter()
 
-	router.GET("/contacts/:id", GetContact)
+	router.GET("/contacts/:id", func(c *gin.Context) {
+		GetContact(c, &config.Config{Timezone: time.UTC})
+	})
 
 	// Create a contact
 	contact := models.Contact{
@@ -109,6 +515,388 @@ func TestGetContact(t *testing.T) {
 	assert.Equal(t, contact.Firstname, responseBody.Firstname)
 }
 
+func TestContactETags(t *testing.T) {
+	db, router := setupRouter()
+
+	cfg := &config.Config{Timezone: time.UTC, DefaultPageLimit: 25, MaxPageLimit: 100}
+	router.GET("/contacts", func(c *gin.Context) {
+		GetContacts(c, cfg)
+	})
+	router.GET("/contacts/:id", func(c *gin.Context) {
+		GetContact(c, cfg)
+	})
+
+	contact := models.Contact{Firstname: "Jane", Lastname: "Doe"}
+	db.Create(&contact)
+	url := "/contacts/" + strconv.Itoa(int(contact.ID))
+
+	get := func(url, ifNoneMatch string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("GET", url, nil)
+		if ifNoneMatch != "" {
+			req.Header.Set("If-None-Match", ifNoneMatch)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	for _, url := range []string{url, "/contacts"} {
+		w := get(url, "")
+		assert.Equal(t, http.StatusOK, w.Code)
+		etag := w.Header().Get("ETag")
+		assert.NotEmpty(t, etag)
+
+		// Unchanged data is not sent again, also when the client lists several or weak ETags
+		w = get(url, etag)
+		assert.Equal(t, http.StatusNotModified, w.Code, url)
+		assert.Empty(t, w.Body.String())
+		assert.Equal(t, http.StatusNotModified, get(url, `"other", W/`+etag).Code, url)
+		assert.Equal(t, http.StatusOK, get(url, `"other"`).Code, url)
+	}
+
+	// Changes of the contact change the ETags of the contact and of the list
+	contactETag := get(url, "").Header().Get("ETag")
+	listETag := get("/contacts", "").Header().Get("ETag")
+	db.Model(&contact).Update("nickname", "JD")
+	assert.Equal(t, http.StatusOK, get(url, contactETag).Code)
+	assert.Equal(t, http.StatusOK, get("/contacts", listETag).Code)
+	assert.NotEqual(t, listETag, get("/contacts?page=2", "").Header().Get("ETag"))
+}
+
+func TestGetContactSummary(t *testing.T) {
+	db, router := setupRouter()
+
+	cfg := &config.Config{DefaultPageLimit: 25, MaxPageLimit: 100, Timezone: time.UTC, ContactSummaryTemplate: "{circles} in {address}, works at {work}, last seen {last_seen}"}
+	router.GET("/contacts/:id", func(c *gin.Context) {
+		GetContact(c, cfg)
+	})
+
+	contact := models.Contact{Firstname: "Jane", Circles: []string{"close friend"}, WorkInformation: "Acme"}
+	db.Create(&contact)
+	db.Create(&models.Note{Content: "Coffee", Date: time.Now().AddDate(0, 0, -3), ContactID: &contact.ID})
+
+	req, _ := http.NewRequest("GET", "/contacts/"+strconv.Itoa(int(contact.ID)), nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// The unknown address leaves out the first part
+	var responseBody models.Contact
+	json.Unmarshal(w.Body.Bytes(), &responseBody)
+	assert.Equal(t, "Works at Acme, last seen 3 days ago", responseBody.Summary)
+}
+
+func TestContactCadenceHealth(t *testing.T) {
+	db, router := setupRouter()
+
+	router.GET("/contacts/:id", func(c *gin.Context) {
+		GetContact(c, &config.Config{Timezone: time.UTC})
+	})
+	router.GET("/contacts", func(c *gin.Context) {
+		GetContacts(c, &config.Config{DefaultPageLimit: 25, MaxPageLimit: 100, Timezone: time.UTC})
+	})
+
+	// Goal of 20 days: on track up to 15 days, slipping up to 20 days and overdue afterwards
+	cases := []struct {
+		name      string
+		frequency int
+		daysAgo   int // -1 for never contacted
+		expected  string
+	}{
+		{"OnTrack", 20, 15, CadenceOnTrack},
+		{"SlippingStart", 20, 16, CadenceSlipping},
+		{"SlippingEnd", 20, 20, CadenceSlipping},
+		{"Overdue", 20, 21, CadenceOverdue},
+		{"Never", 20, -1, CadenceOverdue},
+		{"NoGoal", 0, 100, ""},
+	}
+
+	for _, tc := range cases {
+		contact := models.Contact{Firstname: tc.name, ContactFrequencyDays: tc.frequency}
+		db.Create(&contact)
+		if tc.daysAgo >= 0 {
+			db.Create(&models.Note{Content: "Met", Date: time.Now().AddDate(0, 0, -tc.daysAgo), ContactID: &contact.ID})
+		}
+
+		req, _ := http.NewRequest("GET", "/contacts/"+strconv.Itoa(int(contact.ID)), nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		var responseBody models.Contact
+		json.Unmarshal(w.Body.Bytes(), &responseBody)
+		assert.Equal(t, tc.expected, responseBody.CadenceHealth, tc.name)
+	}
+
+	// An activity counts as being in touch as well
+	activityContact := models.Contact{Firstname: "Activity", ContactFrequencyDays: 20}
+	db.Create(&activityContact)
+	db.Create(&models.Activity{Title: "Lunch", Date: time.Now().AddDate(0, 0, -2), Contacts: []models.Contact{activityContact}})
+
+	var responseBody struct {
+		Contacts []models.Contact `json:"contacts"`
+	}
+	req, _ := http.NewRequest("GET", "/contacts?cadence=slipping", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	json.Unmarshal(w.Body.Bytes(), &responseBody)
+	assert.Len(t, responseBody.Contacts, 2)
+
+	req, _ = http.NewRequest("GET", "/contacts?cadence=on_track", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	json.Unmarshal(w.Body.Bytes(), &responseBody)
+	assert.Len(t, responseBody.Contacts, 2) // OnTrack and Activity
+
+	req, _ = http.NewRequest("GET", "/contacts?cadence=unknown", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestGetContactFormats(t *testing.T) {
+	db, router := setupRouter()
+
+	router.GET("/contacts/:id", func(c *gin.Context) {
+		GetContact(c, &config.Config{Timezone: time.UTC})
+	})
+
+	contact := models.Contact{
+		Firstname: "Jane",
+		Lastname:  "Doe",
+		Email:     "jane@example.com",
+		Birthday:  &models.Date{Time: time.Date(1990, time.May, 4, 0, 0, 0, 0, time.UTC), Valid: true},
+		Circles:   []string{"Friends", "Work"},
+	}
+	db.Create(&contact)
+	url := "/contacts/" + strconv.Itoa(int(contact.ID))
+
+	requestWithAccept := func(accept string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("GET", url, nil)
+		if accept != "" {
+			req.Header.Set("Accept", accept)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	// JSON is the default
+	for _, accept := range []string{"", "application/json", "*/*"} {
+		w := requestWithAccept(accept)
+		assert.Equal(t, http.StatusOK, w.Code, accept)
+		assert.Contains(t, w.Header().Get("Content-Type"), "application/json", accept)
+	}
+
+	w := requestWithAccept("text/vcard")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Header().Get("Content-Type"), "text/vcard")
+	assert.Contains(t, w.Header().Get("Content-Disposition"), `filename="jane_doe.vcf"`)
+	assert.Contains(t, w.Body.String(), "BEGIN:VCARD\r\n")
+	assert.Contains(t, w.Body.String(), "N:Doe;Jane;;;\r\n")
+	assert.Contains(t, w.Body.String(), "BDAY:1990-05-04\r\n")
+
+	w = requestWithAccept("text/csv")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Header().Get("Content-Type"), "text/csv")
+	assert.Contains(t, w.Body.String(), "jane@example.com,,1990-05-04,,,,,,,,,,Friends;Work")
+
+	w = requestWithAccept("application/xml")
+	assert.Equal(t, http.StatusNotAcceptable, w.Code)
+}
+
+func TestExportContactsVCard(t *testing.T) {
+	db, router := setupRouter()
+
+	router.GET("/contacts/vcard", ExportContactsVCard)
+
+	db.Create(&models.Contact{Firstname: "Jane", Lastname: "Doe", Birthday: &models.Date{Time: time.Date(1990, time.May, 4, 0, 0, 0, 0, time.UTC), Valid: true}})
+	db.Create(&models.Contact{Firstname: "John", Lastname: "Smith", Address: models.Address{Street: "Main Street 1", City: "Berlin"}})
+
+	req, _ := http.NewRequest("GET", "/contacts/vcard", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Header().Get("Content-Type"), "text/vcard")
+	assert.Contains(t, w.Header().Get("Content-Disposition"), `filename="contacts.vcf"`)
+
+	body := w.Body.String()
+	assert.Equal(t, 2, strings.Count(body, "BEGIN:VCARD\r\n"))
+	assert.Contains(t, body, "BDAY:1990-05-04\r\n")
+	assert.Contains(t, body, "ADR:;;Main Street 1;Berlin;;;\r\n")
+
+	// Only the contact with a known birthday has a BDAY line
+	assert.Equal(t, 1, strings.Count(body, "BDAY:"))
+}
+
+func TestContactValidation(t *testing.T) {
+	db, router := setupRouter()
+
+	router.POST("/contacts", CreateContact)
+	router.PUT("/contacts/:id", UpdateContact)
+
+	jsonValue, _ := json.Marshal(models.Contact{Firstname: "John", Email: "john@", Phone: "555-CALL"})
+	req, _ := http.NewRequest("POST", "/contacts", bytes.NewBuffer(jsonValue))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	var responseBody struct {
+		Fields map[string]string `json:"fields"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &responseBody)
+	assert.Contains(t, responseBody.Fields, "email")
+	assert.Contains(t, responseBody.Fields, "phone")
+
+	contact := models.Contact{Firstname: "John"}
+	db.Create(&contact)
+
+	jsonValue, _ = json.Marshal(models.Contact{Firstname: "John", Email: "not-an-email", Version: contact.Version})
+	req, _ = http.NewRequest("PUT", "/contacts/"+strconv.Itoa(int(contact.ID)), bytes.NewBuffer(jsonValue))
+	req.Header.Set("Content-Type", "application/json")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	db.First(&contact, contact.ID)
+	assert.Equal(t, "", contact.Email)
+}
+
+func TestImportContactsVCard(t *testing.T) {
+	db, router := setupRouter()
+
+	router.POST("/contacts/import/vcard", ImportContactsVCard)
+
+	db.Create(&models.Contact{Firstname: "Jane", Lastname: "Doe", Email: "jane@example.com"})
+
+	var summary struct {
+		Summary vCardImportSummary `json:"summary"`
+	}
+
+	// A malformed card aborts the whole import
+	invalid := "BEGIN:VCARD\nN:Smith;John\nEND:VCARD\nBEGIN:VCARD\nN:Broken;Bob\nBDAY:someday\nEND:VCARD\n"
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, newUploadRequest("/contacts/import/vcard", "contacts.vcf", []byte(invalid)))
+
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	json.Unmarshal(w.Body.Bytes(), &summary)
+	assert.Equal(t, 0, summary.Summary.Created)
+	assert.Equal(t, 1, summary.Summary.Failed)
+	assert.Equal(t, 2, summary.Summary.Errors[0].Card)
+	assert.Equal(t, "Bob Broken", summary.Summary.Errors[0].Name)
+
+	var count int64
+	db.Model(&models.Contact{}).Count(&count)
+	assert.Equal(t, int64(1), count)
+
+	// Valid cards are created, existing contacts are skipped
+	valid := "BEGIN:VCARD\r\nN:Smith;John\r\nEND:VCARD\r\n" +
+		"BEGIN:VCARD\r\nN:Doe;Jane\r\nEMAIL:jane@example.com\r\nEND:VCARD\r\n"
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, newUploadRequest("/contacts/import/vcard", "contacts.vcf", []byte(valid)))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	json.Unmarshal(w.Body.Bytes(), &summary)
+	assert.Equal(t, 1, summary.Summary.Created)
+	assert.Equal(t, 1, summary.Summary.Skipped)
+	assert.Equal(t, 0, summary.Summary.Failed)
+
+	db.Model(&models.Contact{}).Count(&count)
+	assert.Equal(t, int64(2), count)
+}
+
+func TestImportContactsCSV(t *testing.T) {
+	db, router := setupRouter()
+
+	router.POST("/contacts/import/csv", ImportContactsCSV)
+
+	data := "\ufeffFirstname,lastname,email,phone,birthday,circles\n" +
+		"Jane,Doe,jane@example.com,123,1990-05-04,Friends; Work\n" +
+		",Nobody,,,,\n" +
+		"John,Smith,,,04.05.1985,\n" +
+		"Bob,Broken,,,someday,\n"
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, newUploadRequest("/contacts/import/csv", "contacts.csv", []byte(data)))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var responseBody struct {
+		Created []csvImportRow `json:"created"`
+		Failed  []csvImportRow `json:"failed"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &responseBody)
+	assert.Len(t, responseBody.Created, 2)
+	assert.Equal(t, 2, responseBody.Created[0].Line)
+	assert.Equal(t, 4, responseBody.Created[1].Line)
+	assert.Len(t, responseBody.Failed, 2)
+	assert.Equal(t, 3, responseBody.Failed[0].Line)
+	assert.Equal(t, 5, responseBody.Failed[1].Line)
+	assert.Contains(t, responseBody.Failed[1].Error, "invalid birthday")
+
+	var jane, john models.Contact
+	db.First(&jane, responseBody.Created[0].ContactID)
+	db.First(&john, responseBody.Created[1].ContactID)
+	assert.Equal(t, []string{"Friends", "Work"}, jane.Circles)
+	assert.Equal(t, "1990-05-04", jane.Birthday.Time.Format(models.DateFormat))
+	assert.Equal(t, "1985-05-04", john.Birthday.Time.Format(models.DateFormat))
+
+	// A header without firstname is rejected
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, newUploadRequest("/contacts/import/csv", "contacts.csv", []byte("name,email\nJane,jane@example.com\n")))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestExportContactsCSV(t *testing.T) {
+	db, router := setupRouter()
+
+	router.GET("/contacts/export/csv", ExportContactsCSV)
+
+	for i := 0; i < 150; i++ {
+		db.Create(&models.Contact{Firstname: "Contact" + strconv.Itoa(i)})
+	}
+	db.Create(&models.Contact{
+		Firstname: "Jane",
+		Lastname:  "Doe",
+		Birthday:  &models.Date{Time: time.Date(1990, time.May, 4, 0, 0, 0, 0, time.UTC), Valid: true},
+		Circles:   []string{"Friends", "Work"},
+	})
+
+	req, _ := http.NewRequest("GET", "/contacts/export/csv", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Header().Get("Content-Type"), "text/csv")
+
+	records, err := csv.NewReader(w.Body).ReadAll()
+	assert.NoError(t, err)
+	assert.Len(t, records, 152) // Header and all contacts across batches
+	assert.Equal(t, services.ContactCSVColumns, records[0])
+
+	// The columns are limited to the requested fields in the stable column order
+	req, _ = http.NewRequest("GET", "/contacts/export/csv?fields=circles,firstname,birthday,unknown", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	records, _ = csv.NewReader(w.Body).ReadAll()
+	assert.Equal(t, []string{"firstname", "birthday", "circles"}, records[0])
+	assert.Equal(t, []string{"Jane", "1990-05-04", "Friends;Work"}, records[151])
+
+	req, _ = http.NewRequest("GET", "/contacts/export/csv?fields=unknown", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
 func TestCreateContact(t *testing.T) {
 	_, router := setupRouter()
 
@@ -133,11 +921,36 @@ func TestCreateContact(t *testing.T) {
 	w := httptest.NewRecorder()
 	router.ServeHTTP(w, req)
 
-	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, http.StatusCreated, w.Code)
 
 	var responseBody map[string]any
 	json.Unmarshal(w.Body.Bytes(), &responseBody)
 	assert.Equal(t, "Contact created successfully", responseBody["message"])
+	id := int(responseBody["contact"].(map[string]any)["ID"].(float64))
+	assert.Equal(t, "/api/contacts/"+strconv.Itoa(id), w.Header().Get("Location"))
+}
+
+func TestUpdateContactClearsValues(t *testing.T) {
+	db, router := setupRouter()
+	router.PUT("/contacts/:id", UpdateContact)
+
+	contact := models.Contact{Firstname: "Alice", Deceased: true, ContactFrequencyDays: 30, ReminderLeadDays: 7}
+	db.Create(&contact)
+
+	// false, 0 and empty values replace the stored ones like any other value
+	jsonValue, _ := json.Marshal(models.Contact{Firstname: "Alice", Version: contact.Version})
+	req, _ := http.NewRequest("PUT", "/contacts/"+strconv.Itoa(int(contact.ID)), bytes.NewBuffer(jsonValue))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var stored models.Contact
+	db.First(&stored, contact.ID)
+	assert.False(t, stored.Deceased)
+	assert.Equal(t, 0, stored.ContactFrequencyDays)
+	assert.Equal(t, 0, stored.ReminderLeadDays)
+	assert.Equal(t, contact.Version+1, stored.Version)
 }
 
 func TestUpdateContact(t *testing.T) {
@@ -156,6 +969,7 @@ func TestUpdateContact(t *testing.T) {
 	updatedContact := models.Contact{
 		Firstname: "Alice Updated",
 		Lastname:  "Johnson Updated",
+		Version:   contact.Version,
 	}
 	jsonValue, _ := json.Marshal(updatedContact)
 
@@ -169,6 +983,107 @@ func TestUpdateContact(t *testing.T) {
 	var responseBody models.Contact
 	json.Unmarshal(w.Body.Bytes(), &responseBody)
 	assert.Equal(t, updatedContact.Firstname, responseBody.Firstname)
+	assert.Equal(t, contact.Version+1, responseBody.Version)
+
+	// A second save based on the same version would overwrite the first one
+	updatedContact.Firstname = "Alice from another tab"
+	jsonValue, _ = json.Marshal(updatedContact)
+	req, _ = http.NewRequest("PUT", "/contacts/"+strconv.Itoa(int(contact.ID)), bytes.NewBuffer(jsonValue))
+	req.Header.Set("Content-Type", "application/json")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusConflict, w.Code)
+	var conflict struct {
+		Contact models.Contact `json:"contact"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &conflict)
+	assert.Equal(t, "Alice Updated", conflict.Contact.Firstname)
+	assert.Equal(t, contact.Version+1, conflict.Contact.Version)
+
+	// Genders are stored in their normalized form and unknown genders are rejected
+	updatedContact = models.Contact{Firstname: "Alice", Gender: "Weiblich", Version: contact.Version + 1}
+	jsonValue, _ = json.Marshal(updatedContact)
+	req, _ = http.NewRequest("PUT", "/contacts/"+strconv.Itoa(int(contact.ID)), bytes.NewBuffer(jsonValue))
+	req.Header.Set("Content-Type", "application/json")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	json.Unmarshal(w.Body.Bytes(), &responseBody)
+	assert.Equal(t, "female", responseBody.Gender)
+
+	updatedContact = models.Contact{Firstname: "Alice", Gender: "robot", Version: contact.Version + 2}
+	jsonValue, _ = json.Marshal(updatedContact)
+	req, _ = http.NewRequest("PUT", "/contacts/"+strconv.Itoa(int(contact.ID)), bytes.NewBuffer(jsonValue))
+	req.Header.Set("Content-Type", "application/json")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "nonbinary")
+
+	// Updates without a version are rejected
+	updatedContact.Version = 0
+	jsonValue, _ = json.Marshal(updatedContact)
+	req, _ = http.NewRequest("PUT", "/contacts/"+strconv.Itoa(int(contact.ID)), bytes.NewBuffer(jsonValue))
+	req.Header.Set("Content-Type", "application/json")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestPatchContact(t *testing.T) {
+	db, router := setupRouter()
+
+	router.PATCH("/contacts/:id", PatchContact)
+
+	contact := models.Contact{
+		Firstname:            "Alice",
+		Lastname:             "Johnson",
+		Nickname:             "Ali",
+		ContactFrequencyDays: 30,
+		Circles:              []string{"Friends"},
+	}
+	db.Create(&contact)
+	url := "/contacts/" + strconv.Itoa(int(contact.ID))
+
+	patch := func(body string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("PATCH", url, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	// Only the provided fields change, an empty string clears a field
+	w := patch(`{"lastname": "Smith", "nickname": ""}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var stored models.Contact
+	db.First(&stored, contact.ID)
+	assert.Equal(t, "Alice", stored.Firstname)
+	assert.Equal(t, "Smith", stored.Lastname)
+	assert.Equal(t, "", stored.Nickname)
+	assert.Equal(t, 30, stored.ContactFrequencyDays)
+	assert.Equal(t, []string{"Friends"}, stored.Circles)
+	assert.Equal(t, contact.Version+1, stored.Version)
+
+	// Zero values are written as well
+	assert.Equal(t, http.StatusOK, patch(`{"contact_frequency_days": 0, "circles": []}`).Code)
+	db.First(&stored, contact.ID)
+	assert.Equal(t, 0, stored.ContactFrequencyDays)
+	assert.Equal(t, 0, stored.ReminderLeadDays)
+	assert.Empty(t, stored.Circles)
+	assert.Equal(t, "Smith", stored.Lastname)
+
+	// Invalid values, unknown fields and outdated versions are rejected
+	assert.Equal(t, http.StatusBadRequest, patch(`{"email": "not-an-email"}`).Code)
+	assert.Equal(t, http.StatusBadRequest, patch(`{"contact_frequency_days": "often"}`).Code)
+	assert.Equal(t, http.StatusBadRequest, patch(`{"user_id": 2}`).Code)
+	assert.Equal(t, http.StatusConflict, patch(`{"lastname": "Old", "version": 1}`).Code)
+
+	db.First(&stored, contact.ID)
+	assert.Equal(t, "Smith", stored.Lastname)
+	assert.Equal(t, contact.Version+2, stored.Version)
 }
 
 func TestDeleteContact(t *testing.T) {
@@ -195,27 +1110,486 @@ func TestDeleteContact(t *testing.T) {
 	assert.Equal(t, "Contact deleted", responseBody["message"])
 }
 
+func TestBulkDeleteContacts(t *testing.T) {
+	db, router := setupRouter()
+
+	router.POST("/contacts/bulk/delete", BulkDeleteContacts)
+	router.POST("/contacts/:id/restore", RestoreContact)
+
+	alice := models.Contact{Firstname: "Alice"}
+	bob := models.Contact{Firstname: "Bob"}
+	carol := models.Contact{Firstname: "Carol"}
+	other := models.Contact{Firstname: "Dave", UserID: 2}
+	for _, contact := range []*models.Contact{&alice, &bob, &carol, &other} {
+		db.Create(contact)
+	}
+	db.Create(&models.Note{Content: "Note", Date: time.Now(), ContactID: &alice.ID})
+
+	send := func(url string, body any) *httptest.ResponseRecorder {
+		jsonValue, _ := json.Marshal(body)
+		req, _ := http.NewRequest("POST", url, bytes.NewBuffer(jsonValue))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := send("/contacts/bulk/delete", map[string][]uint{"ids": {bob.ID, alice.ID, other.ID, alice.ID}})
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var responseBody struct {
+		Results []bulkResult `json:"results"`
+		Deleted int          `json:"deleted"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &responseBody)
+	assert.Equal(t, 2, responseBody.Deleted)
+	assert.Equal(t, []bulkResult{
+		{ID: alice.ID, Success: true},
+		{ID: bob.ID, Success: true},
+		{ID: other.ID, Error: "Contact not found"},
+	}, responseBody.Results)
+
+	// The contacts of other users and unselected contacts are kept
+	var remaining []string
+	db.Model(&models.Contact{}).Order("id").Pluck("firstname", &remaining)
+	assert.Equal(t, []string{"Carol", "Dave"}, remaining)
+
+	// The deleted contacts are in the trash together with their rows
+	var notes int64
+	db.Model(&models.Note{}).Count(&notes)
+	assert.Equal(t, int64(0), notes)
+	assert.Equal(t, http.StatusOK, send("/contacts/"+strconv.Itoa(int(alice.ID))+"/restore", nil).Code)
+	db.Model(&models.Note{}).Count(&notes)
+	assert.Equal(t, int64(1), notes)
+
+	assert.Equal(t, http.StatusBadRequest, send("/contacts/bulk/delete", map[string][]uint{"ids": {}}).Code)
+}
+
+func TestDeleteContactCascade(t *testing.T) {
+	db, router := setupRouter()
+
+	router.DELETE("/contacts/:id", DeleteContact)
+	router.POST("/contacts/:id/restore", RestoreContact)
+	router.DELETE("/contacts/:id/permanent", DeleteContactPermanently)
+
+	contact := models.Contact{Firstname: "Alice"}
+	friend := models.Contact{Firstname: "Bob"}
+	db.Create(&contact)
+	db.Create(&friend)
+
+	db.Create(&models.Note{Content: "Note", Date: time.Now(), ContactID: &contact.ID})
+	db.Create(&models.Reminder{Message: "Call", RemindAt: time.Now(), Recurrence: "once", ContactID: &contact.ID})
+	db.Create(&models.Relationship{Name: "Carol", Type: "Sister", ContactID: contact.ID})
+	db.Create(&models.Relationship{Name: "Alice", Type: "Friend", ContactID: friend.ID, RelatedContactID: &contact.ID})
+	db.Create(&models.Activity{Title: "Alone", Date: time.Now(), Contacts: []models.Contact{contact}})
+	db.Create(&models.Activity{Title: "Shared", Date: time.Now(), Contacts: []models.Contact{contact, friend}})
+
+	// An older note in the trash must stay there when the contact is restored
+	trashedNote := models.Note{Content: "Old", Date: time.Now(), ContactID: &contact.ID}
+	db.Create(&trashedNote)
+	db.Delete(&trashedNote)
+
+	count := func(db *gorm.DB, model any) int64 {
+		var count int64
+		db.Model(model).Count(&count)
+		return count
+	}
+	request := func(method, url string) int {
+		req, _ := http.NewRequest(method, url, nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+	url := "/contacts/" + strconv.Itoa(int(contact.ID))
+
+	assert.Equal(t, http.StatusOK, request("DELETE", url))
+	assert.Equal(t, int64(0), count(db, &models.Note{}))
+	assert.Equal(t, int64(0), count(db, &models.Reminder{}))
+	assert.Equal(t, int64(0), count(db, &models.Relationship{}))
+	assert.Equal(t, int64(1), count(db, &models.Activity{})) // The shared activity is kept for Bob
+
+	assert.Equal(t, http.StatusOK, request("POST", url+"/restore"))
+	assert.Equal(t, int64(1), count(db, &models.Note{}))
+	assert.Equal(t, int64(1), count(db, &models.Reminder{}))
+	assert.Equal(t, int64(2), count(db, &models.Relationship{}))
+	assert.Equal(t, int64(2), count(db, &models.Activity{}))
+
+	// A permanent delete removes all rows for good
+	assert.Equal(t, http.StatusOK, request("DELETE", url+"/permanent"))
+	assert.Equal(t, int64(0), count(db.Unscoped(), &models.Note{}))
+	assert.Equal(t, int64(0), count(db.Unscoped(), &models.Reminder{}))
+	assert.Equal(t, int64(0), count(db.Unscoped(), &models.Relationship{}))
+	assert.Equal(t, int64(1), count(db.Unscoped(), &models.Activity{}))
+
+	var links int64
+	db.Table("activity_contacts").Where("contact_id = ?", contact.ID).Count(&links)
+	assert.Equal(t, int64(0), links)
+}
+
+func TestGetDuplicateContacts(t *testing.T) {
+	db, router := setupRouter()
+
+	router.GET("/contacts/duplicates", GetDuplicateContacts)
+
+	db.Create(&models.Contact{Firstname: "Jane", Lastname: "Doe", Phone: "+49 30 1234"})
+	db.Create(&models.Contact{Firstname: "jane", Lastname: "doe"})
+	db.Create(&models.Contact{Firstname: "John", Phone: "+49-30-1234"})
+
+	req, _ := http.NewRequest("GET", "/contacts/duplicates", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var responseBody struct {
+		Clusters []services.DuplicateCluster `json:"clusters"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &responseBody)
+	assert.Len(t, responseBody.Clusters, 2)
+	assert.Equal(t, services.DuplicateName, responseBody.Clusters[0].Reason)
+	assert.Equal(t, services.DuplicatePhone, responseBody.Clusters[1].Reason)
+	assert.Equal(t, "John", responseBody.Clusters[1].Contacts[1].Firstname)
+}
+
+func TestGetRecentContacts(t *testing.T) {
+	db, router := setupRouter()
+
+	router.GET("/contacts/recent", GetRecentContacts)
+
+	now := time.Now()
+	for i, name := range []string{"Oldest", "Older", "Newest"} {
+		created := now.Add(time.Duration(i-3) * time.Hour)
+		db.Create(&models.Contact{Firstname: name, Model: gorm.Model{CreatedAt: created, UpdatedAt: created}})
+	}
+	db.Create(&models.Contact{Firstname: "Mallory", UserID: 2})
+	db.Model(&models.Contact{}).Where("firstname = ?", "Oldest").Update("updated_at", now)
+
+	recent := func(query string) (int, []models.Contact) {
+		req, _ := http.NewRequest("GET", "/contacts/recent"+query, nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		var responseBody struct {
+			Contacts []models.Contact `json:"contacts"`
+		}
+		json.Unmarshal(w.Body.Bytes(), &responseBody)
+		return w.Code, responseBody.Contacts
+	}
+	names := func(contacts []models.Contact) []string {
+		var names []string
+		for _, contact := range contacts {
+			names = append(names, contact.Firstname)
+		}
+		return names
+	}
+
+	code, contacts := recent("")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, []string{"Newest", "Older", "Oldest"}, names(contacts))
+	assert.WithinDuration(t, now.Add(-time.Hour), contacts[0].CreatedAt, time.Second)
+
+	_, contacts = recent("?type=updated&limit=2")
+	assert.Equal(t, []string{"Oldest", "Newest"}, names(contacts))
+	assert.WithinDuration(t, now, contacts[0].UpdatedAt, time.Second)
+
+	// Large limits are capped instead of rejected
+	code, contacts = recent("?limit=1000")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Len(t, contacts, 3)
+
+	code, _ = recent("?type=deleted")
+	assert.Equal(t, http.StatusBadRequest, code)
+	code, _ = recent("?limit=0")
+	assert.Equal(t, http.StatusBadRequest, code)
+}
+
+func TestGetUpcomingBirthdays(t *testing.T) {
+	db, router := setupRouter()
+
+	router.GET("/contacts/birthdays/upcoming", func(c *gin.Context) {
+		GetUpcomingBirthdays(c, &config.Config{Timezone: time.UTC})
+	})
+
+	now := time.Now().UTC()
+	soon := now.AddDate(-30, 0, 5)
+	later := now.AddDate(0, 0, 20)
+	db.Create(&models.Contact{Firstname: "Later", Birthday: &models.Date{Time: time.Date(1, later.Month(), later.Day(), 0, 0, 0, 0, time.UTC), Valid: true}})
+	db.Create(&models.Contact{Firstname: "Soon", Birthday: &models.Date{Time: time.Date(soon.Year(), soon.Month(), soon.Day(), 0, 0, 0, 0, time.UTC), Valid: true}})
+	nobody := models.Contact{Firstname: "Nobody"}
+	db.Create(&nobody)
+	db.Create(&models.CustomDate{ContactID: nobody.ID, Label: "Work anniversary", Date: models.Date{Time: time.Date(now.Year()-10, later.Month(), later.Day(), 0, 0, 0, 0, time.UTC), Valid: true}, Recurring: true})
+
+	req, _ := http.NewRequest("GET", "/contacts/birthdays/upcoming?days=30", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var responseBody struct {
+		Birthdays   []services.UpcomingBirthday   `json:"birthdays"`
+		CustomDates []services.UpcomingCustomDate `json:"custom_dates"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &responseBody)
+	assert.Len(t, responseBody.Birthdays, 2)
+	assert.Len(t, responseBody.CustomDates, 1)
+	assert.Equal(t, "Nobody", responseBody.CustomDates[0].CustomDate.Contact.Firstname)
+	assert.Equal(t, 20, responseBody.CustomDates[0].DaysUntil)
+	assert.Equal(t, "Soon", responseBody.Birthdays[0].Contact.Firstname)
+	assert.Equal(t, 5, responseBody.Birthdays[0].DaysUntil)
+	assert.Equal(t, 30, *responseBody.Birthdays[0].TurningAge)
+	assert.Equal(t, "Later", responseBody.Birthdays[1].Contact.Firstname)
+	assert.Nil(t, responseBody.Birthdays[1].TurningAge)
+
+	req, _ = http.NewRequest("GET", "/contacts/birthdays/upcoming?days=10", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	json.Unmarshal(w.Body.Bytes(), &responseBody)
+	assert.Len(t, responseBody.Birthdays, 1)
+	assert.Empty(t, responseBody.CustomDates)
+
+	req, _ = http.NewRequest("GET", "/contacts/birthdays/upcoming?days=abc", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestGetStaleContacts(t *testing.T) {
+	db, router := setupRouter()
+
+	router.GET("/contacts/stale", func(c *gin.Context) {
+		GetStaleContacts(c, &config.Config{DefaultPageLimit: 25, MaxPageLimit: 100, Timezone: time.UTC})
+	})
+
+	never := models.Contact{Firstname: "Never"}
+	longAgo := models.Contact{Firstname: "LongAgo"}
+	while := models.Contact{Firstname: "While"}
+	recent := models.Contact{Firstname: "Recent"}
+	db.Create(&never)
+	db.Create(&longAgo)
+	db.Create(&while)
+	db.Create(&recent)
+
+	now := time.Now().UTC()
+	db.Create(&models.Note{Content: "Old note", Date: now.AddDate(-1, 0, 0), ContactID: &longAgo.ID})
+	activity := models.Activity{Title: "Lunch", Date: now.AddDate(0, 0, -100)}
+	db.Create(&activity)
+	db.Model(&activity).Association("Contacts").Append(&while)
+	db.Create(&models.Note{Content: "Older note", Date: now.AddDate(0, 0, -200), ContactID: &while.ID})
+	db.Create(&models.Note{Content: "New note", Date: now.AddDate(0, 0, -3), ContactID: &recent.ID})
+
+	get := func(query string) (int, []models.Contact, int64) {
+		req, _ := http.NewRequest("GET", "/contacts/stale"+query, nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		var responseBody struct {
+			Contacts []models.Contact `json:"contacts"`
+			Total    int64            `json:"total"`
+		}
+		json.Unmarshal(w.Body.Bytes(), &responseBody)
+		return w.Code, responseBody.Contacts, responseBody.Total
+	}
+
+	code, contacts, total := get("")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, int64(3), total)
+	assert.Equal(t, "Never", contacts[0].Firstname)
+	assert.Nil(t, contacts[0].LastContacted)
+	assert.Equal(t, "LongAgo", contacts[1].Firstname)
+	assert.Equal(t, "While", contacts[2].Firstname)
+	assert.Equal(t, now.AddDate(0, 0, -100).Format(models.DateFormat), contacts[2].LastContacted.Time.Format(models.DateFormat))
+
+	_, contacts, _ = get("?days=150")
+	assert.Len(t, contacts, 2)
+
+	_, contacts, total = get("?days=1&limit=1&page=4")
+	assert.Equal(t, int64(4), total)
+	assert.Equal(t, "Recent", contacts[0].Firstname)
+
+	code, _, _ = get("?days=-1")
+	assert.Equal(t, http.StatusBadRequest, code)
+}
+
+func TestMergeContact(t *testing.T) {
+	db, router := setupRouter()
+
+	router.POST("/contacts/:id/merge", MergeContact)
+
+	target := models.Contact{Firstname: "Jane", Lastname: "Doe", Circles: []string{"Friends"}}
+	source := models.Contact{Firstname: "Janie", Email: "jane@example.com", Phone: "123", Circles: []string{"Work", "Friends"},
+		Birthday: &models.Date{Time: time.Date(1990, time.May, 4, 0, 0, 0, 0, time.UTC), Valid: true}}
+	other := models.Contact{Firstname: "Bob"}
+	db.Create(&target)
+	db.Create(&source)
+	db.Create(&other)
+
+	db.Create(&models.Note{Content: "Source note", Date: time.Now(), ContactID: &source.ID})
+	db.Create(&models.Reminder{Message: "Call", RemindAt: time.Now(), Recurrence: "once", ContactID: &source.ID})
+	db.Create(&models.Relationship{Name: "Bob", Type: "Friend", ContactID: source.ID, RelatedContactID: &other.ID})
+	db.Create(&models.Relationship{Name: "Janie", Type: "Friend", ContactID: other.ID, RelatedContactID: &source.ID})
+	db.Create(&models.Relationship{Name: "Janie", Type: "Duplicate", ContactID: target.ID, RelatedContactID: &source.ID})
+	db.Create(&models.Activity{Title: "Shared", Date: time.Now(), Contacts: []models.Contact{target, source}})
+	db.Create(&models.Activity{Title: "Source only", Date: time.Now(), Contacts: []models.Contact{source}})
+
+	jsonValue, _ := json.Marshal(map[string]uint{"source_id": source.ID})
+	req, _ := http.NewRequest("POST", "/contacts/"+strconv.Itoa(int(target.ID))+"/merge", bytes.NewBuffer(jsonValue))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var responseBody struct {
+		Contact models.Contact `json:"contact"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &responseBody)
+	merged := responseBody.Contact
+
+	// Filled fields of the target are kept, blank ones are taken from the source
+	assert.Equal(t, "Jane", merged.Firstname)
+	assert.Equal(t, "Doe", merged.Lastname)
+	assert.Equal(t, "jane@example.com", merged.Email)
+	assert.Equal(t, "1990-05-04", merged.Birthday.Time.Format(models.DateFormat))
+	assert.Equal(t, []string{"Friends", "Work"}, merged.Circles)
+
+	assert.Len(t, merged.Notes, 1)
+	assert.Len(t, merged.Reminders, 1)
+	assert.Len(t, merged.Activities, 2)
+	assert.Len(t, merged.Relationships, 1) // The relationship to the duplicate itself is dropped
+	assert.Equal(t, other.ID, *merged.Relationships[0].RelatedContactID)
+
+	var reciprocal models.Relationship
+	db.Where("contact_id = ?", other.ID).First(&reciprocal)
+	assert.Equal(t, target.ID, *reciprocal.RelatedContactID)
+
+	var links int64
+	db.Table("activity_contacts").Where("contact_id = ?", source.ID).Count(&links)
+	assert.Equal(t, int64(0), links)
+
+	var count int64
+	db.Model(&models.Contact{}).Where("id = ?", source.ID).Count(&count)
+	assert.Equal(t, int64(0), count)
+
+	// Merging into itself is rejected
+	jsonValue, _ = json.Marshal(map[string]uint{"source_id": target.ID})
+	req, _ = http.NewRequest("POST", "/contacts/"+strconv.Itoa(int(target.ID))+"/merge", bytes.NewBuffer(jsonValue))
+	req.Header.Set("Content-Type", "application/json")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestContactTrash(t *testing.T) {
+	db, router := setupRouter()
+
+	router.DELETE("/contacts/:id", DeleteContact)
+	router.GET("/contacts/trash", func(c *gin.Context) {
+		GetTrashedContacts(c, &config.Config{DefaultPageLimit: 25, MaxPageLimit: 100})
+	})
+	router.POST("/contacts/:id/restore", RestoreContact)
+	router.DELETE("/contacts/:id/permanent", DeleteContactPermanently)
+	router.GET("/contacts", func(c *gin.Context) {
+		GetContacts(c, &config.Config{DefaultPageLimit: 25, MaxPageLimit: 100})
+	})
+
+	alice := models.Contact{Firstname: "Alice"}
+	bob := models.Contact{Firstname: "Bob"}
+	db.Create(&alice)
+	db.Create(&bob)
+	db.Create(&models.Note{Content: "Keep me", Date: time.Now(), ContactID: &alice.ID})
+
+	request := func(method, url string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(method, url, nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	var responseBody struct {
+		Contacts []models.Contact `json:"contacts"`
+		Total    int64            `json:"total"`
+	}
+
+	// A deleted contact moves to the trash and disappears from the list
+	assert.Equal(t, http.StatusOK, request("DELETE", "/contacts/"+strconv.Itoa(int(alice.ID))).Code)
+
+	json.Unmarshal(request("GET", "/contacts").Body.Bytes(), &responseBody)
+	assert.Len(t, responseBody.Contacts, 1)
+	assert.Equal(t, "Bob", responseBody.Contacts[0].Firstname)
+
+	responseBody.Contacts = nil
+	json.Unmarshal(request("GET", "/contacts/trash").Body.Bytes(), &responseBody)
+	assert.Len(t, responseBody.Contacts, 1)
+	assert.Equal(t, "Alice", responseBody.Contacts[0].Firstname)
+
+	// Restoring brings the contact back together with its notes
+	assert.Equal(t, http.StatusOK, request("POST", "/contacts/"+strconv.Itoa(int(alice.ID))+"/restore").Code)
+	assert.Equal(t, http.StatusNotFound, request("POST", "/contacts/"+strconv.Itoa(int(bob.ID))+"/restore").Code)
+
+	responseBody.Contacts = nil
+	json.Unmarshal(request("GET", "/contacts").Body.Bytes(), &responseBody)
+	assert.Len(t, responseBody.Contacts, 2)
+
+	var notes int64
+	db.Model(&models.Note{}).Where("contact_id = ?", alice.ID).Count(&notes)
+	assert.Equal(t, int64(1), notes)
+
+	// A permanent delete removes the row completely
+	assert.Equal(t, http.StatusOK, request("DELETE", "/contacts/"+strconv.Itoa(int(bob.ID))+"/permanent").Code)
+	assert.Equal(t, http.StatusNotFound, request("DELETE", "/contacts/"+strconv.Itoa(int(bob.ID))+"/permanent").Code)
+
+	var count int64
+	db.Unscoped().Model(&models.Contact{}).Where("id = ?", bob.ID).Count(&count)
+	assert.Equal(t, int64(0), count)
+}
+
 func TestGetCircles(t *testing.T) {
 	db, router := setupRouter()
 
 	router.GET("/contacts/circles", GetCircles)
 
+	getCircles := func() []string {
+		req, _ := http.NewRequest("GET", "/contacts/circles", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		var responseBody []string
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &responseBody))
+		assert.NotNil(t, responseBody)
+		return responseBody
+	}
+
+	assert.Empty(t, getCircles())
+
 	contacts := []models.Contact{
 		{Firstname: "Alice", Lastname: "Johnson", Circles: []string{"Friends", "Family"}},
 		{Firstname: "Bob", Lastname: "Smith", Circles: []string{"Friends", "Work"}},
+		{Firstname: "Carol", Circles: []string{}},
+		{Firstname: "Dave", Circles: nil},
+		{Firstname: "Erin"},
+	}
+	for i := range contacts {
+		db.Create(&contacts[i])
 	}
-	db.Create(&contacts[0])
-	db.Create(&contacts[1])
+	db.Model(&contacts[4]).Update("circles", gorm.Expr("NULL"))
 
-	// Make the request to get circles
-	req, _ := http.NewRequest("GET", "/contacts/circles", nil)
+	assert.ElementsMatch(t, []string{"Friends", "Family", "Work"}, getCircles())
+
+	// Counts are sorted by the number of contacts, ties by name
+	other := models.Contact{Firstname: "Mallory", Circles: []string{"Work"}, UserID: 2}
+	db.Create(&other)
+	req, _ := http.NewRequest("GET", "/contacts/circles?counts=true", nil)
 	w := httptest.NewRecorder()
 	router.ServeHTTP(w, req)
-
 	assert.Equal(t, http.StatusOK, w.Code)
+	var counts []circleCount
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &counts))
+	assert.Equal(t, []circleCount{{Name: "Friends", Count: 2}, {Name: "Family", Count: 1}, {Name: "Work", Count: 1}}, counts)
 
-	var responseBody []string
-	json.Unmarshal(w.Body.Bytes(), &responseBody)
-	assert.Equal(t, int(3), len(responseBody))
-	assert.ElementsMatch(t, []string{"Friends", "Family", "Work"}, responseBody)
+	// The fallback for other databases gives the same result
+	var stored []models.Contact
+	db.Order("id").Find(&stored)
+	assert.Equal(t, []string{"Friends", "Family", "Work"}, distinctCircles(stored))
 }
@@ -0,0 +1,88 @@
+package controllers
+
+import (
+	"net/http"
+	"perema/middleware"
+	"perema/models"
+	"perema/services"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// CreateContactMethod adds an email address or phone number to a contact. The first method of a type and
+// methods sent with is_primary become the primary one, whose value is also stored as email or phone of the contact.
+func CreateContactMethod(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+
+	var contact models.Contact
+	if err := db.Scopes(ownContacts(c)).First(&contact, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Contact not found"})
+		return
+	}
+
+	var method models.ContactMethod
+	if err := c.ShouldBindJSON(&method); err != nil {
+		respondBindingError(c, err)
+		return
+	}
+	fields := map[string]fieldError{}
+	for field, message := range method.Validate() {
+		fields[field] = fieldError{Rule: method.Type, Message: message, Value: method.Value}
+	}
+	if len(fields) > 0 {
+		respondFieldErrors(c, fields)
+		return
+	}
+
+	// Contacts saved before they had contact methods get their existing email and phone first
+	if err := services.SyncLegacyContactMethods(db, contact); err != nil {
+		middleware.Logger(c).Error("Failed to sync contact methods", "contact_id", contact.ID, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save contact method"})
+		return
+	}
+
+	method.ContactID = contact.ID
+	err := db.Create(&method).Error
+	if err == nil && method.IsPrimary {
+		err = services.SetPrimaryContactMethod(db, method)
+	} else if err == nil {
+		err = services.EnsurePrimaryContactMethod(db, contact.ID, method.Type)
+	}
+	if err == nil {
+		err = db.First(&method, method.ID).Error
+	}
+	if err != nil {
+		middleware.Logger(c).Error("Failed to save contact method", "contact_id", contact.ID, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save contact method"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"contact_method": method})
+}
+
+// DeleteContactMethod removes an email address or phone number of a contact. If it was the primary one,
+// the oldest remaining method of the type becomes primary.
+func DeleteContactMethod(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+
+	var method models.ContactMethod
+	if err := db.Where("contact_id = ?", c.Param("id")).
+		Scopes(ownedThroughContact(c, "contact_methods.contact_id")).
+		First(&method, c.Param("mid")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Contact method not found"})
+		return
+	}
+
+	err := db.Delete(&method).Error
+	if err == nil {
+		err = services.EnsurePrimaryContactMethod(db, method.ContactID, method.Type)
+	}
+	if err != nil {
+		middleware.Logger(c).Error("Failed to delete contact method", "contact_id", method.ContactID, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete contact method"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Contact method deleted"})
+}
@@ -0,0 +1,103 @@
+package controllers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"perema/config"
+	"perema/models"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestContactMethods(t *testing.T) {
+	db, router := setupRouter()
+	router.POST("/contacts/:id/contact-methods", CreateContactMethod)
+	router.DELETE("/contacts/:id/contact-methods/:mid", DeleteContactMethod)
+	router.GET("/contacts/:id", func(c *gin.Context) { GetContact(c, &config.Config{Timezone: time.UTC}) })
+
+	// The existing email of the contact becomes its primary method
+	contact := models.Contact{Firstname: "Alice", Email: "alice@example.com"}
+	db.Create(&contact)
+	url := "/contacts/" + strconv.Itoa(int(contact.ID)) + "/contact-methods"
+
+	create := func(body map[string]any) (int, models.ContactMethod) {
+		jsonValue, _ := json.Marshal(body)
+		req, _ := http.NewRequest("POST", url, bytes.NewBuffer(jsonValue))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		var response struct {
+			ContactMethod models.ContactMethod `json:"contact_method"`
+		}
+		json.Unmarshal(w.Body.Bytes(), &response)
+		return w.Code, response.ContactMethod
+	}
+
+	code, work := create(map[string]any{"type": "email", "label": "work", "value": "alice@work.example.com"})
+	assert.Equal(t, http.StatusCreated, code)
+	assert.False(t, work.IsPrimary)
+
+	// The first phone number is primary and stored on the contact
+	code, mobile := create(map[string]any{"type": "phone", "label": "mobile", "value": "+49 170 1234567"})
+	assert.Equal(t, http.StatusCreated, code)
+	assert.True(t, mobile.IsPrimary)
+
+	var stored models.Contact
+	db.First(&stored, contact.ID)
+	assert.Equal(t, "+49 170 1234567", stored.Phone)
+
+	// Invalid values and types are rejected
+	code, _ = create(map[string]any{"type": "email", "value": "not-an-email"})
+	assert.Equal(t, http.StatusBadRequest, code)
+	code, _ = create(map[string]any{"type": "fax", "value": "123"})
+	assert.Equal(t, http.StatusBadRequest, code)
+
+	// A new primary email replaces the email of the contact
+	code, private := create(map[string]any{"type": "email", "label": "home", "value": "alice@home.example.com", "is_primary": true})
+	assert.Equal(t, http.StatusCreated, code)
+	assert.True(t, private.IsPrimary)
+	db.First(&stored, contact.ID)
+	assert.Equal(t, "alice@home.example.com", stored.Email)
+
+	req, _ := http.NewRequest("GET", "/contacts/"+strconv.Itoa(int(contact.ID)), nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	var withMethods models.Contact
+	json.Unmarshal(w.Body.Bytes(), &withMethods)
+	assert.Len(t, withMethods.ContactMethods, 4)
+
+	var primaries int64
+	db.Model(&models.ContactMethod{}).Where("contact_id = ? AND type = ? AND is_primary", contact.ID, "email").Count(&primaries)
+	assert.Equal(t, int64(1), primaries)
+
+	// Deleting the primary email promotes the oldest remaining one
+	req, _ = http.NewRequest("DELETE", url+"/"+strconv.Itoa(int(private.ID)), nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	db.First(&stored, contact.ID)
+	assert.Equal(t, "alice@example.com", stored.Email)
+
+	// Deleting the only phone number clears the phone of the contact
+	req, _ = http.NewRequest("DELETE", url+"/"+strconv.Itoa(int(mobile.ID)), nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	db.First(&stored, contact.ID)
+	assert.Empty(t, stored.Phone)
+
+	// Methods can only be deleted through their own contact
+	other := models.Contact{Firstname: "Bob"}
+	db.Create(&other)
+	req, _ = http.NewRequest("DELETE", "/contacts/"+strconv.Itoa(int(other.ID))+"/contact-methods/"+strconv.Itoa(int(work.ID)), nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
@@ -0,0 +1,105 @@
+package controllers
+
+import (
+	"net/http"
+	"perema/middleware"
+	"perema/models"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// customDateInput is the body for adding a custom date, dates recur every year unless recurring is false
+type customDateInput struct {
+	Label            string      `json:"label"`
+	Date             models.Date `json:"date"`
+	Recurring        *bool       `json:"recurring"`
+	ReminderLeadDays int         `json:"reminder_lead_days"`
+}
+
+// customDateFieldErrors reports the invalid fields of a custom date with their values
+func customDateFieldErrors(date models.CustomDate) map[string]fieldError {
+	values := map[string]any{"label": date.Label, "date": date.Date, "reminder_lead_days": date.ReminderLeadDays}
+	fields := map[string]fieldError{}
+	for field, message := range date.Validate() {
+		fields[field] = fieldError{Rule: field, Message: message, Value: values[field]}
+	}
+	return fields
+}
+
+// GetCustomDates lists the custom dates of a contact in the order they were added
+func GetCustomDates(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+
+	var contact models.Contact
+	if err := db.Scopes(ownContacts(c)).First(&contact, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Contact not found"})
+		return
+	}
+
+	dates := []models.CustomDate{}
+	if err := db.Where("contact_id = ?", contact.ID).Order("id").Find(&dates).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve custom dates"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"custom_dates": dates})
+}
+
+// CreateCustomDate adds a date like a work anniversary to a contact, which is reminded like a birthday
+func CreateCustomDate(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+
+	var contact models.Contact
+	if err := db.Scopes(ownContacts(c)).First(&contact, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Contact not found"})
+		return
+	}
+
+	var input customDateInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		respondBindingError(c, err)
+		return
+	}
+	date := models.CustomDate{
+		ContactID:        contact.ID,
+		Label:            strings.TrimSpace(input.Label),
+		Date:             input.Date,
+		Recurring:        input.Recurring == nil || *input.Recurring,
+		ReminderLeadDays: input.ReminderLeadDays,
+	}
+	if fieldErrors := customDateFieldErrors(date); len(fieldErrors) > 0 {
+		respondFieldErrors(c, fieldErrors)
+		return
+	}
+
+	if err := db.Create(&date).Error; err != nil {
+		middleware.Logger(c).Error("Failed to save custom date", "contact_id", contact.ID, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save custom date"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"custom_date": date})
+}
+
+// DeleteCustomDate removes a custom date of a contact
+func DeleteCustomDate(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+
+	var date models.CustomDate
+	if err := db.Where("contact_id = ?", c.Param("id")).
+		Scopes(ownedThroughContact(c, "custom_dates.contact_id")).
+		First(&date, c.Param("did")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Custom date not found"})
+		return
+	}
+
+	if err := db.Delete(&date).Error; err != nil {
+		middleware.Logger(c).Error("Failed to delete custom date", "contact_id", date.ContactID, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete custom date"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Custom date deleted"})
+}
@@ -0,0 +1,92 @@
+package controllers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"perema/models"
+	"strconv"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCustomDates(t *testing.T) {
+	db, router := setupRouter()
+
+	// Requests are made by user 1, as the auth middleware would set it
+	router.Use(func(c *gin.Context) {
+		c.Set("user_id", uint(1))
+		c.Next()
+	})
+	router.GET("/contacts/:id/custom-dates", GetCustomDates)
+	router.POST("/contacts/:id/custom-dates", CreateCustomDate)
+	router.DELETE("/contacts/:id/custom-dates/:did", DeleteCustomDate)
+
+	contact := models.Contact{Firstname: "Alice", UserID: 1}
+	other := models.Contact{Firstname: "Theirs", UserID: 2}
+	db.Create(&contact)
+	db.Create(&other)
+	url := "/contacts/" + strconv.Itoa(int(contact.ID)) + "/custom-dates"
+
+	send := func(method, url string, body map[string]any) *httptest.ResponseRecorder {
+		jsonValue, _ := json.Marshal(body)
+		req, _ := http.NewRequest(method, url, bytes.NewBuffer(jsonValue))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+	create := func(body map[string]any) (int, models.CustomDate) {
+		w := send("POST", url, body)
+		var response struct {
+			CustomDate models.CustomDate `json:"custom_date"`
+		}
+		json.Unmarshal(w.Body.Bytes(), &response)
+		return w.Code, response.CustomDate
+	}
+
+	// Dates recur every year unless told otherwise
+	code, anniversary := create(map[string]any{"label": " Work anniversary ", "date": "2015-04-01", "reminder_lead_days": 7})
+	assert.Equal(t, http.StatusCreated, code)
+	assert.Equal(t, "Work anniversary", anniversary.Label)
+	assert.True(t, anniversary.Recurring)
+	assert.Equal(t, 7, anniversary.ReminderLeadDays)
+
+	code, moving := create(map[string]any{"label": "Moving", "date": "2025-09-15", "recurring": false})
+	assert.Equal(t, http.StatusCreated, code)
+	assert.False(t, moving.Recurring)
+
+	// A label is required, dates which do not recur need a year
+	code, _ = create(map[string]any{"date": "2015-04-01"})
+	assert.Equal(t, http.StatusBadRequest, code)
+	code, _ = create(map[string]any{"label": "Moving", "date": "0001-09-15", "recurring": false})
+	assert.Equal(t, http.StatusBadRequest, code)
+	code, _ = create(map[string]any{"label": "Wedding", "date": "2015-04-01", "reminder_lead_days": 400})
+	assert.Equal(t, http.StatusBadRequest, code)
+
+	w := send("GET", url, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var list struct {
+		CustomDates []models.CustomDate `json:"custom_dates"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &list)
+	assert.Len(t, list.CustomDates, 2)
+	assert.Equal(t, "Work anniversary", list.CustomDates[0].Label)
+
+	// Dates of other users' contacts look as if they did not exist
+	otherDate := models.CustomDate{ContactID: other.ID, Label: "Wedding", Date: anniversary.Date, Recurring: true}
+	db.Create(&otherDate)
+	otherURL := "/contacts/" + strconv.Itoa(int(other.ID)) + "/custom-dates"
+	assert.Equal(t, http.StatusNotFound, send("GET", otherURL, nil).Code)
+	assert.Equal(t, http.StatusNotFound, send("POST", otherURL, map[string]any{"label": "Wedding", "date": "2015-04-01"}).Code)
+	assert.Equal(t, http.StatusNotFound, send("DELETE", otherURL+"/"+strconv.Itoa(int(otherDate.ID)), nil).Code)
+
+	assert.Equal(t, http.StatusOK, send("DELETE", url+"/"+strconv.Itoa(int(moving.ID)), nil).Code)
+	assert.Equal(t, http.StatusNotFound, send("DELETE", url+"/"+strconv.Itoa(int(moving.ID)), nil).Code)
+	var count int64
+	db.Model(&models.CustomDate{}).Where("contact_id = ?", contact.ID).Count(&count)
+	assert.Equal(t, int64(1), count)
+}
@@ -0,0 +1,306 @@
+package controllers
+
+import (
+	"net/http"
+	"perema/config"
+	"perema/models"
+	"perema/services"
+	"reflect"
+
+	"github.com/gin-gonic/gin"
+	"github.com/graphql-go/graphql"
+	"gorm.io/gorm"
+)
+
+// graphqlSchema exposes the contacts of the user and their related records for reading. Contacts referenced
+// from relationships and activities use the ContactRef type without relations, so queries cannot nest endlessly.
+var graphqlSchema = newGraphQLSchema()
+
+// GraphQL executes a query of the graphqlSchema. Errors of the query are reported in the result like any
+// GraphQL server does, only requests without a query are rejected.
+func GraphQL(c *gin.Context, cfg *config.Config) {
+	var request struct {
+		Query         string         `json:"query" binding:"required"`
+		OperationName string         `json:"operationName"`
+		Variables     map[string]any `json:"variables"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		respondBindingError(c, err)
+		return
+	}
+
+	c.Set("config", cfg)
+	result := graphql.Do(graphql.Params{
+		Schema:         graphqlSchema,
+		RequestString:  request.Query,
+		OperationName:  request.OperationName,
+		VariableValues: request.Variables,
+		Context:        c, // The resolvers find the database, the user and the config in the gin context
+	})
+
+	c.JSON(http.StatusOK, result)
+}
+
+// graphqlContext returns the gin context of the request and its database
+func graphqlContext(p graphql.ResolveParams) (*gin.Context, *gorm.DB) {
+	c := p.Context.(*gin.Context)
+	return c, c.MustGet("db").(*gorm.DB)
+}
+
+// graphqlModelFields returns the fields of the gorm.Model embedded in every model,
+// which the default resolver does not find
+func graphqlModelFields(fields graphql.Fields) graphql.Fields {
+	model := func(source any) gorm.Model {
+		return reflect.Indirect(reflect.ValueOf(source)).FieldByName("Model").Interface().(gorm.Model)
+	}
+	fields["id"] = &graphql.Field{Type: graphql.NewNonNull(graphql.Int), Resolve: func(p graphql.ResolveParams) (any, error) {
+		return model(p.Source).ID, nil
+	}}
+	fields["created_at"] = &graphql.Field{Type: graphql.DateTime, Resolve: func(p graphql.ResolveParams) (any, error) {
+		return model(p.Source).CreatedAt, nil
+	}}
+	fields["updated_at"] = &graphql.Field{Type: graphql.DateTime, Resolve: func(p graphql.ResolveParams) (any, error) {
+		return model(p.Source).UpdatedAt, nil
+	}}
+	return fields
+}
+
+// graphqlDate serializes dates like the REST API as YYYY-MM-DD
+var graphqlDate = graphql.NewScalar(graphql.ScalarConfig{
+	Name:        "Date",
+	Description: "A calendar date formatted as YYYY-MM-DD",
+	Serialize: func(value any) any {
+		date, ok := value.(*models.Date)
+		if !ok || date == nil || !date.Valid {
+			return nil
+		}
+		return date.Time.Format(models.DateFormat)
+	},
+})
+
+func newGraphQLSchema() graphql.Schema {
+	stringList := graphql.NewList(graphql.NewNonNull(graphql.String))
+
+	contactRef := graphql.NewObject(graphql.ObjectConfig{
+		Name: "ContactRef",
+		Fields: graphqlModelFields(graphql.Fields{
+			"firstname": &graphql.Field{Type: graphql.String},
+			"lastname":  &graphql.Field{Type: graphql.String},
+			"nickname":  &graphql.Field{Type: graphql.String},
+		}),
+	})
+
+	address := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Address",
+		Fields: graphql.Fields{
+			"street":      &graphql.Field{Type: graphql.String},
+			"postal_code": &graphql.Field{Type: graphql.String},
+			"city":        &graphql.Field{Type: graphql.String},
+			"region":      &graphql.Field{Type: graphql.String},
+			"country":     &graphql.Field{Type: graphql.String},
+		},
+	})
+
+	contactMethod := graphql.NewObject(graphql.ObjectConfig{
+		Name: "ContactMethod",
+		Fields: graphqlModelFields(graphql.Fields{
+			"type":       &graphql.Field{Type: graphql.String},
+			"label":      &graphql.Field{Type: graphql.String},
+			"value":      &graphql.Field{Type: graphql.String},
+			"is_primary": &graphql.Field{Type: graphql.Boolean},
+		}),
+	})
+
+	note := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Note",
+		Fields: graphqlModelFields(graphql.Fields{
+			"content": &graphql.Field{Type: graphql.String},
+			"date":    &graphql.Field{Type: graphql.DateTime},
+			"tags":    &graphql.Field{Type: stringList},
+		}),
+	})
+
+	activity := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Activity",
+		Fields: graphqlModelFields(graphql.Fields{
+			"title":         &graphql.Field{Type: graphql.String},
+			"description":   &graphql.Field{Type: graphql.String},
+			"location":      &graphql.Field{Type: graphql.String},
+			"activity_type": &graphql.Field{Type: graphql.String},
+			"date":          &graphql.Field{Type: graphql.DateTime},
+			"contacts": &graphql.Field{
+				Type: graphql.NewList(graphql.NewNonNull(contactRef)),
+				Resolve: func(p graphql.ResolveParams) (any, error) {
+					c, db := graphqlContext(p)
+					var contacts []models.Contact
+					err := db.Scopes(ownContacts(c)).
+						Joins("JOIN activity_contacts ON activity_contacts.contact_id = contacts.id").
+						Where("activity_contacts.activity_id = ?", p.Source.(models.Activity).ID).
+						Order("contacts.id").
+						Find(&contacts).Error
+					return contacts, err
+				},
+			},
+		}),
+	})
+
+	relationship := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Relationship",
+		Fields: graphqlModelFields(graphql.Fields{
+			"name":     &graphql.Field{Type: graphql.String},
+			"type":     &graphql.Field{Type: graphql.String},
+			"gender":   &graphql.Field{Type: graphql.String},
+			"birthday": &graphql.Field{Type: graphqlDate},
+			"related_contact": &graphql.Field{
+				Type: contactRef,
+				Resolve: func(p graphql.ResolveParams) (any, error) {
+					relatedID := p.Source.(models.Relationship).RelatedContactID
+					if relatedID == nil {
+						return nil, nil
+					}
+					c, db := graphqlContext(p)
+					var contacts []models.Contact
+					if err := db.Scopes(ownContacts(c)).Where("id = ?", *relatedID).Find(&contacts).Error; err != nil || len(contacts) == 0 {
+						return nil, err
+					}
+					return contacts[0], nil
+				},
+			},
+		}),
+	})
+
+	reminder := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Reminder",
+		Fields: graphqlModelFields(graphql.Fields{
+			"message":             &graphql.Field{Type: graphql.String},
+			"by_mail":             &graphql.Field{Type: graphql.Boolean},
+			"remind_at":           &graphql.Field{Type: graphql.DateTime},
+			"next_due":            &graphql.Field{Type: graphql.DateTime},
+			"recurrence":          &graphql.Field{Type: graphql.String},
+			"recurrence_interval": &graphql.Field{Type: graphql.Int},
+			"completed":           &graphql.Field{Type: graphql.Boolean},
+			"status":              &graphql.Field{Type: graphql.String},
+		}),
+	})
+
+	// contactRelation resolves a list of rows belonging to the contact, which is already owned by the user
+	contactRelation := func(itemType graphql.Output, find func(db *gorm.DB, contactID uint) (any, error)) *graphql.Field {
+		return &graphql.Field{
+			Type: graphql.NewList(graphql.NewNonNull(itemType)),
+			Resolve: func(p graphql.ResolveParams) (any, error) {
+				_, db := graphqlContext(p)
+				return find(db, p.Source.(models.Contact).ID)
+			},
+		}
+	}
+
+	contact := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Contact",
+		Fields: graphqlModelFields(graphql.Fields{
+			"version":                &graphql.Field{Type: graphql.Int},
+			"firstname":              &graphql.Field{Type: graphql.String},
+			"lastname":               &graphql.Field{Type: graphql.String},
+			"nickname":               &graphql.Field{Type: graphql.String},
+			"gender":                 &graphql.Field{Type: graphql.String},
+			"email":                  &graphql.Field{Type: graphql.String},
+			"phone":                  &graphql.Field{Type: graphql.String},
+			"birthday":               &graphql.Field{Type: graphqlDate},
+			"deceased":               &graphql.Field{Type: graphql.Boolean},
+			"deceased_date":          &graphql.Field{Type: graphqlDate},
+			"address":                &graphql.Field{Type: address},
+			"how_we_met":             &graphql.Field{Type: graphql.String},
+			"food_preference":        &graphql.Field{Type: graphql.String},
+			"work_information":       &graphql.Field{Type: graphql.String},
+			"contact_information":    &graphql.Field{Type: graphql.String},
+			"circles":                &graphql.Field{Type: stringList},
+			"tags":                   &graphql.Field{Type: stringList},
+			"contact_frequency_days": &graphql.Field{Type: graphql.Int},
+			"reminder_lead_days":     &graphql.Field{Type: graphql.Int},
+			"notes": contactRelation(note, func(db *gorm.DB, contactID uint) (any, error) {
+				var notes []models.Note
+				err := db.Where("contact_id = ?", contactID).Order("date DESC").Order("id").Find(&notes).Error
+				return notes, err
+			}),
+			"activities": contactRelation(activity, func(db *gorm.DB, contactID uint) (any, error) {
+				var activities []models.Activity
+				err := db.Joins("JOIN activity_contacts ON activity_contacts.activity_id = activities.id").
+					Where("activity_contacts.contact_id = ?", contactID).
+					Order("activities.date DESC").Order("activities.id").
+					Find(&activities).Error
+				return activities, err
+			}),
+			"relationships": contactRelation(relationship, func(db *gorm.DB, contactID uint) (any, error) {
+				var relationships []models.Relationship
+				err := db.Where("contact_id = ?", contactID).Order("id").Find(&relationships).Error
+				return relationships, err
+			}),
+			"reminders": contactRelation(reminder, func(db *gorm.DB, contactID uint) (any, error) {
+				var reminders []models.Reminder
+				err := db.Where("contact_id = ?", contactID).Order("remind_at").Order("id").Find(&reminders).Error
+				return reminders, err
+			}),
+			"contact_methods": contactRelation(contactMethod, func(db *gorm.DB, contactID uint) (any, error) {
+				var methods []models.ContactMethod
+				err := db.Where("contact_id = ?", contactID).Order("type, is_primary DESC, id").Find(&methods).Error
+				return methods, err
+			}),
+		}),
+	})
+
+	query := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Query",
+		Fields: graphql.Fields{
+			"contacts": &graphql.Field{
+				Type:        graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(contact))),
+				Description: "Contacts sorted by name, optionally filtered like the contact list",
+				Args: graphql.FieldConfigArgument{
+					"search": &graphql.ArgumentConfig{Type: graphql.String},
+					"circle": &graphql.ArgumentConfig{Type: graphql.String},
+					"tag":    &graphql.ArgumentConfig{Type: graphql.String},
+					"limit":  &graphql.ArgumentConfig{Type: graphql.Int, Description: "Defaults to DEFAULT_PAGE_LIMIT, at most MAX_PAGE_LIMIT"},
+					"offset": &graphql.ArgumentConfig{Type: graphql.Int, DefaultValue: 0},
+				},
+				Resolve: func(p graphql.ResolveParams) (any, error) {
+					c, db := graphqlContext(p)
+					query := db.Scopes(ownContacts(c))
+					if search, _ := p.Args["search"].(string); search != "" {
+						query = query.Where(searchCondition(services.Dialect(db), defaultSearchFields, search))
+					}
+					if circle, _ := p.Args["circle"].(string); circle != "" {
+						query = query.Where(services.Dialect(db).JSONContains("contacts.circles"), circle)
+					}
+					if tag, _ := p.Args["tag"].(string); tag != "" {
+						query = query.Where(services.Dialect(db).JSONContains("contacts.tags"), tag)
+					}
+					requested, _ := p.Args["limit"].(int)
+					limit := pageLimit(requested, c.MustGet("config").(*config.Config))
+					offset, _ := p.Args["offset"].(int)
+
+					var contacts []models.Contact
+					err := query.Order("LOWER(lastname), LOWER(firstname), id").Limit(limit).Offset(max(offset, 0)).Find(&contacts).Error
+					return contacts, err
+				},
+			},
+			"contact": &graphql.Field{
+				Type: contact,
+				Args: graphql.FieldConfigArgument{
+					"id": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.Int)},
+				},
+				Resolve: func(p graphql.ResolveParams) (any, error) {
+					c, db := graphqlContext(p)
+					var contacts []models.Contact
+					if err := db.Scopes(ownContacts(c)).Where("id = ?", p.Args["id"]).Find(&contacts).Error; err != nil || len(contacts) == 0 {
+						return nil, err
+					}
+					return contacts[0], nil
+				},
+			},
+		},
+	})
+
+	schema, err := graphql.NewSchema(graphql.SchemaConfig{Query: query})
+	if err != nil {
+		panic("invalid GraphQL schema: " + err.Error())
+	}
+	return schema
+}
@@ -0,0 +1,102 @@
+package controllers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"perema/config"
+	"perema/models"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGraphQL(t *testing.T) {
+	db, router := setupRouter()
+	router.POST("/graphql", func(c *gin.Context) {
+		GraphQL(c, &config.Config{DefaultPageLimit: 25, MaxPageLimit: 100})
+	})
+
+	alice := models.Contact{Firstname: "Alice", Lastname: "Johnson", Circles: []string{"Friends"}, Tags: []string{"VIP"},
+		Birthday: &models.Date{Time: time.Date(1990, 5, 4, 0, 0, 0, 0, time.UTC), Valid: true}, Address: models.Address{City: "Berlin"}}
+	bob := models.Contact{Firstname: "Bob", Lastname: "Smith", Circles: []string{"Work"}}
+	other := models.Contact{Firstname: "Mallory", UserID: 2}
+	for _, contact := range []*models.Contact{&alice, &bob, &other} {
+		db.Create(contact)
+	}
+	db.Create(&models.Note{Content: "Likes tea", Date: time.Now(), ContactID: &alice.ID})
+	db.Create(&models.Relationship{Name: "Bob", Type: "Friend", ContactID: alice.ID, RelatedContactID: &bob.ID})
+	db.Create(&models.Relationship{Name: "Mallory", Type: "Colleague", ContactID: alice.ID, RelatedContactID: &other.ID})
+	db.Create(&models.Activity{Title: "Lunch", Date: time.Now(), Contacts: []models.Contact{alice, bob}})
+
+	query := func(query string, variables map[string]any) (int, map[string]any) {
+		jsonValue, _ := json.Marshal(map[string]any{"query": query, "variables": variables})
+		req, _ := http.NewRequest("POST", "/graphql", bytes.NewBuffer(jsonValue))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		var responseBody map[string]any
+		json.Unmarshal(w.Body.Bytes(), &responseBody)
+		return w.Code, responseBody
+	}
+
+	// Only the requested fields and relations are returned
+	code, result := query(`query($id: Int!) {
+		contact(id: $id) {
+			id firstname birthday circles address { city }
+			notes { content }
+			relationships { name related_contact { firstname } }
+			activities { title contacts { firstname } }
+		}
+	}`, map[string]any{"id": alice.ID})
+	assert.Equal(t, http.StatusOK, code)
+	assert.Nil(t, result["errors"])
+
+	expected := map[string]any{
+		"contact": map[string]any{
+			"id":        float64(alice.ID),
+			"firstname": "Alice",
+			"birthday":  "1990-05-04",
+			"circles":   []any{"Friends"},
+			"address":   map[string]any{"city": "Berlin"},
+			"notes":     []any{map[string]any{"content": "Likes tea"}},
+			"relationships": []any{
+				map[string]any{"name": "Bob", "related_contact": map[string]any{"firstname": "Bob"}},
+				// Contacts of other users are not revealed through relationships
+				map[string]any{"name": "Mallory", "related_contact": nil},
+			},
+			"activities": []any{map[string]any{"title": "Lunch", "contacts": []any{
+				map[string]any{"firstname": "Alice"},
+				map[string]any{"firstname": "Bob"},
+			}}},
+		},
+	}
+	assert.Equal(t, expected, result["data"])
+
+	// Lists are filtered and sorted like the contact list, contacts of other users are not found
+	_, result = query(`{ all: contacts { firstname } vip: contacts(tag: "VIP") { firstname } work: contacts(circle: "Work") { firstname } search: contacts(search: "mallory") { firstname } }`, nil)
+	assert.Equal(t, map[string]any{
+		"all":    []any{map[string]any{"firstname": "Alice"}, map[string]any{"firstname": "Bob"}},
+		"vip":    []any{map[string]any{"firstname": "Alice"}},
+		"work":   []any{map[string]any{"firstname": "Bob"}},
+		"search": []any{},
+	}, result["data"])
+
+	_, result = query(`{ contacts(limit: 1, offset: 1) { firstname } }`, nil)
+	assert.Equal(t, map[string]any{"contacts": []any{map[string]any{"firstname": "Bob"}}}, result["data"])
+
+	_, result = query(`query($id: Int!) { contact(id: $id) { firstname } }`, map[string]any{"id": other.ID})
+	assert.Equal(t, map[string]any{"contact": nil}, result["data"])
+
+	// Invalid queries are reported as GraphQL errors
+	code, result = query(`{ contacts { password } }`, nil)
+	assert.Equal(t, http.StatusOK, code)
+	assert.NotEmpty(t, result["errors"])
+
+	code, _ = query("", nil)
+	assert.Equal(t, http.StatusBadRequest, code)
+}
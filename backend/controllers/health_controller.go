@@ -0,0 +1,54 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"runtime/debug"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// Version of the running build, set with -ldflags "-X perema/controllers.Version=..."
+var Version = "dev"
+
+// buildInfo describes what is deployed: the version, the commit it was built from and the Go version
+func buildInfo() gin.H {
+	info := gin.H{"version": Version}
+	if build, ok := debug.ReadBuildInfo(); ok {
+		info["go_version"] = build.GoVersion
+		for _, setting := range build.Settings {
+			switch setting.Key {
+			case "vcs.revision":
+				info["commit"] = setting.Value
+			case "vcs.time":
+				info["build_time"] = setting.Value
+			}
+		}
+	}
+	return info
+}
+
+// GetHealth reports that the process is up
+func GetHealth(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"status": "ok", "build": buildInfo()})
+}
+
+// GetReadiness reports whether the database can be reached, so requests can be served
+func GetReadiness(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+
+	sqlDB, err := db.DB()
+	if err == nil {
+		ctx, cancel := context.WithTimeout(c.Request.Context(), 2*time.Second)
+		defer cancel()
+		err = sqlDB.PingContext(ctx)
+	}
+	if err != nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"status": "unavailable", "error": "Database unreachable"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"status": "ready"})
+}
@@ -0,0 +1,40 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHealthAndReadiness(t *testing.T) {
+	db, router := setupRouter()
+	router.GET("/healthz", GetHealth)
+	router.GET("/readyz", GetReadiness)
+
+	send := func(url string) (int, map[string]any) {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("GET", url, nil))
+		var responseBody map[string]any
+		json.Unmarshal(w.Body.Bytes(), &responseBody)
+		return w.Code, responseBody
+	}
+
+	code, responseBody := send("/healthz")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "dev", responseBody["build"].(map[string]any)["version"])
+
+	code, _ = send("/readyz")
+	assert.Equal(t, http.StatusOK, code)
+
+	// Without a database the service is not ready, but still alive
+	sqlDB, _ := db.DB()
+	sqlDB.Close()
+	code, responseBody = send("/readyz")
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, "unavailable", responseBody["status"])
+	code, _ = send("/healthz")
+	assert.Equal(t, http.StatusOK, code)
+}
@@ -0,0 +1,124 @@
+package controllers
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"perema/middleware"
+	"perema/models"
+	"slices"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// orphanedUpload is a file in the upload directory which is not referenced anymore
+type orphanedUpload struct {
+	Name string `json:"name"`
+	Size int64  `json:"size"`
+}
+
+// isGeneratedUpload reports whether a file name was generated by perema, other files are never touched
+func isGeneratedUpload(name string) bool {
+	return strings.HasSuffix(name, "_photo.jpg") || strings.HasSuffix(name, "_thumbnail.jpg") ||
+		strings.Contains(name, "_attachment.")
+}
+
+// referencedUploads returns the names of all files referenced from the database
+func referencedUploads(db *gorm.DB) (map[string]bool, error) {
+	referenced := map[string]bool{}
+
+	// Soft deleted contacts can still be restored, so their photos are kept
+	var contacts []models.Contact
+	if err := db.Unscoped().Select("Photo", "PhotoThumbnail").
+		Where("photo <> '' OR photo_thumbnail <> ''").
+		Find(&contacts).Error; err != nil {
+		return nil, err
+	}
+	for _, contact := range contacts {
+		referenced[contact.Photo] = true
+		referenced[contact.PhotoThumbnail] = true
+	}
+
+	// Attachment rows are removed together with their files, so only existing rows are relevant
+	var storedNames []string
+	if err := db.Model(&models.NoteAttachment{}).Pluck("stored_name", &storedNames).Error; err != nil {
+		return nil, err
+	}
+	for _, name := range storedNames {
+		referenced[name] = true
+	}
+
+	// Attachments of soft deleted contacts can still be restored with them
+	storedNames = nil
+	if err := db.Unscoped().Model(&models.ContactAttachment{}).Pluck("stored_name", &storedNames).Error; err != nil {
+		return nil, err
+	}
+	for _, name := range storedNames {
+		referenced[name] = true
+	}
+
+	return referenced, nil
+}
+
+// PurgeOrphanedUploads lists uploaded files which are no longer referenced and deletes them unless dry_run=true (default)
+func PurgeOrphanedUploads(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+	dryRun := c.DefaultQuery("dry_run", "true") != "false"
+
+	var uploadDirs []string
+	for _, dir := range []string{os.Getenv("PROFILE_PHOTO_DIR"), attachmentDir()} {
+		if dir != "" && !slices.Contains(uploadDirs, dir) {
+			uploadDirs = append(uploadDirs, dir)
+		}
+	}
+	if len(uploadDirs) == 0 {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Upload directory is not configured"})
+		return
+	}
+
+	referenced, err := referencedUploads(db)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve referenced files"})
+		return
+	}
+
+	orphans := []orphanedUpload{}
+	var reclaimedBytes int64
+	for _, uploadDir := range uploadDirs {
+		entries, err := os.ReadDir(uploadDir)
+		if err != nil && !os.IsNotExist(err) {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read upload directory"})
+			return
+		}
+
+		for _, entry := range entries {
+			if entry.IsDir() || !isGeneratedUpload(entry.Name()) || referenced[entry.Name()] {
+				continue
+			}
+
+			info, err := entry.Info()
+			if err != nil {
+				continue
+			}
+
+			if !dryRun {
+				if err := os.Remove(filepath.Join(uploadDir, entry.Name())); err != nil {
+					middleware.Logger(c).Error("Failed to remove orphaned upload", "file", entry.Name(), "error", err)
+					continue
+				}
+			}
+
+			orphans = append(orphans, orphanedUpload{Name: entry.Name(), Size: info.Size()})
+			reclaimedBytes += info.Size()
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"dry_run":         dryRun,
+		"files":           orphans,
+		"count":           len(orphans),
+		"reclaimed_bytes": reclaimedBytes,
+	})
+}
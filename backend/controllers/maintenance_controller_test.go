@@ -0,0 +1,62 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"perema/models"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPurgeOrphanedUploads(t *testing.T) {
+	db, router := setupRouter()
+	router.POST("/maintenance/purge-uploads", PurgeOrphanedUploads)
+
+	uploadDir := t.TempDir()
+	t.Setenv("PROFILE_PHOTO_DIR", uploadDir)
+
+	files := map[string]string{
+		"used_photo.jpg":       "used",
+		"used_thumbnail.jpg":   "used",
+		"orphan_photo.jpg":     "orphan",
+		"orphan_thumbnail.jpg": "orphan",
+		"README.txt":           "not generated by perema",
+	}
+	for name, content := range files {
+		os.WriteFile(filepath.Join(uploadDir, name), []byte(content), 0644)
+	}
+	db.Create(&models.Contact{Firstname: "Alice", Photo: "used_photo.jpg", PhotoThumbnail: "used_thumbnail.jpg"})
+
+	// The dry run is the default and only reports the orphans
+	req, _ := http.NewRequest("POST", "/maintenance/purge-uploads", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var responseBody struct {
+		DryRun         bool             `json:"dry_run"`
+		Files          []orphanedUpload `json:"files"`
+		ReclaimedBytes int64            `json:"reclaimed_bytes"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &responseBody)
+	assert.True(t, responseBody.DryRun)
+	assert.ElementsMatch(t, []orphanedUpload{{"orphan_photo.jpg", 6}, {"orphan_thumbnail.jpg", 6}}, responseBody.Files)
+	assert.Equal(t, int64(12), responseBody.ReclaimedBytes)
+	assert.FileExists(t, filepath.Join(uploadDir, "orphan_photo.jpg"))
+
+	// The real run deletes only the orphans
+	req, _ = http.NewRequest("POST", "/maintenance/purge-uploads?dry_run=false", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NoFileExists(t, filepath.Join(uploadDir, "orphan_photo.jpg"))
+	assert.NoFileExists(t, filepath.Join(uploadDir, "orphan_thumbnail.jpg"))
+	assert.FileExists(t, filepath.Join(uploadDir, "used_photo.jpg"))
+	assert.FileExists(t, filepath.Join(uploadDir, "README.txt"))
+}
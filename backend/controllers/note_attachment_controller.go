@@ -0,0 +1,181 @@
+package controllers
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"perema/middleware"
+	"perema/models"
+
+	"github.com/gabriel-vasile/mimetype"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Maximum size of a voice memo
+const maxAudioSize = 25 << 20 // 25 MB
+
+// Supported audio content types and the extension used to store them
+var audioExtensions = map[string]string{
+	"audio/mpeg":      ".mp3",
+	"audio/mp4":       ".m4a",
+	"audio/x-m4a":     ".m4a",
+	"audio/ogg":       ".ogg",
+	"application/ogg": ".ogg",
+}
+
+func attachmentDir() string {
+	return os.Getenv("ATTACHMENT_DIR")
+}
+
+// AddAttachmentToNote stores an uploaded audio file and attaches it to the note
+func AddAttachmentToNote(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+
+	var note models.Note
+	if err := db.Scopes(ownedBy(c, "notes")).First(&note, c.Param("id")).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Note not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	file, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "No file uploaded"})
+		return
+	}
+	if file.Size > maxAudioSize {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "File is too large"})
+		return
+	}
+
+	src, err := file.Open()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read file"})
+		return
+	}
+	defer src.Close()
+
+	// Detect the content type from the file content instead of trusting the client
+	detected, err := mimetype.DetectReader(src)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read file"})
+		return
+	}
+	extension, supported := audioExtensions[detected.String()]
+	if !supported {
+		c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": "Unsupported file format, only mp3, m4a and ogg are allowed"})
+		return
+	}
+
+	uploadDir := attachmentDir()
+	if err := os.MkdirAll(uploadDir, os.ModePerm); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create upload directory"})
+		return
+	}
+
+	attachment := models.NoteAttachment{
+		NoteID:      note.ID,
+		Filename:    filepath.Base(file.Filename),
+		StoredName:  uuid.New().String() + "_attachment" + extension,
+		ContentType: detected.String(),
+		Size:        file.Size,
+	}
+	if err := c.SaveUploadedFile(file, filepath.Join(uploadDir, attachment.StoredName)); err != nil {
+		middleware.Logger(c).Error("Failed to save attachment", "note_id", note.ID, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save file"})
+		return
+	}
+
+	if err := db.Create(&attachment).Error; err != nil {
+		os.Remove(filepath.Join(uploadDir, attachment.StoredName))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save attachment"})
+		return
+	}
+
+	respondCreated(c, fmt.Sprintf("/notes/%d/attachments/%d", attachment.NoteID, attachment.ID), gin.H{"message": "Attachment added successfully", "attachment": attachment})
+}
+
+// GetNoteAttachment serves an attached file, supporting range requests for seeking in audio players
+func GetNoteAttachment(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+
+	var attachment models.NoteAttachment
+	if err := db.Where("note_id = ?", c.Param("id")).Scopes(ownedThroughNote(c)).First(&attachment, c.Param("aid")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Attachment not found"})
+		return
+	}
+
+	file, err := os.Open(filepath.Join(attachmentDir(), attachment.StoredName))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "File not found"})
+		return
+	}
+	defer file.Close()
+
+	// ServeContent handles Range and conditional requests and keeps the preset content type
+	c.Header("Content-Type", attachment.ContentType)
+	http.ServeContent(c.Writer, c.Request, attachment.Filename, attachment.UpdatedAt, file)
+}
+
+// ownedThroughNote restricts a query on attachments to the attachments of notes of the authenticated user
+func ownedThroughNote(c *gin.Context) func(*gorm.DB) *gorm.DB {
+	userID := currentUserID(c)
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where("note_id IN (SELECT id FROM notes WHERE user_id = ?)", userID)
+	}
+}
+
+// DeleteNoteAttachment removes an attachment and its file
+func DeleteNoteAttachment(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+
+	var attachment models.NoteAttachment
+	if err := db.Where("note_id = ?", c.Param("id")).Scopes(ownedThroughNote(c)).First(&attachment, c.Param("aid")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Attachment not found"})
+		return
+	}
+
+	if err := deleteNoteAttachments(db, []models.NoteAttachment{attachment}); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete attachment"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Attachment deleted"})
+}
+
+// deleteNoteAttachments removes the given attachments from the database and their files from disk
+func deleteNoteAttachments(db *gorm.DB, attachments []models.NoteAttachment) error {
+	if len(attachments) == 0 {
+		return nil
+	}
+
+	if err := db.Unscoped().Delete(&attachments).Error; err != nil {
+		return err
+	}
+
+	for _, attachment := range attachments {
+		removeAttachmentFile(attachment)
+	}
+	return nil
+}
+
+func removeAttachmentFile(attachment models.NoteAttachment) {
+	if err := os.Remove(filepath.Join(attachmentDir(), attachment.StoredName)); err != nil && !os.IsNotExist(err) {
+		slog.Error("Failed to remove attachment file", "note_id", attachment.NoteID, "file", attachment.StoredName, "error", err)
+	}
+}
+
+func attachmentIDs(attachments []models.NoteAttachment) []uint {
+	ids := make([]uint, len(attachments))
+	for i, attachment := range attachments {
+		ids[i] = attachment.ID
+	}
+	return ids
+}
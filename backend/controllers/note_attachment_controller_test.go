@@ -0,0 +1,126 @@
+package controllers
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"perema/models"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeMP3 returns bytes recognized as an mp3 file
+func fakeMP3() []byte {
+	data := append([]byte("ID3\x03\x00\x00\x00\x00\x00\x00"), bytes.Repeat([]byte{0xFF, 0xFB, 0x90, 0x64}, 256)...)
+	return data
+}
+
+func newUploadRequest(url, filename string, content []byte) *http.Request {
+	return newFormFileRequest(url, "file", filename, content)
+}
+
+func newFormFileRequest(url, field, filename string, content []byte) *http.Request {
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	part, _ := writer.CreateFormFile(field, filename)
+	part.Write(content)
+	writer.Close()
+
+	req, _ := http.NewRequest("POST", url, body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	return req
+}
+
+func TestAddAttachmentToNote(t *testing.T) {
+	db, router := setupRouter()
+	router.POST("/notes/:id/attachments", AddAttachmentToNote)
+	t.Setenv("ATTACHMENT_DIR", t.TempDir())
+
+	note := models.Note{Content: "Call with Tom", Date: time.Now()}
+	db.Create(&note)
+	url := "/notes/" + strconv.Itoa(int(note.ID)) + "/attachments"
+
+	// A text file disguised as mp3 is rejected
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, newUploadRequest(url, "memo.mp3", []byte("<?php echo 'hello'; ?>")))
+	assert.Equal(t, http.StatusUnsupportedMediaType, w.Code)
+
+	// A real mp3 is accepted
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, newUploadRequest(url, "memo.mp3", fakeMP3()))
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	var responseBody struct {
+		Attachment models.NoteAttachment `json:"attachment"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &responseBody)
+	assert.Equal(t, "audio/mpeg", responseBody.Attachment.ContentType)
+	assert.Equal(t, "memo.mp3", responseBody.Attachment.Filename)
+}
+
+func TestGetNoteAttachment(t *testing.T) {
+	db, router := setupRouter()
+	router.GET("/notes/:id/attachments/:aid", GetNoteAttachment)
+	attachmentDir := t.TempDir()
+	t.Setenv("ATTACHMENT_DIR", attachmentDir)
+
+	note := models.Note{Content: "Call with Tom", Date: time.Now()}
+	db.Create(&note)
+	content := fakeMP3()
+	os.WriteFile(attachmentDir+"/memo_attachment.mp3", content, 0644)
+	attachment := models.NoteAttachment{NoteID: note.ID, Filename: "memo.mp3", StoredName: "memo_attachment.mp3", ContentType: "audio/mpeg", Size: int64(len(content))}
+	db.Create(&attachment)
+
+	url := "/notes/" + strconv.Itoa(int(note.ID)) + "/attachments/" + strconv.Itoa(int(attachment.ID))
+
+	req, _ := http.NewRequest("GET", url, nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "audio/mpeg", w.Header().Get("Content-Type"))
+	assert.Equal(t, "bytes", w.Header().Get("Accept-Ranges"))
+	assert.Equal(t, content, w.Body.Bytes())
+
+	// Range requests allow seeking
+	req, _ = http.NewRequest("GET", url, nil)
+	req.Header.Set("Range", "bytes=10-19")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusPartialContent, w.Code)
+	assert.Equal(t, "audio/mpeg", w.Header().Get("Content-Type"))
+	assert.Equal(t, "bytes 10-19/"+strconv.Itoa(len(content)), w.Header().Get("Content-Range"))
+	body, _ := io.ReadAll(w.Body)
+	assert.Equal(t, content[10:20], body)
+}
+
+func TestDeleteNoteRemovesAttachments(t *testing.T) {
+	db, router := setupRouter()
+	router.DELETE("/notes/:id", DeleteNote)
+	attachmentDir := t.TempDir()
+	t.Setenv("ATTACHMENT_DIR", attachmentDir)
+
+	note := models.Note{Content: "Call with Tom", Date: time.Now()}
+	db.Create(&note)
+	os.WriteFile(attachmentDir+"/memo_attachment.mp3", fakeMP3(), 0644)
+	db.Create(&models.NoteAttachment{NoteID: note.ID, Filename: "memo.mp3", StoredName: "memo_attachment.mp3", ContentType: "audio/mpeg"})
+
+	req, _ := http.NewRequest("DELETE", "/notes/"+strconv.Itoa(int(note.ID)), nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NoFileExists(t, attachmentDir+"/memo_attachment.mp3")
+
+	var count int64
+	db.Model(&models.NoteAttachment{}).Count(&count)
+	assert.Equal(t, int64(0), count)
+}
@@ -1,15 +1,21 @@
 package controllers
 
 import (
-	"log"
+	"fmt"
 	"net/http"
+	"perema/config"
+	"perema/middleware"
 	"perema/models"
+	"perema/services"
+	"slices"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
 )
 
-func CreateNote(c *gin.Context) {
+func CreateNote(c *gin.Context, cfg *config.Config) {
 	// Get the database instance from the context
 	db := c.MustGet("db").(*gorm.DB)
 
@@ -18,7 +24,7 @@ func CreateNote(c *gin.Context) {
 
 	// Find the contact by the ID
 	var contact models.Contact
-	if err := db.First(&contact, contactID).Error; err != nil {
+	if err := db.Scopes(ownContacts(c)).First(&contact, contactID).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
 			c.JSON(http.StatusNotFound, gin.H{"error": "Contact not found"})
 		} else {
@@ -30,53 +36,78 @@ func CreateNote(c *gin.Context) {
 	// Bind the incoming JSON request to the Note struct
 	var note models.Note
 	if err := c.ShouldBindJSON(&note); err != nil {
-		log.Println("Error binding JSON for create note:", err)
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		middleware.Logger(c).Warn("Invalid note", "contact_id", contact.ID, "error", err)
+		respondBindingError(c, err)
 		return
 	}
 
 	// Assign the ContactID to the note to link it to the contact
 	note.ContactID = &contact.ID
+	note.UserID = currentUserID(c)
+	note.Content = services.SanitizeHTML(note.Content, cfg.HTMLSanitization)
+	note.Tags = normalizeNames(note.Tags)
+	if fieldErrors := noteFieldErrors(note); len(fieldErrors) > 0 {
+		respondFieldErrors(c, fieldErrors)
+		return
+	}
 
 	// Save the new note to the database
 	if err := db.Create(&note).Error; err != nil {
-		log.Println("Error saving to database:", err)
+		middleware.Logger(c).Error("Failed to save note", "contact_id", contact.ID, "error", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save note"})
 		return
 	}
 
 	// Respond with success and the created note
-	c.JSON(http.StatusOK, gin.H{"message": "Note created successfully", "note": note})
+	respondCreated(c, fmt.Sprintf("/notes/%d", note.ID), gin.H{"message": "Note created successfully", "note": note})
 }
 
-func CreateUnassignedNote(c *gin.Context) {
+func CreateUnassignedNote(c *gin.Context, cfg *config.Config) {
 	// Get the database instance from the context
 	db := c.MustGet("db").(*gorm.DB)
 
 	// Bind the incoming JSON request to the Note struct
 	var note models.Note
 	if err := c.ShouldBindJSON(&note); err != nil {
-		log.Println("Error binding JSON for create note:", err)
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		middleware.Logger(c).Warn("Invalid note", "error", err)
+		respondBindingError(c, err)
+		return
+	}
+
+	note.ContactID = nil
+	note.UserID = currentUserID(c)
+	note.Content = services.SanitizeHTML(note.Content, cfg.HTMLSanitization)
+	note.Tags = normalizeNames(note.Tags)
+	if fieldErrors := noteFieldErrors(note); len(fieldErrors) > 0 {
+		respondFieldErrors(c, fieldErrors)
 		return
 	}
 
 	// Save the new note to the database
 	if err := db.Create(&note).Error; err != nil {
-		log.Println("Error saving to database:", err)
+		middleware.Logger(c).Error("Failed to save note", "error", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save note"})
 		return
 	}
 
 	// Respond with success and the created note
-	c.JSON(http.StatusOK, gin.H{"message": "Note created successfully", "note": note})
+	respondCreated(c, fmt.Sprintf("/notes/%d", note.ID), gin.H{"message": "Note created successfully", "note": note})
+}
+
+// noteFieldErrors rejects notes without text, notes only consisting of HTML tags like <p></p> count as empty
+func noteFieldErrors(note models.Note) map[string]fieldError {
+	fields := map[string]fieldError{}
+	if strings.TrimSpace(services.SanitizeHTML(note.Content, services.SanitizeStrict)) == "" {
+		fields["content"] = fieldError{Rule: "required", Message: "This field is required", Value: note.Content}
+	}
+	return fields
 }
 
 func GetNote(c *gin.Context) {
 	id := c.Param("id")
 	var note models.Note
 	db := c.MustGet("db").(*gorm.DB)
-	if err := db.First(&note, id).Error; err != nil {
+	if err := db.Scopes(ownedBy(c, "notes")).Preload("Attachments").First(&note, id).Error; err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Note not found"})
 		return
 	}
@@ -84,12 +115,96 @@ func GetNote(c *gin.Context) {
 	c.JSON(http.StatusOK, note)
 }
 
+// GetNoteHTML renders the note from Markdown to sanitized HTML, the stored content stays the source.
+// Notes are sanitized when saved like activities, which keeps Markdown without HTML unchanged. The rendered
+// HTML is sanitized again, as notes saved before sanitization was introduced may still contain anything.
+func GetNoteHTML(c *gin.Context, cfg *config.Config) {
+	var note models.Note
+	db := c.MustGet("db").(*gorm.DB)
+	if err := db.Scopes(ownedBy(c, "notes")).First(&note, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Note not found"})
+		return
+	}
+
+	rendered, err := services.RenderMarkdown(note.Content, cfg.HTMLSanitization)
+	if err != nil {
+		middleware.Logger(c).Error("Failed to render note", "note_id", note.ID, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to render note"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"id": note.ID, "html": rendered})
+}
+
+// normalizeNames trims tags or circles and removes empty and duplicate ones
+func normalizeNames(tags []string) []string {
+	normalized := []string{}
+	for _, tag := range tags {
+		if tag = strings.TrimSpace(tag); tag != "" && !slices.Contains(normalized, tag) {
+			normalized = append(normalized, tag)
+		}
+	}
+	return normalized
+}
+
+// GetNotes returns all notes with the given tag if the tag parameter is set, otherwise the unassigned notes
+func GetNotes(c *gin.Context) {
+	if c.Query("tag") != "" {
+		GetNotesByTag(c)
+		return
+	}
+	GetUnassignedNotes(c)
+}
+
+// taggedNote is a note together with the name of the contact it belongs to
+type taggedNote struct {
+	models.Note
+	ContactName string `json:"contact_name"`
+}
+
+// GetNotesByTag returns the notes of all contacts with the given tag, newest first
+func GetNotesByTag(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+
+	notes := []taggedNote{}
+	err := db.Model(&models.Note{}).
+		Select("notes.*, TRIM(COALESCE(contacts.firstname, '') || ' ' || COALESCE(contacts.lastname, '')) AS contact_name").
+		Joins("LEFT JOIN contacts ON contacts.id = notes.contact_id AND contacts.deleted_at IS NULL").
+		Scopes(ownedBy(c, "notes")).
+		Where(services.Dialect(db).JSONContains("notes.tags"), c.Query("tag")).
+		Order("notes.date DESC").
+		Scan(&notes).Error
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error retrieving notes"})
+		return
+	}
+
+	c.JSON(http.StatusOK, notes)
+}
+
+// GetTags returns all unique tags used in notes
+func GetTags(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+	tags := []string{}
+
+	err := db.Raw(`SELECT DISTINCT json_each.value AS tag
+	               FROM notes, `+services.Dialect(db).JSONEach("notes.tags")+`
+	               WHERE notes.deleted_at IS NULL AND notes.user_id = ?
+	               ORDER BY tag`, currentUserID(c)).Scan(&tags).Error
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve tags"})
+		return
+	}
+
+	c.JSON(http.StatusOK, tags)
+}
+
 func GetUnassignedNotes(c *gin.Context) {
 	var notes []models.Note
 	db := c.MustGet("db").(*gorm.DB)
 
 	// Retrieve notes where contact_id is NULL
-	if err := db.Where("contact_id IS NULL").Find(&notes).Error; err != nil {
+	if err := db.Scopes(ownedBy(c, "notes")).Where("contact_id IS NULL").Find(&notes).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error retrieving unassigned notes"})
 		return
 	}
@@ -97,30 +212,47 @@ func GetUnassignedNotes(c *gin.Context) {
 	c.JSON(http.StatusOK, notes)
 }
 
-func UpdateNote(c *gin.Context) {
+func UpdateNote(c *gin.Context, cfg *config.Config) {
 	db := c.MustGet("db").(*gorm.DB)
 
 	id := c.Param("id")
 	var note models.Note
 
 	// Retrieve the existing note from the database
-	if err := db.First(&note, id).Error; err != nil {
+	if err := db.Scopes(ownedBy(c, "notes")).First(&note, id).Error; err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Note not found"})
 		return
 	}
 
 	var updatedNote models.Note
 	if err := c.ShouldBindJSON(&updatedNote); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondBindingError(c, err)
 		return
 	}
 
+	// Notes can only be assigned to own contacts
+	if updatedNote.ContactID != nil {
+		if err := db.Scopes(ownContacts(c)).First(&models.Contact{}, *updatedNote.ContactID).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Contact not found"})
+			return
+		}
+	}
+
 	// Updateable fields
-	note.Content = updatedNote.Content
+	note.Content = services.SanitizeHTML(updatedNote.Content, cfg.HTMLSanitization)
+	note.Tags = normalizeNames(updatedNote.Tags)
 	note.Date = updatedNote.Date
 	note.ContactID = updatedNote.ContactID
+	if fieldErrors := noteFieldErrors(note); len(fieldErrors) > 0 {
+		respondFieldErrors(c, fieldErrors)
+		return
+	}
 
-	db.Updates(&note)
+	if err := db.Updates(&note).Error; err != nil {
+		middleware.Logger(c).Error("Failed to update note", "note_id", note.ID, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update note"})
+		return
+	}
 
 	c.JSON(http.StatusOK, gin.H{"message": "Note updated successfully", "note": note})
 }
@@ -128,27 +260,43 @@ func UpdateNote(c *gin.Context) {
 func DeleteNote(c *gin.Context) {
 	id := c.Param("id")
 	db := c.MustGet("db").(*gorm.DB)
+
+	if err := db.Scopes(ownedBy(c, "notes")).First(&models.Note{}, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Note not found"})
+		return
+	}
+
+	var attachments []models.NoteAttachment
+	if err := db.Where("note_id = ?", id).Find(&attachments).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve attachments"})
+		return
+	}
+
 	if err := db.Delete(&models.Note{}, id).Error; err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Note not found"})
 		return
 	}
 
+	// Attached files are removed together with the note
+	if err := deleteNoteAttachments(db, attachments); err != nil {
+		middleware.Logger(c).Error("Failed to delete attachments of note", "note_id", id, "error", err)
+	}
+
 	c.JSON(http.StatusOK, gin.H{"message": "Note deleted"})
 }
 
-// GetNotesForContact retrieves all notes for a given contact
-func GetNotesForContact(c *gin.Context) {
+// GetNotesForContact retrieves the notes of a contact page by page, newest first unless order=asc is given.
+// The notes can be limited to a date range with from and to, both inclusive.
+func GetNotesForContact(c *gin.Context, cfg *config.Config) {
 	// Get contact ID from the request URL
 	contactID := c.Param("id")
 
 	// Get the database instance from the context
 	db := c.MustGet("db").(*gorm.DB)
 
-	// Initialize a variable to store the contact
+	// Make sure the contact exists
 	var contact models.Contact
-
-	// Fetch the contact and preload associated notes
-	if err := db.Preload("Notes").First(&contact, contactID).Error; err != nil {
+	if err := db.Scopes(ownContacts(c)).First(&contact, contactID).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
 			// If no contact found, return a 404 error
 			c.JSON(http.StatusNotFound, gin.H{"error": "Contact not found"})
@@ -159,8 +307,112 @@ func GetNotesForContact(c *gin.Context) {
 		return
 	}
 
-	// If successful, return the contact and its notes as JSON
+	// Get pagination parameters
+	page, limit, offset := paginationParams(c, cfg)
+
+	order := "date DESC"
+	switch c.DefaultQuery("order", "desc") {
+	case "asc":
+		order = "date ASC"
+	case "desc":
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid order, use asc or desc"})
+		return
+	}
+
+	query := db.Model(&models.Note{}).Where("contact_id = ?", contact.ID)
+	if from := c.Query("from"); from != "" {
+		fromDate, err := time.Parse(models.DateFormat, from)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid from date, use YYYY-MM-DD"})
+			return
+		}
+		query = query.Where("date >= ?", fromDate)
+	}
+	if to := c.Query("to"); to != "" {
+		toDate, err := time.Parse(models.DateFormat, to)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid to date, use YYYY-MM-DD"})
+			return
+		}
+		query = query.Where("date < ?", toDate.AddDate(0, 0, 1))
+	}
+
+	var total int64
+	query.Count(&total)
+
+	var notes []models.Note
+	if err := query.Order(order).Order("id").Limit(limit).Offset(offset).Find(&notes).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve notes"})
+		return
+	}
+
+	c.JSON(http.StatusOK, paginatedResponse("notes", notes, total, page, limit))
+}
+
+// noteReplacement is a note affected by a search and replace
+type noteReplacement struct {
+	ID           uint  `json:"id"`
+	ContactID    *uint `json:"contact_id"`
+	Replacements int   `json:"replacements"`
+}
+
+// ReplaceInNotes replaces all occurrences of a text in the notes of the user, case-sensitively.
+// Nothing is changed unless dry_run=false is given, so the affected notes can be previewed first.
+// It is only available to the admin, who like any user can only change their own notes.
+func ReplaceInNotes(c *gin.Context, cfg *config.Config) {
+	db := c.MustGet("db").(*gorm.DB)
+	dryRun := c.DefaultQuery("dry_run", "true") != "false"
+
+	var request struct {
+		Search  string `json:"search" binding:"required"`
+		Replace string `json:"replace"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		respondBindingError(c, err)
+		return
+	}
+
+	// LIKE only preselects the notes, it ignores the case and treats % and _ as wildcards
+	var notes []models.Note
+	if err := db.Scopes(ownedBy(c, "notes")).Select("ID", "Content", "ContactID").
+		Where("content "+services.Dialect(db).Like()+" ?", "%"+request.Search+"%").
+		Order("id").Find(&notes).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve notes"})
+		return
+	}
+
+	changed := []noteReplacement{}
+	replacements := 0
+	err := db.Transaction(func(tx *gorm.DB) error {
+		for _, note := range notes {
+			count := strings.Count(note.Content, request.Search)
+			if count == 0 {
+				continue
+			}
+			changed = append(changed, noteReplacement{ID: note.ID, ContactID: note.ContactID, Replacements: count})
+			replacements += count
+			if dryRun {
+				continue
+			}
+
+			content := services.SanitizeHTML(strings.ReplaceAll(note.Content, request.Search, request.Replace), cfg.HTMLSanitization)
+			if err := tx.Model(&note).Update("content", content).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		middleware.Logger(c).Error("Failed to replace text in notes", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update notes"})
+		return
+	}
+
 	c.JSON(http.StatusOK, gin.H{
-		"notes": contact.Notes,
+		"dry_run":       dryRun,
+		"notes":         changed,
+		"notes_changed": len(changed),
+		"replacements":  replacements,
 	})
 }
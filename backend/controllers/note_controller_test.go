@@ -5,18 +5,22 @@ import (
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"perema/config"
 	"perema/models"
 	"strconv"
 	"testing"
 	"time"
 
+	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
 )
 
 func TestGetContactNotes(t *testing.T) {
 	db, router := setupRouter()
 
-	router.GET("/contacts/:id/notes", GetNotesForContact)
+	router.GET("/contacts/:id/notes", func(c *gin.Context) {
+		GetNotesForContact(c, &config.Config{DefaultPageLimit: 25, MaxPageLimit: 100})
+	})
 
 	// Create a contact
 	contact := models.Contact{
@@ -43,16 +47,86 @@ func TestGetContactNotes(t *testing.T) {
 	}
 	json.Unmarshal(w.Body.Bytes(), &responseBody)
 
-	// Assert that the notes returned belong to the contact
+	// Assert that the notes returned belong to the contact, newest first
 	assert.Len(t, responseBody.Notes, 2)
-	assert.Equal(t, note1.Content, responseBody.Notes[0].Content)
-	assert.Equal(t, note2.Content, responseBody.Notes[1].Content)
+	assert.Equal(t, note2.Content, responseBody.Notes[0].Content)
+	assert.Equal(t, note1.Content, responseBody.Notes[1].Content)
+}
+
+func TestGetContactNotesPaginated(t *testing.T) {
+	db, router := setupRouter()
+
+	router.GET("/contacts/:id/notes", func(c *gin.Context) {
+		GetNotesForContact(c, &config.Config{DefaultPageLimit: 3, MaxPageLimit: 5})
+	})
+
+	contact := models.Contact{Firstname: "John"}
+	db.Create(&contact)
+	for day := 1; day <= 10; day++ {
+		db.Create(&models.Note{Content: "Day " + strconv.Itoa(day), Date: time.Date(2024, time.May, day, 12, 0, 0, 0, time.UTC), ContactID: &contact.ID})
+	}
+
+	type notesResponse struct {
+		Notes []models.Note `json:"notes"`
+		Total int64         `json:"total"`
+		Page  int           `json:"page"`
+		Limit int           `json:"limit"`
+	}
+	request := func(query string) (int, notesResponse) {
+		req, _ := http.NewRequest("GET", "/contacts/"+strconv.Itoa(int(contact.ID))+"/notes"+query, nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		var responseBody notesResponse
+		json.Unmarshal(w.Body.Bytes(), &responseBody)
+		return w.Code, responseBody
+	}
+
+	// The page size follows the configured default and maximum
+	code, responseBody := request("")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, 3, responseBody.Limit)
+	assert.Len(t, responseBody.Notes, 3)
+
+	req, _ := http.NewRequest("GET", "/contacts/"+strconv.Itoa(int(contact.ID))+"/notes?limit=50", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, "50", w.Header().Get(LimitClampedHeader))
+	assert.Contains(t, w.Body.String(), `"limit":5`)
+
+	code, responseBody = request("?limit=4&page=2")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, int64(10), responseBody.Total)
+	assert.Equal(t, 2, responseBody.Page)
+	assert.Equal(t, 4, responseBody.Limit)
+	assert.Len(t, responseBody.Notes, 4)
+	assert.Equal(t, "Day 6", responseBody.Notes[0].Content)
+
+	// Both ends of the date range are inclusive
+	code, responseBody = request("?from=2024-05-03&to=2024-05-05&order=asc")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, int64(3), responseBody.Total)
+	assert.Equal(t, "Day 3", responseBody.Notes[0].Content)
+	assert.Equal(t, "Day 5", responseBody.Notes[2].Content)
+
+	code, _ = request("?from=05/03/2024")
+	assert.Equal(t, http.StatusBadRequest, code)
+
+	code, _ = request("?order=random")
+	assert.Equal(t, http.StatusBadRequest, code)
+
+	req, _ = http.NewRequest("GET", "/contacts/999/notes", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNotFound, w.Code)
 }
 
 func TestCreateContactNote(t *testing.T) {
 	db, router := setupRouter()
 
-	router.POST("/contacts/:id/notes", CreateNote)
+	router.POST("/contacts/:id/notes", func(c *gin.Context) {
+		CreateNote(c, &config.Config{})
+	})
 
 	// Create a contact
 	contact := models.Contact{
@@ -74,7 +148,7 @@ func TestCreateContactNote(t *testing.T) {
 	w := httptest.NewRecorder()
 	router.ServeHTTP(w, req)
 
-	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, http.StatusCreated, w.Code)
 
 	var responseBody map[string]interface{}
 	json.Unmarshal(w.Body.Bytes(), &responseBody)
@@ -104,6 +178,42 @@ func TestGetNote(t *testing.T) {
 	assert.Equal(t, note.Content, responseBody.Content)
 }
 
+func TestGetNoteHTML(t *testing.T) {
+	db, router := setupRouter()
+
+	router.GET("/notes/:id/html", func(c *gin.Context) {
+		GetNoteHTML(c, &config.Config{HTMLSanitization: "safe"})
+	})
+
+	note := models.Note{Content: "Ideas:\n\n- [Concert](https://example.com)\n- <a href=\"javascript:alert(1)\" onclick=\"alert(2)\">Trip</a>"}
+	foreign := models.Note{Content: "Secret", UserID: 2}
+	db.Create(&note)
+	db.Create(&foreign)
+
+	req, _ := http.NewRequest("GET", "/notes/"+strconv.Itoa(int(note.ID))+"/html", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var responseBody struct {
+		HTML string `json:"html"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &responseBody)
+	assert.Contains(t, responseBody.HTML, `<a href="https://example.com" rel="nofollow">Concert</a>`)
+	assert.Contains(t, responseBody.HTML, "Trip")
+	assert.NotContains(t, responseBody.HTML, "alert")
+
+	// The Markdown stays the stored content
+	var stored models.Note
+	db.First(&stored, note.ID)
+	assert.Equal(t, note.Content, stored.Content)
+
+	req, _ = http.NewRequest("GET", "/notes/"+strconv.Itoa(int(foreign.ID))+"/html", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
 func TestGetNotes(t *testing.T) {
 	db, router := setupRouter()
 
@@ -135,7 +245,9 @@ func TestGetNotes(t *testing.T) {
 func TestCreateNote(t *testing.T) {
 	_, router := setupRouter()
 
-	router.POST("/notes", CreateUnassignedNote)
+	router.POST("/notes", func(c *gin.Context) {
+		CreateUnassignedNote(c, &config.Config{})
+	})
 
 	// Create a note
 	newNote := models.Note{
@@ -150,7 +262,7 @@ func TestCreateNote(t *testing.T) {
 	w := httptest.NewRecorder()
 	router.ServeHTTP(w, req)
 
-	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, http.StatusCreated, w.Code)
 
 	var responseBody map[string]any
 	json.Unmarshal(w.Body.Bytes(), &responseBody)
@@ -160,7 +272,9 @@ func TestCreateNote(t *testing.T) {
 func TestUpdateNote(t *testing.T) {
 	db, router := setupRouter()
 
-	router.PUT("/notes/:id", UpdateNote)
+	router.PUT("/notes/:id", func(c *gin.Context) {
+		UpdateNote(c, &config.Config{})
+	})
 	router.GET("/notes/:id", GetNote)
 
 	// Create a note
@@ -219,3 +333,211 @@ func TestDeleteNote(t *testing.T) {
 	json.Unmarshal(w.Body.Bytes(), &responseBody)
 	assert.Equal(t, "Note deleted", responseBody["message"])
 }
+
+func TestNoteContentSanitized(t *testing.T) {
+	db, router := setupRouter()
+
+	cfg := &config.Config{HTMLSanitization: "safe"}
+	router.POST("/notes", func(c *gin.Context) {
+		CreateUnassignedNote(c, cfg)
+	})
+	router.PUT("/notes/:id", func(c *gin.Context) {
+		UpdateNote(c, cfg)
+	})
+	router.GET("/notes/:id/html", func(c *gin.Context) {
+		GetNoteHTML(c, cfg)
+	})
+
+	save := func(method, path, content string) models.Note {
+		jsonValue, _ := json.Marshal(models.Note{Content: content})
+		req, _ := http.NewRequest(method, path, bytes.NewBuffer(jsonValue))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Less(t, w.Code, 300, w.Body.String())
+
+		var note models.Note
+		db.Order("updated_at DESC").First(&note)
+		return note
+	}
+	render := func(note models.Note) string {
+		req, _ := http.NewRequest("GET", "/notes/"+strconv.Itoa(int(note.ID))+"/html", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		var responseBody struct {
+			HTML string `json:"html"`
+		}
+		json.Unmarshal(w.Body.Bytes(), &responseBody)
+		return responseBody.HTML
+	}
+
+	// Markdown without HTML is stored unchanged, including characters with a meaning in HTML
+	source := "> Tom & Jerry\n\nSee `a < b` and **this**"
+	note := save("POST", "/notes", source)
+	assert.Equal(t, source, note.Content)
+	rendered := render(note)
+	assert.Contains(t, rendered, "<blockquote>\n<p>Tom &amp; Jerry</p>\n</blockquote>")
+	assert.Contains(t, rendered, "<code>a &lt; b</code>")
+	assert.Contains(t, rendered, "<strong>this</strong>")
+
+	// Safe formatting is kept, scripts and event handlers are stored stripped like in activities
+	note = save("POST", "/notes", `Met at <b onclick="alert(1)">the café</b><script>alert("xss")</script>`)
+	assert.Equal(t, "Met at <b>the café</b>", note.Content)
+	assert.Contains(t, render(note), "<b>the café</b>")
+
+	// Strict mode removes all markup on update, also markup escaped as entities
+	cfg.HTMLSanitization = "strict"
+	note = save("PUT", "/notes/"+strconv.Itoa(int(note.ID)), `<i>Call</i> <img src=x onerror="alert(1)">back`)
+	assert.Equal(t, "Call back", note.Content)
+	note = save("PUT", "/notes/"+strconv.Itoa(int(note.ID)), "Call back &lt;img src=x onerror=alert(1)&gt;")
+	assert.Equal(t, "Call back ", note.Content)
+	assert.NotContains(t, render(note), "<img")
+}
+
+func TestNoteTags(t *testing.T) {
+	db, router := setupRouter()
+
+	router.POST("/contacts/:id/notes", func(c *gin.Context) {
+		CreateNote(c, &config.Config{})
+	})
+	router.GET("/notes", GetNotes)
+	router.GET("/tags", GetTags)
+
+	jane := models.Contact{Firstname: "Jane", Lastname: "Doe"}
+	john := models.Contact{Firstname: "John"}
+	db.Create(&jane)
+	db.Create(&john)
+
+	// Tags are trimmed and deduplicated on save
+	jsonValue, _ := json.Marshal(models.Note{Content: "Likes pottery", Date: time.Now(), Tags: []string{" gift-idea", "hobby", "gift-idea", ""}})
+	req, _ := http.NewRequest("POST", "/contacts/"+strconv.Itoa(int(jane.ID))+"/notes", bytes.NewBuffer(jsonValue))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	var created models.Note
+	db.First(&created)
+	assert.Equal(t, []string{"gift-idea", "hobby"}, created.Tags)
+
+	db.Create(&models.Note{Content: "Wants a new bike", Date: time.Now().Add(time.Hour), Tags: []string{"gift-idea"}, ContactID: &john.ID})
+	db.Create(&models.Note{Content: "Back pain", Date: time.Now(), Tags: []string{"health"}, ContactID: &john.ID})
+	db.Create(&models.Note{Content: "Unassigned idea", Date: time.Now().Add(-time.Hour), Tags: []string{"gift-idea"}})
+
+	req, _ = http.NewRequest("GET", "/notes?tag=gift-idea", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var tagged []taggedNote
+	json.Unmarshal(w.Body.Bytes(), &tagged)
+	assert.Len(t, tagged, 3)
+	assert.Equal(t, "Wants a new bike", tagged[0].Content)
+	assert.Equal(t, "John", tagged[0].ContactName)
+	assert.Equal(t, "Jane Doe", tagged[1].ContactName)
+	assert.Equal(t, "", tagged[2].ContactName)
+
+	req, _ = http.NewRequest("GET", "/tags", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	var tags []string
+	json.Unmarshal(w.Body.Bytes(), &tags)
+	assert.Equal(t, []string{"gift-idea", "health", "hobby"}, tags)
+}
+
+func TestReplaceInNotes(t *testing.T) {
+	db, router := setupRouter()
+	router.POST("/notes/replace", func(c *gin.Context) {
+		ReplaceInNotes(c, &config.Config{HTMLSanitization: "safe"})
+	})
+
+	contact := models.Contact{Firstname: "Alice"}
+	db.Create(&contact)
+	notes := []models.Note{
+		{Content: "Call 0170 111 or 0170 111 after five", Date: time.Now(), ContactID: &contact.ID},
+		{Content: "Unrelated", Date: time.Now()},
+		{Content: "Number was 0170 111", Date: time.Now()},
+		{Content: "Other user has 0170 111 too", Date: time.Now(), UserID: 2},
+	}
+	for i := range notes {
+		db.Create(&notes[i])
+	}
+
+	replace := func(query, body string) (int, map[string]any) {
+		req, _ := http.NewRequest("POST", "/notes/replace"+query, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		var responseBody map[string]any
+		json.Unmarshal(w.Body.Bytes(), &responseBody)
+		return w.Code, responseBody
+	}
+	contentOf := func(note models.Note) string {
+		var stored models.Note
+		db.First(&stored, note.ID)
+		return stored.Content
+	}
+
+	// The dry run is the default and only reports the affected notes
+	code, result := replace("", `{"search": "0170 111", "replace": "0170 222"}`)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, true, result["dry_run"])
+	assert.Equal(t, float64(2), result["notes_changed"])
+	assert.Equal(t, float64(3), result["replacements"])
+	assert.Equal(t, "Call 0170 111 or 0170 111 after five", contentOf(notes[0]))
+
+	code, result = replace("?dry_run=false", `{"search": "0170 111", "replace": "0170 222"}`)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, false, result["dry_run"])
+	assert.Equal(t, float64(2), result["notes_changed"])
+	assert.Equal(t, "Call 0170 222 or 0170 222 after five", contentOf(notes[0]))
+	assert.Equal(t, "Number was 0170 222", contentOf(notes[2]))
+	assert.Equal(t, "Other user has 0170 111 too", contentOf(notes[3]))
+
+	// The search is case-sensitive and replacements are sanitized like edited notes
+	_, result = replace("?dry_run=false", `{"search": "number", "replace": "x"}`)
+	assert.Equal(t, float64(0), result["notes_changed"])
+	replace("?dry_run=false", `{"search": "Unrelated", "replace": "<script>alert(1)</script>Related"}`)
+	assert.Equal(t, "Related", contentOf(notes[1]))
+
+	code, _ = replace("", `{"replace": "x"}`)
+	assert.Equal(t, http.StatusBadRequest, code)
+}
+
+func TestNoteRequiresContent(t *testing.T) {
+	db, router := setupRouter()
+	cfg := &config.Config{HTMLSanitization: "safe"}
+	router.POST("/contacts/:id/notes", func(c *gin.Context) { CreateNote(c, cfg) })
+	router.POST("/notes", func(c *gin.Context) { CreateUnassignedNote(c, cfg) })
+	router.PUT("/notes/:id", func(c *gin.Context) { UpdateNote(c, cfg) })
+
+	contact := models.Contact{Firstname: "Alice"}
+	db.Create(&contact)
+	note := models.Note{Content: "Likes tea", Date: time.Now(), ContactID: &contact.ID}
+	db.Create(&note)
+
+	send := func(method, url, content string) int {
+		jsonValue, _ := json.Marshal(map[string]any{"content": content, "date": time.Now()})
+		req, _ := http.NewRequest(method, url, bytes.NewBuffer(jsonValue))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	// Blank notes and notes only consisting of markup are rejected
+	for _, content := range []string{"", "   ", "<p> </p>", "<script>alert(1)</script>"} {
+		assert.Equal(t, http.StatusBadRequest, send("POST", "/contacts/"+strconv.Itoa(int(contact.ID))+"/notes", content), content)
+		assert.Equal(t, http.StatusBadRequest, send("POST", "/notes", content), content)
+		assert.Equal(t, http.StatusBadRequest, send("PUT", "/notes/"+strconv.Itoa(int(note.ID)), content), content)
+	}
+	var count int64
+	db.Model(&models.Note{}).Count(&count)
+	assert.Equal(t, int64(1), count)
+
+	assert.Equal(t, http.StatusOK, send("PUT", "/notes/"+strconv.Itoa(int(note.ID)), "<p>Likes coffee</p>"))
+}
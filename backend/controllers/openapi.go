@@ -0,0 +1,85 @@
+package controllers
+
+import (
+	"net/http"
+	"perema/docs"
+	"perema/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// errorResponse is the body of all error responses
+type errorResponse struct {
+	Error string `json:"error" example:"Contact not found"`
+}
+
+// validationErrorResponse reports the invalid fields of a request by their JSON name
+type validationErrorResponse struct {
+	Error  string                `json:"error" example:"Validation failed"`
+	Fields map[string]fieldError `json:"fields"`
+}
+
+// fileUpload is the multipart form of the file imports
+type fileUpload struct {
+	File string `json:"file" format:"binary"`
+}
+
+// pagination is the metadata added to paginated lists by paginatedResponse
+type pagination struct {
+	Total      int64 `json:"total"`
+	Page       int   `json:"page"`
+	Limit      int   `json:"limit"`
+	TotalPages int   `json:"total_pages"`
+	HasNext    bool  `json:"has_next"`
+	HasPrev    bool  `json:"has_prev"`
+}
+
+// The paginated lists of contacts and reminders as documented in the spec
+
+type contactPage struct {
+	Contacts []models.Contact `json:"contacts"`
+	pagination
+}
+
+type agendaReminderPage struct {
+	Reminders []agendaReminder `json:"reminders"`
+	pagination
+}
+
+type upcomingReminderPage struct {
+	Contacts []upcomingReminderGroup `json:"contacts"`
+	pagination
+}
+
+type overdueReminderPage struct {
+	Reminders []models.Reminder `json:"reminders"`
+	GraceDays int               `json:"grace_days"`
+	pagination
+}
+
+// swaggerUI loads Swagger UI from the CDN, which is also used by the frontend, and points it to the spec
+const swaggerUI = `<!DOCTYPE html>
+<html lang="en">
+<head>
+  <meta charset="utf-8">
+  <title>perema API</title>
+  <link rel="stylesheet" href="https://cdn.jsdelivr.net/npm/swagger-ui-dist@5/swagger-ui.css">
+</head>
+<body>
+  <div id="swagger-ui"></div>
+  <script src="https://cdn.jsdelivr.net/npm/swagger-ui-dist@5/swagger-ui-bundle.js"></script>
+  <script>
+    window.ui = SwaggerUIBundle({url: "api/openapi.json", dom_id: "#swagger-ui"});
+  </script>
+</body>
+</html>`
+
+// GetOpenAPISpec serves the OpenAPI spec generated from the annotations of the handlers
+func GetOpenAPISpec(c *gin.Context) {
+	c.Data(http.StatusOK, "application/json; charset=utf-8", []byte(docs.SwaggerInfo.ReadDoc()))
+}
+
+// GetSwaggerUI serves an interactive documentation of the OpenAPI spec
+func GetSwaggerUI(c *gin.Context) {
+	c.Data(http.StatusOK, "text/html; charset=utf-8", []byte(swaggerUI))
+}
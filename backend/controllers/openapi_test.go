@@ -0,0 +1,49 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetOpenAPISpec(t *testing.T) {
+	router := gin.New()
+	router.GET("/openapi.json", GetOpenAPISpec)
+
+	req, _ := http.NewRequest("GET", "/openapi.json", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Header().Get("Content-Type"), "application/json")
+
+	var spec struct {
+		OpenAPI string                    `json:"openapi"`
+		Paths   map[string]map[string]any `json:"paths"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &spec))
+	assert.Equal(t, "3.1.0", spec.OpenAPI)
+
+	// The annotated contact and reminder handlers are documented
+	assert.Contains(t, spec.Paths["/contacts"], "get")
+	assert.Contains(t, spec.Paths["/contacts"], "post")
+	assert.Contains(t, spec.Paths["/contacts/{id}"], "patch")
+	assert.Contains(t, spec.Paths["/reminders/{id}/snooze"], "post")
+}
+
+func TestGetSwaggerUI(t *testing.T) {
+	router := gin.New()
+	router.GET("/swagger", GetSwaggerUI)
+
+	req, _ := http.NewRequest("GET", "/swagger", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Header().Get("Content-Type"), "text/html")
+	assert.Contains(t, w.Body.String(), `url: "api/openapi.json"`)
+}
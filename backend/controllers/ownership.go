@@ -0,0 +1,34 @@
+package controllers
+
+import (
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// currentUserID returns the ID of the user authenticated by the auth middleware
+func currentUserID(c *gin.Context) uint {
+	return c.GetUint("user_id")
+}
+
+// ownedBy restricts a query to the rows of the given table which belong to the authenticated user.
+// Rows of other users are treated as if they did not exist.
+func ownedBy(c *gin.Context, table string) func(*gorm.DB) *gorm.DB {
+	userID := currentUserID(c)
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where(table+".user_id = ?", userID)
+	}
+}
+
+// ownContacts restricts a query on contacts to the contacts of the authenticated user
+func ownContacts(c *gin.Context) func(*gorm.DB) *gorm.DB {
+	return ownedBy(c, "contacts")
+}
+
+// ownedThroughContact restricts a query to the rows whose contact, referenced by the given column,
+// belongs to the authenticated user. Deleted contacts are included so their rows can still be restored.
+func ownedThroughContact(c *gin.Context, column string) func(*gorm.DB) *gorm.DB {
+	userID := currentUserID(c)
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where(column+" IN (SELECT id FROM contacts WHERE user_id = ?)", userID)
+	}
+}
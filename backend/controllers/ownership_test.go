@@ -0,0 +1,79 @@
+package controllers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"perema/config"
+	"perema/models"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOwnershipScoping(t *testing.T) {
+	db, router := setupRouter()
+	cfg := &config.Config{DefaultPageLimit: 25, MaxPageLimit: 100, Timezone: time.UTC}
+
+	// Requests are made by user 1, as the auth middleware would set it
+	router.Use(func(c *gin.Context) {
+		c.Set("user_id", uint(1))
+		c.Next()
+	})
+	router.GET("/contacts", func(c *gin.Context) { GetContacts(c, cfg) })
+	router.POST("/contacts", CreateContact)
+	router.GET("/contacts/:id", func(c *gin.Context) { GetContact(c, cfg) })
+	router.DELETE("/contacts/:id", DeleteContact)
+	router.POST("/contacts/:id/notes", func(c *gin.Context) { CreateNote(c, cfg) })
+	router.GET("/notes/:id", GetNote)
+	router.GET("/reminders/:id", GetReminder)
+
+	own := models.Contact{Firstname: "Mine", UserID: 1}
+	other := models.Contact{Firstname: "Theirs", UserID: 2}
+	db.Create(&own)
+	db.Create(&other)
+	otherNote := models.Note{Content: "Private", ContactID: &other.ID, UserID: 2}
+	db.Create(&otherNote)
+	otherReminder := models.Reminder{Message: "Call", RemindAt: time.Now(), ContactID: &other.ID}
+	db.Create(&otherReminder)
+
+	send := func(method, url, body string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(method, url, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	// Only own contacts are listed
+	w := send("GET", "/contacts", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	var list struct {
+		Contacts []models.Contact `json:"contacts"`
+		Total    int64            `json:"total"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &list)
+	assert.Equal(t, int64(1), list.Total)
+
+	// Data of other users looks as if it did not exist
+	otherID := strconv.Itoa(int(other.ID))
+	assert.Equal(t, http.StatusNotFound, send("GET", "/contacts/"+otherID, "").Code)
+	assert.Equal(t, http.StatusNotFound, send("DELETE", "/contacts/"+otherID, "").Code)
+	assert.Equal(t, http.StatusNotFound, send("POST", "/contacts/"+otherID+"/notes", `{"content": "Hi", "date": "2024-01-01T00:00:00Z"}`).Code)
+	assert.Equal(t, http.StatusNotFound, send("GET", "/notes/"+strconv.Itoa(int(otherNote.ID)), "").Code)
+	assert.Equal(t, http.StatusNotFound, send("GET", "/reminders/"+strconv.Itoa(int(otherReminder.ID)), "").Code)
+
+	var stillThere models.Contact
+	assert.NoError(t, db.First(&stillThere, other.ID).Error)
+
+	// Created contacts belong to the authenticated user
+	w = send("POST", "/contacts", `{"firstname": "New"}`)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	var created models.Contact
+	db.Where("firstname = ?", "New").First(&created)
+	assert.Equal(t, uint(1), created.UserID)
+}
@@ -0,0 +1,53 @@
+package controllers
+
+import (
+	"perema/config"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// paginatedResponse returns a page of items under the given key together with the pagination metadata,
+// so clients do not have to compute the number of pages themselves
+func paginatedResponse(key string, items any, total int64, page, limit int) gin.H {
+	totalPages := int((total + int64(limit) - 1) / int64(limit))
+	return gin.H{
+		key:           items,
+		"total":       total,
+		"page":        page,
+		"limit":       limit,
+		"total_pages": totalPages,
+		"has_next":    page < totalPages,
+		"has_prev":    page > 1,
+	}
+}
+
+// LimitClampedHeader is set to the requested limit when it exceeded the maximum and the maximum was used instead
+const LimitClampedHeader = "X-Pagination-Limit-Clamped"
+
+// paginationParams reads the page and limit query parameters of the paginated lists. A missing or invalid limit
+// falls back to the configured default, a limit above the maximum is clamped and reported in LimitClampedHeader.
+func paginationParams(c *gin.Context, cfg *config.Config) (page, limit, offset int) {
+	page, _ = strconv.Atoi(c.DefaultQuery("page", "1"))
+	if page < 1 {
+		page = 1
+	}
+	requested, err := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(cfg.DefaultPageLimit)))
+	if err != nil {
+		requested = 0
+	}
+	limit = pageLimit(requested, cfg)
+	if requested > cfg.MaxPageLimit {
+		c.Header(LimitClampedHeader, strconv.Itoa(requested))
+	}
+	return page, limit, (page - 1) * limit
+}
+
+// pageLimit applies the configured default and maximum to a requested number of items, 0 or less means no limit
+// was requested
+func pageLimit(requested int, cfg *config.Config) int {
+	if requested < 1 {
+		return cfg.DefaultPageLimit
+	}
+	return min(requested, cfg.MaxPageLimit)
+}
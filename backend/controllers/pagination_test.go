@@ -0,0 +1,65 @@
+package controllers
+
+import (
+	"net/http/httptest"
+	"perema/config"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPaginatedResponse(t *testing.T) {
+	cases := []struct {
+		total      int64
+		page       int
+		totalPages int
+		hasNext    bool
+		hasPrev    bool
+	}{
+		{0, 1, 0, false, false},
+		{25, 1, 1, false, false},
+		{26, 1, 2, true, false},
+		{26, 2, 2, false, true},
+		{75, 2, 3, true, true},
+		{10, 5, 1, false, true}, // Pages past the end have no next page
+	}
+
+	for _, tc := range cases {
+		response := paginatedResponse("items", []string{}, tc.total, tc.page, 25)
+		assert.Equal(t, tc.total, response["total"])
+		assert.Equal(t, tc.page, response["page"])
+		assert.Equal(t, 25, response["limit"])
+		assert.Equal(t, tc.totalPages, response["total_pages"], "total %d", tc.total)
+		assert.Equal(t, tc.hasNext, response["has_next"], "total %d, page %d", tc.total, tc.page)
+		assert.Equal(t, tc.hasPrev, response["has_prev"], "total %d, page %d", tc.total, tc.page)
+	}
+}
+
+func TestPaginationParams(t *testing.T) {
+	cfg := &config.Config{DefaultPageLimit: 10, MaxPageLimit: 50}
+	cases := []struct {
+		query   string
+		page    int
+		limit   int
+		offset  int
+		clamped string
+	}{
+		{"", 1, 10, 0, ""},
+		{"page=3&limit=20", 3, 20, 40, ""},
+		{"page=0&limit=0", 1, 10, 0, ""},
+		{"limit=many", 1, 10, 0, ""},
+		{"page=2&limit=500", 2, 50, 50, "500"}, // Too large limits are clamped instead of rejected
+	}
+
+	for _, tc := range cases {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest("GET", "/contacts?"+tc.query, nil)
+		page, limit, offset := paginationParams(c, cfg)
+		assert.Equal(t, tc.page, page, tc.query)
+		assert.Equal(t, tc.limit, limit, tc.query)
+		assert.Equal(t, tc.offset, offset, tc.query)
+		assert.Equal(t, tc.clamped, w.Header().Get(LimitClampedHeader), tc.query)
+	}
+}
@@ -3,24 +3,70 @@ package controllers
 import (
 	"errors"
 	"image"
+	"image/draw"
 	"image/jpeg"
 	"image/png"
-	"log"
+	"io"
 	"mime/multipart"
 	"net/http"
 	"os"
 	"path/filepath"
 	"strconv"
 
+	"perema/config"
+	"perema/middleware"
 	"perema/models"
 
+	"github.com/gabriel-vasile/mimetype"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	"github.com/nfnt/resize"
+	"golang.org/x/image/webp"
 	"gorm.io/gorm"
 )
 
+// Supported profile photo formats by their detected content type
+var photoFormats = map[string]struct {
+	decode       func(io.Reader) (image.Image, error)
+	decodeConfig func(io.Reader) (image.Config, error)
+}{
+	"image/jpeg": {jpeg.Decode, jpeg.DecodeConfig},
+	"image/png":  {png.Decode, png.DecodeConfig},
+	"image/webp": {webp.Decode, webp.DecodeConfig},
+}
+
+// Width and height of the square profile photo thumbnails
+const thumbnailSize = 128
+
+// Maximum number of pixels of a profile photo, a small file may still decode to a huge image
+const maxPhotoPixels = 50_000_000
+
+var errUnsupportedPhoto = errors.New("unsupported file format")
+
+// GetProfilePicture serves the profile photo of a contact in its original size or a placeholder if it has none
 func GetProfilePicture(c *gin.Context) {
+	serveContactPhoto(c, func(contact models.Contact) string { return contact.Photo }, true)
+}
+
+// GetContactPhoto serves the profile photo of a contact in its original size, 404 if it has none
+func GetContactPhoto(c *gin.Context) {
+	serveContactPhoto(c, func(contact models.Contact) string { return contact.Photo }, false)
+}
+
+// GetProfilePictureThumbnail serves the square thumbnail of the profile photo, which is much smaller for lists.
+// Contacts whose photo was uploaded before thumbnails were cropped fall back to their thumbnail or photo.
+func GetProfilePictureThumbnail(c *gin.Context) {
+	serveContactPhoto(c, func(contact models.Contact) string {
+		if contact.PhotoThumbnail != "" {
+			return contact.PhotoThumbnail
+		}
+		return contact.Photo
+	}, false)
+}
+
+// serveContactPhoto serves the image file selected from a contact of the user. Photos are only served through
+// this handler, never from a public directory. Contacts without a photo get a placeholder if requested, else 404.
+func serveContactPhoto(c *gin.Context, file func(models.Contact) string, placeholder bool) {
 	idParam := c.Param("id")
 	contactID, err := strconv.Atoi(idParam)
 	if err != nil {
@@ -31,7 +77,7 @@ func GetProfilePicture(c *gin.Context) {
 	db := c.MustGet("db").(*gorm.DB)
 
 	// Find the contact in the database
-	if err := db.First(&contact, contactID).Error; err != nil {
+	if err := db.Scopes(ownContacts(c)).First(&contact, contactID).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
 			c.JSON(http.StatusNotFound, gin.H{"error": "Contact not found"})
 			return
@@ -42,16 +88,19 @@ func GetProfilePicture(c *gin.Context) {
 
 	uploadDir := os.Getenv("PROFILE_PHOTO_DIR")
 
-	if contact.Photo == "" {
+	filename := file(contact)
+	if filename == "" {
+		if !placeholder {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Contact has no photo"})
+			return
+		}
 		filePath := "./static/placeholder-avatar.png"
 		c.File(filePath)
 		return
 	}
 
 	// Construct the full path to the image
-	filePath := filepath.Join(uploadDir, contact.Photo)
-
-	log.Println(filePath)
+	filePath := filepath.Join(uploadDir, filepath.Base(filename))
 
 	// Check if the file exists
 	if _, err := os.Stat(filePath); os.IsNotExist(err) {
@@ -59,11 +108,18 @@ func GetProfilePicture(c *gin.Context) {
 		return
 	}
 
-	// Serve the image
+	// Every upload gets a new file name, so it identifies the image. Browsers revalidate it because
+	// the photo of the contact may change, and shared caches must not keep private photos.
+	c.Header("Cache-Control", "private, no-cache")
+	c.Header("ETag", `"`+filename+`"`)
+	c.Header("X-Content-Type-Options", "nosniff")
+
+	// Serve the image, the content type follows from the file extension
 	c.File(filePath)
 }
 
-func AddPhotoToContact(c *gin.Context) {
+// AddPhotoToContact stores an uploaded jpeg, png or webp image as profile photo of the contact together with a thumbnail
+func AddPhotoToContact(c *gin.Context, cfg *config.Config) {
 	idParam := c.Param("id")
 	contactID, err := strconv.Atoi(idParam)
 	if err != nil {
@@ -74,7 +130,7 @@ func AddPhotoToContact(c *gin.Context) {
 	db := c.MustGet("db").(*gorm.DB)
 
 	// Find the contact in the database
-	if err := db.First(&contact, contactID).Error; err != nil {
+	if err := db.Scopes(ownContacts(c)).First(&contact, contactID).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
 			c.JSON(http.StatusNotFound, gin.H{"error": "Contact not found"})
 			return
@@ -83,8 +139,17 @@ func AddPhotoToContact(c *gin.Context) {
 		return
 	}
 
+	// Stop reading oversized uploads early instead of buffering them to disk, leaving room for the form fields
+	maxSize := int64(cfg.MaxPhotoSizeMB) << 20
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxSize+1<<20)
+
 	// Check if there's an uploaded file
 	file, err := c.FormFile("photo")
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) || (err == nil && file.Size > maxSize) {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "File is too large"})
+		return
+	}
 	if err == nil {
 		// Handle the file upload
 		uploadDir := os.Getenv("PROFILE_PHOTO_DIR")
@@ -93,6 +158,10 @@ func AddPhotoToContact(c *gin.Context) {
 			return
 		}
 		photoPath, thumbnailPath, err := processAndSavePhoto(file, uploadDir)
+		if errors.Is(err, errUnsupportedPhoto) {
+			c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": "Unsupported file format, only jpeg, png and webp are allowed"})
+			return
+		}
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to process photo"})
 			return
@@ -110,6 +179,43 @@ func AddPhotoToContact(c *gin.Context) {
 	c.JSON(http.StatusOK, contact)
 }
 
+// DeleteProfilePicture removes the profile photo and thumbnail of a contact. Files which are already
+// missing are ignored, so the photo can always be cleared.
+func DeleteProfilePicture(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+
+	var contact models.Contact
+	if err := db.Scopes(ownContacts(c)).First(&contact, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Contact not found"})
+		return
+	}
+
+	files := []string{contact.Photo, contact.PhotoThumbnail}
+	if err := db.Model(&contact).Select("Photo", "PhotoThumbnail").Updates(models.Contact{}).Error; err != nil {
+		middleware.Logger(c).Error("Failed to remove profile photo", "contact_id", contact.ID, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update contact"})
+		return
+	}
+
+	uploadDir := os.Getenv("PROFILE_PHOTO_DIR")
+	for _, file := range files {
+		if file == "" {
+			continue
+		}
+		// A merged contact in the trash may still use the same file
+		var users int64
+		db.Unscoped().Model(&models.Contact{}).Where("photo = ? OR photo_thumbnail = ?", file, file).Count(&users)
+		if users > 0 {
+			continue
+		}
+		if err := os.Remove(filepath.Join(uploadDir, file)); err != nil && !os.IsNotExist(err) {
+			middleware.Logger(c).Error("Failed to remove profile photo file", "contact_id", contact.ID, "file", file, "error", err)
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Profile photo deleted"})
+}
+
 func processAndSavePhoto(file *multipart.FileHeader, uploadDir string) (string, string, error) {
 	// Open the uploaded file
 	src, err := file.Open()
@@ -118,35 +224,34 @@ func processAndSavePhoto(file *multipart.FileHeader, uploadDir string) (string,
 	}
 	defer src.Close()
 
-	// Detect the image format
-	buf := make([]byte, 512)
-	_, err = src.Read(buf)
+	// Detect the image format from the content instead of trusting the file extension
+	detected, err := mimetype.DetectReader(src)
 	if err != nil {
 		return "", "", err
 	}
-	contentType := http.DetectContentType(buf)
-
-	// Validate supported content types
-	if contentType != "image/jpeg" && contentType != "image/png" {
-		return "", "", errors.New("unsupported file format")
+	format, supported := photoFormats[detected.String()]
+	if !supported {
+		return "", "", errUnsupportedPhoto
 	}
 
-	// Rewind the file reader
-	src.Seek(0, 0)
+	// Check the dimensions before decoding the whole image
+	if _, err := src.Seek(0, io.SeekStart); err != nil {
+		return "", "", err
+	}
+	imageConfig, err := format.decodeConfig(src)
+	if err != nil || imageConfig.Width*imageConfig.Height > maxPhotoPixels {
+		return "", "", errUnsupportedPhoto
+	}
 
-	// Decode the image (handle both JPEG and PNG)
-	var img image.Image
-	switch contentType {
-	case "image/jpeg":
-		img, err = jpeg.Decode(src)
-	case "image/png":
-		img, err = png.Decode(src)
+	if _, err := src.Seek(0, io.SeekStart); err != nil {
+		return "", "", err
 	}
+	img, err := format.decode(src)
 	if err != nil {
-		return "", "", err
+		return "", "", errUnsupportedPhoto
 	}
 
-	// Generate unique filenames
+	// Generate unique filenames, the name sent by the client is never used for storing files
 	baseFilename := uuid.New().String()
 	photoPath := baseFilename + "_photo.jpg" // Always save as JPG
 	thumbnailPath := baseFilename + "_thumbnail.jpg"
@@ -163,7 +268,7 @@ func processAndSavePhoto(file *multipart.FileHeader, uploadDir string) (string,
 	}
 
 	// Create and save the thumbnail
-	thumbnail := resize.Resize(100, 100, img, resize.Lanczos3)
+	thumbnail := resize.Resize(thumbnailSize, thumbnailSize, cropToSquare(img), resize.Lanczos3)
 	fullThumbnailPath := filepath.Join(uploadDir, thumbnailPath)
 	if err := saveImage(fullThumbnailPath, thumbnail); err != nil {
 		return "", "", err
@@ -172,6 +277,17 @@ func processAndSavePhoto(file *multipart.FileHeader, uploadDir string) (string,
 	return photoPath, thumbnailPath, nil
 }
 
+// cropToSquare cuts the largest centered square out of the image, so thumbnails of portraits are not distorted
+func cropToSquare(img image.Image) image.Image {
+	bounds := img.Bounds()
+	size := min(bounds.Dx(), bounds.Dy())
+	offset := image.Pt(bounds.Min.X+(bounds.Dx()-size)/2, bounds.Min.Y+(bounds.Dy()-size)/2)
+
+	square := image.NewRGBA(image.Rect(0, 0, size, size))
+	draw.Draw(square, square.Bounds(), img, offset, draw.Src)
+	return square
+}
+
 func saveImage(path string, img image.Image) error {
 	out, err := os.Create(path)
 	if err != nil {
@@ -0,0 +1,169 @@
+package controllers
+
+import (
+	"bytes"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"perema/config"
+	"perema/models"
+	"strconv"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAddPhotoToContact(t *testing.T) {
+	db, router := setupRouter()
+	cfg := &config.Config{MaxPhotoSizeMB: 1}
+	router.POST("/contacts/:id/profile_picture", func(c *gin.Context) { AddPhotoToContact(c, cfg) })
+	uploadDir := t.TempDir()
+	t.Setenv("PROFILE_PHOTO_DIR", uploadDir)
+
+	contact := models.Contact{Firstname: "Alice"}
+	db.Create(&contact)
+	url := "/contacts/" + strconv.Itoa(int(contact.ID)) + "/profile_picture"
+
+	upload := func(filename string, content []byte) int {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, newFormFileRequest(url, "photo", filename, content))
+		return w.Code
+	}
+
+	// A script named like an image is rejected by its content
+	assert.Equal(t, http.StatusUnsupportedMediaType, upload("photo.jpg", []byte("<?php echo 'hello'; ?>")))
+
+	// Files over the configured size are rejected
+	assert.Equal(t, http.StatusRequestEntityTooLarge, upload("photo.jpg", bytes.Repeat([]byte{0xFF}, 2<<20)))
+
+	var photo bytes.Buffer
+	png.Encode(&photo, image.NewRGBA(image.Rect(0, 0, 300, 200)))
+	assert.Equal(t, http.StatusOK, upload("photo.png", photo.Bytes()))
+
+	var stored models.Contact
+	db.First(&stored, contact.ID)
+	assert.NotEmpty(t, stored.Photo)
+	_, err := os.Stat(filepath.Join(uploadDir, stored.Photo))
+	assert.NoError(t, err)
+
+	// Uploading a file with the same name again stores it under a new name
+	assert.Equal(t, http.StatusOK, upload("photo.png", photo.Bytes()))
+	var replaced models.Contact
+	db.First(&replaced, contact.ID)
+	assert.NotEqual(t, stored.Photo, replaced.Photo)
+
+	// The thumbnail of the landscape photo is cropped to a square
+	thumbnail, err := os.Open(filepath.Join(uploadDir, replaced.PhotoThumbnail))
+	assert.NoError(t, err)
+	defer thumbnail.Close()
+	thumbnailConfig, err := jpeg.DecodeConfig(thumbnail)
+	assert.NoError(t, err)
+	assert.Equal(t, thumbnailSize, thumbnailConfig.Width)
+	assert.Equal(t, thumbnailSize, thumbnailConfig.Height)
+}
+
+func TestGetProfilePictureThumbnail(t *testing.T) {
+	db, router := setupRouter()
+	router.GET("/contacts/:id/photo", GetContactPhoto)
+	router.GET("/contacts/:id/photo/thumb", GetProfilePictureThumbnail)
+	uploadDir := t.TempDir()
+	t.Setenv("PROFILE_PHOTO_DIR", uploadDir)
+
+	os.WriteFile(filepath.Join(uploadDir, "a_photo.jpg"), []byte("original"), 0o644)
+	os.WriteFile(filepath.Join(uploadDir, "a_thumbnail.jpg"), []byte("thumbnail"), 0o644)
+	contact := models.Contact{Firstname: "Alice", Photo: "a_photo.jpg", PhotoThumbnail: "a_thumbnail.jpg"}
+	db.Create(&contact)
+	legacy := models.Contact{Firstname: "Bob", Photo: "a_photo.jpg"}
+	db.Create(&legacy)
+	withoutPhoto := models.Contact{Firstname: "Carol"}
+	db.Create(&withoutPhoto)
+
+	get := func(url string, headers ...string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("GET", url, nil)
+		for i := 0; i+1 < len(headers); i += 2 {
+			req.Header.Set(headers[i], headers[i+1])
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	id := strconv.Itoa(int(contact.ID))
+	w := get("/contacts/" + id + "/photo")
+	assert.Equal(t, "original", w.Body.String())
+	assert.Equal(t, "image/jpeg", w.Header().Get("Content-Type"))
+	assert.Equal(t, "private, no-cache", w.Header().Get("Cache-Control"))
+	assert.Equal(t, http.StatusNotModified, get("/contacts/"+id+"/photo", "If-None-Match", w.Header().Get("ETag")).Code)
+	assert.Equal(t, "thumbnail", get("/contacts/"+id+"/photo/thumb").Body.String())
+	assert.Equal(t, http.StatusNotFound, get("/contacts/"+strconv.Itoa(int(withoutPhoto.ID))+"/photo").Code)
+
+	// Contacts without a thumbnail get their photo
+	assert.Equal(t, "original", get("/contacts/"+strconv.Itoa(int(legacy.ID))+"/photo/thumb").Body.String())
+	assert.Equal(t, http.StatusNotFound, get("/contacts/999/photo/thumb").Code)
+}
+
+func TestDeleteProfilePicture(t *testing.T) {
+	db, router := setupRouter()
+	router.DELETE("/contacts/:id/photo", DeleteProfilePicture)
+	uploadDir := t.TempDir()
+	t.Setenv("PROFILE_PHOTO_DIR", uploadDir)
+
+	os.WriteFile(filepath.Join(uploadDir, "a_photo.jpg"), []byte("original"), 0o644)
+	contact := models.Contact{Firstname: "Alice", Photo: "a_photo.jpg", PhotoThumbnail: "a_thumbnail.jpg"}
+	db.Create(&contact)
+	url := "/contacts/" + strconv.Itoa(int(contact.ID)) + "/photo"
+
+	// The missing thumbnail file does not prevent the deletion
+	req, _ := http.NewRequest("DELETE", url, nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var stored models.Contact
+	db.First(&stored, contact.ID)
+	assert.Empty(t, stored.Photo)
+	assert.Empty(t, stored.PhotoThumbnail)
+	_, err := os.Stat(filepath.Join(uploadDir, "a_photo.jpg"))
+	assert.True(t, os.IsNotExist(err))
+
+	// Deleting again succeeds as well
+	req, _ = http.NewRequest("DELETE", url, nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestAddPhotoToContactIgnoresClientFilename(t *testing.T) {
+	db, router := setupRouter()
+	cfg := &config.Config{MaxPhotoSizeMB: 1}
+	router.POST("/contacts/:id/profile_picture", func(c *gin.Context) { AddPhotoToContact(c, cfg) })
+	root := t.TempDir()
+	uploadDir := filepath.Join(root, "photos", "contacts")
+	t.Setenv("PROFILE_PHOTO_DIR", uploadDir)
+
+	contact := models.Contact{Firstname: "Alice"}
+	db.Create(&contact)
+
+	var photo bytes.Buffer
+	png.Encode(&photo, image.NewRGBA(image.Rect(0, 0, 10, 10)))
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, newFormFileRequest("/contacts/"+strconv.Itoa(int(contact.ID))+"/profile_picture", "photo", "../../etc/foo", photo.Bytes()))
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// The stored files have server generated names inside the upload directory
+	var stored models.Contact
+	db.First(&stored, contact.ID)
+	for _, name := range []string{stored.Photo, stored.PhotoThumbnail} {
+		assert.Equal(t, filepath.Base(name), name)
+		assert.NotContains(t, name, "foo")
+		_, err := os.Stat(filepath.Join(uploadDir, name))
+		assert.NoError(t, err)
+	}
+	_, err := os.Stat(filepath.Join(root, "etc"))
+	assert.True(t, os.IsNotExist(err))
+}
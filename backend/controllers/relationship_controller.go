@@ -1,9 +1,14 @@
 package controllers
 
 import (
+	"errors"
 	"net/http"
+	"perema/config"
+	"perema/middleware"
 	"perema/models"
+	"sort"
 	"strconv"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
@@ -14,6 +19,16 @@ func GetRelationships(c *gin.Context) {
 	db := c.MustGet("db").(*gorm.DB)
 	contactID := c.Param("id")
 
+	if err := db.Scopes(ownContacts(c)).First(&models.Contact{}, contactID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Contact not found"})
+		return
+	}
+
+	respondRelationships(c, db, contactID)
+}
+
+// respondRelationships responds with all relationships of the contact
+func respondRelationships(c *gin.Context, db *gorm.DB, contactID string) {
 	// Define a slice to hold the retrieved relationships
 	var relationships []models.Relationship
 
@@ -29,8 +44,9 @@ func GetRelationships(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"relationships": relationships})
 }
 
-// CreateRelationship creates a new relationship for a given contact
-func CreateRelationship(c *gin.Context) {
+// CreateRelationship creates a new relationship for a given contact. If it links to another contact and its type
+// has an inverse, the inverse relationship is created on the linked contact as well, unless inverse=false is given.
+func CreateRelationship(c *gin.Context, cfg *config.Config) {
 	// Retrieve the database instance from context
 	db := c.MustGet("db").(*gorm.DB)
 
@@ -51,21 +67,89 @@ func CreateRelationship(c *gin.Context) {
 
 	// Set the ContactID to associate the relationship with the given contact
 	relationship.ContactID = uint(contactID)
-	// Save the new relationship to the database
-	if err := db.Create(&relationship).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	if !ownsRelationshipContacts(c, db, relationship) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Contact not found"})
+		return
+	}
+	if !checkRelationshipLink(c, db, relationship) {
+		return
+	}
+
+	var inverse *models.Relationship
+	err = db.Transaction(func(tx *gorm.DB) error {
+		// Save the new relationship to the database
+		if err := tx.Create(&relationship).Error; err != nil {
+			return err
+		}
+		if c.DefaultQuery("inverse", "true") == "false" {
+			return nil
+		}
+		var err error
+		inverse, err = createInverseRelationship(tx, cfg, relationship)
+		return err
+	})
+	if err != nil {
+		middleware.Logger(c).Error("Failed to create relationship", "contact_id", contactID, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create relationship"})
 		return
 	}
 
 	// Return the created relationship in JSON format
-	c.JSON(http.StatusCreated, gin.H{"relationship": relationship})
+	response := gin.H{"relationship": relationship}
+	if inverse != nil {
+		response["inverse"] = inverse
+	}
+	c.JSON(http.StatusCreated, response)
 }
 
-func UpdateRelationship(c *gin.Context) {
-	id := c.Param("rid")
+// createInverseRelationship adds the inverse of the relationship to the linked contact, describing the contact
+// the relationship belongs to. Nothing is created for types without an inverse or if the linked contact
+// already has a relationship to the contact, whose type may be more specific like Mother instead of Parent.
+func createInverseRelationship(tx *gorm.DB, cfg *config.Config, relationship models.Relationship) (*models.Relationship, error) {
+	inverseType, hasInverse := cfg.RelationshipInverses[strings.ToLower(strings.TrimSpace(relationship.Type))]
+	if relationship.RelatedContactID == nil || *relationship.RelatedContactID == relationship.ContactID || !hasInverse {
+		return nil, nil
+	}
+
+	var existing int64
+	if err := tx.Model(&models.Relationship{}).
+		Where("contact_id = ? AND related_contact_id = ?", *relationship.RelatedContactID, relationship.ContactID).
+		Count(&existing).Error; err != nil || existing > 0 {
+		return nil, err
+	}
+
+	var contact models.Contact
+	if err := tx.First(&contact, relationship.ContactID).Error; err != nil {
+		return nil, err
+	}
+	inverse := models.Relationship{
+		Name:             strings.TrimSpace(contact.Firstname + " " + contact.Lastname),
+		Type:             inverseType,
+		Gender:           contact.Gender,
+		Birthday:         contact.Birthday,
+		ContactID:        *relationship.RelatedContactID,
+		RelatedContactID: &contact.ID,
+	}
+	if err := tx.Create(&inverse).Error; err != nil {
+		return nil, err
+	}
+	return &inverse, nil
+}
+
+// findContactRelationship loads the relationship of the URL, which must belong to the contact of the URL
+func findContactRelationship(c *gin.Context, db *gorm.DB) (models.Relationship, error) {
 	var relationship models.Relationship
+	err := db.Where("relationships.contact_id = ?", c.Param("id")).
+		Scopes(ownedThroughContact(c, "relationships.contact_id")).
+		First(&relationship, c.Param("rid")).Error
+	return relationship, err
+}
+
+// UpdateRelationship changes a relationship of the contact and responds with all relationships of the contact
+func UpdateRelationship(c *gin.Context) {
 	db := c.MustGet("db").(*gorm.DB)
-	if err := db.First(&relationship, id).Error; err != nil {
+	relationship, err := findContactRelationship(c, db)
+	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Relationship not found"})
 		return
 	}
@@ -81,21 +165,347 @@ func UpdateRelationship(c *gin.Context) {
 	relationship.Type = updatedRelationship.Type
 	relationship.Gender = updatedRelationship.Gender
 	relationship.Birthday = updatedRelationship.Birthday
-	relationship.ContactID = updatedRelationship.ContactID
+	if updatedRelationship.ContactID != 0 {
+		relationship.ContactID = updatedRelationship.ContactID
+	}
 	relationship.RelatedContactID = updatedRelationship.RelatedContactID
+	if !ownsRelationshipContacts(c, db, relationship) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Contact not found"})
+		return
+	}
+	if !checkRelationshipLink(c, db, relationship) {
+		return
+	}
 
-	db.Updates(&relationship)
+	if err := db.Updates(&relationship).Error; err != nil {
+		middleware.Logger(c).Error("Failed to update relationship", "relationship_id", relationship.ID, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update relationship"})
+		return
+	}
 
-	c.JSON(http.StatusOK, relationship)
+	respondRelationships(c, db, c.Param("id"))
 }
 
-func DeleteRelationship(c *gin.Context) {
-	id := c.Param("rid")
+// DeleteRelationship removes a relationship of the contact together with its inverse on the linked contact,
+// unless inverse=false is given. It responds with the remaining relationships of the contact.
+func DeleteRelationship(c *gin.Context, cfg *config.Config) {
 	db := c.MustGet("db").(*gorm.DB)
-	if err := db.Delete(&models.Relationship{}, id).Error; err != nil {
+	relationship, err := findContactRelationship(c, db)
+	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Relationship not found"})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Relationship deleted"})
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(&relationship).Error; err != nil {
+			return err
+		}
+		if c.DefaultQuery("inverse", "true") == "false" {
+			return nil
+		}
+		return deleteInverseRelationship(tx, cfg, relationship)
+	})
+	if err != nil {
+		middleware.Logger(c).Error("Failed to delete relationship", "relationship_id", relationship.ID, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete relationship"})
+		return
+	}
+
+	respondRelationships(c, db, c.Param("id"))
+}
+
+// deleteInverseRelationship removes the relationships of the linked contact back to the contact
+// whose type is the inverse of the relationship type
+func deleteInverseRelationship(tx *gorm.DB, cfg *config.Config, relationship models.Relationship) error {
+	inverseType, hasInverse := cfg.RelationshipInverses[strings.ToLower(strings.TrimSpace(relationship.Type))]
+	if relationship.RelatedContactID == nil || !hasInverse {
+		return nil
+	}
+	return tx.Where("contact_id = ? AND related_contact_id = ? AND LOWER(type) = LOWER(?)", *relationship.RelatedContactID, relationship.ContactID, inverseType).
+		Delete(&models.Relationship{}).Error
+}
+
+// ownsRelationshipContacts reports whether both contacts linked by the relationship belong to the authenticated user
+func ownsRelationshipContacts(c *gin.Context, db *gorm.DB, relationship models.Relationship) bool {
+	ids := []uint{relationship.ContactID}
+	if relationship.RelatedContactID != nil && *relationship.RelatedContactID != relationship.ContactID {
+		ids = append(ids, *relationship.RelatedContactID)
+	}
+	var count int64
+	if err := db.Model(&models.Contact{}).Scopes(ownContacts(c)).Where("id IN ?", ids).Count(&count).Error; err != nil {
+		return false
+	}
+	return count == int64(len(ids))
+}
+
+// checkRelationshipLink rejects relationships of a contact to itself and relationships that already exist
+// with the same linked contact and type. It writes the error response and returns false if the link is invalid.
+func checkRelationshipLink(c *gin.Context, db *gorm.DB, relationship models.Relationship) bool {
+	if relationship.RelatedContactID == nil {
+		return true
+	}
+	if *relationship.RelatedContactID == relationship.ContactID {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "A contact cannot have a relationship to itself"})
+		return false
+	}
+
+	var existing models.Relationship
+	err := db.Where("contact_id = ? AND related_contact_id = ? AND LOWER(type) = LOWER(?) AND id <> ?",
+		relationship.ContactID, *relationship.RelatedContactID, strings.TrimSpace(relationship.Type), relationship.ID).
+		First(&existing).Error
+	if err == nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "Relationship already exists", "relationship": existing})
+		return false
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return false
+	}
+	return true
+}
+
+// relationshipEdge is a link between two contacts in the relationship graph
+type relationshipEdge struct {
+	ID      uint // ID of the relationship
+	To      uint
+	Type    string
+	Inverse bool // True if the edge is traversed against the direction of the relationship
+}
+
+// relationshipGraph loads all relationships linking two existing contacts as undirected adjacency list
+func relationshipGraph(db *gorm.DB) (map[uint][]relationshipEdge, error) {
+	var relationships []models.Relationship
+	if err := db.Select("ID", "Type", "ContactID", "RelatedContactID").
+		Where("related_contact_id IS NOT NULL").
+		Find(&relationships).Error; err != nil {
+		return nil, err
+	}
+
+	graph := map[uint][]relationshipEdge{}
+	for _, relationship := range relationships {
+		from, to := relationship.ContactID, *relationship.RelatedContactID
+		graph[from] = append(graph[from], relationshipEdge{ID: relationship.ID, To: to, Type: relationship.Type})
+		graph[to] = append(graph[to], relationshipEdge{ID: relationship.ID, To: from, Type: relationship.Type, Inverse: true})
+	}
+	return graph, nil
+}
+
+// pathStep is a contact on a connection path together with the relationship leading to it
+type pathStep struct {
+	ContactID        uint   `json:"contact_id"`
+	Firstname        string `json:"firstname"`
+	Lastname         string `json:"lastname"`
+	RelationshipType string `json:"relationship_type,omitempty"`
+	Inverse          bool   `json:"inverse,omitempty"`
+}
+
+// shortestPath runs a breadth-first search from one contact to another, visiting each contact only once.
+// It returns nil if the target cannot be reached within maxDepth relationships.
+func shortestPath(graph map[uint][]relationshipEdge, from, to uint, maxDepth int) []pathStep {
+	type visit struct {
+		previous uint
+		edge     relationshipEdge
+	}
+
+	visited := map[uint]visit{from: {}}
+	queue := []uint{from}
+	for depth := 0; depth < maxDepth && len(queue) > 0; depth++ {
+		var next []uint
+		for _, current := range queue {
+			for _, edge := range graph[current] {
+				if _, seen := visited[edge.To]; seen {
+					continue
+				}
+				visited[edge.To] = visit{previous: current, edge: edge}
+				next = append(next, edge.To)
+			}
+		}
+		queue = next
+	}
+
+	if _, reached := visited[to]; !reached {
+		return nil
+	}
+
+	// Walk back from the target to reconstruct the path
+	path := []pathStep{{ContactID: to}}
+	for current := to; current != from; current = visited[current].previous {
+		path[0].RelationshipType = visited[current].edge.Type
+		path[0].Inverse = visited[current].edge.Inverse
+		path = append([]pathStep{{ContactID: visited[current].previous}}, path...)
+	}
+	return path
+}
+
+// GetConnectionPath finds the shortest chain of relationships between two contacts
+func GetConnectionPath(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+
+	from, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid contact ID"})
+		return
+	}
+	to, err := strconv.Atoi(c.Query("to"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid target contact ID"})
+		return
+	}
+	maxDepth, err := strconv.Atoi(c.DefaultQuery("max_depth", "4"))
+	if err != nil || maxDepth < 1 || maxDepth > 6 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "max_depth must be between 1 and 6"})
+		return
+	}
+
+	var contacts []models.Contact
+	if err := db.Scopes(ownContacts(c)).Select("ID", "Firstname", "Lastname").Where("id IN ?", []int{from, to}).Find(&contacts).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if (from == to && len(contacts) != 1) || (from != to && len(contacts) != 2) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Contact not found"})
+		return
+	}
+
+	graph, err := relationshipGraph(db.Scopes(ownedThroughContact(c, "relationships.contact_id")))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	path := shortestPath(graph, uint(from), uint(to), maxDepth)
+	if path == nil {
+		c.JSON(http.StatusOK, gin.H{"path": []pathStep{}, "length": 0})
+		return
+	}
+
+	// Add the names of all contacts on the path
+	ids := make([]uint, len(path))
+	for i, step := range path {
+		ids[i] = step.ContactID
+	}
+	var pathContacts []models.Contact
+	if err := db.Scopes(ownContacts(c)).Select("ID", "Firstname", "Lastname").Where("id IN ?", ids).Find(&pathContacts).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	for _, contact := range pathContacts {
+		for i := range path {
+			if path[i].ContactID == contact.ID {
+				path[i].Firstname = contact.Firstname
+				path[i].Lastname = contact.Lastname
+			}
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"path": path, "length": len(path) - 1})
+}
+
+// graphNode is a contact in the exported relationship graph
+type graphNode struct {
+	ID        uint   `json:"id"`
+	Label     string `json:"label"`
+	Firstname string `json:"firstname"`
+	Lastname  string `json:"lastname"`
+}
+
+// graphEdge is a relationship in the exported relationship graph, pointing from the contact it belongs to
+type graphEdge struct {
+	ID     uint   `json:"id"`
+	Source uint   `json:"source"`
+	Target uint   `json:"target"`
+	Label  string `json:"label"`
+}
+
+// reachableContacts runs a breadth-first search from a contact and returns all contacts within maxDepth
+// relationships. Each contact is visited only once, so cycles in the graph do not matter.
+func reachableContacts(graph map[uint][]relationshipEdge, from uint, maxDepth int) map[uint]bool {
+	visited := map[uint]bool{from: true}
+	queue := []uint{from}
+	for depth := 0; depth < maxDepth && len(queue) > 0; depth++ {
+		var next []uint
+		for _, current := range queue {
+			for _, edge := range graph[current] {
+				if visited[edge.To] {
+					continue
+				}
+				visited[edge.To] = true
+				next = append(next, edge.To)
+			}
+		}
+		queue = next
+	}
+	return visited
+}
+
+// GetRelationshipGraph exports the contacts and the relationships between them as nodes and edges for
+// a force-directed graph. With contact_id only the contacts within depth relationships of that contact are
+// included, otherwise all contacts linked by at least one relationship.
+func GetRelationshipGraph(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+
+	graph, err := relationshipGraph(db.Scopes(ownedThroughContact(c, "relationships.contact_id")))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	included := map[uint]bool{}
+	if contactParam := c.Query("contact_id"); contactParam != "" {
+		contactID, err := strconv.Atoi(contactParam)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid contact ID"})
+			return
+		}
+		depth, err := strconv.Atoi(c.DefaultQuery("depth", "2"))
+		if err != nil || depth < 1 || depth > 6 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "depth must be between 1 and 6"})
+			return
+		}
+		if err := db.Scopes(ownContacts(c)).First(&models.Contact{}, contactID).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Contact not found"})
+			return
+		}
+		included = reachableContacts(graph, uint(contactID), depth)
+	} else {
+		for contactID := range graph {
+			included[contactID] = true
+		}
+	}
+
+	ids := make([]uint, 0, len(included))
+	for contactID := range included {
+		ids = append(ids, contactID)
+	}
+	var contacts []models.Contact
+	if err := db.Scopes(ownContacts(c)).Select("ID", "Firstname", "Lastname").Where("id IN ?", ids).Order("id").Find(&contacts).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Only contacts that exist and belong to the user become nodes, edges need both of their ends
+	nodes := make([]graphNode, 0, len(contacts))
+	found := map[uint]bool{}
+	for _, contact := range contacts {
+		found[contact.ID] = true
+		nodes = append(nodes, graphNode{
+			ID:        contact.ID,
+			Label:     strings.TrimSpace(contact.Firstname + " " + contact.Lastname),
+			Firstname: contact.Firstname,
+			Lastname:  contact.Lastname,
+		})
+	}
+	edges := []graphEdge{}
+	for from, fromEdges := range graph {
+		for _, edge := range fromEdges {
+			// Every relationship is stored in both directions, export it once from the contact it belongs to
+			if edge.Inverse || !found[from] || !found[edge.To] {
+				continue
+			}
+			edges = append(edges, graphEdge{ID: edge.ID, Source: from, Target: edge.To, Label: edge.Type})
+		}
+	}
+	sort.Slice(edges, func(i, j int) bool { return edges[i].ID < edges[j].ID })
+
+	c.JSON(http.StatusOK, gin.H{"nodes": nodes, "edges": edges})
 }
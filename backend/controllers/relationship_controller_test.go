@@ -5,10 +5,12 @@ import (
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"perema/config"
 	"perema/models"
 	"strconv"
 	"testing"
 
+	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -53,7 +55,9 @@ func TestGetRelationships(t *testing.T) {
 
 func TestCreateRelationship(t *testing.T) {
 	db, router := setupRouter()
-	router.POST("/contacts/:id/relationships", CreateRelationship)
+	router.POST("/contacts/:id/relationships", func(c *gin.Context) {
+		CreateRelationship(c, &config.Config{})
+	})
 
 	// Create a contact to associate with the relationship
 	contact := models.Contact{
@@ -83,15 +87,125 @@ func TestCreateRelationship(t *testing.T) {
 	assert.Equal(t, newRelationship.Name, responseBody["relationship"].(map[string]any)["name"]) // Checking if the created relationship name matches
 }
 
+func TestCreateRelationshipInvalidLinks(t *testing.T) {
+	db, router := setupRouter()
+	router.POST("/contacts/:id/relationships", func(c *gin.Context) {
+		CreateRelationship(c, &config.Config{})
+	})
+	router.PUT("/contacts/:id/relationships/:rid", UpdateRelationship)
+
+	alice := models.Contact{Firstname: "Alice"}
+	bob := models.Contact{Firstname: "Bob"}
+	db.Create(&alice)
+	db.Create(&bob)
+	url := "/contacts/" + strconv.Itoa(int(alice.ID)) + "/relationships"
+
+	send := func(method, url string, relationship models.Relationship) int {
+		jsonValue, _ := json.Marshal(relationship)
+		req, _ := http.NewRequest(method, url, bytes.NewBuffer(jsonValue))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	// A contact cannot be related to itself
+	assert.Equal(t, http.StatusBadRequest, send("POST", url, models.Relationship{Name: "Alice", Type: "Friend", RelatedContactID: &alice.ID}))
+
+	// The same relationship cannot be added twice, regardless of the case of its type
+	assert.Equal(t, http.StatusCreated, send("POST", url, models.Relationship{Name: "Bob", Type: "Friend", RelatedContactID: &bob.ID}))
+	assert.Equal(t, http.StatusConflict, send("POST", url, models.Relationship{Name: "Bob", Type: "friend", RelatedContactID: &bob.ID}))
+
+	// Other types to the same contact and unlinked relationships of the same type are fine
+	assert.Equal(t, http.StatusCreated, send("POST", url, models.Relationship{Name: "Bob", Type: "Colleague", RelatedContactID: &bob.ID}))
+	assert.Equal(t, http.StatusCreated, send("POST", url, models.Relationship{Name: "Carol", Type: "Friend"}))
+	assert.Equal(t, http.StatusCreated, send("POST", url, models.Relationship{Name: "Dave", Type: "Friend"}))
+
+	// Updates cannot turn a relationship into a duplicate or a link to the contact itself,
+	// but saving a relationship unchanged is no duplicate of itself
+	var colleague models.Relationship
+	db.Where("type = ?", "Colleague").First(&colleague)
+	colleagueURL := url + "/" + strconv.Itoa(int(colleague.ID))
+	assert.Equal(t, http.StatusConflict, send("PUT", colleagueURL, models.Relationship{Name: "Bob", Type: "Friend", RelatedContactID: &bob.ID}))
+	assert.Equal(t, http.StatusBadRequest, send("PUT", colleagueURL, models.Relationship{Name: "Bob", Type: "Colleague", RelatedContactID: &alice.ID}))
+	assert.Equal(t, http.StatusOK, send("PUT", colleagueURL, models.Relationship{Name: "Bob B.", Type: "Colleague", RelatedContactID: &bob.ID}))
+
+	var count int64
+	db.Model(&models.Relationship{}).Count(&count)
+	assert.Equal(t, int64(4), count)
+}
+
+func TestCreateRelationshipInverse(t *testing.T) {
+	db, router := setupRouter()
+	cfg := &config.Config{RelationshipInverses: map[string]string{"mother": "Child", "parent": "Child", "child": "Parent", "son": "Parent", "sibling": "Sibling"}}
+	router.POST("/contacts/:id/relationships", func(c *gin.Context) {
+		CreateRelationship(c, cfg)
+	})
+
+	alice := models.Contact{Firstname: "Alice", Lastname: "Doe", Gender: "female"}
+	bob := models.Contact{Firstname: "Bob", Lastname: "Doe"}
+	carol := models.Contact{Firstname: "Carol"}
+	db.Create(&alice)
+	db.Create(&bob)
+	db.Create(&carol)
+
+	create := func(contact models.Contact, query string, relationship models.Relationship) int {
+		jsonValue, _ := json.Marshal(relationship)
+		req, _ := http.NewRequest("POST", "/contacts/"+strconv.Itoa(int(contact.ID))+"/relationships"+query, bytes.NewBuffer(jsonValue))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+	relationshipsOf := func(contact models.Contact) []models.Relationship {
+		var relationships []models.Relationship
+		db.Where("contact_id = ?", contact.ID).Order("id").Find(&relationships)
+		return relationships
+	}
+
+	// Bob is the child of Alice, who is his mother
+	assert.Equal(t, http.StatusCreated, create(bob, "", models.Relationship{Name: "Alice Doe", Type: "Mother", RelatedContactID: &alice.ID}))
+	inverse := relationshipsOf(alice)
+	assert.Len(t, inverse, 1)
+	assert.Equal(t, "Child", inverse[0].Type)
+	assert.Equal(t, "Bob Doe", inverse[0].Name)
+	assert.Equal(t, bob.ID, *inverse[0].RelatedContactID)
+
+	// The inverse cannot be added again, and since Bob already has a relationship to Alice,
+	// a second one from Alice does not add another to Bob
+	assert.Equal(t, http.StatusConflict, create(alice, "", models.Relationship{Name: "Bob Doe", Type: "child", RelatedContactID: &bob.ID}))
+	assert.Equal(t, http.StatusCreated, create(alice, "", models.Relationship{Name: "Bob Doe", Type: "Son", RelatedContactID: &bob.ID}))
+	assert.Len(t, relationshipsOf(bob), 1)
+	assert.Len(t, relationshipsOf(alice), 2)
+
+	// Types without an inverse and opted out relationships are only created once
+	assert.Equal(t, http.StatusCreated, create(carol, "", models.Relationship{Name: "Alice", Type: "Boss", RelatedContactID: &alice.ID}))
+	assert.Equal(t, http.StatusCreated, create(carol, "?inverse=false", models.Relationship{Name: "Bob", Type: "Sibling", RelatedContactID: &bob.ID}))
+	assert.Len(t, relationshipsOf(alice), 2)
+	assert.Len(t, relationshipsOf(bob), 1)
+
+	// Relationships to people without a contact have nobody to add the inverse to
+	assert.Equal(t, http.StatusCreated, create(carol, "", models.Relationship{Name: "Dave", Type: "Sibling"}))
+	var count int64
+	db.Model(&models.Relationship{}).Count(&count)
+	assert.Equal(t, int64(6), count)
+}
+
 func TestUpdateRelationship(t *testing.T) {
 	db, router := setupRouter()
-	router.PUT("/relationships/:rid", UpdateRelationship)
+	router.PUT("/contacts/:id/relationships/:rid", UpdateRelationship)
+
+	contact := models.Contact{Firstname: "Jane"}
+	other := models.Contact{Firstname: "John"}
+	db.Create(&contact)
+	db.Create(&other)
 
 	// Create a relationship to update
 	existingRelationship := models.Relationship{
-		Name:   "Colleague",
-		Type:   "Work",
-		Gender: "Male",
+		Name:      "Colleague",
+		ContactID: contact.ID,
+		Type:      "Work",
+		Gender:    "Male",
 	}
 	db.Create(&existingRelationship)
 
@@ -101,46 +215,206 @@ func TestUpdateRelationship(t *testing.T) {
 		Type:   "Work",
 		Gender: "Male",
 	}
-	jsonValue, _ := json.Marshal(updatedRelationship)
-
-	req, _ := http.NewRequest("PUT", "/relationships/"+strconv.Itoa(int(existingRelationship.ID)), bytes.NewBuffer(jsonValue))
-	req.Header.Set("Content-Type", "application/json")
+	update := func(contactID uint) *httptest.ResponseRecorder {
+		jsonValue, _ := json.Marshal(updatedRelationship)
+		url := "/contacts/" + strconv.Itoa(int(contactID)) + "/relationships/" + strconv.Itoa(int(existingRelationship.ID))
+		req, _ := http.NewRequest("PUT", url, bytes.NewBuffer(jsonValue))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
 
-	w := httptest.NewRecorder()
-	router.ServeHTTP(w, req)
+	// The relationship can only be changed through its own contact
+	assert.Equal(t, http.StatusNotFound, update(other.ID).Code)
 
+	w := update(contact.ID)
 	assert.Equal(t, http.StatusOK, w.Code)
 
-	var responseBody models.Relationship
+	// The response contains the updated relationships of the contact
+	var responseBody struct {
+		Relationships []models.Relationship `json:"relationships"`
+	}
 	json.Unmarshal(w.Body.Bytes(), &responseBody)
-	assert.Equal(t, updatedRelationship.Name, responseBody.Name) // Checking if the updated relationship name matches
+	assert.Len(t, responseBody.Relationships, 1)
+	assert.Equal(t, updatedRelationship.Name, responseBody.Relationships[0].Name)
 }
 
 func TestDeleteRelationship(t *testing.T) {
 	db, router := setupRouter()
-	router.DELETE("/relationships/:rid", DeleteRelationship)
+	cfg := &config.Config{RelationshipInverses: map[string]string{"parent": "Child", "child": "Parent"}}
+	router.DELETE("/contacts/:id/relationships/:rid", func(c *gin.Context) {
+		DeleteRelationship(c, cfg)
+	})
+
+	contact := models.Contact{Firstname: "Jane"}
+	child := models.Contact{Firstname: "Jim"}
+	db.Create(&contact)
+	db.Create(&child)
 
-	// Create a relationship to delete
+	// Create relationships to delete, the linked ones have an inverse on the child
 	relationshipToDelete := models.Relationship{
-		Name:   "Cousin",
-		Type:   "Family",
-		Gender: "Female",
+		Name:      "Cousin",
+		ContactID: contact.ID,
+		Type:      "Family",
+		Gender:    "Female",
+	}
+	parent := models.Relationship{Name: "Jim", ContactID: contact.ID, Type: "Child", RelatedContactID: &child.ID}
+	inverse := models.Relationship{Name: "Jane", ContactID: child.ID, Type: "Parent", RelatedContactID: &contact.ID}
+	for _, relationship := range []*models.Relationship{&relationshipToDelete, &parent, &inverse} {
+		db.Create(relationship)
 	}
-	db.Create(&relationshipToDelete)
 
-	// Make the request to delete the relationship
-	req, _ := http.NewRequest("DELETE", "/relationships/"+strconv.Itoa(int(relationshipToDelete.ID)), nil)
-	w := httptest.NewRecorder()
-	router.ServeHTTP(w, req)
+	deleteRelationship := func(contactID, relationshipID uint, query string) *httptest.ResponseRecorder {
+		url := "/contacts/" + strconv.Itoa(int(contactID)) + "/relationships/" + strconv.Itoa(int(relationshipID)) + query
+		req, _ := http.NewRequest("DELETE", url, nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	// The relationship can only be deleted through its own contact
+	assert.Equal(t, http.StatusNotFound, deleteRelationship(child.ID, relationshipToDelete.ID, "").Code)
 
+	w := deleteRelationship(contact.ID, relationshipToDelete.ID, "")
 	assert.Equal(t, http.StatusOK, w.Code)
 
-	var responseBody map[string]string
+	// The response contains the remaining relationships of the contact
+	var responseBody struct {
+		Relationships []models.Relationship `json:"relationships"`
+	}
 	json.Unmarshal(w.Body.Bytes(), &responseBody)
-	assert.Equal(t, "Relationship deleted", responseBody["message"])
+	assert.Len(t, responseBody.Relationships, 1)
+	assert.Equal(t, parent.ID, responseBody.Relationships[0].ID)
 
 	// Verify relationship has been deleted
 	var deletedRelationship models.Relationship
 	result := db.First(&deletedRelationship, relationshipToDelete.ID)
 	assert.Error(t, result.Error) // This should return an error as it has been deleted
+
+	// The inverse is kept when opted out, and removed together with the relationship otherwise
+	assert.Equal(t, http.StatusOK, deleteRelationship(child.ID, inverse.ID, "?inverse=false").Code)
+	assert.NoError(t, db.First(&models.Relationship{}, parent.ID).Error)
+
+	db.Create(&models.Relationship{Name: "Jane", ContactID: child.ID, Type: "parent", RelatedContactID: &contact.ID})
+	assert.Equal(t, http.StatusOK, deleteRelationship(contact.ID, parent.ID, "").Code)
+	var count int64
+	db.Model(&models.Relationship{}).Count(&count)
+	assert.Equal(t, int64(0), count)
+}
+
+func TestGetConnectionPath(t *testing.T) {
+	db, router := setupRouter()
+	router.GET("/contacts/:id/path", GetConnectionPath)
+
+	// Me -> Alice -> Bob -> Carol, Dave is not connected, Alice and Carol also know each other
+	contacts := []models.Contact{{Firstname: "Me"}, {Firstname: "Alice"}, {Firstname: "Bob"}, {Firstname: "Carol"}, {Firstname: "Dave"}}
+	for i := range contacts {
+		db.Create(&contacts[i])
+	}
+	me, alice, bob, carol, dave := contacts[0], contacts[1], contacts[2], contacts[3], contacts[4]
+	db.Create(&models.Relationship{Type: "Friend", ContactID: me.ID, RelatedContactID: &alice.ID})
+	db.Create(&models.Relationship{Type: "Colleague", ContactID: bob.ID, RelatedContactID: &alice.ID})
+	db.Create(&models.Relationship{Type: "Sibling", ContactID: bob.ID, RelatedContactID: &carol.ID})
+	db.Create(&models.Relationship{Type: "Neighbor", ContactID: carol.ID, RelatedContactID: &alice.ID})
+	db.Create(&models.Relationship{Name: "Unlinked", Type: "Friend", ContactID: dave.ID})
+
+	getPath := func(from, to uint, query string) (int, []pathStep) {
+		url := "/contacts/" + strconv.Itoa(int(from)) + "/path?to=" + strconv.Itoa(int(to)) + query
+		req, _ := http.NewRequest("GET", url, nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		var responseBody struct {
+			Path []pathStep `json:"path"`
+		}
+		json.Unmarshal(w.Body.Bytes(), &responseBody)
+		return w.Code, responseBody.Path
+	}
+
+	code, path := getPath(me.ID, bob.ID, "")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, []pathStep{
+		{ContactID: me.ID, Firstname: "Me"},
+		{ContactID: alice.ID, Firstname: "Alice", RelationshipType: "Friend"},
+		{ContactID: bob.ID, Firstname: "Bob", RelationshipType: "Colleague", Inverse: true},
+	}, path)
+
+	// The cycle via Carol does not lead to a longer path
+	_, path = getPath(me.ID, carol.ID, "")
+	assert.Len(t, path, 3)
+
+	// Paths longer than the depth cap are not found
+	_, path = getPath(me.ID, bob.ID, "&max_depth=1")
+	assert.Empty(t, path)
+
+	// Unconnected contacts have no path
+	code, path = getPath(me.ID, dave.ID, "")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Empty(t, path)
+
+	code, _ = getPath(me.ID, 999, "")
+	assert.Equal(t, http.StatusNotFound, code)
+}
+
+func TestGetRelationshipGraph(t *testing.T) {
+	db, router := setupRouter()
+	router.GET("/relationships/graph", GetRelationshipGraph)
+
+	// Alice -> Bob -> Carol -> Alice form a cycle, Dave is only linked to Carol, Erin has no relationships
+	contacts := []models.Contact{{Firstname: "Alice"}, {Firstname: "Bob"}, {Firstname: "Carol"}, {Firstname: "Dave"}, {Firstname: "Erin"}}
+	for i := range contacts {
+		db.Create(&contacts[i])
+	}
+	alice, bob, carol, dave := contacts[0], contacts[1], contacts[2], contacts[3]
+	mallory := models.Contact{Firstname: "Mallory", UserID: 2}
+	db.Create(&mallory)
+	db.Create(&models.Relationship{Type: "Friend", ContactID: alice.ID, RelatedContactID: &bob.ID})
+	db.Create(&models.Relationship{Type: "Sibling", ContactID: bob.ID, RelatedContactID: &carol.ID})
+	db.Create(&models.Relationship{Type: "Neighbor", ContactID: carol.ID, RelatedContactID: &alice.ID})
+	db.Create(&models.Relationship{Type: "Colleague", ContactID: dave.ID, RelatedContactID: &carol.ID})
+	db.Create(&models.Relationship{Name: "Unlinked", Type: "Friend", ContactID: alice.ID})
+	db.Create(&models.Relationship{Type: "Friend", ContactID: mallory.ID, RelatedContactID: &alice.ID})
+
+	getGraph := func(query string) (int, []uint, []graphEdge) {
+		req, _ := http.NewRequest("GET", "/relationships/graph"+query, nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		var responseBody struct {
+			Nodes []graphNode `json:"nodes"`
+			Edges []graphEdge `json:"edges"`
+		}
+		json.Unmarshal(w.Body.Bytes(), &responseBody)
+		var ids []uint
+		for _, node := range responseBody.Nodes {
+			ids = append(ids, node.ID)
+		}
+		return w.Code, ids, responseBody.Edges
+	}
+
+	// All linked contacts of the user, each relationship once and labeled with its type
+	code, nodes, edges := getGraph("")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, []uint{alice.ID, bob.ID, carol.ID, dave.ID}, nodes)
+	assert.Len(t, edges, 4)
+	assert.Equal(t, graphEdge{ID: edges[0].ID, Source: alice.ID, Target: bob.ID, Label: "Friend"}, edges[0])
+
+	// The traversal follows relationships in both directions and stops at the depth
+	_, nodes, edges = getGraph("?contact_id=" + strconv.Itoa(int(bob.ID)) + "&depth=1")
+	assert.Equal(t, []uint{alice.ID, bob.ID, carol.ID}, nodes)
+	assert.Len(t, edges, 3)
+
+	_, nodes, _ = getGraph("?contact_id=" + strconv.Itoa(int(bob.ID)))
+	assert.Equal(t, []uint{alice.ID, bob.ID, carol.ID, dave.ID}, nodes)
+
+	// A contact without relationships is a graph of its own
+	_, nodes, edges = getGraph("?contact_id=" + strconv.Itoa(int(contacts[4].ID)))
+	assert.Equal(t, []uint{contacts[4].ID}, nodes)
+	assert.Empty(t, edges)
+
+	code, _, _ = getGraph("?contact_id=" + strconv.Itoa(int(mallory.ID)))
+	assert.Equal(t, http.StatusNotFound, code)
+	code, _, _ = getGraph("?contact_id=" + strconv.Itoa(int(alice.ID)) + "&depth=0")
+	assert.Equal(t, http.StatusBadRequest, code)
 }
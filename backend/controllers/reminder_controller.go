@@ -1,14 +1,33 @@
 package controllers
 
 import (
-	"log"
+	"fmt"
 	"net/http"
+	"perema/config"
+	"perema/middleware"
 	"perema/models"
+	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
 )
 
+// CreateReminder adds a reminder to a contact
+//
+//	@Summary	Create a reminder
+//	@Tags		reminders
+//	@Accept		json
+//	@Produce	json
+//	@Param		id			path		int				true	"Contact ID"
+//	@Param		reminder	body		models.Reminder	true	"Reminder to create"
+//	@Success	201			{object}	object{message=string,reminder=models.Reminder}
+//	@Header		201			{string}	Location	"URL of the created reminder"
+//	@Failure	400			{object}	validationErrorResponse
+//	@Failure	404			{object}	errorResponse
+//	@Failure	500			{object}	errorResponse
+//	@Security	bearerauth
+//	@Router		/contacts/{id}/reminders [post]
 func CreateReminder(c *gin.Context) {
 	db := c.MustGet("db").(*gorm.DB)
 
@@ -16,7 +35,7 @@ func CreateReminder(c *gin.Context) {
 
 	// Find the contact by the ID
 	var contact models.Contact
-	if err := db.First(&contact, contactID).Error; err != nil {
+	if err := db.Scopes(ownContacts(c)).First(&contact, contactID).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
 			c.JSON(http.StatusNotFound, gin.H{"error": "Contact not found"})
 		} else {
@@ -28,29 +47,55 @@ func CreateReminder(c *gin.Context) {
 	// Bind the incoming JSON request to the Reminder struct
 	var reminder models.Reminder
 	if err := c.ShouldBindJSON(&reminder); err != nil {
-		log.Println("Error binding JSON for create reminder:", err)
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		middleware.Logger(c).Warn("Invalid reminder", "contact_id", contact.ID, "error", err)
+		respondBindingError(c, err)
+		return
+	}
+	if fieldErrors := reminderFieldErrors(reminder); len(fieldErrors) > 0 {
+		respondFieldErrors(c, fieldErrors)
 		return
 	}
 
 	// Assign the ContactID to the reminder to link it to the contact
 	reminder.ContactID = &contact.ID
+	reminder.ScheduleNextDue()
 
 	// Save the new reminder to the database
 	if err := db.Create(&reminder).Error; err != nil {
-		log.Println("Error saving to database:", err)
+		middleware.Logger(c).Error("Failed to save reminder", "contact_id", contact.ID, "error", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save reminder"})
 		return
 	}
+	recordAudit(c, db, reminderAudit(reminder, models.AuditCreate), nil, reminder)
+
+	respondCreated(c, fmt.Sprintf("/reminders/%d", reminder.ID), gin.H{"message": "Reminder created successfully", "reminder": reminder})
+}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Reminder created successfully", "reminder": reminder})
+// reminderFieldErrors validates the recurrence settings of a reminder
+func reminderFieldErrors(reminder models.Reminder) map[string]fieldError {
+	fields := map[string]fieldError{}
+	values := map[string]any{"recurrence": reminder.Recurrence, "recurrence_interval": reminder.RecurrenceInterval}
+	for field, message := range reminder.Validate() {
+		fields[field] = fieldError{Rule: field, Message: message, Value: values[field]}
+	}
+	return fields
 }
 
+// GetReminder returns a single reminder
+//
+//	@Summary	Get a reminder
+//	@Tags		reminders
+//	@Produce	json
+//	@Param		id	path		int	true	"Reminder ID"
+//	@Success	200	{object}	models.Reminder
+//	@Failure	404	{object}	errorResponse
+//	@Security	bearerauth
+//	@Router		/reminders/{id} [get]
 func GetReminder(c *gin.Context) {
 	id := c.Param("id")
 	var reminder models.Reminder
 	db := c.MustGet("db").(*gorm.DB)
-	if err := db.First(&reminder, id).Error; err != nil {
+	if err := db.Scopes(ownedThroughContact(c, "reminders.contact_id")).First(&reminder, id).Error; err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Reminder not found"})
 		return
 	}
@@ -58,45 +103,229 @@ func GetReminder(c *gin.Context) {
 	c.JSON(http.StatusOK, reminder)
 }
 
+// UpdateReminder replaces the message, schedule and state of a reminder and may move it to another contact
+//
+//	@Summary	Update a reminder
+//	@Tags		reminders
+//	@Accept		json
+//	@Produce	json
+//	@Param		id			path		int				true	"Reminder ID"
+//	@Param		reminder	body		models.Reminder	true	"Reminder"
+//	@Success	200			{object}	object{message=string,reminder=models.Reminder}
+//	@Failure	400			{object}	validationErrorResponse
+//	@Failure	404			{object}	errorResponse
+//	@Security	bearerauth
+//	@Router		/reminders/{id} [put]
 func UpdateReminder(c *gin.Context) {
 	id := c.Param("id")
 	var reminder models.Reminder
 	db := c.MustGet("db").(*gorm.DB)
-	if err := db.First(&reminder, id).Error; err != nil {
+	if err := db.Scopes(ownedThroughContact(c, "reminders.contact_id")).First(&reminder, id).Error; err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Reminder not found"})
 		return
 	}
 
 	var updatedReminder models.Reminder
 	if err := c.ShouldBindJSON(&updatedReminder); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondBindingError(c, err)
+		return
+	}
+	if fieldErrors := reminderFieldErrors(updatedReminder); len(fieldErrors) > 0 {
+		respondFieldErrors(c, fieldErrors)
 		return
 	}
+	before := reminder
+	if updatedReminder.ContactID != nil {
+		if err := db.Scopes(ownContacts(c)).First(&models.Contact{}, *updatedReminder.ContactID).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Contact not found"})
+			return
+		}
+		reminder.ContactID = updatedReminder.ContactID
+	}
 
 	// Updateable fields
 	reminder.Message = updatedReminder.Message
 	reminder.ByMail = updatedReminder.ByMail
 	reminder.RemindAt = updatedReminder.RemindAt
 	reminder.Recurrence = updatedReminder.Recurrence
+	reminder.RecurrenceInterval = updatedReminder.RecurrenceInterval
 	reminder.ReocurrFromCompletion = updatedReminder.ReocurrFromCompletion
-	reminder.ContactID = updatedReminder.ContactID
+	reminder.Completed = updatedReminder.Completed
+	reminder.ScheduleNextDue()
+	if reminder.Completed {
+		reminder.Status = models.ReminderDone
+	} else if reminder.Status == models.ReminderDone {
+		reminder.Status = models.ReminderPending
+	}
 
 	db.Updates(&reminder)
+	db.Model(&reminder).Update("next_due", reminder.NextDue) // Updates skips the next due date once it is cleared
+	recordAudit(c, db, reminderAudit(reminder, models.AuditUpdate), before, reminder)
 
 	c.JSON(http.StatusOK, gin.H{"message": "Reminder updated successfully", "reminder": reminder})
 }
 
+// CompleteReminder marks a one-off reminder as done and moves a recurring reminder to its next occurrence
+//
+//	@Summary	Complete a reminder
+//	@Tags		reminders
+//	@Produce	json
+//	@Param		id	path		int	true	"Reminder ID"
+//	@Success	200	{object}	object{message=string,reminder=models.Reminder}
+//	@Failure	404	{object}	errorResponse
+//	@Failure	500	{object}	errorResponse
+//	@Security	bearerauth
+//	@Router		/reminders/{id}/complete [post]
+func CompleteReminder(c *gin.Context) {
+	id := c.Param("id")
+	db := c.MustGet("db").(*gorm.DB)
+
+	var reminder models.Reminder
+	if err := db.Scopes(ownedThroughContact(c, "reminders.contact_id")).First(&reminder, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Reminder not found"})
+		return
+	}
+
+	before := reminder
+	reminder.Complete(time.Now())
+	if err := saveReminderSchedule(db, &reminder); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to complete reminder"})
+		return
+	}
+	recordAudit(c, db, reminderAudit(reminder, models.AuditUpdate), before, reminder)
+
+	c.JSON(http.StatusOK, gin.H{"message": "Reminder completed", "reminder": reminder})
+}
+
+// SnoozeReminder pushes the due date of a reminder forward, either by a number of days or to a given date.
+// Days are added to the due date, or to the current time if the reminder is already overdue.
+//
+//	@Summary	Snooze a reminder
+//	@Tags		reminders
+//	@Accept		json
+//	@Produce	json
+//	@Param		id		path		int									true	"Reminder ID"
+//	@Param		request	body		object{days=integer,until=string}	true	"Days to snooze or a date like 2006-01-02 or time like 2006-01-02T15:04:05Z"
+//	@Success	200		{object}	object{message=string,reminder=models.Reminder}
+//	@Failure	400		{object}	validationErrorResponse
+//	@Failure	404		{object}	errorResponse
+//	@Failure	500		{object}	errorResponse
+//	@Security	bearerauth
+//	@Router		/reminders/{id}/snooze [post]
+func SnoozeReminder(c *gin.Context, cfg *config.Config) {
+	id := c.Param("id")
+	db := c.MustGet("db").(*gorm.DB)
+
+	var request struct {
+		Days  int    `json:"days" binding:"min=0"`
+		Until string `json:"until"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		respondBindingError(c, err)
+		return
+	}
+	if (request.Days == 0) == (request.Until == "") {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Either days or until is required"})
+		return
+	}
+
+	var reminder models.Reminder
+	if err := db.Scopes(ownedThroughContact(c, "reminders.contact_id")).First(&reminder, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Reminder not found"})
+		return
+	}
+
+	now := time.Now().In(cfg.Timezone)
+	var until time.Time
+	if request.Days > 0 {
+		until = reminder.RemindAt
+		if until.Before(now) {
+			until = now
+		}
+		until = until.AddDate(0, 0, request.Days)
+	} else {
+		var err error
+		if until, err = parseSnoozeUntil(request.Until, reminder.RemindAt, cfg.Timezone); err != nil {
+			respondFieldErrors(c, map[string]fieldError{
+				"until": {Rule: "until", Message: "Must be a date like 2006-01-02 or a time like 2006-01-02T15:04:05Z", Value: request.Until},
+			})
+			return
+		}
+		if !until.After(now) {
+			respondFieldErrors(c, map[string]fieldError{
+				"until": {Rule: "until", Message: "Must be in the future", Value: request.Until},
+			})
+			return
+		}
+	}
+
+	before := reminder
+	reminder.Snooze(until)
+	if err := saveReminderSchedule(db, &reminder); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to snooze reminder"})
+		return
+	}
+	recordAudit(c, db, reminderAudit(reminder, models.AuditUpdate), before, reminder)
+
+	c.JSON(http.StatusOK, gin.H{"message": "Reminder snoozed", "reminder": reminder})
+}
+
+// parseSnoozeUntil accepts a full timestamp or a date, which keeps the time of day of the current due date
+func parseSnoozeUntil(value string, remindAt time.Time, location *time.Location) (time.Time, error) {
+	if until, err := time.Parse(time.RFC3339, value); err == nil {
+		return until, nil
+	}
+	date, err := time.ParseInLocation(models.DateFormat, value, location)
+	if err != nil {
+		return time.Time{}, err
+	}
+	remindAt = remindAt.In(location)
+	return time.Date(date.Year(), date.Month(), date.Day(), remindAt.Hour(), remindAt.Minute(), remindAt.Second(), 0, location), nil
+}
+
+// saveReminderSchedule stores the due dates and state of a reminder including cleared values
+func saveReminderSchedule(db *gorm.DB, reminder *models.Reminder) error {
+	return db.Model(reminder).Select("RemindAt", "NextDue", "Completed", "Status", "LastSent").Updates(reminder).Error
+}
+
+// DeleteReminder deletes a reminder
+//
+//	@Summary	Delete a reminder
+//	@Tags		reminders
+//	@Produce	json
+//	@Param		id	path		int	true	"Reminder ID"
+//	@Success	200	{object}	object{message=string}
+//	@Failure	404	{object}	errorResponse
+//	@Security	bearerauth
+//	@Router		/reminders/{id} [delete]
 func DeleteReminder(c *gin.Context) {
 	id := c.Param("id")
 	db := c.MustGet("db").(*gorm.DB)
-	if err := db.Delete(&models.Reminder{}, id).Error; err != nil {
+	var reminder models.Reminder
+	if err := db.Scopes(ownedThroughContact(c, "reminders.contact_id")).First(&reminder, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Reminder not found"})
+		return
+	}
+	if err := db.Delete(&reminder).Error; err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Reminder not found"})
 		return
 	}
+	recordAudit(c, db, reminderAudit(reminder, models.AuditDelete), reminder, nil)
 
 	c.JSON(http.StatusOK, gin.H{"message": "Reminder deleted"})
 }
 
+// GetRemindersForContact lists all reminders of a contact
+//
+//	@Summary	List the reminders of a contact
+//	@Tags		reminders
+//	@Produce	json
+//	@Param		id	path		int	true	"Contact ID"
+//	@Success	200	{object}	object{reminders=[]models.Reminder}
+//	@Failure	404	{object}	errorResponse
+//	@Failure	500	{object}	errorResponse
+//	@Security	bearerauth
+//	@Router		/contacts/{id}/reminders [get]
 func GetRemindersForContact(c *gin.Context) {
 	contactID := c.Param("id")
 
@@ -104,7 +333,7 @@ func GetRemindersForContact(c *gin.Context) {
 
 	var contact models.Contact
 
-	if err := db.Preload("Reminders").First(&contact, contactID).Error; err != nil {
+	if err := db.Scopes(ownContacts(c)).Preload("Reminders").First(&contact, contactID).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
 			c.JSON(http.StatusNotFound, gin.H{"error": "Contact not found"})
 		} else {
@@ -117,3 +346,197 @@ func GetRemindersForContact(c *gin.Context) {
 		"reminders": contact.Reminders,
 	})
 }
+
+// agendaReminder is a reminder together with the name of the contact it belongs to
+type agendaReminder struct {
+	models.Reminder
+	ContactName string `json:"contact_name"`
+}
+
+// GetReminders lists the reminders of all contacts, by default ordered by their due date starting with the earliest.
+// The reminders can be filtered by their status and by a due date before the given day.
+//
+//	@Summary	List reminders
+//	@Tags		reminders
+//	@Produce	json
+//	@Param		page		query		int		false	"Page number"					default(1)
+//	@Param		limit		query		int		false	"Items per page, up to the configured maximum"
+//	@Param		order		query		string	false	"Order of the due date"			Enums(asc, desc)	default(asc)
+//	@Param		status		query		string	false	"Status of the reminders"		Enums(pending, done, snoozed)
+//	@Param		due_before	query		string	false	"Only reminders due before the day, YYYY-MM-DD"
+//	@Success	200			{object}	agendaReminderPage
+//	@Failure	400			{object}	errorResponse
+//	@Failure	500			{object}	errorResponse
+//	@Security	bearerauth
+//	@Router		/reminders [get]
+func GetReminders(c *gin.Context, cfg *config.Config) {
+	db := c.MustGet("db").(*gorm.DB)
+
+	// Get pagination parameters
+	page, limit, offset := paginationParams(c, cfg)
+
+	order := c.DefaultQuery("order", "asc")
+	if order != "asc" && order != "desc" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid order, use asc or desc"})
+		return
+	}
+
+	query := db.Model(&models.Reminder{}).
+		Joins("JOIN contacts ON contacts.id = reminders.contact_id AND contacts.deleted_at IS NULL").
+		Scopes(ownContacts(c))
+
+	if status := c.Query("status"); status != "" {
+		if status != models.ReminderPending && status != models.ReminderDone && status != models.ReminderSnoozed {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid status, use pending, done or snoozed"})
+			return
+		}
+		query = query.Where("reminders.status = ?", status)
+	}
+
+	if dueBefore := c.Query("due_before"); dueBefore != "" {
+		date, err := time.ParseInLocation(models.DateFormat, dueBefore, cfg.Timezone)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid due_before date, use YYYY-MM-DD"})
+			return
+		}
+		query = query.Where("reminders.remind_at < ?", date.UTC())
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve reminders"})
+		return
+	}
+
+	reminders := []agendaReminder{}
+	if err := query.Select("reminders.*, TRIM(contacts.firstname || ' ' || COALESCE(contacts.lastname, '')) AS contact_name").
+		Order("reminders.remind_at " + order).Order("reminders.id").
+		Limit(limit).Offset(offset).
+		Scan(&reminders).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve reminders"})
+		return
+	}
+
+	c.JSON(http.StatusOK, paginatedResponse("reminders", reminders, total, page, limit))
+}
+
+// upcomingReminderGroup bundles the upcoming reminders of a single contact
+type upcomingReminderGroup struct {
+	ContactID uint              `json:"contact_id"`
+	Firstname string            `json:"firstname"`
+	Lastname  string            `json:"lastname"`
+	Reminders []models.Reminder `json:"reminders"`
+}
+
+// GetUpcomingReminders returns all open reminders due within the next days across all contacts, grouped by contact
+//
+//	@Summary	List upcoming reminders grouped by contact
+//	@Tags		reminders
+//	@Produce	json
+//	@Param		days	query		int	false	"Days to look ahead"			default(14)
+//	@Param		page	query		int	false	"Page number"					default(1)
+//	@Param		limit	query		int	false	"Items per page, up to the configured maximum"
+//	@Success	200		{object}	upcomingReminderPage
+//	@Failure	400		{object}	errorResponse
+//	@Failure	500		{object}	errorResponse
+//	@Security	bearerauth
+//	@Router		/reminders/upcoming [get]
+func GetUpcomingReminders(c *gin.Context, cfg *config.Config) {
+	db := c.MustGet("db").(*gorm.DB)
+
+	days, err := strconv.Atoi(c.DefaultQuery("days", "14"))
+	if err != nil || days < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid number of days"})
+		return
+	}
+
+	// Get pagination parameters
+	page, limit, offset := paginationParams(c, cfg)
+
+	// The window starts at the beginning of today in the configured timezone
+	now := time.Now().In(cfg.Timezone)
+	windowStart := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, cfg.Timezone)
+	windowEnd := windowStart.AddDate(0, 0, days+1)
+
+	query := db.Model(&models.Reminder{}).
+		InnerJoins("Contact").
+		Where("Contact.user_id = ?", currentUserID(c)).
+		Where("reminders.remind_at >= ? AND reminders.remind_at < ?", windowStart.UTC(), windowEnd.UTC()).
+		Where("reminders.completed = ?", false)
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve reminders"})
+		return
+	}
+
+	var reminders []models.Reminder
+	if err := query.Order("reminders.remind_at ASC").Limit(limit).Offset(offset).Find(&reminders).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve reminders"})
+		return
+	}
+
+	// Group by contact, keeping the groups ordered by their earliest due reminder
+	groups := []upcomingReminderGroup{}
+	groupIndex := map[uint]int{}
+	for _, reminder := range reminders {
+		contact := reminder.Contact
+		reminder.Contact = models.Contact{}
+
+		index, exists := groupIndex[contact.ID]
+		if !exists {
+			index = len(groups)
+			groupIndex[contact.ID] = index
+			groups = append(groups, upcomingReminderGroup{
+				ContactID: contact.ID,
+				Firstname: contact.Firstname,
+				Lastname:  contact.Lastname,
+			})
+		}
+		groups[index].Reminders = append(groups[index].Reminders, reminder)
+	}
+
+	c.JSON(http.StatusOK, paginatedResponse("contacts", groups, total, page, limit))
+}
+
+// GetOverdueReminders returns all open reminders which are overdue, taking the configured grace window into account
+//
+//	@Summary	List overdue reminders
+//	@Tags		reminders
+//	@Produce	json
+//	@Param		page	query		int	false	"Page number"					default(1)
+//	@Param		limit	query		int	false	"Items per page, up to the configured maximum"
+//	@Success	200		{object}	overdueReminderPage
+//	@Failure	500		{object}	errorResponse
+//	@Security	bearerauth
+//	@Router		/reminders/overdue [get]
+func GetOverdueReminders(c *gin.Context, cfg *config.Config) {
+	db := c.MustGet("db").(*gorm.DB)
+
+	// Get pagination parameters
+	page, limit, offset := paginationParams(c, cfg)
+
+	cutoff := models.OverdueCutoff(time.Now().In(cfg.Timezone), cfg.OverdueGraceDays)
+
+	query := db.Model(&models.Reminder{}).
+		InnerJoins("Contact").
+		Where("Contact.user_id = ?", currentUserID(c)).
+		Where("reminders.remind_at < ?", cutoff.UTC()).
+		Where("reminders.completed = ?", false)
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve reminders"})
+		return
+	}
+
+	var reminders []models.Reminder
+	if err := query.Order("reminders.remind_at ASC").Limit(limit).Offset(offset).Find(&reminders).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve reminders"})
+		return
+	}
+
+	response := paginatedResponse("reminders", reminders, total, page, limit)
+	response["grace_days"] = cfg.OverdueGraceDays
+	c.JSON(http.StatusOK, response)
+}
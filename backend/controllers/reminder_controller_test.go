@@ -6,11 +6,13 @@ import (
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"perema/config"
 	"perema/models"
 	"strconv"
 	"testing"
 	"time"
 
+	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -41,11 +43,13 @@ func TestCreateReminder(t *testing.T) {
 	w := httptest.NewRecorder()
 	router.ServeHTTP(w, req)
 
-	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, http.StatusCreated, w.Code)
 
 	var responseBody map[string]any
 	json.Unmarshal(w.Body.Bytes(), &responseBody)
 	assert.Equal(t, "Reminder created successfully", responseBody["message"])
+	id := int(responseBody["reminder"].(map[string]any)["ID"].(float64))
+	assert.Equal(t, "/api/reminders/"+strconv.Itoa(id), w.Header().Get("Location"))
 }
 
 func TestGetReminder(t *testing.T) {
@@ -131,8 +135,12 @@ func TestDeleteReminder(t *testing.T) {
 	db, router := setupRouter()
 	router.DELETE("/reminders/:id", DeleteReminder)
 
+	contact := models.Contact{Firstname: "Joan"}
+	db.Create(&contact)
+
 	// Create a reminder
 	reminder := models.Reminder{
+		ContactID:             &contact.ID,
 		Message:               "Wish happy birthday to Joan",
 		ByMail:                true,
 		RemindAt:              time.Date(2025, 05, 22, 12, 0, 0, 0, time.UTC), // Fixed date
@@ -200,3 +208,302 @@ func TestGetRemindersForContact(t *testing.T) {
 	json.Unmarshal(w.Body.Bytes(), &responseBody)
 	assert.Len(t, responseBody["reminders"], 2) // Should return both reminders for the contact
 }
+
+func TestGetUpcomingReminders(t *testing.T) {
+	db, router := setupRouter()
+	cfg := &config.Config{Timezone: time.UTC, DefaultPageLimit: 25, MaxPageLimit: 100}
+	router.GET("/reminders/upcoming", func(c *gin.Context) {
+		GetUpcomingReminders(c, cfg)
+	})
+
+	alice := models.Contact{Firstname: "Alice", Lastname: "Johnson"}
+	bob := models.Contact{Firstname: "Bob", Lastname: "Smith"}
+	db.Create(&alice)
+	db.Create(&bob)
+
+	reminders := []models.Reminder{
+		{Message: "Call Bob", RemindAt: time.Now().Add(48 * time.Hour), Recurrence: "Once", ContactID: &bob.ID},
+		{Message: "Call Alice", RemindAt: time.Now().Add(72 * time.Hour), Recurrence: "Once", ContactID: &alice.ID},
+		{Message: "Lunch with Bob", RemindAt: time.Now().Add(96 * time.Hour), Recurrence: "Once", ContactID: &bob.ID},
+		{Message: "Too far away", RemindAt: time.Now().Add(20 * 24 * time.Hour), Recurrence: "Once", ContactID: &alice.ID},
+		{Message: "Already done", RemindAt: time.Now().Add(24 * time.Hour), Recurrence: "Once", Completed: true, ContactID: &alice.ID},
+	}
+	for i := range reminders {
+		db.Create(&reminders[i])
+	}
+
+	req, _ := http.NewRequest("GET", "/reminders/upcoming?days=14", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var responseBody struct {
+		Contacts []upcomingReminderGroup `json:"contacts"`
+		Total    int64                   `json:"total"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &responseBody)
+	assert.Equal(t, int64(3), responseBody.Total)
+	assert.Len(t, responseBody.Contacts, 2)
+	assert.Equal(t, "Bob", responseBody.Contacts[0].Firstname)
+	assert.Len(t, responseBody.Contacts[0].Reminders, 2)
+	assert.Equal(t, "Call Bob", responseBody.Contacts[0].Reminders[0].Message)
+	assert.Equal(t, "Alice", responseBody.Contacts[1].Firstname)
+}
+
+func TestGetOverdueReminders(t *testing.T) {
+	db, router := setupRouter()
+	cfg := &config.Config{Timezone: time.UTC, OverdueGraceDays: 2, DefaultPageLimit: 25, MaxPageLimit: 100}
+	router.GET("/reminders/overdue", func(c *gin.Context) {
+		GetOverdueReminders(c, cfg)
+	})
+
+	contact := models.Contact{Firstname: "Alice"}
+	db.Create(&contact)
+
+	startOfToday := time.Now().UTC().Truncate(24 * time.Hour)
+	reminders := []models.Reminder{
+		{Message: "Within grace", RemindAt: startOfToday.AddDate(0, 0, -2), Recurrence: "Once", ContactID: &contact.ID},
+		{Message: "Just overdue", RemindAt: startOfToday.AddDate(0, 0, -2).Add(-time.Minute), Recurrence: "Once", ContactID: &contact.ID},
+		{Message: "Long overdue", RemindAt: startOfToday.AddDate(0, 0, -10), Recurrence: "Once", ContactID: &contact.ID},
+		{Message: "Done", RemindAt: startOfToday.AddDate(0, 0, -10), Recurrence: "Once", Completed: true, ContactID: &contact.ID},
+	}
+	for i := range reminders {
+		db.Create(&reminders[i])
+	}
+
+	req, _ := http.NewRequest("GET", "/reminders/overdue", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var responseBody struct {
+		Reminders []models.Reminder `json:"reminders"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &responseBody)
+	assert.Len(t, responseBody.Reminders, 2)
+	assert.Equal(t, "Long overdue", responseBody.Reminders[0].Message)
+	assert.Equal(t, "Just overdue", responseBody.Reminders[1].Message)
+
+	// Without a grace window everything due before today is overdue
+	cfg.OverdueGraceDays = 0
+	req, _ = http.NewRequest("GET", "/reminders/overdue", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	json.Unmarshal(w.Body.Bytes(), &responseBody)
+	assert.Len(t, responseBody.Reminders, 3)
+}
+
+func TestReminderRecurrence(t *testing.T) {
+	db, router := setupRouter()
+	router.POST("/contacts/:id/reminders", CreateReminder)
+	router.PUT("/reminders/:id", UpdateReminder)
+
+	contact := models.Contact{Firstname: "Mom"}
+	db.Create(&contact)
+
+	send := func(method, url, body string) (int, map[string]any) {
+		req, _ := http.NewRequest(method, url, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		var responseBody map[string]any
+		json.Unmarshal(w.Body.Bytes(), &responseBody)
+		return w.Code, responseBody
+	}
+
+	// Every two weeks
+	code, responseBody := send("POST", "/contacts/"+strconv.Itoa(int(contact.ID))+"/reminders",
+		`{"message": "Call mom", "remind_at": "2024-03-01T10:00:00Z", "recurrence": "Weekly", "recurrence_interval": 2}`)
+	assert.Equal(t, http.StatusCreated, code)
+	reminder := responseBody["reminder"].(map[string]any)
+	assert.Equal(t, "2024-03-15T10:00:00Z", reminder["next_due"])
+
+	// Reminders which do not recur have no next due date
+	id := strconv.Itoa(int(reminder["ID"].(float64)))
+	code, responseBody = send("PUT", "/reminders/"+id, `{"message": "Call mom", "remind_at": "2024-03-01T10:00:00Z", "recurrence": "Once"}`)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Nil(t, responseBody["reminder"].(map[string]any)["next_due"])
+
+	var stored models.Reminder
+	db.First(&stored, id)
+	assert.Nil(t, stored.NextDue)
+
+	// Unknown recurrence types and negative intervals are rejected
+	code, responseBody = send("POST", "/contacts/"+strconv.Itoa(int(contact.ID))+"/reminders",
+		`{"message": "Call mom", "remind_at": "2024-03-01T10:00:00Z", "recurrence": "Fortnightly", "recurrence_interval": -1}`)
+	assert.Equal(t, http.StatusBadRequest, code)
+	fields := responseBody["fields"].(map[string]any)
+	assert.Contains(t, fields, "recurrence")
+	assert.Contains(t, fields, "recurrence_interval")
+
+	code, _ = send("PUT", "/reminders/"+id, `{"message": "Call mom", "remind_at": "2024-03-01T10:00:00Z", "recurrence": "Fortnightly"}`)
+	assert.Equal(t, http.StatusBadRequest, code)
+}
+
+func TestCompleteReminder(t *testing.T) {
+	db, router := setupRouter()
+	router.POST("/reminders/:id/complete", CompleteReminder)
+
+	contact := models.Contact{Firstname: "Mom"}
+	db.Create(&contact)
+
+	dueAt := time.Date(2024, time.March, 1, 10, 0, 0, 0, time.UTC)
+	once := models.Reminder{Message: "Buy flowers", RemindAt: dueAt, Recurrence: "Once", ContactID: &contact.ID}
+	monthly := models.Reminder{Message: "Call mom", RemindAt: dueAt, Recurrence: "Monthly", ContactID: &contact.ID}
+	db.Create(&once)
+	db.Create(&monthly)
+	db.Model(&monthly).Update("reocurr_from_completion", false)
+
+	complete := func(id uint) models.Reminder {
+		req, _ := http.NewRequest("POST", "/reminders/"+strconv.Itoa(int(id))+"/complete", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		var responseBody struct {
+			Reminder models.Reminder `json:"reminder"`
+		}
+		json.Unmarshal(w.Body.Bytes(), &responseBody)
+		return responseBody.Reminder
+	}
+
+	// One-off reminders are done
+	reminder := complete(once.ID)
+	assert.True(t, reminder.Completed)
+	assert.Equal(t, models.ReminderDone, reminder.Status)
+
+	// Recurring reminders move on to their next occurrence
+	reminder = complete(monthly.ID)
+	assert.False(t, reminder.Completed)
+	assert.Equal(t, models.ReminderPending, reminder.Status)
+	assert.True(t, dueAt.AddDate(0, 1, 0).Equal(reminder.RemindAt))
+
+	var stored models.Reminder
+	db.First(&stored, monthly.ID)
+	assert.True(t, dueAt.AddDate(0, 1, 0).Equal(stored.RemindAt))
+	assert.True(t, dueAt.AddDate(0, 2, 0).Equal(*stored.NextDue))
+
+	req, _ := http.NewRequest("POST", "/reminders/999/complete", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestSnoozeReminder(t *testing.T) {
+	db, router := setupRouter()
+	router.POST("/reminders/:id/snooze", func(c *gin.Context) {
+		SnoozeReminder(c, &config.Config{Timezone: time.UTC})
+	})
+
+	contact := models.Contact{Firstname: "Bob"}
+	db.Create(&contact)
+
+	dueAt := time.Now().UTC().Add(48 * time.Hour).Truncate(time.Second)
+	reminder := models.Reminder{Message: "Call Bob", RemindAt: dueAt, Recurrence: "Once", ContactID: &contact.ID}
+	db.Create(&reminder)
+	url := "/reminders/" + strconv.Itoa(int(reminder.ID)) + "/snooze"
+
+	snooze := func(body string) (int, models.Reminder) {
+		req, _ := http.NewRequest("POST", url, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		var responseBody struct {
+			Reminder models.Reminder `json:"reminder"`
+		}
+		json.Unmarshal(w.Body.Bytes(), &responseBody)
+		return w.Code, responseBody.Reminder
+	}
+
+	code, snoozed := snooze(`{"days": 3}`)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, models.ReminderSnoozed, snoozed.Status)
+	assert.True(t, dueAt.AddDate(0, 0, 3).Equal(snoozed.RemindAt))
+
+	// A date keeps the time of day of the due date
+	until := dueAt.AddDate(0, 0, 10)
+	code, snoozed = snooze(`{"until": "` + until.Format(models.DateFormat) + `"}`)
+	assert.Equal(t, http.StatusOK, code)
+	assert.True(t, until.Equal(snoozed.RemindAt))
+
+	code, _ = snooze(`{"until": "2000-01-01"}`)
+	assert.Equal(t, http.StatusBadRequest, code)
+	code, _ = snooze(`{"until": "tomorrow"}`)
+	assert.Equal(t, http.StatusBadRequest, code)
+	code, _ = snooze(`{}`)
+	assert.Equal(t, http.StatusBadRequest, code)
+	code, _ = snooze(`{"days": 1, "until": "2030-01-01"}`)
+	assert.Equal(t, http.StatusBadRequest, code)
+
+	var stored models.Reminder
+	db.First(&stored, reminder.ID)
+	assert.Equal(t, models.ReminderSnoozed, stored.Status)
+	assert.True(t, until.Equal(stored.RemindAt))
+}
+
+func TestGetReminders(t *testing.T) {
+	db, router := setupRouter()
+	router.GET("/reminders", func(c *gin.Context) {
+		GetReminders(c, &config.Config{Timezone: time.UTC, DefaultPageLimit: 25, MaxPageLimit: 100})
+	})
+
+	alice := models.Contact{Firstname: "Alice", Lastname: "Smith"}
+	bob := models.Contact{Firstname: "Bob"}
+	gone := models.Contact{Firstname: "Gone"}
+	db.Create(&alice)
+	db.Create(&bob)
+	db.Create(&gone)
+
+	dueAt := time.Date(2024, time.March, 1, 10, 0, 0, 0, time.UTC)
+	db.Create(&models.Reminder{Message: "Later", RemindAt: dueAt.AddDate(0, 0, 5), Recurrence: "Once", ContactID: &alice.ID})
+	db.Create(&models.Reminder{Message: "First", RemindAt: dueAt, Recurrence: "Once", ContactID: &bob.ID})
+	db.Create(&models.Reminder{Message: "Done", RemindAt: dueAt.AddDate(0, 0, 1), Recurrence: "Once", ContactID: &bob.ID, Completed: true, Status: models.ReminderDone})
+	db.Create(&models.Reminder{Message: "Of deleted contact", RemindAt: dueAt, Recurrence: "Once", ContactID: &gone.ID})
+	deleted := models.Reminder{Message: "Deleted", RemindAt: dueAt, Recurrence: "Once", ContactID: &alice.ID}
+	db.Create(&deleted)
+	db.Delete(&deleted)
+	db.Delete(&gone)
+
+	get := func(query string) (int, []agendaReminder, int64) {
+		req, _ := http.NewRequest("GET", "/reminders"+query, nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		var responseBody struct {
+			Reminders []agendaReminder `json:"reminders"`
+			Total     int64            `json:"total"`
+		}
+		json.Unmarshal(w.Body.Bytes(), &responseBody)
+		return w.Code, responseBody.Reminders, responseBody.Total
+	}
+
+	code, reminders, total := get("")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, int64(3), total)
+	assert.Equal(t, "First", reminders[0].Message)
+	assert.Equal(t, "Bob", reminders[0].ContactName)
+	assert.Equal(t, "Later", reminders[2].Message)
+	assert.Equal(t, "Alice Smith", reminders[2].ContactName)
+
+	_, reminders, _ = get("?order=desc&limit=1")
+	assert.Len(t, reminders, 1)
+	assert.Equal(t, "Later", reminders[0].Message)
+
+	_, reminders, total = get("?status=pending&due_before=2024-03-06")
+	assert.Equal(t, int64(1), total)
+	assert.Equal(t, "First", reminders[0].Message)
+
+	_, reminders, _ = get("?status=done")
+	assert.Len(t, reminders, 1)
+	assert.Equal(t, "Done", reminders[0].Message)
+
+	code, _, _ = get("?status=unknown")
+	assert.Equal(t, http.StatusBadRequest, code)
+	code, _, _ = get("?due_before=March")
+	assert.Equal(t, http.StatusBadRequest, code)
+}
@@ -0,0 +1,50 @@
+package controllers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// respondCreated responds with 201 Created and the body, the Location header points at the new resource
+// given by its path below the API, e.g. /contacts/1
+func respondCreated(c *gin.Context, path string, body any) {
+	c.Header("Location", "/api"+path)
+	c.JSON(http.StatusCreated, body)
+}
+
+// respondJSONWithETag responds with the body and an ETag derived from its JSON. If the client already has this
+// representation, as told by If-None-Match, only 304 Not Modified is sent.
+func respondJSONWithETag(c *gin.Context, body any) {
+	data, err := json.Marshal(body)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to encode response"})
+		return
+	}
+	hash := sha256.Sum256(data)
+	etag := `"` + hex.EncodeToString(hash[:16]) + `"`
+
+	// Clients may cache the response but have to revalidate it, as it depends on the authenticated user
+	c.Header("ETag", etag)
+	c.Header("Cache-Control", "private, no-cache")
+	if etagMatches(c.GetHeader("If-None-Match"), etag) {
+		c.Status(http.StatusNotModified)
+		return
+	}
+	c.Data(http.StatusOK, "application/json; charset=utf-8", data)
+}
+
+// etagMatches reports whether the If-None-Match header contains the ETag, weak ETags are compared by their value
+func etagMatches(ifNoneMatch, etag string) bool {
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == "*" || candidate == etag {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,156 @@
+package controllers
+
+import (
+	"fmt"
+	"net/http"
+	"perema/config"
+	"perema/models"
+	"perema/services"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// Number of characters shown around a match in a snippet
+const snippetRadius = 40
+
+// textMatch is a piece of text belonging to a contact which matches a search term
+type textMatch struct {
+	ContactID uint
+	Source    string // contact, note or activity
+	SourceID  uint
+	Text      string
+}
+
+// textSearcher finds texts of the contacts of a user matching a search term. The LIKE based implementation can be replaced by
+// one using an FTS5 virtual table without changing the search endpoint.
+type textSearcher interface {
+	Search(db *gorm.DB, userID uint, term string) ([]textMatch, error)
+}
+
+// likeSearcher searches contact names, notes and activities with parameterized LIKE conditions
+type likeSearcher struct{}
+
+func (likeSearcher) Search(db *gorm.DB, userID uint, term string) ([]textMatch, error) {
+	pattern := "%" + term + "%"
+	like := services.Dialect(db).Like()
+
+	var matches []textMatch
+	err := db.Raw(fmt.Sprintf(`
+		SELECT id AS contact_id, 'contact' AS source, id AS source_id, TRIM(firstname || ' ' || COALESCE(lastname, '') || ' ' || COALESCE(nickname, '')) AS text
+		FROM contacts
+		WHERE deleted_at IS NULL AND user_id = @user AND (firstname %[1]s @pattern OR lastname %[1]s @pattern OR nickname %[1]s @pattern)
+		UNION ALL
+		SELECT notes.contact_id, 'note', notes.id, notes.content
+		FROM notes JOIN contacts ON contacts.id = notes.contact_id AND contacts.deleted_at IS NULL AND contacts.user_id = @user
+		WHERE notes.deleted_at IS NULL AND notes.content %[1]s @pattern
+		UNION ALL
+		SELECT activity_contacts.contact_id, 'activity', activities.id, activities.title || ': ' || COALESCE(activities.description, '')
+		FROM activities
+		JOIN activity_contacts ON activity_contacts.activity_id = activities.id
+		JOIN contacts ON contacts.id = activity_contacts.contact_id AND contacts.deleted_at IS NULL AND contacts.user_id = @user
+		WHERE activities.deleted_at IS NULL AND (activities.title %[1]s @pattern OR activities.description %[1]s @pattern)`, like),
+		map[string]any{"pattern": pattern, "user": userID}).Scan(&matches).Error
+	return matches, err
+}
+
+var searcher textSearcher = likeSearcher{}
+
+type searchMatch struct {
+	Source  string `json:"source"`
+	ID      uint   `json:"id"`
+	Snippet string `json:"snippet"`
+}
+
+type searchResult struct {
+	Contact models.Contact `json:"contact"`
+	Matches []searchMatch  `json:"matches"`
+}
+
+// Search finds contacts by their names or the content of their notes and activities.
+// Every contact is returned once together with snippets of all matching texts.
+func Search(c *gin.Context, cfg *config.Config) {
+	db := c.MustGet("db").(*gorm.DB)
+
+	term := strings.TrimSpace(c.Query("q"))
+	if term == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Search term q is required"})
+		return
+	}
+
+	requested, _ := strconv.Atoi(c.Query("limit"))
+	limit := pageLimit(requested, cfg)
+
+	matches, err := searcher.Search(db, currentUserID(c), term)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to search"})
+		return
+	}
+
+	// Group the matches by contact in the order the contacts were first found
+	var contactIDs []uint
+	matchesByContact := map[uint][]searchMatch{}
+	for _, match := range matches {
+		if _, exists := matchesByContact[match.ContactID]; !exists {
+			contactIDs = append(contactIDs, match.ContactID)
+		}
+		matchesByContact[match.ContactID] = append(matchesByContact[match.ContactID], searchMatch{
+			Source:  match.Source,
+			ID:      match.SourceID,
+			Snippet: searchSnippet(match.Text, term),
+		})
+	}
+
+	total := len(contactIDs)
+	if len(contactIDs) > limit {
+		contactIDs = contactIDs[:limit]
+	}
+
+	var contacts []models.Contact
+	if err := db.Scopes(ownContacts(c)).Select("ID", "Firstname", "Lastname", "Nickname", "Photo", "PhotoThumbnail").Find(&contacts, contactIDs).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve contacts"})
+		return
+	}
+	contactsByID := map[uint]models.Contact{}
+	for _, contact := range contacts {
+		contactsByID[contact.ID] = contact
+	}
+
+	results := []searchResult{}
+	for _, id := range contactIDs {
+		results = append(results, searchResult{Contact: contactsByID[id], Matches: matchesByContact[id]})
+	}
+
+	c.JSON(http.StatusOK, gin.H{"results": results, "total": total})
+}
+
+// searchSnippet cuts the text around the first case-insensitive occurrence of the term
+func searchSnippet(text, term string) string {
+	runes := []rune(text)
+	termLength := len([]rune(term))
+
+	position := -1
+	for i := 0; i+termLength <= len(runes); i++ {
+		if strings.EqualFold(string(runes[i:i+termLength]), term) {
+			position = i
+			break
+		}
+	}
+	if position < 0 {
+		position = 0
+	}
+
+	start := max(position-snippetRadius, 0)
+	end := min(position+termLength+snippetRadius, len(runes))
+
+	snippet := strings.TrimSpace(string(runes[start:end]))
+	if start > 0 {
+		snippet = "…" + snippet
+	}
+	if end < len(runes) {
+		snippet += "…"
+	}
+	return snippet
+}
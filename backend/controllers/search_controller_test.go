@@ -0,0 +1,82 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"perema/config"
+	"perema/models"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSearch(t *testing.T) {
+	db, router := setupRouter()
+
+	router.GET("/search", func(c *gin.Context) {
+		Search(c, &config.Config{DefaultPageLimit: 25, MaxPageLimit: 100})
+	})
+
+	berlin := models.Contact{Firstname: "Berlinda", Lastname: "Meyer"}
+	anna := models.Contact{Firstname: "Anna", Lastname: "Schmidt"}
+	tom := models.Contact{Firstname: "Tom"}
+	trashed := models.Contact{Firstname: "Trashed"}
+	db.Create(&berlin)
+	db.Create(&anna)
+	db.Create(&tom)
+	db.Create(&trashed)
+
+	db.Create(&models.Note{Content: "We first met at the Berlin conference on distributed systems back in 2019.", Date: time.Now(), ContactID: &anna.ID})
+	db.Create(&models.Note{Content: "Moved to berlin", Date: time.Now(), ContactID: &anna.ID})
+	db.Create(&models.Note{Content: "Berlin trip", Date: time.Now(), ContactID: &trashed.ID})
+	db.Create(&models.Activity{Title: "Dinner", Description: "Flight to Berlin got cancelled", Date: time.Now(), Contacts: []models.Contact{tom, berlin}})
+	db.Delete(&trashed)
+
+	search := func(term string) (int, []searchResult) {
+		req, _ := http.NewRequest("GET", "/search?q="+url.QueryEscape(term), nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		var responseBody struct {
+			Results []searchResult `json:"results"`
+		}
+		json.Unmarshal(w.Body.Bytes(), &responseBody)
+		return w.Code, responseBody.Results
+	}
+
+	code, results := search("berlin")
+	assert.Equal(t, http.StatusOK, code)
+
+	// Every contact appears once, name matches first, trashed contacts are excluded
+	assert.Len(t, results, 3)
+	assert.Equal(t, "Berlinda", results[0].Contact.Firstname)
+	assert.Len(t, results[0].Matches, 2) // Name and activity
+	assert.Equal(t, "contact", results[0].Matches[0].Source)
+	assert.Equal(t, "activity", results[0].Matches[1].Source)
+
+	assert.Equal(t, "Anna", results[1].Contact.Firstname)
+	assert.Len(t, results[1].Matches, 2)
+	assert.Equal(t, "note", results[1].Matches[0].Source)
+	assert.Equal(t, "We first met at the Berlin conference on distributed systems back…", results[1].Matches[0].Snippet)
+
+	assert.Equal(t, "Tom", results[2].Contact.Firstname)
+	assert.Equal(t, "Dinner: Flight to Berlin got cancelled", results[2].Matches[0].Snippet)
+
+	code, results = search("Berlin conference")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Len(t, results, 1)
+
+	code, _ = search("")
+	assert.Equal(t, http.StatusBadRequest, code)
+}
+
+func TestSearchSnippet(t *testing.T) {
+	text := "This is a long note. In the middle of it we talked about the upcoming Gift for her birthday and many other things."
+
+	assert.Equal(t, "…In the middle of it we talked about the upcoming Gift for her birthday and many other things.", searchSnippet(text, "upcoming gift"))
+	assert.Equal(t, "Short", searchSnippet("Short", "short"))
+}
@@ -0,0 +1,69 @@
+package controllers
+
+import (
+	"net/http"
+	"perema/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// relationshipTypeCount is the number of relationships of a single type
+type relationshipTypeCount struct {
+	Type  string `json:"type"`
+	Count int64  `json:"count"`
+}
+
+// GetStats returns an overview of the data stored for the dashboard
+func GetStats(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+
+	var contacts, notes, activities, reminders, contactsWithoutRelationships int64
+	counts := []struct {
+		model any
+		scope func(*gorm.DB) *gorm.DB
+		total *int64
+	}{
+		{&models.Contact{}, ownContacts(c), &contacts},
+		{&models.Note{}, ownedBy(c, "notes"), &notes},
+		{&models.Activity{}, ownedBy(c, "activities"), &activities},
+		{&models.Reminder{}, ownedThroughContact(c, "reminders.contact_id"), &reminders},
+	}
+	for _, count := range counts {
+		if err := db.Model(count.model).Scopes(count.scope).Count(count.total).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve statistics"})
+			return
+		}
+	}
+
+	// Relationship types are free text, so group them case-insensitively
+	relationshipTypes := []relationshipTypeCount{}
+	if err := db.Model(&models.Relationship{}).
+		Scopes(ownedThroughContact(c, "relationships.contact_id")).
+		Select("LOWER(TRIM(type)) AS type, COUNT(*) AS count").
+		Group("LOWER(TRIM(type))").
+		Order("count DESC, type ASC").
+		Scan(&relationshipTypes).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve statistics"})
+		return
+	}
+
+	// Contacts which neither have a relationship nor are linked by one
+	if err := db.Model(&models.Contact{}).
+		Scopes(ownContacts(c)).
+		Where(`NOT EXISTS (SELECT 1 FROM relationships WHERE relationships.deleted_at IS NULL
+		       AND (relationships.contact_id = contacts.id OR relationships.related_contact_id = contacts.id))`).
+		Count(&contactsWithoutRelationships).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve statistics"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"contacts":                       contacts,
+		"notes":                          notes,
+		"activities":                     activities,
+		"reminders":                      reminders,
+		"relationship_types":             relationshipTypes,
+		"contacts_without_relationships": contactsWithoutRelationships,
+	})
+}
@@ -0,0 +1,50 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"perema/models"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetStats(t *testing.T) {
+	db, router := setupRouter()
+	router.GET("/stats", GetStats)
+
+	alice := models.Contact{Firstname: "Alice"}
+	bob := models.Contact{Firstname: "Bob"}
+	carol := models.Contact{Firstname: "Carol"}
+	dave := models.Contact{Firstname: "Dave"}
+	db.Create(&alice)
+	db.Create(&bob)
+	db.Create(&carol)
+	db.Create(&dave)
+
+	relationships := []models.Relationship{
+		{Name: "Tom", Type: "Spouse", ContactID: alice.ID},
+		{Name: "Bob", Type: "spouse ", ContactID: carol.ID, RelatedContactID: &bob.ID},
+		{Name: "Lisa", Type: "Child", ContactID: alice.ID},
+	}
+	for i := range relationships {
+		db.Create(&relationships[i])
+	}
+
+	req, _ := http.NewRequest("GET", "/stats", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var responseBody struct {
+		Contacts                     int64                   `json:"contacts"`
+		RelationshipTypes            []relationshipTypeCount `json:"relationship_types"`
+		ContactsWithoutRelationships int64                   `json:"contacts_without_relationships"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &responseBody)
+	assert.Equal(t, int64(4), responseBody.Contacts)
+	assert.Equal(t, []relationshipTypeCount{{Type: "spouse", Count: 2}, {Type: "child", Count: 1}}, responseBody.RelationshipTypes)
+	assert.Equal(t, int64(1), responseBody.ContactsWithoutRelationships) // Only Dave
+}
@@ -3,6 +3,7 @@ package controllers
 import (
 	"net/http"
 	"perema/config"
+	"perema/middleware"
 	"perema/models"
 	"perema/services"
 
@@ -11,7 +12,23 @@ import (
 	"gorm.io/gorm"
 )
 
-func RegisterUser(context *gin.Context) {
+// RegisterUser creates a user account. Once the first user exists, further users can only register
+// if registration is allowed in the configuration.
+func RegisterUser(context *gin.Context, cfg *config.Config) {
+	db := context.MustGet("db").(*gorm.DB)
+
+	if !cfg.AllowRegistration {
+		var users int64
+		if err := db.Model(&models.User{}).Count(&users).Error; err != nil {
+			context.JSON(http.StatusInternalServerError, gin.H{"error": "Could not check users"})
+			return
+		}
+		if users > 0 {
+			context.JSON(http.StatusForbidden, gin.H{"error": "Registration is disabled"})
+			return
+		}
+	}
+
 	var user models.User
 	err := context.ShouldBindJSON(&user)
 
@@ -27,12 +44,16 @@ func RegisterUser(context *gin.Context) {
 	}
 	user.Password = hashedPassword
 
-	db := context.MustGet("db").(*gorm.DB)
 	if err := db.Create(&user).Error; err != nil {
 		context.JSON(http.StatusConflict, gin.H{"error": "User already exists"})
 		return
 	}
 
+	// The first user takes over the data stored before registration
+	if err := services.AssignOwnerlessRecords(db); err != nil {
+		middleware.Logger(context).Error("Failed to assign existing data to user", "user_id", user.ID, "error", err)
+	}
+
 	context.JSON(http.StatusCreated, gin.H{"message": "User registered successfully"})
 }
 
@@ -16,7 +16,9 @@ import (
 
 func TestRegisterUser(t *testing.T) {
 	_, router := setupRouter()
-	router.POST("/register", RegisterUser)
+	router.POST("/register", func(c *gin.Context) {
+		RegisterUser(c, &config.Config{})
+	})
 
 	// Create a new user
 	newUser := models.User{
@@ -41,7 +43,9 @@ func TestRegisterUser(t *testing.T) {
 
 func TestRegisterUser_InvalidInput(t *testing.T) {
 	_, router := setupRouter()
-	router.POST("/register", RegisterUser)
+	router.POST("/register", func(c *gin.Context) {
+		RegisterUser(c, &config.Config{})
+	})
 
 	// Invalid input (no email)
 	invalidUser := models.User{
@@ -63,6 +67,31 @@ func TestRegisterUser_InvalidInput(t *testing.T) {
 	assert.Equal(t, "Invalid input", responseBody["error"])
 }
 
+func TestRegisterUser_Disabled(t *testing.T) {
+	db, router := setupRouter()
+	cfg := config.Config{}
+	router.POST("/register", func(c *gin.Context) {
+		RegisterUser(c, &cfg)
+	})
+
+	db.Create(&models.User{Username: "admin", Email: "admin@example.com", Password: "hash"})
+
+	register := func() int {
+		jsonValue, _ := json.Marshal(models.User{Username: "intruder", Email: "intruder@example.com", Password: "password123"})
+		req, _ := http.NewRequest("POST", "/register", bytes.NewBuffer(jsonValue))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	// Only the first user can register unless registration is allowed
+	assert.Equal(t, http.StatusForbidden, register())
+
+	cfg.AllowRegistration = true
+	assert.Equal(t, http.StatusCreated, register())
+}
+
 func TestLoginUser(t *testing.T) {
 	config := config.Config{
 		JWTSecretKey:   "mysecretkey",
@@ -0,0 +1,78 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+)
+
+// fieldError describes why the value of a single request field was rejected
+type fieldError struct {
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+	Value   any    `json:"value"`
+}
+
+func init() {
+	// Report fields by their JSON names instead of the struct field names
+	if engine, ok := binding.Validator.Engine().(*validator.Validate); ok {
+		engine.RegisterTagNameFunc(func(field reflect.StructField) string {
+			name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+			if name == "-" {
+				return ""
+			}
+			return name
+		})
+	}
+}
+
+// respondBindingError answers a failed ShouldBindJSON with the offending fields, the failed rule and the rejected value
+func respondBindingError(c *gin.Context, err error) {
+	var validationErrors validator.ValidationErrors
+	var typeError *json.UnmarshalTypeError
+	switch {
+	case errors.As(err, &validationErrors):
+		fields := map[string]fieldError{}
+		for _, validationError := range validationErrors {
+			fields[validationError.Field()] = fieldError{
+				Rule:    validationError.Tag(),
+				Message: validationMessage(validationError),
+				Value:   validationError.Value(),
+			}
+		}
+		respondFieldErrors(c, fields)
+	case errors.As(err, &typeError):
+		respondFieldErrors(c, map[string]fieldError{
+			typeError.Field: {Rule: "type", Message: "Must be of type " + typeError.Type.String(), Value: typeError.Value},
+		})
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+	}
+}
+
+func respondFieldErrors(c *gin.Context, fields map[string]fieldError) {
+	c.JSON(http.StatusBadRequest, gin.H{"error": "Validation failed", "fields": fields})
+}
+
+// validationMessage returns a readable message for the failed validation rule
+func validationMessage(validationError validator.FieldError) string {
+	switch validationError.Tag() {
+	case "required":
+		return "This field is required"
+	case "email":
+		return "Must be a valid email address"
+	case "min":
+		return "Must be at least " + validationError.Param()
+	case "max":
+		return "Must be at most " + validationError.Param()
+	case "oneof":
+		return "Must be one of " + validationError.Param()
+	}
+	return "Invalid value"
+}
@@ -0,0 +1,64 @@
+package controllers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"perema/models"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBindingErrors(t *testing.T) {
+	db, router := setupRouter()
+
+	router.POST("/contacts/:id/reminders", CreateReminder)
+	router.POST("/contacts", CreateContact)
+
+	contact := models.Contact{Firstname: "Jane"}
+	db.Create(&contact)
+
+	var responseBody struct {
+		Error  string                `json:"error"`
+		Fields map[string]fieldError `json:"fields"`
+	}
+
+	post := func(url, body string) int {
+		req, _ := http.NewRequest("POST", url, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		responseBody.Fields = nil
+		json.Unmarshal(w.Body.Bytes(), &responseBody)
+		return w.Code
+	}
+
+	// Missing required fields are reported by their JSON names
+	code := post("/contacts/"+strconv.Itoa(int(contact.ID))+"/reminders", `{"message": "", "recurrence": "Once"}`)
+	assert.Equal(t, http.StatusBadRequest, code)
+	assert.Equal(t, "Validation failed", responseBody.Error)
+	assert.Len(t, responseBody.Fields, 2)
+	assert.Equal(t, "required", responseBody.Fields["message"].Rule)
+	assert.Equal(t, "", responseBody.Fields["message"].Value)
+	assert.Equal(t, "required", responseBody.Fields["remind_at"].Rule)
+
+	// Values of the wrong type
+	code = post("/contacts", `{"firstname": "John", "deceased": "yes"}`)
+	assert.Equal(t, http.StatusBadRequest, code)
+	assert.Equal(t, "type", responseBody.Fields["deceased"].Rule)
+	assert.Equal(t, "string", responseBody.Fields["deceased"].Value)
+
+	// Format checks of the contact use the same structure
+	code = post("/contacts", `{"firstname": "John", "email": "john@"}`)
+	assert.Equal(t, http.StatusBadRequest, code)
+	assert.Equal(t, "email", responseBody.Fields["email"].Rule)
+	assert.Equal(t, "john@", responseBody.Fields["email"].Value)
+
+	code = post("/contacts", `{"firstname": `)
+	assert.Equal(t, http.StatusBadRequest, code)
+	assert.Equal(t, "Invalid request body", responseBody.Error)
+}
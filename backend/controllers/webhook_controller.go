@@ -0,0 +1,133 @@
+package controllers
+
+import (
+	"net/http"
+	"net/url"
+	"perema/config"
+	"perema/middleware"
+	"perema/models"
+	"perema/services"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// webhookInput is the body for registering a webhook, the secret is generated
+type webhookInput struct {
+	URL    string   `json:"url" binding:"required,max=2000"`
+	Events []string `json:"events" binding:"required,min=1"`
+}
+
+// Webhook event of each audited contact action
+var contactWebhookEvents = map[string]string{
+	models.AuditCreate: models.WebhookContactCreated,
+	models.AuditUpdate: models.WebhookContactUpdated,
+	models.AuditDelete: models.WebhookContactDeleted,
+}
+
+// dispatchWebhook sends the event to the webhooks of the current user once the request is committed.
+// Nothing is sent if the server runs without a webhook dispatcher.
+func dispatchWebhook(c *gin.Context, event string, data any) {
+	dispatcher, ok := c.Get("webhooks")
+	if !ok {
+		return
+	}
+	userID := currentUserID(c)
+	middleware.AfterCommit(c, func() {
+		dispatcher.(*services.WebhookDispatcher).Dispatch(userID, event, data)
+	})
+}
+
+// CreateWebhook registers a webhook for the authenticated user. The secret for verifying the signatures
+// is only returned in this response.
+func CreateWebhook(c *gin.Context, cfg *config.Config) {
+	db := c.MustGet("db").(*gorm.DB)
+
+	var input webhookInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		respondBindingError(c, err)
+		return
+	}
+	fields := map[string]fieldError{}
+	if parsed, err := url.Parse(input.URL); err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		fields["url"] = fieldError{Rule: "url", Message: "Must be an http or https URL", Value: input.URL}
+	} else if !cfg.WebhookPrivateTargets && services.CheckWebhookTarget(parsed) != nil {
+		fields["url"] = fieldError{Rule: "public_url", Message: "Must not be a loopback, private or link-local address", Value: input.URL}
+	}
+	for i, event := range input.Events {
+		if !models.ValidWebhookEvent(event) {
+			fields["events["+strconv.Itoa(i)+"]"] = fieldError{Rule: "oneof", Message: models.InvalidWebhookEventMessage(), Value: event}
+		}
+	}
+	if len(fields) > 0 {
+		respondFieldErrors(c, fields)
+		return
+	}
+
+	secret, err := services.GenerateWebhookSecret()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate webhook secret"})
+		return
+	}
+	webhook := models.Webhook{UserID: currentUserID(c), URL: input.URL, Secret: secret, Events: normalizeNames(input.Events)}
+	if err := db.Create(&webhook).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save webhook"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"message": "Webhook created, the secret will not be shown again", "webhook": webhook, "secret": secret})
+}
+
+// GetWebhooks lists the webhooks of the authenticated user
+func GetWebhooks(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+
+	webhooks := []models.Webhook{}
+	if err := db.Scopes(ownedBy(c, "webhooks")).Order("created_at DESC").Find(&webhooks).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve webhooks"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"webhooks": webhooks, "events": models.WebhookEvents})
+}
+
+// GetWebhookDeadLetters lists the deliveries of a webhook which failed after all retries, newest first
+func GetWebhookDeadLetters(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+
+	var webhook models.Webhook
+	if err := db.Scopes(ownedBy(c, "webhooks")).First(&webhook, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Webhook not found"})
+		return
+	}
+
+	deadLetters := []models.WebhookDeadLetter{}
+	if err := db.Where("webhook_id = ?", webhook.ID).Order("created_at DESC, id DESC").Limit(100).Find(&deadLetters).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve failed deliveries"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"dead_letters": deadLetters})
+}
+
+// DeleteWebhook removes a webhook together with its failed deliveries
+func DeleteWebhook(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+
+	var webhook models.Webhook
+	if err := db.Scopes(ownedBy(c, "webhooks")).First(&webhook, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Webhook not found"})
+		return
+	}
+	if err := db.Where("webhook_id = ?", webhook.ID).Delete(&models.WebhookDeadLetter{}).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete webhook"})
+		return
+	}
+	if err := db.Delete(&webhook).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete webhook"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Webhook deleted"})
+}
@@ -0,0 +1,106 @@
+package controllers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"perema/config"
+	"perema/models"
+	"perema/services"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWebhooks(t *testing.T) {
+	db, router := setupRouter()
+	sqlDB, _ := db.DB()
+	sqlDB.SetMaxOpenConns(1)                                 // The dispatcher reads from other goroutines
+	dispatcher := services.NewWebhookDispatcher(db, 1, true) // The test server listens on the loopback address
+	router.Use(func(c *gin.Context) {
+		c.Set("user_id", uint(1))
+		c.Set("webhooks", dispatcher)
+		c.Next()
+	})
+	cfg := &config.Config{}
+	router.POST("/webhooks", func(c *gin.Context) {
+		CreateWebhook(c, cfg)
+	})
+	router.GET("/webhooks", GetWebhooks)
+	router.DELETE("/webhooks/:id", DeleteWebhook)
+	router.GET("/webhooks/:id/dead-letters", GetWebhookDeadLetters)
+	router.POST("/contacts", CreateContact)
+
+	var mu sync.Mutex
+	var events []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		events = append(events, r.Header.Get(services.WebhookEventHeader))
+		mu.Unlock()
+	}))
+	defer server.Close()
+
+	send := func(method, url, body string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(method, url, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	assert.Equal(t, http.StatusBadRequest, send("POST", "/webhooks", `{"url": "ftp://example.com", "events": ["contact.created"]}`).Code)
+	assert.Equal(t, http.StatusBadRequest, send("POST", "/webhooks", `{"url": "https://example.com", "events": ["contact.renamed"]}`).Code)
+	assert.Equal(t, http.StatusBadRequest, send("POST", "/webhooks", `{"url": "https://example.com", "events": []}`).Code)
+
+	// Services in the network of the server cannot be targeted unless configured
+	for _, target := range []string{"http://localhost:8080", "http://127.0.0.1/hook", "http://10.1.2.3", "http://169.254.169.254/latest/meta-data", server.URL} {
+		w := send("POST", "/webhooks", `{"url": "`+target+`", "events": ["contact.created"]}`)
+		assert.Equal(t, http.StatusBadRequest, w.Code, target)
+		assert.Contains(t, w.Body.String(), "public_url", target)
+	}
+	cfg.WebhookPrivateTargets = true
+
+	// The secret is returned once and only the subscribed events are delivered
+	w := send("POST", "/webhooks", `{"url": "`+server.URL+`", "events": ["contact.created", "contact.created"]}`)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	var created struct {
+		Webhook models.Webhook `json:"webhook"`
+		Secret  string         `json:"secret"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &created)
+	assert.Len(t, created.Secret, 64)
+	assert.Equal(t, []string{models.WebhookContactCreated}, created.Webhook.Events)
+	assert.NotContains(t, send("GET", "/webhooks", "").Body.String(), created.Secret)
+
+	assert.Equal(t, http.StatusCreated, send("POST", "/contacts", `{"firstname": "Alice"}`).Code)
+	assert.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(events) == 1 && events[0] == models.WebhookContactCreated
+	}, time.Second, 5*time.Millisecond)
+
+	var deadLetters struct {
+		DeadLetters []models.WebhookDeadLetter `json:"dead_letters"`
+	}
+	json.Unmarshal(send("GET", "/webhooks/"+strconv.Itoa(int(created.Webhook.ID))+"/dead-letters", "").Body.Bytes(), &deadLetters)
+	assert.Empty(t, deadLetters.DeadLetters)
+
+	// Webhooks of other users are neither listed nor deleted
+	other := models.Webhook{UserID: 2, URL: "https://example.com", Secret: "secret", Events: []string{models.WebhookContactCreated}}
+	db.Create(&other)
+	var list struct {
+		Webhooks []models.Webhook `json:"webhooks"`
+	}
+	json.Unmarshal(send("GET", "/webhooks", "").Body.Bytes(), &list)
+	assert.Len(t, list.Webhooks, 1)
+	assert.Equal(t, http.StatusNotFound, send("DELETE", "/webhooks/"+strconv.Itoa(int(other.ID)), "").Code)
+	assert.Equal(t, http.StatusNotFound, send("GET", "/webhooks/"+strconv.Itoa(int(other.ID))+"/dead-letters", "").Code)
+
+	assert.Equal(t, http.StatusOK, send("DELETE", "/webhooks/"+strconv.Itoa(int(created.Webhook.ID)), "").Code)
+	dispatcher.Stop()
+}
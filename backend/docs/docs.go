@@ -0,0 +1,32 @@
+// Code generated by swaggo/swag. DO NOT EDIT.
+
+package docs
+
+import "github.com/swaggo/swag/v2"
+
+const docTemplate = `{
+    "schemes": {{ marshal .Schemes }},
+    "components": {"schemas":{"controllers.agendaReminder":{"properties":{"CreatedAt":{"type":"string"},"DeletedAt":{"type":"string"},"ID":{"type":"integer"},"UpdatedAt":{"type":"string"},"by_mail":{"type":"boolean"},"completed":{"type":"boolean"},"contact":{"$ref":"#/components/schemas/models.Contact"},"contact_id":{"type":"integer"},"contact_name":{"type":"string"},"last_sent":{"type":"string"},"message":{"type":"string"},"next_due":{"description":"Occurrence after the current due date of recurring reminders","type":"string"},"recurrence":{"type":"string"},"recurrence_interval":{"description":"Repeat every n periods of the recurrence","type":"integer"},"remind_at":{"type":"string"},"reoccur_from_completion":{"type":"boolean"},"status":{"description":"pending, done or snoozed","type":"string"}},"required":["message","recurrence","remind_at"],"type":"object"},"controllers.agendaReminderPage":{"properties":{"has_next":{"type":"boolean"},"has_prev":{"type":"boolean"},"limit":{"type":"integer"},"page":{"type":"integer"},"reminders":{"items":{"$ref":"#/components/schemas/controllers.agendaReminder"},"type":"array","uniqueItems":false},"total":{"type":"integer"},"total_pages":{"type":"integer"}},"type":"object"},"controllers.bulkContactsRequest":{"properties":{"ids":{"items":{"type":"integer"},"maxItems":500,"minItems":1,"type":"array","uniqueItems":false}},"required":["ids"],"type":"object"},"controllers.bulkResult":{"properties":{"error":{"type":"string"},"id":{"type":"integer"},"success":{"type":"boolean"}},"type":"object"},"controllers.contactPage":{"properties":{"contacts":{"items":{"$ref":"#/components/schemas/models.Contact"},"type":"array","uniqueItems":false},"has_next":{"type":"boolean"},"has_prev":{"type":"boolean"},"limit":{"type":"integer"},"page":{"type":"integer"},"total":{"type":"integer"},"total_pages":{"type":"integer"}},"type":"object"},"controllers.csvImportRow":{"properties":{"contact_id":{"type":"integer"},"error":{"type":"string"},"line":{"type":"integer"}},"type":"object"},"controllers.errorResponse":{"properties":{"error":{"example":"Contact not found","type":"string"}},"type":"object"},"controllers.fieldError":{"properties":{"message":{"type":"string"},"rule":{"type":"string"},"value":{}},"type":"object"},"controllers.fileUpload":{"properties":{"file":{"format":"binary","type":"string"}},"type":"object"},"controllers.overdueReminderPage":{"properties":{"grace_days":{"type":"integer"},"has_next":{"type":"boolean"},"has_prev":{"type":"boolean"},"limit":{"type":"integer"},"page":{"type":"integer"},"reminders":{"items":{"$ref":"#/components/schemas/models.Reminder"},"type":"array","uniqueItems":false},"total":{"type":"integer"},"total_pages":{"type":"integer"}},"type":"object"},"controllers.upcomingReminderGroup":{"properties":{"contact_id":{"type":"integer"},"firstname":{"type":"string"},"lastname":{"type":"string"},"reminders":{"items":{"$ref":"#/components/schemas/models.Reminder"},"type":"array","uniqueItems":false}},"type":"object"},"controllers.upcomingReminderPage":{"properties":{"contacts":{"items":{"$ref":"#/components/schemas/controllers.upcomingReminderGroup"},"type":"array","uniqueItems":false},"has_next":{"type":"boolean"},"has_prev":{"type":"boolean"},"limit":{"type":"integer"},"page":{"type":"integer"},"total":{"type":"integer"},"total_pages":{"type":"integer"}},"type":"object"},"controllers.vCardImportError":{"properties":{"card":{"description":"Position of the card in the file, starting at 1","type":"integer"},"error":{"type":"string"},"name":{"type":"string"}},"type":"object"},"controllers.vCardImportSummary":{"properties":{"created":{"type":"integer"},"errors":{"items":{"$ref":"#/components/schemas/controllers.vCardImportError"},"type":"array","uniqueItems":false},"failed":{"type":"integer"},"skipped":{"description":"Cards matching an existing contact by name and email","type":"integer"}},"type":"object"},"controllers.validationErrorResponse":{"properties":{"error":{"example":"Validation failed","type":"string"},"fields":{"additionalProperties":{"$ref":"#/components/schemas/controllers.fieldError"},"type":"object"}},"type":"object"},"models.Activity":{"properties":{"CreatedAt":{"type":"string"},"DeletedAt":{"type":"string"},"ID":{"type":"integer"},"UpdatedAt":{"type":"string"},"activity_type":{"description":"One of ActivityTypes","type":"string"},"contacts":{"items":{"$ref":"#/components/schemas/models.Contact"},"type":"array","uniqueItems":false},"date":{"type":"string"},"description":{"type":"string"},"location":{"type":"string"},"title":{"type":"string"}},"type":"object"},"models.Address":{"properties":{"city":{"type":"string"},"country":{"type":"string"},"postal_code":{"type":"string"},"region":{"type":"string"},"street":{"type":"string"}},"type":"object"},"models.Contact":{"properties":{"CreatedAt":{"type":"string"},"DeletedAt":{"type":"string"},"ID":{"type":"integer"},"UpdatedAt":{"type":"string"},"activities":{"items":{"$ref":"#/components/schemas/models.Activity"},"type":"array","uniqueItems":false},"address":{"$ref":"#/components/schemas/models.Address"},"attachments":{"description":"Files attached to the contact","items":{"$ref":"#/components/schemas/models.ContactAttachment"},"type":"array","uniqueItems":false},"birthday":{"type":"string"},"cadence_health":{"description":"Computed status of the contact frequency goal","type":"string"},"circles":{"description":"Serialize Circles properly","items":{"type":"string"},"type":"array","uniqueItems":false},"contact_frequency_days":{"description":"Goal to get in touch every n days, 0 for none","type":"integer"},"contact_information":{"description":"Additional contact information","type":"string"},"contact_methods":{"description":"All email addresses and phone numbers","items":{"$ref":"#/components/schemas/models.ContactMethod"},"type":"array","uniqueItems":false},"custom_dates":{"description":"Anniversaries and other dates reminded like birthdays","items":{"$ref":"#/components/schemas/models.CustomDate"},"type":"array","uniqueItems":false},"deceased":{"type":"boolean"},"deceased_date":{"description":"Optional date of death","type":"string"},"email":{"type":"string"},"favorite":{"description":"Pinned to the top of the contact list","type":"boolean"},"firstname":{"type":"string"},"food_preference":{"description":"Text field","type":"string"},"gender":{"type":"string"},"how_we_met":{"description":"Text field","type":"string"},"last_contacted":{"description":"Computed date of the latest activity or note","type":"string"},"lastname":{"type":"string"},"nickname":{"type":"string"},"notes":{"description":"One-to-many relationship with notes","items":{"$ref":"#/components/schemas/models.Note"},"type":"array","uniqueItems":false},"phone":{"type":"string"},"photo":{"description":"Path to the profile photo","type":"string"},"photo_thumnbnail":{"description":"Path to the profile photo thumbnail","type":"string"},"relationships":{"description":"Has many relationships","items":{"$ref":"#/components/schemas/models.Relationship"},"type":"array","uniqueItems":false},"reminder_lead_days":{"description":"Days before the birthday the reminder is sent, 0 for the day itself","type":"integer"},"reminders":{"description":"One-to-many relationship with reminders","items":{"$ref":"#/components/schemas/models.Reminder"},"type":"array","uniqueItems":false},"summary":{"description":"Computed one-line description of the contact","type":"string"},"tags":{"description":"Free labels, unlike circles they do not group contacts","items":{"type":"string"},"type":"array","uniqueItems":false},"version":{"description":"Incremented on every update to detect concurrent edits","type":"integer"},"work_information":{"description":"Text field","type":"string"}},"type":"object"},"models.ContactAttachment":{"properties":{"CreatedAt":{"type":"string"},"DeletedAt":{"type":"string"},"ID":{"type":"integer"},"UpdatedAt":{"type":"string"},"contact_id":{"type":"integer"},"content_type":{"description":"Detected content type of the file","type":"string"},"filename":{"description":"Original name of the uploaded file","type":"string"},"size":{"description":"Size in bytes","type":"integer"},"uploaded_at":{"type":"string"}},"type":"object"},"models.ContactMethod":{"properties":{"CreatedAt":{"type":"string"},"DeletedAt":{"type":"string"},"ID":{"type":"integer"},"UpdatedAt":{"type":"string"},"contact_id":{"type":"integer"},"is_primary":{"description":"Exactly one method of each type is primary","type":"boolean"},"label":{"enum":["home","work","mobile","other"],"type":"string"},"type":{"enum":["email","phone"],"type":"string"},"value":{"maxLength":255,"type":"string"}},"required":["type","value"],"type":"object"},"models.CustomDate":{"description":"Includes the contact","properties":{"CreatedAt":{"type":"string"},"DeletedAt":{"type":"string"},"ID":{"type":"integer"},"UpdatedAt":{"type":"string"},"contact":{"$ref":"#/components/schemas/models.Contact"},"contact_id":{"type":"integer"},"date":{"type":"string"},"label":{"type":"string"},"recurring":{"description":"Reminded every year, otherwise only on the date itself","type":"boolean"},"reminder_lead_days":{"description":"Days before the date the reminder is sent, 0 for the day itself","type":"integer"}},"type":"object"},"models.Note":{"properties":{"CreatedAt":{"type":"string"},"DeletedAt":{"type":"string"},"ID":{"type":"integer"},"UpdatedAt":{"type":"string"},"attachments":{"description":"Files such as voice memos attached to the note","items":{"$ref":"#/components/schemas/models.NoteAttachment"},"type":"array","uniqueItems":false},"contact":{"$ref":"#/components/schemas/models.Contact"},"contact_id":{"type":"integer"},"content":{"type":"string"},"date":{"type":"string"},"tags":{"description":"Serialized like the circles of contacts","items":{"type":"string"},"type":"array","uniqueItems":false}},"type":"object"},"models.NoteAttachment":{"properties":{"CreatedAt":{"type":"string"},"DeletedAt":{"type":"string"},"ID":{"type":"integer"},"UpdatedAt":{"type":"string"},"content_type":{"description":"Detected content type of the file","type":"string"},"filename":{"description":"Original name of the uploaded file","type":"string"},"note_id":{"type":"integer"},"size":{"description":"Size in bytes","type":"integer"}},"type":"object"},"models.Relationship":{"properties":{"CreatedAt":{"type":"string"},"DeletedAt":{"type":"string"},"ID":{"type":"integer"},"UpdatedAt":{"type":"string"},"birthday":{"description":"Birthday of the related person","type":"string"},"contact_id":{"description":"Contact this relationship belongs to","type":"integer"},"gender":{"description":"Gender of the related person","type":"string"},"name":{"description":"Name of the related person","type":"string"},"related_contact":{"$ref":"#/components/schemas/models.Contact"},"related_contact_id":{"description":"Optional link to an existing Contact","type":"integer"},"type":{"description":"Relationship type (e.g., \"Child\", \"Mother\")","type":"string"}},"type":"object"},"models.Reminder":{"properties":{"CreatedAt":{"type":"string"},"DeletedAt":{"type":"string"},"ID":{"type":"integer"},"UpdatedAt":{"type":"string"},"by_mail":{"type":"boolean"},"completed":{"type":"boolean"},"contact":{"$ref":"#/components/schemas/models.Contact"},"contact_id":{"type":"integer"},"last_sent":{"type":"string"},"message":{"type":"string"},"next_due":{"description":"Occurrence after the current due date of recurring reminders","type":"string"},"recurrence":{"type":"string"},"recurrence_interval":{"description":"Repeat every n periods of the recurrence","type":"integer"},"remind_at":{"type":"string"},"reoccur_from_completion":{"type":"boolean"},"status":{"description":"pending, done or snoozed","type":"string"}},"required":["message","recurrence","remind_at"],"type":"object"},"services.DuplicateCluster":{"properties":{"contacts":{"items":{"$ref":"#/components/schemas/models.Contact"},"type":"array","uniqueItems":false},"reason":{"type":"string"},"value":{"description":"Normalized value shared by the contacts","type":"string"}},"type":"object"},"services.UpcomingBirthday":{"properties":{"contact":{"$ref":"#/components/schemas/models.Contact"},"date":{"description":"Date of the next birthday in YYYY-MM-DD format","type":"string"},"days_until":{"type":"integer"},"turning_age":{"description":"Only set if the birth year is known","type":"integer"}},"type":"object"},"services.UpcomingCustomDate":{"properties":{"custom_date":{"$ref":"#/components/schemas/models.CustomDate"},"date":{"description":"Date of the next occurrence in YYYY-MM-DD format","type":"string"},"days_until":{"type":"integer"},"years":{"description":"Years since the date, only set for recurring dates with a known year","type":"integer"}},"type":"object"}},"securitySchemes":{"bearerauth":{"bearerFormat":"JWT","scheme":"bearer","type":"http"}}},
+    "info": {"description":"{{escape .Description}}","license":{"name":"MIT"},"title":"{{.Title}}","version":"{{.Version}}"},
+    "externalDocs": {"description":"","url":""},
+    "paths": {"/circles/{name}/contacts":{"get":{"parameters":[{"description":"Circle","in":"path","name":"name","required":true,"schema":{"type":"string"}},{"description":"Page number","in":"query","name":"page","schema":{"default":1,"type":"integer"}},{"description":"Contacts per page, up to the configured maximum","in":"query","name":"limit","schema":{"type":"integer"}},{"description":"Comma separated fields to return","in":"query","name":"fields","schema":{"type":"string"}},{"description":"Comma separated relations to include: notes, activities, relationships, reminders","in":"query","name":"includes","schema":{"type":"string"}},{"description":"Named view of fields and includes from the configuration","in":"query","name":"view","schema":{"type":"string"}},{"description":"Search term","in":"query","name":"search","schema":{"type":"string"}},{"description":"Comma separated fields to search in","in":"query","name":"search_fields","schema":{"type":"string"}},{"description":"Sort column, favorites come first without it","in":"query","name":"sort","schema":{"enum":["lastname","firstname","birthday","created_at","updated_at"],"type":"string"}},{"description":"Sort order","in":"query","name":"order","schema":{"default":"asc","enum":["asc","desc"],"type":"string"}},{"description":"Health of the contact frequency goal","in":"query","name":"cadence","schema":{"enum":["on_track","slipping","overdue"],"type":"string"}},{"description":"Only favorite contacts","in":"query","name":"favorites","schema":{"type":"boolean"}},{"description":"City of the address, case insensitive","in":"query","name":"city","schema":{"type":"string"}},{"description":"Circle the contact is in","in":"query","name":"circle","schema":{"type":"string"}},{"description":"Tags the contact needs all of","in":"query","name":"tag","schema":{"items":{"type":"string"},"type":"array"},"style":"form"},{"description":"ETag of a previous response","in":"header","name":"If-None-Match","schema":{"type":"string"}}],"responses":{"200":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/controllers.contactPage"}}},"description":"OK","headers":{"ETag":{"description":"Tag of the response for conditional requests","schema":{"type":"string"}},"X-Pagination-Limit-Clamped":{"description":"Set if the requested limit exceeded the maximum","schema":{"type":"string"}}}},"304":{"description":"Not modified since the ETag"},"400":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/controllers.errorResponse"}}},"description":"Bad Request"},"500":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/controllers.errorResponse"}}},"description":"Internal Server Error"}},"security":[{"bearerauth":[]}],"summary":"List the contacts of a circle","tags":["contacts"]}},"/contacts":{"get":{"parameters":[{"description":"Page number","in":"query","name":"page","schema":{"default":1,"type":"integer"}},{"description":"Contacts per page, up to the configured maximum","in":"query","name":"limit","schema":{"type":"integer"}},{"description":"Comma separated fields to return","in":"query","name":"fields","schema":{"type":"string"}},{"description":"Comma separated relations to include: notes, activities, relationships, reminders","in":"query","name":"includes","schema":{"type":"string"}},{"description":"Named view of fields and includes from the configuration","in":"query","name":"view","schema":{"type":"string"}},{"description":"Search term","in":"query","name":"search","schema":{"type":"string"}},{"description":"Comma separated fields to search in","in":"query","name":"search_fields","schema":{"type":"string"}},{"description":"Sort column, favorites come first without it","in":"query","name":"sort","schema":{"enum":["lastname","firstname","birthday","created_at","updated_at"],"type":"string"}},{"description":"Sort order","in":"query","name":"order","schema":{"default":"asc","enum":["asc","desc"],"type":"string"}},{"description":"Health of the contact frequency goal","in":"query","name":"cadence","schema":{"enum":["on_track","slipping","overdue"],"type":"string"}},{"description":"Only favorite contacts","in":"query","name":"favorites","schema":{"type":"boolean"}},{"description":"City of the address, case insensitive","in":"query","name":"city","schema":{"type":"string"}},{"description":"Circle the contact is in","in":"query","name":"circle","schema":{"type":"string"}},{"description":"Tags the contact needs all of","in":"query","name":"tag","schema":{"items":{"type":"string"},"type":"array"},"style":"form"},{"description":"ETag of a previous response","in":"header","name":"If-None-Match","schema":{"type":"string"}}],"responses":{"200":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/controllers.contactPage"}}},"description":"OK","headers":{"ETag":{"description":"Tag of the response for conditional requests","schema":{"type":"string"}},"X-Pagination-Limit-Clamped":{"description":"Set if the requested limit exceeded the maximum","schema":{"type":"string"}}}},"304":{"description":"Not modified since the ETag"},"400":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/controllers.errorResponse"}}},"description":"Bad Request"},"500":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/controllers.errorResponse"}}},"description":"Internal Server Error"}},"security":[{"bearerauth":[]}],"summary":"List contacts","tags":["contacts"]},"post":{"requestBody":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/models.Contact"}}},"description":"Contact to create","required":true},"responses":{"201":{"content":{"application/json":{"schema":{"properties":{"contact":{"$ref":"#/components/schemas/models.Contact"},"message":{"type":"string"}},"type":"object"}}},"description":"Created","headers":{"Location":{"description":"URL of the created contact","schema":{"type":"string"}}}},"400":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/controllers.validationErrorResponse"}}},"description":"Bad Request"},"500":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/controllers.errorResponse"}}},"description":"Internal Server Error"}},"security":[{"bearerauth":[]}],"summary":"Create a contact","tags":["contacts"]}},"/contacts/birthdays/upcoming":{"get":{"parameters":[{"description":"Days to look ahead, at most 366","in":"query","name":"days","schema":{"default":30,"type":"integer"}}],"responses":{"200":{"content":{"application/json":{"schema":{"properties":{"birthdays":{"items":{"$ref":"#/components/schemas/services.UpcomingBirthday"},"type":"array"},"custom_dates":{"items":{"$ref":"#/components/schemas/services.UpcomingCustomDate"},"type":"array"}},"type":"object"}}},"description":"OK"},"400":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/controllers.errorResponse"}}},"description":"Bad Request"},"500":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/controllers.errorResponse"}}},"description":"Internal Server Error"}},"security":[{"bearerauth":[]}],"summary":"List upcoming birthdays and custom dates","tags":["contacts"]}},"/contacts/bulk/delete":{"post":{"requestBody":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/controllers.bulkContactsRequest"}}},"description":"Contacts to delete","required":true},"responses":{"200":{"content":{"application/json":{"schema":{"properties":{"deleted":{"type":"integer"},"results":{"items":{"$ref":"#/components/schemas/controllers.bulkResult"},"type":"array"}},"type":"object"}}},"description":"OK"},"400":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/controllers.validationErrorResponse"}}},"description":"Bad Request"},"500":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/controllers.errorResponse"}}},"description":"Internal Server Error"}},"security":[{"bearerauth":[]}],"summary":"Move several contacts to the trash","tags":["contacts"]}},"/contacts/circles":{"get":{"parameters":[{"description":"Return circleCount objects with the number of contacts instead of names","in":"query","name":"counts","schema":{"type":"boolean"}}],"responses":{"200":{"content":{"application/json":{"schema":{"items":{"type":"string"},"type":"array"}}},"description":"OK"},"500":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/controllers.errorResponse"}}},"description":"Internal Server Error"}},"security":[{"bearerauth":[]}],"summary":"List the circles of contacts","tags":["contacts"]}},"/contacts/duplicates":{"get":{"responses":{"200":{"content":{"application/json":{"schema":{"properties":{"clusters":{"items":{"$ref":"#/components/schemas/services.DuplicateCluster"},"type":"array"}},"type":"object"}}},"description":"OK"},"500":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/controllers.errorResponse"}}},"description":"Internal Server Error"}},"security":[{"bearerauth":[]}],"summary":"List likely duplicate contacts","tags":["contacts"]}},"/contacts/export/csv":{"get":{"parameters":[{"description":"Comma separated columns to export","in":"query","name":"fields","schema":{"type":"string"}}],"responses":{"200":{"content":{"application/json":{"schema":{"type":"string"}},"text/csv":{"schema":{"type":"string"}}},"description":"CSV file"},"400":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/controllers.errorResponse"}}},"description":"Bad Request"}},"security":[{"bearerauth":[]}],"summary":"Export all contacts as CSV","tags":["contacts"]}},"/contacts/import/csv":{"post":{"requestBody":{"content":{"multipart/form-data":{"schema":{"$ref":"#/components/schemas/controllers.fileUpload"}}},"description":"CSV file of at most 5 MB with a header row","required":true},"responses":{"200":{"content":{"application/json":{"schema":{"properties":{"created":{"items":{"$ref":"#/components/schemas/controllers.csvImportRow"},"type":"array"},"failed":{"items":{"$ref":"#/components/schemas/controllers.csvImportRow"},"type":"array"},"message":{"type":"string"}},"type":"object"}}},"description":"OK"},"400":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/controllers.errorResponse"}}},"description":"Bad Request"},"413":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/controllers.errorResponse"}}},"description":"Request Entity Too Large"},"500":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/controllers.errorResponse"}}},"description":"Internal Server Error"}},"security":[{"bearerauth":[]}],"summary":"Import contacts from a CSV file","tags":["contacts"]}},"/contacts/import/vcard":{"post":{"requestBody":{"content":{"multipart/form-data":{"schema":{"$ref":"#/components/schemas/controllers.fileUpload"}}},"description":"vCard file of at most 5 MB","required":true},"responses":{"200":{"content":{"application/json":{"schema":{"properties":{"message":{"type":"string"},"summary":{"$ref":"#/components/schemas/controllers.vCardImportSummary"}},"type":"object"}}},"description":"OK"},"400":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/controllers.errorResponse"}}},"description":"Bad Request"},"413":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/controllers.errorResponse"}}},"description":"Request Entity Too Large"},"422":{"content":{"application/json":{"schema":{"properties":{"error":{"type":"string"},"summary":{"$ref":"#/components/schemas/controllers.vCardImportSummary"}},"type":"object"}}},"description":"Unprocessable Entity"},"500":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/controllers.errorResponse"}}},"description":"Internal Server Error"}},"security":[{"bearerauth":[]}],"summary":"Import contacts from a vCard file","tags":["contacts"]}},"/contacts/recent":{"get":{"parameters":[{"description":"Timestamp to sort by","in":"query","name":"type","schema":{"default":"created","enum":["created","updated"],"type":"string"}},{"description":"Number of contacts, at most 50","in":"query","name":"limit","schema":{"default":10,"type":"integer"}}],"responses":{"200":{"content":{"application/json":{"schema":{"properties":{"contacts":{"items":{"$ref":"#/components/schemas/models.Contact"},"type":"array"}},"type":"object"}}},"description":"OK"},"400":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/controllers.errorResponse"}}},"description":"Bad Request"},"500":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/controllers.errorResponse"}}},"description":"Internal Server Error"}},"security":[{"bearerauth":[]}],"summary":"List recently created or modified contacts","tags":["contacts"]}},"/contacts/stale":{"get":{"parameters":[{"description":"Days without activity or note","in":"query","name":"days","schema":{"default":90,"type":"integer"}},{"description":"Page number","in":"query","name":"page","schema":{"default":1,"type":"integer"}},{"description":"Contacts per page, up to the configured maximum","in":"query","name":"limit","schema":{"type":"integer"}}],"responses":{"200":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/controllers.contactPage"}}},"description":"OK"},"400":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/controllers.errorResponse"}}},"description":"Bad Request"},"500":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/controllers.errorResponse"}}},"description":"Internal Server Error"}},"security":[{"bearerauth":[]}],"summary":"List contacts without recent activity or note","tags":["contacts"]}},"/contacts/tags":{"get":{"responses":{"200":{"content":{"application/json":{"schema":{"items":{"type":"string"},"type":"array"}}},"description":"OK"},"500":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/controllers.errorResponse"}}},"description":"Internal Server Error"}},"security":[{"bearerauth":[]}],"summary":"List the tags of contacts","tags":["contacts"]}},"/contacts/trash":{"get":{"parameters":[{"description":"Page number","in":"query","name":"page","schema":{"default":1,"type":"integer"}},{"description":"Items per page, up to the configured maximum","in":"query","name":"limit","schema":{"type":"integer"}}],"responses":{"200":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/controllers.contactPage"}}},"description":"OK"},"500":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/controllers.errorResponse"}}},"description":"Internal Server Error"}},"security":[{"bearerauth":[]}],"summary":"List contacts in the trash","tags":["contacts"]}},"/contacts/vcard":{"get":{"responses":{"200":{"content":{"application/json":{"schema":{"type":"string"}},"text/vcard":{"schema":{"type":"string"}}},"description":"vCard file"},"500":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/controllers.errorResponse"}}},"description":"Internal Server Error"}},"security":[{"bearerauth":[]}],"summary":"Export all contacts as vCard","tags":["contacts"]}},"/contacts/{id}":{"delete":{"parameters":[{"description":"Contact ID","in":"path","name":"id","required":true,"schema":{"type":"integer"}}],"responses":{"200":{"content":{"application/json":{"schema":{"properties":{"message":{"type":"string"}},"type":"object"}}},"description":"OK"},"404":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/controllers.errorResponse"}}},"description":"Not Found"},"500":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/controllers.errorResponse"}}},"description":"Internal Server Error"}},"security":[{"bearerauth":[]}],"summary":"Move a contact to the trash","tags":["contacts"]},"get":{"parameters":[{"description":"Contact ID","in":"path","name":"id","required":true,"schema":{"type":"integer"}},{"description":"ETag of a previous response","in":"header","name":"If-None-Match","schema":{"type":"string"}}],"responses":{"200":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/models.Contact"}},"text/csv":{"schema":{"type":"string"}},"text/vcard":{"schema":{"type":"string"}}},"description":"OK","headers":{"ETag":{"description":"Tag of the response for conditional requests","schema":{"type":"string"}}}},"304":{"description":"Not modified since the ETag"},"404":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/controllers.errorResponse"}}},"description":"Not Found"},"406":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/controllers.errorResponse"}}},"description":"Not Acceptable"},"500":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/controllers.errorResponse"}}},"description":"Internal Server Error"}},"security":[{"bearerauth":[]}],"summary":"Get a contact","tags":["contacts"]},"patch":{"parameters":[{"description":"Contact ID","in":"path","name":"id","required":true,"schema":{"type":"integer"}}],"requestBody":{"content":{"application/json":{"schema":{"type":"object"}}},"description":"Fields of the contact to change, optionally with its version","required":true},"responses":{"200":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/models.Contact"}}},"description":"OK"},"400":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/controllers.validationErrorResponse"}}},"description":"Bad Request"},"404":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/controllers.errorResponse"}}},"description":"Not Found"},"409":{"content":{"application/json":{"schema":{"properties":{"contact":{"$ref":"#/components/schemas/models.Contact"},"error":{"type":"string"}},"type":"object"}}},"description":"Conflict"},"500":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/controllers.errorResponse"}}},"description":"Internal Server Error"}},"security":[{"bearerauth":[]}],"summary":"Change some fields of a contact","tags":["contacts"]},"put":{"parameters":[{"description":"Contact ID","in":"path","name":"id","required":true,"schema":{"type":"integer"}}],"requestBody":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/models.Contact"}}},"description":"Contact with the version it is based on","required":true},"responses":{"200":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/models.Contact"}}},"description":"OK"},"400":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/controllers.validationErrorResponse"}}},"description":"Bad Request"},"404":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/controllers.errorResponse"}}},"description":"Not Found"},"409":{"content":{"application/json":{"schema":{"properties":{"contact":{"$ref":"#/components/schemas/models.Contact"},"error":{"type":"string"}},"type":"object"}}},"description":"Conflict"},"500":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/controllers.errorResponse"}}},"description":"Internal Server Error"}},"security":[{"bearerauth":[]}],"summary":"Update a contact","tags":["contacts"]}},"/contacts/{id}/favorite":{"post":{"parameters":[{"description":"Contact ID","in":"path","name":"id","required":true,"schema":{"type":"integer"}}],"responses":{"200":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/models.Contact"}}},"description":"OK"},"404":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/controllers.errorResponse"}}},"description":"Not Found"},"500":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/controllers.errorResponse"}}},"description":"Internal Server Error"}},"security":[{"bearerauth":[]}],"summary":"Pin or unpin a contact","tags":["contacts"]}},"/contacts/{id}/merge":{"post":{"parameters":[{"description":"Contact ID of the target","in":"path","name":"id","required":true,"schema":{"type":"integer"}}],"requestBody":{"content":{"application/json":{"schema":{"properties":{"source_id":{"type":"integer"}},"type":"object"}}},"description":"Contact to merge and delete","required":true},"responses":{"200":{"content":{"application/json":{"schema":{"properties":{"contact":{"$ref":"#/components/schemas/models.Contact"},"message":{"type":"string"}},"type":"object"}}},"description":"OK"},"400":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/controllers.validationErrorResponse"}}},"description":"Bad Request"},"404":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/controllers.errorResponse"}}},"description":"Not Found"},"500":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/controllers.errorResponse"}}},"description":"Internal Server Error"}},"security":[{"bearerauth":[]}],"summary":"Merge another contact into a contact","tags":["contacts"]}},"/contacts/{id}/permanent":{"delete":{"parameters":[{"description":"Contact ID","in":"path","name":"id","required":true,"schema":{"type":"integer"}}],"responses":{"200":{"content":{"application/json":{"schema":{"properties":{"message":{"type":"string"}},"type":"object"}}},"description":"OK"},"404":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/controllers.errorResponse"}}},"description":"Not Found"},"500":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/controllers.errorResponse"}}},"description":"Internal Server Error"}},"security":[{"bearerauth":[]}],"summary":"Delete a contact permanently","tags":["contacts"]}},"/contacts/{id}/reminders":{"get":{"parameters":[{"description":"Contact ID","in":"path","name":"id","required":true,"schema":{"type":"integer"}}],"responses":{"200":{"content":{"application/json":{"schema":{"properties":{"reminders":{"items":{"$ref":"#/components/schemas/models.Reminder"},"type":"array"}},"type":"object"}}},"description":"OK"},"404":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/controllers.errorResponse"}}},"description":"Not Found"},"500":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/controllers.errorResponse"}}},"description":"Internal Server Error"}},"security":[{"bearerauth":[]}],"summary":"List the reminders of a contact","tags":["reminders"]},"post":{"parameters":[{"description":"Contact ID","in":"path","name":"id","required":true,"schema":{"type":"integer"}}],"requestBody":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/models.Reminder"}}},"description":"Reminder to create","required":true},"responses":{"201":{"content":{"application/json":{"schema":{"properties":{"message":{"type":"string"},"reminder":{"$ref":"#/components/schemas/models.Reminder"}},"type":"object"}}},"description":"Created","headers":{"Location":{"description":"URL of the created reminder","schema":{"type":"string"}}}},"400":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/controllers.validationErrorResponse"}}},"description":"Bad Request"},"404":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/controllers.errorResponse"}}},"description":"Not Found"},"500":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/controllers.errorResponse"}}},"description":"Internal Server Error"}},"security":[{"bearerauth":[]}],"summary":"Create a reminder","tags":["reminders"]}},"/contacts/{id}/restore":{"post":{"parameters":[{"description":"Contact ID","in":"path","name":"id","required":true,"schema":{"type":"integer"}}],"responses":{"200":{"content":{"application/json":{"schema":{"properties":{"contact":{"$ref":"#/components/schemas/models.Contact"},"message":{"type":"string"}},"type":"object"}}},"description":"OK"},"404":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/controllers.errorResponse"}}},"description":"Not Found"},"500":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/controllers.errorResponse"}}},"description":"Internal Server Error"}},"security":[{"bearerauth":[]}],"summary":"Restore a contact from the trash","tags":["contacts"]}},"/contacts/{id}/vcard":{"get":{"parameters":[{"description":"Contact ID","in":"path","name":"id","required":true,"schema":{"type":"integer"}}],"responses":{"200":{"content":{"application/json":{"schema":{"type":"string"}},"text/vcard":{"schema":{"type":"string"}}},"description":"vCard file"},"404":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/controllers.errorResponse"}}},"description":"Not Found"}},"security":[{"bearerauth":[]}],"summary":"Export a contact as vCard","tags":["contacts"]}},"/genders":{"get":{"responses":{"200":{"content":{"application/json":{"schema":{"properties":{"genders":{"items":{"type":"string"},"type":"array"}},"type":"object"}}},"description":"OK"}},"security":[{"bearerauth":[]}],"summary":"List the genders of contacts","tags":["contacts"]}},"/reminders":{"get":{"parameters":[{"description":"Page number","in":"query","name":"page","schema":{"default":1,"type":"integer"}},{"description":"Items per page, up to the configured maximum","in":"query","name":"limit","schema":{"type":"integer"}},{"description":"Order of the due date","in":"query","name":"order","schema":{"default":"asc","enum":["asc","desc"],"type":"string"}},{"description":"Status of the reminders","in":"query","name":"status","schema":{"enum":["pending","done","snoozed"],"type":"string"}},{"description":"Only reminders due before the day, YYYY-MM-DD","in":"query","name":"due_before","schema":{"type":"string"}}],"responses":{"200":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/controllers.agendaReminderPage"}}},"description":"OK"},"400":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/controllers.errorResponse"}}},"description":"Bad Request"},"500":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/controllers.errorResponse"}}},"description":"Internal Server Error"}},"security":[{"bearerauth":[]}],"summary":"List reminders","tags":["reminders"]}},"/reminders/overdue":{"get":{"parameters":[{"description":"Page number","in":"query","name":"page","schema":{"default":1,"type":"integer"}},{"description":"Items per page, up to the configured maximum","in":"query","name":"limit","schema":{"type":"integer"}}],"responses":{"200":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/controllers.overdueReminderPage"}}},"description":"OK"},"500":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/controllers.errorResponse"}}},"description":"Internal Server Error"}},"security":[{"bearerauth":[]}],"summary":"List overdue reminders","tags":["reminders"]}},"/reminders/upcoming":{"get":{"parameters":[{"description":"Days to look ahead","in":"query","name":"days","schema":{"default":14,"type":"integer"}},{"description":"Page number","in":"query","name":"page","schema":{"default":1,"type":"integer"}},{"description":"Items per page, up to the configured maximum","in":"query","name":"limit","schema":{"type":"integer"}}],"responses":{"200":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/controllers.upcomingReminderPage"}}},"description":"OK"},"400":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/controllers.errorResponse"}}},"description":"Bad Request"},"500":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/controllers.errorResponse"}}},"description":"Internal Server Error"}},"security":[{"bearerauth":[]}],"summary":"List upcoming reminders grouped by contact","tags":["reminders"]}},"/reminders/{id}":{"delete":{"parameters":[{"description":"Reminder ID","in":"path","name":"id","required":true,"schema":{"type":"integer"}}],"responses":{"200":{"content":{"application/json":{"schema":{"properties":{"message":{"type":"string"}},"type":"object"}}},"description":"OK"},"404":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/controllers.errorResponse"}}},"description":"Not Found"}},"security":[{"bearerauth":[]}],"summary":"Delete a reminder","tags":["reminders"]},"get":{"parameters":[{"description":"Reminder ID","in":"path","name":"id","required":true,"schema":{"type":"integer"}}],"responses":{"200":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/models.Reminder"}}},"description":"OK"},"404":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/controllers.errorResponse"}}},"description":"Not Found"}},"security":[{"bearerauth":[]}],"summary":"Get a reminder","tags":["reminders"]},"put":{"parameters":[{"description":"Reminder ID","in":"path","name":"id","required":true,"schema":{"type":"integer"}}],"requestBody":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/models.Reminder"}}},"description":"Reminder","required":true},"responses":{"200":{"content":{"application/json":{"schema":{"properties":{"message":{"type":"string"},"reminder":{"$ref":"#/components/schemas/models.Reminder"}},"type":"object"}}},"description":"OK"},"400":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/controllers.validationErrorResponse"}}},"description":"Bad Request"},"404":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/controllers.errorResponse"}}},"description":"Not Found"}},"security":[{"bearerauth":[]}],"summary":"Update a reminder","tags":["reminders"]}},"/reminders/{id}/complete":{"post":{"parameters":[{"description":"Reminder ID","in":"path","name":"id","required":true,"schema":{"type":"integer"}}],"responses":{"200":{"content":{"application/json":{"schema":{"properties":{"message":{"type":"string"},"reminder":{"$ref":"#/components/schemas/models.Reminder"}},"type":"object"}}},"description":"OK"},"404":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/controllers.errorResponse"}}},"description":"Not Found"},"500":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/controllers.errorResponse"}}},"description":"Internal Server Error"}},"security":[{"bearerauth":[]}],"summary":"Complete a reminder","tags":["reminders"]}},"/reminders/{id}/snooze":{"post":{"parameters":[{"description":"Reminder ID","in":"path","name":"id","required":true,"schema":{"type":"integer"}}],"requestBody":{"content":{"application/json":{"schema":{"properties":{"days":{"type":"integer"},"until":{"type":"string"}},"type":"object"}}},"description":"Days to snooze or a date like 2006-01-02 or time like 2006-01-02T15:04:05Z","required":true},"responses":{"200":{"content":{"application/json":{"schema":{"properties":{"message":{"type":"string"},"reminder":{"$ref":"#/components/schemas/models.Reminder"}},"type":"object"}}},"description":"OK"},"400":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/controllers.validationErrorResponse"}}},"description":"Bad Request"},"404":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/controllers.errorResponse"}}},"description":"Not Found"},"500":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/controllers.errorResponse"}}},"description":"Internal Server Error"}},"security":[{"bearerauth":[]}],"summary":"Snooze a reminder","tags":["reminders"]}}},
+    "openapi": "3.1.0",
+    "servers": [
+        {"url":"/api"}
+    ]
+}`
+
+// SwaggerInfo holds exported Swagger Info so clients can modify it
+var SwaggerInfo = &swag.Spec{
+	Version:          "1.0",
+	Title:            "perema API",
+	Description:      "REST API of perema, a personal relationship manager.",
+	InfoInstanceName: "swagger",
+	SwaggerTemplate:  docTemplate,
+	LeftDelim:        "{{",
+	RightDelim:       "}}",
+}
+
+func init() {
+	swag.Register(SwaggerInfo.InstanceName(), SwaggerInfo)
+}
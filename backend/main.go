@@ -1,77 +1,202 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"log"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
 	"perema/config"
+	"perema/controllers"
+	"perema/middleware"
 	"perema/models"
 	"perema/routes"
 	"perema/services"
-
+	"syscall"
 	"time"
+	_ "time/tzdata" // Timezones work without zoneinfo installed on the host
 
-	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
 	"github.com/go-co-op/gocron"
-	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
 )
 
+// The OpenAPI spec in docs is generated from the annotations of the handlers, run go generate after changing them
+//
+//go:generate go run github.com/swaggo/swag/v2/cmd/swag init --quiet --v3.1 --parseDependencyLevel 1 --propertyStrategy pascalcase --outputTypes go,json --output docs
+
+//	@title			perema API
+//	@version		1.0
+//	@description	REST API of perema, a personal relationship manager.
+//	@license.name	MIT
+//	@servers.url	/api
+
+//	@securitydefinitions.bearerauth	BearerAuth
+
 func main() {
+	// Everything is logged as JSON, including messages of the log package
+	slog.SetDefault(slog.New(slog.NewJSONHandler(os.Stdout, nil)))
+
 	log.Println("Loading server...")
 
 	log.Println("Loading configuration...")
 	cfg := config.LoadConfig()
 
 	log.Println("Loading database...")
-	db, err := gorm.Open(sqlite.Open(cfg.DBPath), &gorm.Config{})
+	if cfg.DBDriver == services.DriverSQLite {
+		restored, err := services.ApplyPendingRestore(cfg.DBPath, time.Now())
+		if err != nil {
+			log.Fatalf("failed to restore backup: %v", err)
+		}
+		if restored {
+			log.Println("Restored database from uploaded backup")
+		}
+	}
+	db, err := services.OpenDatabase(cfg)
 	if err != nil {
-		log.Fatalf("failed to connect database")
+		log.Fatalf("failed to connect database: %v", err)
+	}
+	if err := services.RegisterCircleNormalization(db, cfg.CircleCaseFolding); err != nil {
+		log.Fatalf("failed to register circle normalization: %v", err)
 	}
 
 	log.Println("Loading migrations...")
-	if err := db.AutoMigrate(&models.Contact{}, &models.Activity{}, &models.Note{}, models.Relationship{}, models.Reminder{}, models.User{}); err != nil {
+	if err := db.AutoMigrate(&models.Contact{}, &models.Activity{}, &models.Note{}, models.Relationship{}, models.Reminder{}, models.User{}, models.NoteAttachment{}, models.APIKey{}, models.AuditLog{}, models.ContactMethod{}, models.ContactAttachment{}, models.Webhook{}, models.WebhookDeadLetter{}, models.CustomDate{}); err != nil {
 		log.Fatalf("failed to migrate database schema: %v", err)
 	}
+	if err := services.MigrateAddresses(db); err != nil {
+		log.Fatalf("failed to migrate addresses: %v", err)
+	}
+	if err := services.BackfillReminderStatus(db); err != nil {
+		log.Fatalf("failed to backfill the status of reminders: %v", err)
+	}
+	if err := services.BackfillContactMethods(db); err != nil {
+		log.Fatalf("failed to create contact methods: %v", err)
+	}
+	if err := services.SeedAdminUser(db, cfg); err != nil {
+		log.Fatalf("failed to create admin user: %v", err)
+	}
+	if err := services.AssignOwnerlessRecords(db); err != nil {
+		log.Fatalf("failed to assign existing data to a user: %v", err)
+	}
+	if err := services.NormalizeGenders(db); err != nil {
+		log.Fatalf("failed to normalize the genders of contacts: %v", err)
+	}
 
 	log.Println("Running scheduler...")
-	// Schedule the reminder task daily
-	if !cfg.UseSendgrid {
-		log.Printf("WARN: No Mails to be sent since Sendgrid configuration is not set")
+	// Mails are sent at the configured times of the local timezone
+	scheduler := gocron.NewScheduler(cfg.Timezone)
+	emailSender, err := services.NewEmailSender(cfg)
+	if err != nil {
+		log.Printf("ERROR: No mails are sent since the %s configuration is not complete: %v", cfg.EmailProvider, err)
+	} else {
+		scheduleMailJobs(scheduler, db, cfg, emailSender)
+	}
+	if cfg.BackupDir != "" && cfg.DBDriver != services.DriverSQLite {
+		log.Println("WARN: Scheduled backups are only supported for SQLite, use pg_dump for PostgreSQL")
+	} else if cfg.BackupDir != "" {
+		scheduleBackupJob(scheduler, db, cfg)
+	}
+	webhooks := services.NewWebhookDispatcher(db, 4, cfg.WebhookPrivateTargets)
+	scheduleWebhookJobs(scheduler, db, cfg, webhooks)
+	if len(scheduler.Jobs()) > 0 {
+		scheduler.StartAsync()
+		log.Printf("Scheduler started with %d jobs", len(scheduler.Jobs()))
 	}
-	s := gocron.NewScheduler(time.UTC)
-	s.Every(1).Day().At(cfg.ReminderTime).Do(func() {
-		if err := services.SendBirthdayReminders(db); err != nil {
-			log.Printf("Error sending birthday reminders: %v", err)
-		}
-	})
-	go s.StartBlocking()
 
-	r := gin.Default()
+	r := gin.New()
+	r.Use(middleware.RequestLogger(), gin.Recovery())
 
-	// Enable CORS for all origins, methods, and headers
-	r.Use(cors.New(cors.Config{
-		AllowOrigins:     []string{cfg.FrontendURL},
-		AllowMethods:     []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowHeaders:     []string{"Origin", "Content-Type", "Authorization"},
-		ExposeHeaders:    []string{"Content-Length"},
-		AllowCredentials: true,
-		MaxAge:           12 * time.Hour,
-	}))
+	// The frontend is served separately and may only call the API from the configured origins
+	r.Use(middleware.CORS(cfg, "Content-Length", "ETag", middleware.RequestIDHeader, controllers.LimitClampedHeader))
 
 	r.SetTrustedProxies(cfg.TrustedProxies)
+	r.Use(middleware.RateLimit(cfg.RateLimitPerMinute, cfg.RateLimitBurst))
 
-	// Inject db into context
+	// Inject db and the webhook dispatcher into context
 	r.Use(func(c *gin.Context) {
 		c.Set("db", db)
+		c.Set("webhooks", webhooks)
 		c.Next()
 	})
+	r.Use(middleware.Transaction())
 
 	// Register all routes from routes.go
 	routes.RegisterRoutes(r, cfg)
 
-	log.Printf("Starting server on Port :%s...", cfg.Port)
-	if err := r.Run(fmt.Sprintf(":%s", cfg.Port)); err != nil {
-		log.Fatalf("Failed to run server: %v", err)
+	server := &http.Server{Addr: fmt.Sprintf(":%s", cfg.Port), Handler: r}
+	go func() {
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatalf("Failed to run server: %v", err)
+		}
+	}()
+	log.Printf("Server listening on port :%s", cfg.Port)
+
+	// On SIGINT or SIGTERM stop accepting requests, let running ones finish for up to 10 seconds,
+	// then stop the scheduler and close the database
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+	<-quit
+	log.Println("Shutting down...")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := server.Shutdown(ctx); err != nil {
+		log.Printf("Failed to shut down server gracefully: %v", err)
+	}
+	if scheduler.IsRunning() {
+		scheduler.Stop()
+	}
+	webhooks.Stop()
+	if sqlDB, err := db.DB(); err == nil {
+		if err := sqlDB.Close(); err != nil {
+			log.Printf("Failed to close database: %v", err)
+		}
+	}
+	log.Println("Shutdown complete")
+}
+
+// scheduleMailJobs registers the daily birthday and reminder mails and the weekly digest as enabled in the configuration
+func scheduleMailJobs(s *gocron.Scheduler, db *gorm.DB, cfg *config.Config, emailSender services.EmailSender) {
+	if cfg.DailyReminders {
+		s.Every(1).Day().At(cfg.ReminderTime).Do(func() {
+			if err := services.SendBirthdayReminders(db, cfg, emailSender); err != nil {
+				slog.Error("Failed to send birthday reminders", "error", err)
+			}
+			if err := services.SendDueReminders(db, cfg, emailSender); err != nil {
+				slog.Error("Failed to send due reminders", "error", err)
+			}
+		})
 	}
+	if cfg.WeeklyDigest {
+		s.Every(1).Monday().At(cfg.DigestTime).Do(func() {
+			if err := services.SendWeeklyDigest(db, cfg, emailSender); err != nil {
+				slog.Error("Failed to send weekly digest", "error", err)
+			}
+		})
+	}
+}
+
+// scheduleWebhookJobs notifies webhooks of the reminders due each day at the reminder time
+func scheduleWebhookJobs(s *gocron.Scheduler, db *gorm.DB, cfg *config.Config, webhooks *services.WebhookDispatcher) {
+	s.Every(1).Day().At(cfg.ReminderTime).Do(func() {
+		if err := services.DispatchDueReminders(db, cfg, webhooks); err != nil {
+			slog.Error("Failed to notify webhooks of due reminders", "error", err)
+		}
+	})
+}
+
+// scheduleBackupJob backs up the SQLite database on startup and then every configured interval
+func scheduleBackupJob(s *gocron.Scheduler, db *gorm.DB, cfg *config.Config) {
+	s.Every(cfg.BackupIntervalHours).Hours().Do(func() {
+		path, size, err := services.CreateScheduledBackup(db, cfg.BackupDir, cfg.BackupRetention, time.Now())
+		if err != nil {
+			slog.Error("Failed to create backup", "dir", cfg.BackupDir, "error", err)
+			return
+		}
+		slog.Info("Created backup", "path", path, "size", size)
+	})
 }
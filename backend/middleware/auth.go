@@ -4,10 +4,13 @@ import (
 	"fmt"
 	"net/http"
 	"perema/config"
+	"perema/models"
+	"perema/services"
 	"strings"
 
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v4"
+	"gorm.io/gorm"
 )
 
 func AuthMiddleware(cfg *config.Config) gin.HandlerFunc {
@@ -28,6 +31,27 @@ func AuthMiddleware(cfg *config.Config) gin.HandlerFunc {
 
 		tokenString = strings.TrimPrefix(tokenString, "Bearer ")
 
+		// API keys are an alternative to tokens for scripts
+		if strings.HasPrefix(tokenString, models.APIKeyPrefix) {
+			db := c.MustGet("db").(*gorm.DB)
+			apiKey, err := services.AuthenticateAPIKey(db, tokenString)
+			if err != nil {
+				c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid API key"})
+				c.Abort()
+				return
+			}
+			c.Set("user_id", apiKey.UserID)
+			c.Next()
+			return
+		}
+
+		// Tokens signed with an empty key could be forged by anyone
+		if cfg.JWTSecretKey == "" {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+			c.Abort()
+			return
+		}
+
 		token, err := jwt.Parse(tokenString, func(token *jwt.Token) (any, error) {
 			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
 				return nil, fmt.Errorf("unexpected signing method")
@@ -41,6 +65,55 @@ func AuthMiddleware(cfg *config.Config) gin.HandlerFunc {
 			return
 		}
 
+		// All data is scoped to the user the token was issued for
+		claims, _ := token.Claims.(jwt.MapClaims)
+		userID, ok := claims["user_id"].(float64)
+		if !ok || userID < 1 {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+			c.Abort()
+			return
+		}
+		c.Set("user_id", uint(userID))
+
+		c.Next()
+	}
+}
+
+// CardDAVAuth authenticates address book clients, which only support basic authentication, with an API key
+// as password. The user name is not checked.
+func CardDAVAuth() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		_, password, ok := c.Request.BasicAuth()
+		if !ok || !strings.HasPrefix(password, models.APIKeyPrefix) {
+			c.Header("WWW-Authenticate", `Basic realm="perema", charset="UTF-8"`)
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "API key required as password"})
+			c.Abort()
+			return
+		}
+
+		db := c.MustGet("db").(*gorm.DB)
+		apiKey, err := services.AuthenticateAPIKey(db, password)
+		if err != nil {
+			c.Header("WWW-Authenticate", `Basic realm="perema", charset="UTF-8"`)
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid API key"})
+			c.Abort()
+			return
+		}
+		c.Set("user_id", apiKey.UserID)
+		c.Next()
+	}
+}
+
+// AdminOnly restricts routes to the admin user configured by ADMIN_EMAIL. It must run after AuthMiddleware.
+func AdminOnly(cfg *config.Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		db := c.MustGet("db").(*gorm.DB)
+		var user models.User
+		if cfg.AdminEmail == "" || db.First(&user, c.GetUint("user_id")).Error != nil || !strings.EqualFold(user.Email, cfg.AdminEmail) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Admin access required"})
+			c.Abort()
+			return
+		}
 		c.Next()
 	}
 }
@@ -0,0 +1,28 @@
+package middleware
+
+import (
+	"perema/config"
+	"slices"
+	"time"
+
+	"github.com/gin-contrib/cors"
+	"github.com/gin-gonic/gin"
+)
+
+// CORS lets the configured origins call the API from the browser and answers their preflight requests.
+// Requests from other origins are rejected, so without any allowed origin only same-origin requests are served.
+// Allowing all origins echoes the origin of the request, as browsers reject a wildcard for requests with credentials.
+func CORS(cfg *config.Config, exposeHeaders ...string) gin.HandlerFunc {
+	allowed := func(origin string) bool {
+		return cfg.CORSAllowAllOrigins || slices.Contains(cfg.CORSAllowedOrigins, origin)
+	}
+
+	return cors.New(cors.Config{
+		AllowOriginFunc:  allowed,
+		AllowMethods:     cfg.CORSAllowedMethods,
+		AllowHeaders:     cfg.CORSAllowedHeaders,
+		ExposeHeaders:    exposeHeaders,
+		AllowCredentials: cfg.CORSAllowCredentials,
+		MaxAge:           12 * time.Hour,
+	})
+}
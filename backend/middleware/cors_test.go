@@ -0,0 +1,65 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"perema/config"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCORS(t *testing.T) {
+	gin.SetMode(gin.ReleaseMode)
+	cfg := &config.Config{
+		CORSAllowedOrigins:   []string{"http://localhost:8081"},
+		CORSAllowedMethods:   []string{"GET", "POST", "OPTIONS"},
+		CORSAllowedHeaders:   []string{"Content-Type", "Authorization"},
+		CORSAllowCredentials: true,
+	}
+
+	router := gin.New()
+	router.Use(CORS(cfg, "ETag"))
+	router.GET("/api/contacts", func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	send := func(method, origin string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "http://perema.example.com/api/contacts", nil)
+		if origin != "" {
+			req.Header.Set("Origin", origin)
+		}
+		if method == "OPTIONS" {
+			req.Header.Set("Access-Control-Request-Method", "GET")
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	// The frontend gets its origin back together with the credentials and exposed headers
+	w := send("GET", "http://localhost:8081")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "http://localhost:8081", w.Header().Get("Access-Control-Allow-Origin"))
+	assert.Equal(t, "true", w.Header().Get("Access-Control-Allow-Credentials"))
+	assert.Equal(t, "Etag", w.Header().Get("Access-Control-Expose-Headers"))
+
+	// Preflight requests are answered without reaching the handlers
+	w = send("OPTIONS", "http://localhost:8081")
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	assert.Equal(t, "GET,POST,OPTIONS", w.Header().Get("Access-Control-Allow-Methods"))
+	assert.Equal(t, "Content-Type,Authorization", w.Header().Get("Access-Control-Allow-Headers"))
+
+	// Other origins are rejected, requests of the same origin or without an origin are not affected
+	assert.Equal(t, http.StatusForbidden, send("GET", "http://evil.example.com").Code)
+	assert.Equal(t, http.StatusForbidden, send("OPTIONS", "http://evil.example.com").Code)
+	assert.Equal(t, http.StatusOK, send("GET", "http://perema.example.com").Code)
+	assert.Equal(t, http.StatusOK, send("GET", "").Code)
+
+	// For local development all origins can be allowed, the origin is echoed as a wildcard would be rejected
+	cfg.CORSAllowAllOrigins = true
+	w = send("GET", "http://localhost:3000")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "http://localhost:3000", w.Header().Get("Access-Control-Allow-Origin"))
+}
@@ -0,0 +1,48 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRateLimiterAllow(t *testing.T) {
+	limiter := &rateLimiter{rate: 1, burst: 2, buckets: map[string]*bucket{}}
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	allowed, _ := limiter.allow("a", now)
+	assert.True(t, allowed)
+	allowed, _ = limiter.allow("a", now)
+	assert.True(t, allowed)
+
+	// The burst is used up, other clients are not affected
+	allowed, retryAfter := limiter.allow("a", now)
+	assert.False(t, allowed)
+	assert.Equal(t, time.Second, retryAfter)
+	allowed, _ = limiter.allow("b", now)
+	assert.True(t, allowed)
+
+	// Tokens are refilled over time
+	allowed, _ = limiter.allow("a", now.Add(time.Second))
+	assert.True(t, allowed)
+}
+
+func TestRateLimit(t *testing.T) {
+	gin.SetMode(gin.ReleaseMode)
+	router := gin.New()
+	router.Use(RateLimit(1, 1))
+	router.GET("/", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "60", w.Header().Get("Retry-After"))
+}
@@ -0,0 +1,59 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRequestLogger(t *testing.T) {
+	gin.SetMode(gin.ReleaseMode)
+	var output bytes.Buffer
+	defaultLogger := slog.Default()
+	slog.SetDefault(slog.New(slog.NewJSONHandler(&output, nil)))
+	defer slog.SetDefault(defaultLogger)
+
+	router := gin.New()
+	router.Use(RequestLogger())
+	router.GET("/contacts/:id", func(c *gin.Context) {
+		Logger(c).Warn("Contact not found", "contact_id", c.Param("id"))
+		c.Status(http.StatusNotFound)
+	})
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/contacts/7", nil))
+	requestID := w.Header().Get(RequestIDHeader)
+	assert.Len(t, requestID, 16)
+
+	// Both the message of the handler and the request are logged with the request ID
+	var entries []map[string]any
+	for _, line := range bytes.Split(bytes.TrimSpace(output.Bytes()), []byte("\n")) {
+		var entry map[string]any
+		assert.NoError(t, json.Unmarshal(line, &entry))
+		entries = append(entries, entry)
+	}
+	assert.Len(t, entries, 2)
+	assert.Equal(t, "7", entries[0]["contact_id"])
+	assert.Equal(t, requestID, entries[0]["request_id"])
+	assert.Equal(t, "/contacts/7", entries[1]["path"])
+	assert.Equal(t, float64(http.StatusNotFound), entries[1]["status"])
+	assert.Equal(t, requestID, entries[1]["request_id"])
+
+	// IDs set by a proxy are kept unless they are unsafe to log
+	req := httptest.NewRequest("GET", "/contacts/7", nil)
+	req.Header.Set(RequestIDHeader, "proxy-id-1")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, "proxy-id-1", w.Header().Get(RequestIDHeader))
+
+	req.Header.Set(RequestIDHeader, "bad\nid")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.NotEqual(t, "bad\nid", w.Header().Get(RequestIDHeader))
+}
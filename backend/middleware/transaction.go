@@ -0,0 +1,147 @@
+package middleware
+
+import (
+	"bytes"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// Context key of the functions to run once the transaction of the request is committed
+const afterCommitKey = "after_commit"
+
+// AfterCommit runs fn once the changes of the request are committed, e.g. to notify other systems only of
+// changes which are saved. Requests without a transaction run fn right away. fn is dropped on a rollback.
+func AfterCommit(c *gin.Context, fn func()) {
+	hooks, ok := c.Get(afterCommitKey)
+	if !ok {
+		fn()
+		return
+	}
+	c.Set(afterCommitKey, append(hooks.([]func()), fn))
+}
+
+// bufferedWriter holds back the response of the handler, so that it is only sent once the transaction is
+// committed and a failed commit can still be answered with an error
+type bufferedWriter struct {
+	gin.ResponseWriter
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func newBufferedWriter(w gin.ResponseWriter) *bufferedWriter {
+	return &bufferedWriter{ResponseWriter: w, header: http.Header{}}
+}
+
+func (w *bufferedWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *bufferedWriter) WriteHeader(code int) {
+	if w.status == 0 {
+		w.status = code
+	}
+}
+
+func (w *bufferedWriter) WriteHeaderNow() {
+	w.WriteHeader(http.StatusOK)
+}
+
+func (w *bufferedWriter) Write(data []byte) (int, error) {
+	w.WriteHeader(http.StatusOK)
+	return w.body.Write(data)
+}
+
+func (w *bufferedWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+func (w *bufferedWriter) Status() int {
+	if w.status == 0 {
+		return http.StatusOK
+	}
+	return w.status
+}
+
+func (w *bufferedWriter) Size() int {
+	if w.status == 0 {
+		return -1
+	}
+	return w.body.Len()
+}
+
+func (w *bufferedWriter) Written() bool {
+	return w.status != 0
+}
+
+// Flush is a no-op, the response is sent at once by flush
+func (w *bufferedWriter) Flush() {}
+
+// flush sends the held back response
+func (w *bufferedWriter) flush() {
+	if w.status == 0 {
+		return
+	}
+	for key, values := range w.header {
+		w.ResponseWriter.Header()[key] = values
+	}
+	w.ResponseWriter.WriteHeader(w.status)
+	w.ResponseWriter.Write(w.body.Bytes())
+}
+
+// Transaction runs each request in a database transaction which replaces the "db" in the context.
+// The transaction is committed if the handler responds with a 2xx status and rolled back otherwise,
+// so handlers with several writes never leave partial changes behind. The response is held back until the
+// commit, if it fails the client gets an error instead of a success for changes which were not saved.
+// Read-only requests use the database directly without the overhead of a transaction, this includes the
+// CardDAV methods PROPFIND and REPORT.
+func Transaction() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		switch c.Request.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions, "PROPFIND", "REPORT":
+			c.Next()
+			return
+		}
+
+		tx := c.MustGet("db").(*gorm.DB).Begin()
+		if tx.Error != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start transaction"})
+			c.Abort()
+			return
+		}
+		c.Set("db", tx)
+		c.Set(afterCommitKey, []func(){})
+
+		writer := newBufferedWriter(c.Writer)
+		c.Writer = writer
+
+		committed := false
+		defer func() {
+			// Also rolls back if the handler panicked, the recovery then responds with the original writer
+			c.Writer = writer.ResponseWriter
+			if !committed {
+				tx.Rollback()
+			}
+		}()
+
+		c.Next()
+		c.Writer = writer.ResponseWriter
+
+		if writer.Status() >= 200 && writer.Status() < 300 && len(c.Errors) == 0 {
+			if err := tx.Commit().Error; err != nil {
+				Logger(c).Error("Failed to commit transaction", "error", err)
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save changes"})
+				return
+			}
+			committed = true
+			writer.flush()
+			for _, hook := range c.MustGet(afterCommitKey).([]func()) {
+				hook()
+			}
+			return
+		}
+		writer.flush()
+	}
+}
@@ -0,0 +1,122 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+type record struct {
+	ID   uint
+	Name string
+}
+
+func TestTransaction(t *testing.T) {
+	gin.SetMode(gin.ReleaseMode)
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	assert.NoError(t, err)
+	sqlDB, _ := db.DB()
+	sqlDB.SetMaxOpenConns(1) // Every connection to an in-memory database has its own database
+	db.AutoMigrate(&record{})
+
+	committedHooks := 0
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Set("db", db)
+		c.Next()
+	})
+	router.Use(Transaction())
+	router.POST("/records/:status", func(c *gin.Context) {
+		tx := c.MustGet("db").(*gorm.DB)
+		tx.Create(&record{Name: "first"})
+		tx.Create(&record{Name: "second"})
+		AfterCommit(c, func() { committedHooks++ })
+		if c.Param("status") == "fail" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Failed"})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{})
+	})
+	router.GET("/records", func(c *gin.Context) {
+		assert.Same(t, db, c.MustGet("db"))
+		c.Status(http.StatusOK)
+	})
+
+	send := func(method, url string) int {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(method, url, nil))
+		return w.Code
+	}
+
+	// Failed requests leave no partial changes behind
+	assert.Equal(t, http.StatusBadRequest, send("POST", "/records/fail"))
+	var count int64
+	db.Model(&record{}).Count(&count)
+	assert.Equal(t, int64(0), count)
+	assert.Zero(t, committedHooks)
+
+	assert.Equal(t, http.StatusOK, send("POST", "/records/ok"))
+	db.Model(&record{}).Count(&count)
+	assert.Equal(t, int64(2), count)
+	assert.Equal(t, 1, committedHooks)
+
+	// Reads use the database without a transaction
+	assert.Equal(t, http.StatusOK, send("GET", "/records"))
+}
+
+func TestTransactionCommitFailure(t *testing.T) {
+	gin.SetMode(gin.ReleaseMode)
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	assert.NoError(t, err)
+	sqlDB, _ := db.DB()
+	sqlDB.SetMaxOpenConns(1)
+
+	// Deferred foreign keys are only checked on commit, which makes the commit fail
+	db.Exec("PRAGMA foreign_keys = ON")
+	db.Exec("CREATE TABLE parents (id INTEGER PRIMARY KEY)")
+	db.Exec("CREATE TABLE children (id INTEGER PRIMARY KEY, parent_id INTEGER REFERENCES parents(id) DEFERRABLE INITIALLY DEFERRED)")
+
+	committedHooks := 0
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Set("db", db)
+		c.Next()
+	})
+	router.Use(Transaction())
+	router.POST("/children/:parent", func(c *gin.Context) {
+		tx := c.MustGet("db").(*gorm.DB)
+		if err := tx.Exec("INSERT INTO children (parent_id) VALUES (?)", c.Param("parent")).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		AfterCommit(c, func() { committedHooks++ })
+		c.Header("Location", "/children/1")
+		c.JSON(http.StatusCreated, gin.H{"message": "Child created"})
+	})
+
+	// The success of the handler is not sent since its changes were not saved
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("POST", "/children/99", nil))
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.Empty(t, w.Header().Get("Location"))
+	assert.NotContains(t, w.Body.String(), "Child created")
+	assert.Zero(t, committedHooks)
+
+	var count int64
+	db.Table("children").Count(&count)
+	assert.Equal(t, int64(0), count)
+
+	// Responses of committed transactions are sent unchanged
+	db.Exec("INSERT INTO parents (id) VALUES (1)")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("POST", "/children/1", nil))
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Equal(t, "/children/1", w.Header().Get("Location"))
+	assert.Contains(t, w.Body.String(), "Child created")
+	assert.Equal(t, 1, committedHooks)
+}
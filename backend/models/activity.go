@@ -1,6 +1,8 @@
 package models
 
 import (
+	"slices"
+	"strings"
 	"time"
 
 	"gorm.io/gorm"
@@ -9,9 +11,32 @@ import (
 // Activity struct to represent shared activities with one or more contacts
 type Activity struct {
 	gorm.Model
-	Title       string    `json:"title"`
-	Description string    `json:"description"`
-	Location    string    `json:"location"`
-	Date        time.Time `json:"date"`
-	Contacts    []Contact `gorm:"many2many:activity_contacts;foreignKey:ID;joinForeignKey:ActivityID;References:ID;joinReferences:ContactID" json:"contacts,omitempty"`
+	UserID       uint      `gorm:"index" json:"-"` // Owner of the activity
+	Title        string    `json:"title"`
+	Description  string    `json:"description"`
+	Location     string    `json:"location"`
+	ActivityType string    `gorm:"default:other" json:"activity_type"` // One of ActivityTypes
+	Date         time.Time `json:"date"`
+	Contacts     []Contact `gorm:"many2many:activity_contacts;foreignKey:ID;joinForeignKey:ActivityID;References:ID;joinReferences:ContactID" json:"contacts,omitempty"`
+}
+
+// Kinds of activities
+const (
+	ActivityCall    = "call"
+	ActivityMeeting = "meeting"
+	ActivityMessage = "message"
+	ActivityGift    = "gift"
+	ActivityOther   = "other"
+)
+
+var ActivityTypes = []string{ActivityCall, ActivityMeeting, ActivityMessage, ActivityGift, ActivityOther}
+
+// ValidActivityType reports whether the type is one of ActivityTypes
+func ValidActivityType(activityType string) bool {
+	return slices.Contains(ActivityTypes, activityType)
+}
+
+// InvalidActivityTypeMessage lists the valid activity types
+func InvalidActivityTypeMessage() string {
+	return "Invalid activity type, use one of " + strings.Join(ActivityTypes, ", ")
 }
@@ -0,0 +1,50 @@
+package models
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// Address is the postal address of a contact, stored in the address_ columns of the contacts table
+type Address struct {
+	Street     string `json:"street"`
+	PostalCode string `json:"postal_code"`
+	City       string `gorm:"index" json:"city"`
+	Region     string `json:"region"`
+	Country    string `json:"country"`
+}
+
+// AddressColumns are the columns of the embedded address of a contact
+var AddressColumns = []string{"address_street", "address_postal_code", "address_city", "address_region", "address_country"}
+
+// UnmarshalJSON accepts the address object and, as before addresses were structured, a plain string
+// which is taken as the street. Fields missing in the object keep their value.
+func (a *Address) UnmarshalJSON(data []byte) error {
+	var street *string
+	if err := json.Unmarshal(data, &street); err == nil {
+		*a = Address{}
+		if street != nil {
+			a.Street = *street
+		}
+		return nil
+	}
+
+	type fields Address // Without the UnmarshalJSON method
+	return json.Unmarshal(data, (*fields)(a))
+}
+
+// String formats the address on one line, e.g. "Main Street 1, 10115 Berlin, Germany"
+func (a Address) String() string {
+	var parts []string
+	for _, part := range []string{a.Street, strings.TrimSpace(a.PostalCode + " " + a.City), a.Region, a.Country} {
+		if part != "" {
+			parts = append(parts, part)
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// IsEmpty reports whether no part of the address is known
+func (a Address) IsEmpty() bool {
+	return a == Address{}
+}
@@ -0,0 +1,29 @@
+package models
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAddressUnmarshalJSON(t *testing.T) {
+	var address Address
+	assert.NoError(t, json.Unmarshal([]byte(`{"street": "Main Street 1", "city": "Berlin"}`), &address))
+	assert.Equal(t, Address{Street: "Main Street 1", City: "Berlin"}, address)
+
+	// Plain strings replace the whole address
+	assert.NoError(t, json.Unmarshal([]byte(`"Station Road 2"`), &address))
+	assert.Equal(t, Address{Street: "Station Road 2"}, address)
+
+	assert.NoError(t, json.Unmarshal([]byte(`null`), &address))
+	assert.True(t, address.IsEmpty())
+
+	assert.Error(t, json.Unmarshal([]byte(`42`), &address))
+}
+
+func TestAddressString(t *testing.T) {
+	assert.Equal(t, "Main Street 1, 10115 Berlin, Germany", Address{Street: "Main Street 1", PostalCode: "10115", City: "Berlin", Country: "Germany"}.String())
+	assert.Equal(t, "Berlin", Address{City: "Berlin"}.String())
+	assert.Equal(t, "", Address{}.String())
+}
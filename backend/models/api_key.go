@@ -0,0 +1,20 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// APIKeyPrefix starts every API key, so keys can be told apart from JWTs in the Authorization header
+const APIKeyPrefix = "prm_"
+
+// APIKey allows scripts to authenticate as a user without logging in. Only a hash of the key is stored.
+type APIKey struct {
+	gorm.Model
+	UserID     uint       `gorm:"index" json:"-"`
+	Name       string     `json:"name" binding:"required,max=100"`
+	Hint       string     `json:"hint"`                 // Last characters of the key to recognize it
+	KeyHash    string     `gorm:"uniqueIndex" json:"-"` // SHA-256 hash of the key
+	LastUsedAt *time.Time `json:"last_used_at"`
+}
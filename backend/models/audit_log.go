@@ -0,0 +1,31 @@
+package models
+
+import "time"
+
+// Audited entity types and actions
+const (
+	AuditEntityContact  = "contact"
+	AuditEntityReminder = "reminder"
+
+	AuditCreate = "create"
+	AuditUpdate = "update"
+	AuditDelete = "delete"
+)
+
+// AuditChange holds the previous and the new value of a changed field
+type AuditChange struct {
+	Old any `json:"old"`
+	New any `json:"new"`
+}
+
+// AuditLog records a change of a contact or of one of its reminders. Only the changed fields are stored.
+type AuditLog struct {
+	ID         uint                   `gorm:"primarykey" json:"id"`
+	CreatedAt  time.Time              `json:"created_at"`
+	UserID     uint                   `gorm:"index" json:"-"`
+	ContactID  uint                   `gorm:"index" json:"contact_id"` // Contact the entity belongs to, to list its history
+	EntityType string                 `gorm:"not null" json:"entity_type"`
+	EntityID   uint                   `gorm:"not null" json:"entity_id"`
+	Action     string                 `gorm:"not null" json:"action"`
+	Changes    map[string]AuditChange `gorm:"type:text;serializer:json" json:"changes"` // Changed fields by their JSON names
+}
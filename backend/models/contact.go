@@ -1,28 +1,174 @@
 package models
 
 import (
+	"net/mail"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
 	"gorm.io/gorm"
 )
 
 type Contact struct {
 	gorm.Model
-	Firstname          string         `gorm:"type:text not null COLLATE NOCASE" json:"firstname"`
-	Lastname           string         `gorm:"type:text COLLATE NOCASE" json:"lastname"`
-	Nickname           string         `gorm:"type:text COLLATE NOCASE" json:"nickname"`
-	Gender             string         `json:"gender"`
-	Email              string         `gorm:"type:text COLLATE NOCASE" json:"email"`
-	Phone              string         `json:"phone"`
-	Birthday           *Date          `json:"birthday"`
-	Photo              string         `json:"photo"`                                     // Path to the profile photo
-	PhotoThumbnail     string         `json:"photo_thumnbnail"`                          // Path to the profile photo thumbnail
-	Relationships      []Relationship `gorm:"foreignKey:ContactID" json:"relationships"` // Has many relationships
-	Address            string         `json:"address"`                                   // Full address as a string
-	HowWeMet           string         `json:"how_we_met"`                                // Text field
-	FoodPreference     string         `json:"food_preference"`                           // Text field
-	WorkInformation    string         `json:"work_information"`                          // Text field
-	ContactInformation string         `json:"contact_information"`                       // Additional contact information
-	Circles            []string       `gorm:"type:text;serializer:json" json:"circles"`  // Serialize Circles properly
-	Activities         []Activity     `gorm:"many2many:activity_contacts;foreignKey:ID;joinForeignKey:ContactID;References:ID;joinReferences:ActivityID" json:"activities,omitempty"`
-	Notes              []Note         `json:"notes,omitempty"`     // One-to-many relationship with notes
-	Reminders          []Reminder     `json:"reminders,omitempty"` // One-to-many relationship with reminders
+	UserID               uint                `gorm:"index" json:"-"`           // Owner of the contact
+	Version              uint                `gorm:"default:1" json:"version"` // Incremented on every update to detect concurrent edits
+	Firstname            string              `gorm:"type:text not null" json:"firstname"`
+	Lastname             string              `gorm:"type:text" json:"lastname"`
+	Nickname             string              `gorm:"type:text" json:"nickname"`
+	Gender               string              `json:"gender"`
+	Email                string              `gorm:"type:text" json:"email"`
+	Phone                string              `json:"phone"`
+	Birthday             *Date               `json:"birthday"`
+	Deceased             bool                `gorm:"default:false" json:"deceased"`
+	DeceasedDate         *Date               `json:"deceased_date"`                             // Optional date of death
+	Favorite             bool                `gorm:"default:false;index" json:"favorite"`       // Pinned to the top of the contact list
+	Photo                string              `json:"photo"`                                     // Path to the profile photo
+	PhotoThumbnail       string              `json:"photo_thumnbnail"`                          // Path to the profile photo thumbnail
+	Relationships        []Relationship      `gorm:"foreignKey:ContactID" json:"relationships"` // Has many relationships
+	Address              Address             `gorm:"embedded;embeddedPrefix:address_" json:"address"`
+	HowWeMet             string              `json:"how_we_met"`                               // Text field
+	FoodPreference       string              `json:"food_preference"`                          // Text field
+	WorkInformation      string              `json:"work_information"`                         // Text field
+	ContactInformation   string              `json:"contact_information"`                      // Additional contact information
+	Circles              []string            `gorm:"type:text;serializer:json" json:"circles"` // Serialize Circles properly
+	Tags                 []string            `gorm:"type:text;serializer:json" json:"tags"`    // Free labels, unlike circles they do not group contacts
+	ContactFrequencyDays int                 `gorm:"default:0" json:"contact_frequency_days"`  // Goal to get in touch every n days, 0 for none
+	ReminderLeadDays     int                 `gorm:"default:0" json:"reminder_lead_days"`      // Days before the birthday the reminder is sent, 0 for the day itself
+	CadenceHealth        string              `gorm:"-" json:"cadence_health,omitempty"`        // Computed status of the contact frequency goal
+	Summary              string              `gorm:"-" json:"summary,omitempty"`               // Computed one-line description of the contact
+	LastContacted        *Date               `gorm:"-" json:"last_contacted,omitempty"`        // Computed date of the latest activity or note
+	Activities           []Activity          `gorm:"many2many:activity_contacts;foreignKey:ID;joinForeignKey:ContactID;References:ID;joinReferences:ActivityID" json:"activities,omitempty"`
+	Notes                []Note              `json:"notes,omitempty"`           // One-to-many relationship with notes
+	Reminders            []Reminder          `json:"reminders,omitempty"`       // One-to-many relationship with reminders
+	ContactMethods       []ContactMethod     `json:"contact_methods,omitempty"` // All email addresses and phone numbers
+	Attachments          []ContactAttachment `json:"attachments,omitempty"`     // Files attached to the contact
+	CustomDates          []CustomDate        `json:"custom_dates,omitempty"`    // Anniversaries and other dates reminded like birthdays
+}
+
+// Genders a contact can have, an empty gender is allowed as well
+var Genders = []string{"male", "female", "nonbinary", GenderOther, "unspecified"}
+
+// GenderOther is used for genders which are not listed
+const GenderOther = "other"
+
+// Other spellings of the genders, including the German ones used by the frontend
+var genderAliases = map[string]string{
+	"m": "male", "man": "male", "männlich": "male", "mann": "male",
+	"f": "female", "w": "female", "woman": "female", "weiblich": "female", "frau": "female",
+	"non-binary": "nonbinary", "non binary": "nonbinary", "nb": "nonbinary", "divers": "nonbinary", "d": "nonbinary",
+	"o": "other", "andere": "other", "anderes": "other",
+	"u": "unspecified", "unknown": "unspecified", "unbekannt": "unspecified", "keine angabe": "unspecified",
+}
+
+// NormalizeGender returns the gender from Genders matching the value case-insensitively.
+// The second return value is false if the value is not a known gender.
+func NormalizeGender(value string) (string, bool) {
+	gender := strings.ToLower(strings.TrimSpace(value))
+	if alias, ok := genderAliases[gender]; ok {
+		gender = alias
+	}
+	if gender == "" || slices.Contains(Genders, gender) {
+		return gender, true
+	}
+	return value, false
+}
+
+// Digits with an optional leading plus and the usual separators, e.g. "+49 (30) 123-456"
+var phonePattern = regexp.MustCompile(`^\+?[0-9 ()./-]*[0-9][0-9 ()./-]*$`)
+
+// Validate checks the gender, the format of the optional email and phone fields and the range of the reminder lead days.
+// It returns a map of the invalid JSON field names to an error message.
+func (c Contact) Validate() map[string]string {
+	fieldErrors := map[string]string{}
+	if _, ok := NormalizeGender(c.Gender); !ok {
+		fieldErrors["gender"] = "Unknown gender, use one of " + strings.Join(Genders, ", ")
+	}
+	if c.Email != "" && !validEmail(c.Email) {
+		fieldErrors["email"] = "Invalid email address"
+	}
+	if c.Phone != "" && !phonePattern.MatchString(c.Phone) {
+		fieldErrors["phone"] = "Phone number may only contain digits, spaces and + ( ) - . /"
+	}
+	if c.ReminderLeadDays < 0 || c.ReminderLeadDays > 365 {
+		fieldErrors["reminder_lead_days"] = "Reminder lead days must be between 0 and 365"
+	}
+	return fieldErrors
+}
+
+// validEmail accepts plain addresses like "jane@example.com" with a dot in the domain
+func validEmail(email string) bool {
+	address, err := mail.ParseAddress(email)
+	if err != nil || address.Address != email {
+		return false
+	}
+	_, domain, _ := strings.Cut(email, "@")
+	return strings.Contains(strings.Trim(domain, "."), ".")
+}
+
+// AgeAtNextBirthday returns the age the contact turns on their next birthday on or after the given day.
+// The second return value is false if the birthday or its year is unknown.
+func (c Contact) AgeAtNextBirthday(from time.Time, leapDay string) (int, bool) {
+	if c.Birthday == nil || !c.Birthday.HasYear() {
+		return 0, false
+	}
+	return c.Birthday.NextOccurrence(from, leapDay).Year() - c.Birthday.Time.Year(), true
+}
+
+// FillBlanks copies all fields which are empty on the contact from the other contact and adds the circles
+// and tags of the other contact which are missing
+func (c *Contact) FillBlanks(other Contact) {
+	fields := []struct {
+		target *string
+		value  string
+	}{
+		{&c.Firstname, other.Firstname},
+		{&c.Lastname, other.Lastname},
+		{&c.Nickname, other.Nickname},
+		{&c.Gender, other.Gender},
+		{&c.Email, other.Email},
+		{&c.Phone, other.Phone},
+		{&c.HowWeMet, other.HowWeMet},
+		{&c.FoodPreference, other.FoodPreference},
+		{&c.WorkInformation, other.WorkInformation},
+		{&c.ContactInformation, other.ContactInformation},
+	}
+	for _, field := range fields {
+		if *field.target == "" {
+			*field.target = field.value
+		}
+	}
+
+	if c.Birthday == nil || !c.Birthday.Valid {
+		c.Birthday = other.Birthday
+	}
+	if !c.Deceased && other.Deceased {
+		c.Deceased = true
+		c.DeceasedDate = other.DeceasedDate
+	}
+	if c.Address.IsEmpty() {
+		c.Address = other.Address
+	}
+	if c.Photo == "" {
+		c.Photo = other.Photo
+		c.PhotoThumbnail = other.PhotoThumbnail
+	}
+	if c.ContactFrequencyDays == 0 {
+		c.ContactFrequencyDays = other.ContactFrequencyDays
+	}
+	if c.ReminderLeadDays == 0 {
+		c.ReminderLeadDays = other.ReminderLeadDays
+	}
+
+	for _, circle := range other.Circles {
+		if !slices.Contains(c.Circles, circle) {
+			c.Circles = append(c.Circles, circle)
+		}
+	}
+	for _, tag := range other.Tags {
+		if !slices.Contains(c.Tags, tag) {
+			c.Tags = append(c.Tags, tag)
+		}
+	}
 }
@@ -0,0 +1,18 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ContactAttachment is a file, e.g. a scanned document, attached to a contact
+type ContactAttachment struct {
+	gorm.Model
+	ContactID   uint      `gorm:"not null;index" json:"contact_id"`
+	Filename    string    `json:"filename"`     // Original name of the uploaded file
+	StoredName  string    `json:"-"`            // Generated name of the file on disk
+	ContentType string    `json:"content_type"` // Detected content type of the file
+	Size        int64     `json:"size"`         // Size in bytes
+	UploadedAt  time.Time `json:"uploaded_at"`
+}
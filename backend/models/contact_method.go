@@ -0,0 +1,38 @@
+package models
+
+import (
+	"gorm.io/gorm"
+)
+
+// Types of contact methods, the primary method of each type is also stored in the field of the contact
+const (
+	ContactMethodEmail = "email"
+	ContactMethodPhone = "phone"
+)
+
+// ContactMethod is one of possibly several email addresses or phone numbers of a contact
+type ContactMethod struct {
+	gorm.Model
+	ContactID uint   `gorm:"not null;index" json:"contact_id"`
+	Type      string `gorm:"not null" json:"type" binding:"required,oneof=email phone"`
+	Label     string `json:"label" binding:"omitempty,oneof=home work mobile other"`
+	Value     string `gorm:"not null" json:"value" binding:"required,max=255"`
+	IsPrimary bool   `gorm:"default:false" json:"is_primary"` // Exactly one method of each type is primary
+}
+
+// Validate checks the format of the value according to the type.
+// It returns a map of the invalid JSON field names to an error message.
+func (m ContactMethod) Validate() map[string]string {
+	fieldErrors := map[string]string{}
+	switch m.Type {
+	case ContactMethodEmail:
+		if !validEmail(m.Value) {
+			fieldErrors["value"] = "Invalid email address"
+		}
+	case ContactMethodPhone:
+		if !phonePattern.MatchString(m.Value) {
+			fieldErrors["value"] = "Phone number may only contain digits, spaces and + ( ) - . /"
+		}
+	}
+	return fieldErrors
+}
@@ -0,0 +1,91 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAgeAtNextBirthday(t *testing.T) {
+	contact := Contact{Birthday: &Date{Time: time.Date(1990, time.March, 10, 0, 0, 0, 0, time.UTC), Valid: true}}
+
+	cases := []struct {
+		name     string
+		from     time.Time
+		expected int
+	}{
+		{"DayBeforeBirthday", time.Date(2024, time.March, 9, 0, 0, 0, 0, time.UTC), 34},
+		{"OnBirthday", time.Date(2024, time.March, 10, 23, 59, 0, 0, time.UTC), 34},
+		{"DayAfterBirthday", time.Date(2024, time.March, 11, 0, 0, 0, 0, time.UTC), 35},
+		{"EndOfYear", time.Date(2024, time.December, 31, 0, 0, 0, 0, time.UTC), 35},
+	}
+
+	for _, tc := range cases {
+		age, known := contact.AgeAtNextBirthday(tc.from, "feb28")
+		assert.True(t, known, tc.name)
+		assert.Equal(t, tc.expected, age, tc.name)
+	}
+}
+
+func TestAgeAtNextBirthday_Unknown(t *testing.T) {
+	from := time.Date(2024, time.March, 9, 0, 0, 0, 0, time.UTC)
+
+	// Birthdays without a year are stored with year 1
+	yearless := Contact{Birthday: &Date{Time: time.Date(1, time.March, 10, 0, 0, 0, 0, time.UTC), Valid: true}}
+	_, known := yearless.AgeAtNextBirthday(from, "feb28")
+	assert.False(t, known)
+
+	_, known = Contact{}.AgeAtNextBirthday(from, "feb28")
+	assert.False(t, known)
+}
+
+func TestContactValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		contact Contact
+		invalid []string
+	}{
+		{"empty fields", Contact{}, nil},
+		{"valid", Contact{Email: "john.doe+perema@example.co.uk", Phone: "+49 (30) 123-456/7"}, nil},
+		{"email without domain", Contact{Email: "john@"}, []string{"email"}},
+		{"email without dot in domain", Contact{Email: "john@localhost"}, []string{"email"}},
+		{"email with display name", Contact{Email: "John <john@example.com>"}, []string{"email"}},
+		{"phone with letters", Contact{Phone: "call me"}, []string{"phone"}},
+		{"phone without digits", Contact{Phone: "+()"}, []string{"phone"}},
+		{"both invalid", Contact{Email: "john", Phone: "123abc"}, []string{"email", "phone"}},
+		{"known gender in other case", Contact{Gender: "Female"}, nil},
+		{"unknown gender", Contact{Gender: "robot"}, []string{"gender"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fieldErrors := tt.contact.Validate()
+			assert.Len(t, fieldErrors, len(tt.invalid))
+			for _, field := range tt.invalid {
+				assert.Contains(t, fieldErrors, field)
+			}
+		})
+	}
+}
+
+func TestNormalizeGender(t *testing.T) {
+	tests := map[string]string{
+		"male":        "male",
+		" MALE ":      "male",
+		"m":           "male",
+		"Weiblich":    "female",
+		"Non-Binary":  "nonbinary",
+		"Unknown":     "unspecified",
+		"":            "",
+		"unspecified": "unspecified",
+	}
+	for value, expected := range tests {
+		gender, ok := NormalizeGender(value)
+		assert.True(t, ok, value)
+		assert.Equal(t, expected, gender, value)
+	}
+
+	_, ok := NormalizeGender("robot")
+	assert.False(t, ok)
+}
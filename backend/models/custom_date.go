@@ -0,0 +1,38 @@
+package models
+
+import (
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// CustomDate is a date of a contact which is reminded like a birthday, e.g. a work anniversary or the day we met
+type CustomDate struct {
+	gorm.Model
+	ContactID        uint     `gorm:"not null;index" json:"contact_id"`
+	Label            string   `gorm:"not null" json:"label"`
+	Date             Date     `json:"date"`
+	Recurring        bool     `json:"recurring"`                                                              // Reminded every year, otherwise only on the date itself
+	ReminderLeadDays int      `gorm:"default:0" json:"reminder_lead_days"`                                    // Days before the date the reminder is sent, 0 for the day itself
+	Contact          *Contact `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" json:"contact,omitempty"` // Only loaded for reminders
+}
+
+// Validate checks that the custom date has a label, a date and a lead time in the range allowed for birthdays.
+// It returns a map of the invalid JSON field names to an error message.
+func (d CustomDate) Validate() map[string]string {
+	fieldErrors := map[string]string{}
+	if label := strings.TrimSpace(d.Label); label == "" {
+		fieldErrors["label"] = "This field is required"
+	} else if len(label) > 100 {
+		fieldErrors["label"] = "Label must be at most 100 characters"
+	}
+	if !d.Date.Valid {
+		fieldErrors["date"] = "This field is required"
+	} else if !d.Recurring && !d.Date.HasYear() {
+		fieldErrors["date"] = "Dates which do not recur need a year"
+	}
+	if d.ReminderLeadDays < 0 || d.ReminderLeadDays > 365 {
+		fieldErrors["reminder_lead_days"] = "Reminder lead days must be between 0 and 365"
+	}
+	return fieldErrors
+}
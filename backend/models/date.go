@@ -4,6 +4,9 @@ import (
 	"database/sql/driver"
 	"fmt"
 	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
 )
 
 // Custom type for handling dates in "YYYY-MM-DD" format
@@ -49,6 +52,43 @@ func (d Date) ToTime() (time.Time, bool) {
 	return d.Time, d.Valid
 }
 
+// HasYear reports whether the year is known, dates without a known year are stored with year 1
+func (d Date) HasYear() bool {
+	return d.Valid && d.Time.Year() > 1
+}
+
+// NextOccurrence returns the next anniversary of the date (month and day) on or after the given day. In years
+// without Feb 29 dates on Feb 29 occur on Mar 1 if leapDay is "mar1" and on Feb 28 otherwise.
+func (d Date) NextOccurrence(from time.Time, leapDay string) time.Time {
+	from = time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, from.Location())
+	next := d.occurrenceIn(from.Year(), from.Location(), leapDay)
+	if next.Before(from) {
+		next = d.occurrenceIn(from.Year()+1, from.Location(), leapDay)
+	}
+	return next
+}
+
+// occurrenceIn returns the anniversary of the date in the given year
+func (d Date) occurrenceIn(year int, loc *time.Location, leapDay string) time.Time {
+	month, day := d.Time.Month(), d.Time.Day()
+	if month == time.February && day == 29 && time.Date(year, time.February, 29, 0, 0, 0, 0, loc).Day() != 29 {
+		if leapDay == "mar1" {
+			month, day = time.March, 1
+		} else {
+			day = 28
+		}
+	}
+	return time.Date(year, month, day, 0, 0, 0, 0, loc)
+}
+
+// GormDBDataType stores dates as date in PostgreSQL, SQLite keeps the column type chosen by its driver
+func (Date) GormDBDataType(db *gorm.DB, field *schema.Field) string {
+	if db.Dialector.Name() == "postgres" {
+		return "date"
+	}
+	return ""
+}
+
 // Implement the driver.Valuer interface for GORM to handle the Date type
 func (d Date) Value() (driver.Value, error) {
 	if !d.Valid {
@@ -9,8 +9,11 @@ import (
 // Note struct to represent notes attached to a contact
 type Note struct {
 	gorm.Model
-	Content   string    `json:"content"`
-	Date      time.Time `json:"date"`
-	ContactID *uint     `json:"contact_id"`
-	Contact   Contact   `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" json:"contact,omitempty"`
+	UserID      uint             `gorm:"index" json:"-"` // Owner of the note
+	Content     string           `json:"content"`
+	Date        time.Time        `json:"date"`
+	Tags        []string         `gorm:"type:text;serializer:json" json:"tags"` // Serialized like the circles of contacts
+	ContactID   *uint            `json:"contact_id"`
+	Contact     Contact          `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" json:"contact,omitempty"`
+	Attachments []NoteAttachment `json:"attachments,omitempty"` // Files such as voice memos attached to the note
 }
@@ -0,0 +1,15 @@
+package models
+
+import (
+	"gorm.io/gorm"
+)
+
+// NoteAttachment is a file, e.g. a voice memo, attached to a note
+type NoteAttachment struct {
+	gorm.Model
+	NoteID      uint   `gorm:"not null" json:"note_id"`
+	Filename    string `json:"filename"`     // Original name of the uploaded file
+	StoredName  string `json:"-"`            // Generated name of the file on disk
+	ContentType string `json:"content_type"` // Detected content type of the file
+	Size        int64  `json:"size"`         // Size in bytes
+}
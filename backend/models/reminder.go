@@ -1,6 +1,7 @@
 package models
 
 import (
+	"strings"
 	"time"
 
 	"gorm.io/gorm"
@@ -8,12 +9,109 @@ import (
 
 type Reminder struct {
 	gorm.Model
-	Message               string     `gorm:"not null type:text" json:"message"`
+	Message               string     `gorm:"not null type:text" json:"message" binding:"required"`
 	ByMail                bool       `gorm:"default:false" json:"by_mail"`
-	RemindAt              time.Time  `gorm:"not null" json:"remind_at"`
-	Recurrence            string     `gorm:"not null" json:"recurrence"`
+	RemindAt              time.Time  `gorm:"not null" json:"remind_at" binding:"required"`
+	Recurrence            string     `gorm:"not null" json:"recurrence" binding:"required"`
+	RecurrenceInterval    int        `gorm:"default:1" json:"recurrence_interval"` // Repeat every n periods of the recurrence
+	NextDue               *time.Time `gorm:"default:null" json:"next_due"`         // Occurrence after the current due date of recurring reminders
 	ReocurrFromCompletion bool       `gorm:"default:true" json:"reoccur_from_completion"`
 	LastSent              *time.Time `gorm:"default:null" json:"last_sent"`
+	Completed             bool       `gorm:"default:false" json:"completed"`
+	Status                string     `gorm:"default:pending" json:"status"` // pending, done or snoozed
 	ContactID             *uint      `gorm:"not null" json:"contact_id"`
 	Contact               Contact    `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" json:"contact,omitempty"`
 }
+
+// Reminder states
+const (
+	ReminderPending = "pending"
+	ReminderDone    = "done"
+	ReminderSnoozed = "snoozed"
+)
+
+// OverdueCutoff returns the point in time before which open reminders count as overdue.
+// A reminder is overdue once its due day plus the grace days lies before today. Snoozing a
+// reminder moves its due date, so the grace window starts again from the snoozed date.
+func OverdueCutoff(now time.Time, graceDays int) time.Time {
+	if graceDays < 0 {
+		graceDays = 0
+	}
+	startOfToday := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	return startOfToday.AddDate(0, 0, -graceDays)
+}
+
+// Recurrence types by their English and German names as used by the frontend, mapped to their period.
+// Types without a period like "Once" are not repeated.
+var recurrencePeriods = map[string]struct{ months, days int }{
+	"once": {}, "no recurrence": {}, "keine wiederholung": {},
+	"daily": {days: 1}, "täglich": {days: 1},
+	"weekly": {days: 7}, "wöchentlich": {days: 7},
+	"monthly": {months: 1}, "monatlich": {months: 1},
+	"quarterly": {months: 3}, "vierteljährlich": {months: 3},
+	"six-months": {months: 6}, "halbjährlich": {months: 6},
+	"yearly": {months: 12}, "jährlich": {months: 12},
+}
+
+// Validate checks the recurrence type and interval.
+// It returns a map of the invalid JSON field names to an error message.
+func (r Reminder) Validate() map[string]string {
+	fieldErrors := map[string]string{}
+	if _, ok := recurrencePeriods[strings.ToLower(strings.TrimSpace(r.Recurrence))]; !ok {
+		fieldErrors["recurrence"] = "Unknown recurrence, use once, daily, weekly, monthly, quarterly, six-months or yearly"
+	}
+	if r.RecurrenceInterval < 0 {
+		fieldErrors["recurrence_interval"] = "Recurrence interval must not be negative"
+	}
+	return fieldErrors
+}
+
+// NextRemindAt returns the due date following the given one according to the recurrence and its interval.
+// The second return value is false if the reminder does not recur.
+func (r Reminder) NextRemindAt(from time.Time) (time.Time, bool) {
+	period := recurrencePeriods[strings.ToLower(strings.TrimSpace(r.Recurrence))]
+	if period.months == 0 && period.days == 0 {
+		return from, false
+	}
+	interval := max(r.RecurrenceInterval, 1)
+	return from.AddDate(0, period.months*interval, period.days*interval), true
+}
+
+// ScheduleNextDue sets the occurrence following the current due date, it is cleared for reminders which do not recur
+func (r *Reminder) ScheduleNextDue() {
+	if next, recurs := r.NextRemindAt(r.RemindAt); recurs {
+		r.NextDue = &next
+	} else {
+		r.NextDue = nil
+	}
+}
+
+// Complete marks a one-off reminder as done. Recurring reminders are moved to their next occurrence instead,
+// counted from the completion time if they recur from completion or from their due date otherwise.
+func (r *Reminder) Complete(now time.Time) {
+	from := r.RemindAt
+	if r.ReocurrFromCompletion {
+		from = now
+	}
+	next, recurs := r.NextRemindAt(from)
+	if !recurs {
+		r.Completed = true
+		r.Status = ReminderDone
+		return
+	}
+
+	r.RemindAt = next
+	r.ScheduleNextDue()
+	r.Completed = false
+	r.Status = ReminderPending
+	r.LastSent = nil
+}
+
+// Snooze moves the due date of the reminder to the given time
+func (r *Reminder) Snooze(until time.Time) {
+	r.RemindAt = until
+	r.ScheduleNextDue()
+	r.Completed = false
+	r.Status = ReminderSnoozed
+	r.LastSent = nil
+}
@@ -6,7 +6,8 @@ import (
 
 type User struct {
 	gorm.Model
-	Username string `gorm:"unique"`
-	Password string
-	Email    string `gorm:"unique"`
+	Username          string `gorm:"unique"`
+	Password          string
+	Email             string `gorm:"unique"`
+	CalendarTokenHash string `gorm:"index" json:"-"` // SHA-256 hash of the token of the calendar feed, empty if it is disabled
 }
@@ -0,0 +1,55 @@
+package models
+
+import (
+	"slices"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Events webhooks can subscribe to
+const (
+	WebhookContactCreated = "contact.created"
+	WebhookContactUpdated = "contact.updated"
+	WebhookContactDeleted = "contact.deleted"
+	WebhookReminderDue    = "reminder.due"
+)
+
+// WebhookEvents lists all events in the order they are documented
+var WebhookEvents = []string{WebhookContactCreated, WebhookContactUpdated, WebhookContactDeleted, WebhookReminderDue}
+
+// Webhook receives a signed HTTP POST for each subscribed event of its user
+type Webhook struct {
+	gorm.Model
+	UserID uint     `gorm:"index" json:"-"`
+	URL    string   `gorm:"not null" json:"url"`
+	Secret string   `gorm:"not null" json:"-"` // Key of the HMAC-SHA256 signature of the payloads
+	Events []string `gorm:"type:text;serializer:json" json:"events"`
+}
+
+// Subscribes reports whether the webhook receives the event
+func (w Webhook) Subscribes(event string) bool {
+	return slices.Contains(w.Events, event)
+}
+
+// ValidWebhookEvent reports whether the event is one of WebhookEvents
+func ValidWebhookEvent(event string) bool {
+	return slices.Contains(WebhookEvents, event)
+}
+
+// InvalidWebhookEventMessage lists the valid webhook events
+func InvalidWebhookEventMessage() string {
+	return "Invalid event, use one of " + strings.Join(WebhookEvents, ", ")
+}
+
+// WebhookDeadLetter is a delivery which still failed after all retries, kept so it can be inspected
+type WebhookDeadLetter struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	WebhookID uint      `gorm:"index" json:"webhook_id"`
+	Event     string    `json:"event"`
+	Payload   string    `gorm:"type:text" json:"payload"`
+	Attempts  int       `json:"attempts"`
+	Error     string    `json:"error"` // Error of the last attempt
+}
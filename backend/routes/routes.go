@@ -9,53 +9,245 @@ import (
 )
 
 func RegisterRoutes(router *gin.Engine, cfg *config.Config) {
+	// Logins and imports are expensive or targets of brute force, so they get a stricter limit
+	strictRateLimit := middleware.RateLimit(cfg.AuthRateLimitPerMinute, cfg.AuthRateLimitPerMinute)
 
-	router.POST("/register", controllers.RegisterUser)
-	router.POST("/login", func(c *gin.Context) {
+	// Probes for container orchestration, outside of /api and without authentication
+	router.GET("/healthz", controllers.GetHealth)
+	router.GET("/readyz", controllers.GetReadiness)
+
+	// Interactive API documentation, the spec itself is served below /api
+	router.GET("/swagger", controllers.GetSwaggerUI)
+
+	// Address book clients expect CardDAV outside of /api and only support basic authentication
+	router.GET("/.well-known/carddav", controllers.CardDAVWellKnown)
+	router.Handle("PROPFIND", "/.well-known/carddav", controllers.CardDAVWellKnown)
+	router.OPTIONS("/carddav/*path", controllers.CardDAVOptions)
+	carddav := router.Group("/carddav")
+	carddav.Use(middleware.CardDAVAuth())
+	carddav.Handle("PROPFIND", "/", controllers.PropfindCardDAVPrincipal)
+	carddav.Handle("PROPFIND", "/contacts/", controllers.PropfindCardDAVAddressBook)
+	carddav.Handle("REPORT", "/contacts/", controllers.ReportCardDAVAddressBook)
+	carddav.GET("/contacts/:card", controllers.GetCard)
+	carddav.PUT("/contacts/:card", controllers.PutCard)
+	carddav.DELETE("/contacts/:card", controllers.DeleteCard)
+
+	// All endpoints are served below /api
+	api := router.Group("/api")
+
+	api.POST("/register", strictRateLimit, func(c *gin.Context) {
+		controllers.RegisterUser(c, cfg)
+	})
+	api.POST("/login", strictRateLimit, func(c *gin.Context) {
 		controllers.LoginUser(c, cfg)
 	})
-	protected := router.Group("/")
+	// The OpenAPI spec is public so that clients can be generated without an account
+	api.GET("/openapi.json", controllers.GetOpenAPISpec)
+	// Calendar apps authenticate with the token in the URL
+	api.GET("/calendar.ics", func(c *gin.Context) {
+		controllers.GetCalendarFeed(c, cfg)
+	})
+	protected := api.Group("/")
 	protected.Use(middleware.AuthMiddleware(cfg))
 
+	// Routes from API key controller
+	protected.GET("/api-keys", controllers.GetAPIKeys)
+	protected.POST("/api-keys", controllers.CreateAPIKey)
+	protected.DELETE("/api-keys/:id", controllers.RevokeAPIKey)
+
+	// Routes from webhook controller
+	protected.GET("/webhooks", controllers.GetWebhooks)
+	protected.POST("/webhooks", func(c *gin.Context) {
+		controllers.CreateWebhook(c, cfg)
+	})
+	protected.DELETE("/webhooks/:id", controllers.DeleteWebhook)
+	protected.GET("/webhooks/:id/dead-letters", controllers.GetWebhookDeadLetters)
+
+	// Routes from calendar controller
+	protected.POST("/calendar/token", controllers.CreateCalendarToken)
+	protected.DELETE("/calendar/token", controllers.RevokeCalendarToken)
+
 	// Routes from contact controller
-	protected.GET("/contacts", controllers.GetContacts)
+	protected.GET("/contacts", func(c *gin.Context) {
+		controllers.GetContacts(c, cfg)
+	})
 	protected.POST("/contacts", controllers.CreateContact)
-	protected.GET("/contacts/:id", controllers.GetContact)
+	protected.GET("/contacts/:id", func(c *gin.Context) {
+		controllers.GetContact(c, cfg)
+	})
+	protected.GET("/contacts/:id/vcard", controllers.ExportContactVCard)
+	protected.GET("/contacts/:id/export", controllers.ExportContactBundle)
+	protected.GET("/contacts/:id/history", func(c *gin.Context) {
+		controllers.GetContactHistory(c, cfg)
+	})
 	protected.PUT("/contacts/:id", controllers.UpdateContact)
+	protected.PATCH("/contacts/:id", controllers.PatchContact)
 	protected.DELETE("/contacts/:id", controllers.DeleteContact)
+	protected.GET("/contacts/trash", func(c *gin.Context) {
+		controllers.GetTrashedContacts(c, cfg)
+	})
+	protected.GET("/contacts/duplicates", controllers.GetDuplicateContacts)
+	protected.GET("/contacts/recent", controllers.GetRecentContacts)
+	protected.GET("/contacts/stale", func(c *gin.Context) {
+		controllers.GetStaleContacts(c, cfg)
+	})
+	protected.GET("/contacts/birthdays/upcoming", func(c *gin.Context) {
+		controllers.GetUpcomingBirthdays(c, cfg)
+	})
+	protected.POST("/contacts/:id/restore", controllers.RestoreContact)
+	protected.POST("/contacts/:id/favorite", controllers.ToggleFavorite)
+	protected.POST("/contacts/:id/merge", controllers.MergeContact)
+	protected.POST("/contacts/bulk/delete", controllers.BulkDeleteContacts)
+	protected.POST("/contacts/bulk/circles", controllers.BulkUpdateCircles)
+	protected.DELETE("/contacts/:id/permanent", controllers.DeleteContactPermanently)
 	protected.GET("/contacts/circles", controllers.GetCircles)
+	protected.GET("/contacts/tags", controllers.GetContactTags)
+	protected.GET("/genders", controllers.GetGenders)
+	protected.GET("/contacts/vcard", controllers.ExportContactsVCard)
+	protected.GET("/contacts/export/csv", controllers.ExportContactsCSV)
+	protected.POST("/contacts/import/vcard", strictRateLimit, controllers.ImportContactsVCard)
+	protected.POST("/contacts/import/csv", strictRateLimit, controllers.ImportContactsCSV)
+	protected.POST("/contacts/import", strictRateLimit, func(c *gin.Context) {
+		controllers.ImportContactBundle(c, cfg)
+	})
+
+	// Routes from circle controller
+	protected.POST("/circles/merge/preview", controllers.PreviewCircleMerge)
+	protected.POST("/circles/merge", controllers.MergeCircles)
+	protected.POST("/circles/rename", controllers.RenameCircles)
+	protected.GET("/circles/:name/contacts", func(c *gin.Context) {
+		controllers.GetCircleContacts(c, cfg)
+	})
+	protected.PUT("/circles/:name", controllers.RenameCircle)
+	protected.DELETE("/circles/:name", controllers.DeleteCircle)
 
 	// Routes from relationship controller
 	protected.GET("/contacts/:id/relationships", controllers.GetRelationships)
-	protected.POST("/contacts/:id/relationships", controllers.CreateRelationship)
+	protected.POST("/contacts/:id/relationships", func(c *gin.Context) {
+		controllers.CreateRelationship(c, cfg)
+	})
 	protected.PUT("/contacts/:id/relationships/:rid", controllers.UpdateRelationship)
-	protected.DELETE("/contacts/:id/relationships/:rid", controllers.DeleteRelationship)
+	protected.DELETE("/contacts/:id/relationships/:rid", func(c *gin.Context) {
+		controllers.DeleteRelationship(c, cfg)
+	})
+	protected.GET("/contacts/:id/path", controllers.GetConnectionPath)
+	protected.GET("/relationships/graph", controllers.GetRelationshipGraph)
+
+	// Routes from contact method controller
+	protected.POST("/contacts/:id/contact-methods", controllers.CreateContactMethod)
+	protected.DELETE("/contacts/:id/contact-methods/:mid", controllers.DeleteContactMethod)
+
+	// Routes from custom date controller
+	protected.GET("/contacts/:id/custom-dates", controllers.GetCustomDates)
+	protected.POST("/contacts/:id/custom-dates", controllers.CreateCustomDate)
+	protected.DELETE("/contacts/:id/custom-dates/:did", controllers.DeleteCustomDate)
 
 	// Routes from profile picture controller
-	protected.POST("/contacts/:id/profile_picture", controllers.AddPhotoToContact)
+	protected.POST("/contacts/:id/profile_picture", func(c *gin.Context) {
+		controllers.AddPhotoToContact(c, cfg)
+	})
 	protected.GET("/contacts/:id/profile_picture", controllers.GetProfilePicture)
+	protected.GET("/contacts/:id/photo", controllers.GetContactPhoto)
+	protected.GET("/contacts/:id/photo/thumb", controllers.GetProfilePictureThumbnail)
+	protected.DELETE("/contacts/:id/photo", controllers.DeleteProfilePicture)
+
+	// Routes from contact attachment controller
+	protected.GET("/contacts/:id/attachments", controllers.GetContactAttachments)
+	protected.POST("/contacts/:id/attachments", func(c *gin.Context) {
+		controllers.AddAttachmentToContact(c, cfg)
+	})
+	protected.GET("/contacts/:id/attachments/:aid", controllers.GetContactAttachment)
+	protected.DELETE("/contacts/:id/attachments/:aid", controllers.DeleteContactAttachment)
 
 	// Routes from note controller
-	protected.GET("/contacts/:id/notes", controllers.GetNotesForContact)
-	protected.POST("/contacts/:id/notes", controllers.CreateNote)
+	protected.GET("/contacts/:id/notes", func(c *gin.Context) {
+		controllers.GetNotesForContact(c, cfg)
+	})
+	protected.POST("/contacts/:id/notes", func(c *gin.Context) {
+		controllers.CreateNote(c, cfg)
+	})
 	protected.GET("/notes/:id", controllers.GetNote)
-	protected.GET("/notes", controllers.GetUnassignedNotes)
-	protected.POST("/notes", controllers.CreateUnassignedNote)
-	protected.PUT("/notes/:id", controllers.UpdateNote)
+	protected.GET("/notes/:id/html", func(c *gin.Context) {
+		controllers.GetNoteHTML(c, cfg)
+	})
+	protected.GET("/notes", controllers.GetNotes)
+	protected.GET("/tags", controllers.GetTags)
+	protected.POST("/notes", func(c *gin.Context) {
+		controllers.CreateUnassignedNote(c, cfg)
+	})
+	protected.PUT("/notes/:id", func(c *gin.Context) {
+		controllers.UpdateNote(c, cfg)
+	})
 	protected.DELETE("/notes/:id", controllers.DeleteNote)
+	protected.POST("/notes/:id/attachments", controllers.AddAttachmentToNote)
+	protected.GET("/notes/:id/attachments/:aid", controllers.GetNoteAttachment)
+	protected.DELETE("/notes/:id/attachments/:aid", controllers.DeleteNoteAttachment)
 
 	// Routes from activity controller
 	protected.GET("/contacts/:id/activities", controllers.GetActivitiesForContact)
-	protected.POST("/activities", controllers.CreateActivity)
-	protected.GET("/activities", controllers.GetActivities)
+	protected.POST("/contacts/:id/activities", func(c *gin.Context) {
+		controllers.CreateContactActivity(c, cfg)
+	})
+	protected.POST("/activities", func(c *gin.Context) {
+		controllers.CreateActivity(c, cfg)
+	})
+	protected.GET("/activities", func(c *gin.Context) {
+		controllers.GetActivities(c, cfg)
+	})
 	protected.GET("/activities/:id", controllers.GetActivity)
-	protected.PUT("/activities/:id", controllers.UpdateActivity)
+	protected.PUT("/activities/:id", func(c *gin.Context) {
+		controllers.UpdateActivity(c, cfg)
+	})
 	protected.DELETE("/activities/:id", controllers.DeleteActivity)
 
+	// Routes from search controller
+	protected.GET("/search", func(c *gin.Context) {
+		controllers.Search(c, cfg)
+	})
+
+	// Routes from GraphQL controller
+	protected.POST("/graphql", func(c *gin.Context) {
+		controllers.GraphQL(c, cfg)
+	})
+
+	// Routes from stats controller
+	protected.GET("/stats", controllers.GetStats)
+
+	// Routes from admin controller, only for the admin user
+	admin := protected.Group("/admin")
+	admin.Use(middleware.AdminOnly(cfg))
+	admin.GET("/backup", func(c *gin.Context) {
+		controllers.GetBackup(c, cfg)
+	})
+	admin.POST("/restore", func(c *gin.Context) {
+		controllers.RestoreBackup(c, cfg)
+	})
+
+	// Routes from maintenance controller, the uploads of all users are affected
+	admin.POST("/maintenance/purge-uploads", controllers.PurgeOrphanedUploads)
+
+	// Routes from note controller, the admin only changes their own notes
+	admin.POST("/notes/replace", func(c *gin.Context) {
+		controllers.ReplaceInNotes(c, cfg)
+	})
+
 	// Routes from reminder controller
 	protected.GET("/contacts/:id/reminders", controllers.GetRemindersForContact)
 	protected.POST("/contacts/:id/reminders", controllers.CreateReminder)
+	protected.GET("/reminders", func(c *gin.Context) {
+		controllers.GetReminders(c, cfg)
+	})
+	protected.GET("/reminders/upcoming", func(c *gin.Context) {
+		controllers.GetUpcomingReminders(c, cfg)
+	})
+	protected.GET("/reminders/overdue", func(c *gin.Context) {
+		controllers.GetOverdueReminders(c, cfg)
+	})
 	protected.GET("/reminders/:id", controllers.GetReminder)
 	protected.PUT("/reminders/:id", controllers.UpdateReminder)
+	protected.POST("/reminders/:id/complete", controllers.CompleteReminder)
+	protected.POST("/reminders/:id/snooze", func(c *gin.Context) {
+		controllers.SnoozeReminder(c, cfg)
+	})
 	protected.DELETE("/reminders/:id", controllers.DeleteReminder)
 }
@@ -0,0 +1,78 @@
+package routes
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"perema/config"
+	"perema/models"
+	"perema/services"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestRegisterRoutes(t *testing.T) {
+	gin.SetMode(gin.ReleaseMode)
+	router := gin.New()
+	RegisterRoutes(router, &config.Config{JWTSecretKey: "secret", AuthRateLimitPerMinute: 10})
+
+	send := func(method, url string) int {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(method, url, nil))
+		return w.Code
+	}
+
+	// Endpoints are served below /api and protected ones require authentication
+	assert.Equal(t, http.StatusUnauthorized, send("GET", "/api/contacts"))
+	assert.Equal(t, http.StatusUnauthorized, send("POST", "/api/reminders/1/complete"))
+	assert.Equal(t, http.StatusNotFound, send("GET", "/contacts"))
+	assert.Equal(t, http.StatusBadRequest, send("POST", "/api/login"))
+
+	// The API documentation does not require authentication
+	assert.Equal(t, http.StatusOK, send("GET", "/api/openapi.json"))
+	assert.Equal(t, http.StatusOK, send("GET", "/swagger"))
+}
+
+func TestAdminRoutes(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	assert.NoError(t, err)
+	db.AutoMigrate(&models.User{}, &models.Contact{}, &models.NoteAttachment{}, &models.ContactAttachment{})
+
+	admin := models.User{Username: "admin", Email: "admin@example.com"}
+	user := models.User{Username: "jane", Email: "jane@example.com"}
+	db.Create(&admin)
+	db.Create(&user)
+
+	gin.SetMode(gin.ReleaseMode)
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Set("db", db)
+		c.Next()
+	})
+	cfg := &config.Config{JWTSecretKey: "secret", JWTExpiryHours: 1, AuthRateLimitPerMinute: 10, AdminEmail: "admin@example.com"}
+	RegisterRoutes(router, cfg)
+
+	send := func(method, url string, as models.User) int {
+		token, err := services.GenerateToken(as, cfg)
+		assert.NoError(t, err)
+		req := httptest.NewRequest(method, url, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	// Purging uploads affects all users, so other users are not allowed to do it
+	assert.Equal(t, http.StatusForbidden, send("POST", "/api/admin/maintenance/purge-uploads", user))
+	assert.Equal(t, http.StatusForbidden, send("GET", "/api/admin/backup", user))
+	assert.Equal(t, http.StatusNotFound, send("POST", "/api/maintenance/purge-uploads", user))
+	assert.Equal(t, http.StatusForbidden, send("POST", "/api/admin/notes/replace", user))
+	assert.Equal(t, http.StatusNotFound, send("POST", "/api/notes/replace", user))
+
+	t.Setenv("PROFILE_PHOTO_DIR", t.TempDir())
+	assert.Equal(t, http.StatusOK, send("POST", "/api/admin/maintenance/purge-uploads", admin))
+	assert.Equal(t, http.StatusBadRequest, send("POST", "/api/admin/notes/replace", admin)) // Reaches the handler without a body
+}
@@ -0,0 +1,41 @@
+package services
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"perema/models"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// GenerateAPIKey returns a new random API key together with its hash to be stored
+func GenerateAPIKey() (string, string, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", "", err
+	}
+	key := models.APIKeyPrefix + hex.EncodeToString(secret)
+	return key, HashAPIKey(key), nil
+}
+
+// HashAPIKey hashes an API key for storage and lookup. Keys are random, so a fast hash is sufficient.
+func HashAPIKey(key string) string {
+	hash := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(hash[:])
+}
+
+// AuthenticateAPIKey finds the API key and records that it was used. Revoked keys are not found.
+func AuthenticateAPIKey(db *gorm.DB, key string) (models.APIKey, error) {
+	var apiKey models.APIKey
+	if err := db.Where("key_hash = ?", HashAPIKey(key)).First(&apiKey).Error; err != nil {
+		return apiKey, err
+	}
+
+	now := time.Now()
+	if err := db.Model(&apiKey).Update("last_used_at", now).Error; err != nil {
+		return apiKey, err
+	}
+	return apiKey, nil
+}
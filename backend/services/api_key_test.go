@@ -0,0 +1,35 @@
+package services
+
+import (
+	"perema/models"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAuthenticateAPIKey(t *testing.T) {
+	db := setupDB()
+
+	key, hash, err := GenerateAPIKey()
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(key, models.APIKeyPrefix))
+	assert.NotContains(t, hash, key)
+
+	apiKey := models.APIKey{UserID: 3, Name: "Backup", KeyHash: hash}
+	db.Create(&apiKey)
+
+	found, err := AuthenticateAPIKey(db, key)
+	assert.NoError(t, err)
+	assert.Equal(t, uint(3), found.UserID)
+	db.First(&apiKey, apiKey.ID)
+	assert.NotNil(t, apiKey.LastUsedAt)
+
+	_, err = AuthenticateAPIKey(db, key+"0")
+	assert.Error(t, err)
+
+	// Revoked keys no longer authenticate
+	db.Delete(&apiKey)
+	_, err = AuthenticateAPIKey(db, key)
+	assert.Error(t, err)
+}
@@ -0,0 +1,95 @@
+package services
+
+import (
+	"encoding/json"
+	"perema/models"
+	"reflect"
+
+	"gorm.io/gorm"
+)
+
+// Fields which are not part of the history because they change on every save or are loaded associations
+var auditIgnoredFields = map[string]bool{
+	"ID": true, "CreatedAt": true, "UpdatedAt": true, "DeletedAt": true, "version": true,
+	"contact": true, "relationships": true, "activities": true, "notes": true, "reminders": true, "contact_methods": true,
+	"attachments": true, "custom_dates": true, "cadence_health": true, "summary": true, "last_contacted": true,
+}
+
+// RecordAudit stores the entry with the fields which differ between the entity before and after the change.
+// Before is nil for created and after is nil for deleted entities. Updates which changed nothing are not stored.
+func RecordAudit(db *gorm.DB, entry models.AuditLog, before, after any) error {
+	changes, err := AuditDiff(before, after)
+	if err != nil {
+		return err
+	}
+	if len(changes) == 0 && entry.Action == models.AuditUpdate {
+		return nil
+	}
+	entry.Changes = changes
+	return db.Create(&entry).Error
+}
+
+// AuditDiff compares the JSON representations of two entities and returns the changed fields.
+// A nil entity counts as empty, fields which are empty on both sides are left out.
+func AuditDiff(before, after any) (map[string]models.AuditChange, error) {
+	old, err := auditFields(before)
+	if err != nil {
+		return nil, err
+	}
+	updated, err := auditFields(after)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := map[string]models.AuditChange{}
+	for _, fields := range []map[string]any{old, updated} {
+		for key := range fields {
+			if _, done := changes[key]; done || auditIgnoredFields[key] {
+				continue
+			}
+			if reflect.DeepEqual(old[key], updated[key]) || (emptyJSONValue(old[key]) && emptyJSONValue(updated[key])) {
+				continue
+			}
+			changes[key] = models.AuditChange{Old: old[key], New: updated[key]}
+		}
+	}
+	return changes, nil
+}
+
+// auditFields returns the fields of the entity by their JSON names
+func auditFields(entity any) (map[string]any, error) {
+	fields := map[string]any{}
+	if entity == nil {
+		return fields, nil
+	}
+	data, err := json.Marshal(entity)
+	if err != nil {
+		return nil, err
+	}
+	return fields, json.Unmarshal(data, &fields)
+}
+
+// emptyJSONValue reports whether a decoded JSON value is null, false, zero, an empty string or array or an object
+// without non-empty fields
+func emptyJSONValue(value any) bool {
+	switch v := value.(type) {
+	case nil:
+		return true
+	case bool:
+		return !v
+	case float64:
+		return v == 0
+	case string:
+		return v == ""
+	case []any:
+		return len(v) == 0
+	case map[string]any:
+		for _, field := range v {
+			if !emptyJSONValue(field) {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
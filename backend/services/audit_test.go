@@ -0,0 +1,50 @@
+package services
+
+import (
+	"perema/models"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAuditDiff(t *testing.T) {
+	before := models.Contact{Firstname: "Alice", Lastname: "Johnson", Circles: []string{"Friends"}, Version: 1}
+	after := before
+	after.Lastname = "Smith"
+	after.Email = "alice@example.com"
+	after.Version = 2
+
+	changes, err := AuditDiff(before, after)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]models.AuditChange{
+		"lastname": {Old: "Johnson", New: "Smith"},
+		"email":    {Old: "", New: "alice@example.com"},
+	}, changes)
+
+	// Created entities only record the fields which are set
+	changes, err = AuditDiff(nil, before)
+	assert.NoError(t, err)
+	assert.Len(t, changes, 3)
+	assert.Equal(t, models.AuditChange{Old: nil, New: []any{"Friends"}}, changes["circles"])
+}
+
+func TestRecordAudit(t *testing.T) {
+	db := setupDB()
+	contact := models.Contact{Firstname: "Alice"}
+	entry := models.AuditLog{UserID: 1, ContactID: 4, EntityType: models.AuditEntityContact, EntityID: 4, Action: models.AuditUpdate}
+
+	// Saving without changes leaves no entry
+	assert.NoError(t, RecordAudit(db, entry, contact, contact))
+	var count int64
+	db.Model(&models.AuditLog{}).Count(&count)
+	assert.Equal(t, int64(0), count)
+
+	updated := contact
+	updated.Nickname = "Ali"
+	assert.NoError(t, RecordAudit(db, entry, contact, updated))
+
+	var stored models.AuditLog
+	assert.NoError(t, db.First(&stored).Error)
+	assert.Equal(t, models.AuditUpdate, stored.Action)
+	assert.Equal(t, map[string]models.AuditChange{"nickname": {Old: "", New: "Ali"}}, stored.Changes)
+}
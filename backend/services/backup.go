@@ -0,0 +1,135 @@
+package services
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"perema/models"
+	"strings"
+	"time"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// Every SQLite database file starts with this header
+var sqliteHeader = []byte("SQLite format 3\x00")
+
+// ErrInvalidBackup is returned for files which are no intact perema database
+var ErrInvalidBackup = errors.New("invalid backup")
+
+// Scheduled backups and downloads are named after the time they were created, so their names sort chronologically
+const (
+	backupPrefix = "perema-backup-"
+	backupSuffix = ".db"
+)
+
+// BackupFilename returns the file name of a backup created at the given time
+func BackupFilename(now time.Time) string {
+	return backupPrefix + now.UTC().Format("20060102-150405") + backupSuffix
+}
+
+// PendingRestorePath returns the path a validated backup is staged at until it replaces the database on the next start
+func PendingRestorePath(dbPath string) string {
+	return dbPath + ".restore"
+}
+
+// BackupSQLite writes a consistent copy of the database to the given path, which must not exist yet.
+// VACUUM INTO reads from a single transaction, so writes of other requests cannot leave the copy half updated.
+func BackupSQLite(db *gorm.DB, path string) error {
+	return db.Exec("VACUUM INTO ?", path).Error
+}
+
+// CreateScheduledBackup writes a backup into the directory and deletes all but the latest retention backups.
+// It returns the path and size of the new backup.
+func CreateScheduledBackup(db *gorm.DB, dir string, retention int, now time.Time) (string, int64, error) {
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return "", 0, err
+	}
+	path := filepath.Join(dir, BackupFilename(now))
+	if err := BackupSQLite(db, path); err != nil {
+		return "", 0, err
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", 0, err
+	}
+	return path, info.Size(), pruneBackups(dir, retention)
+}
+
+// pruneBackups deletes the oldest backups in the directory until only retention are left, other files are kept
+func pruneBackups(dir string, retention int) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	var backups []string
+	for _, entry := range entries {
+		if entry.Type().IsRegular() && strings.HasPrefix(entry.Name(), backupPrefix) && strings.HasSuffix(entry.Name(), backupSuffix) {
+			backups = append(backups, entry.Name())
+		}
+	}
+	// ReadDir returns the entries sorted by name, which is the order of creation
+	for len(backups) > retention {
+		if err := os.Remove(filepath.Join(dir, backups[0])); err != nil {
+			return err
+		}
+		backups = backups[1:]
+	}
+	return nil
+}
+
+// ValidateSQLiteBackup checks that the file is an intact SQLite database containing the tables of perema
+func ValidateSQLiteBackup(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	header := make([]byte, len(sqliteHeader))
+	_, err = io.ReadFull(file, header)
+	file.Close()
+	if err != nil || !bytes.Equal(header, sqliteHeader) {
+		return fmt.Errorf("%w: not an SQLite database", ErrInvalidBackup)
+	}
+
+	backup, err := gorm.Open(sqlite.Open("file:"+path+"?mode=ro"), &gorm.Config{})
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidBackup, err)
+	}
+	if sqlDB, err := backup.DB(); err == nil {
+		defer sqlDB.Close()
+	}
+
+	var result string
+	if err := backup.Raw("PRAGMA integrity_check").Scan(&result).Error; err != nil || result != "ok" {
+		return fmt.Errorf("%w: integrity check failed", ErrInvalidBackup)
+	}
+	for _, model := range []any{&models.Contact{}, &models.User{}} {
+		if !backup.Migrator().HasTable(model) {
+			return fmt.Errorf("%w: tables of perema are missing", ErrInvalidBackup)
+		}
+	}
+	return nil
+}
+
+// ApplyPendingRestore replaces the database with a staged backup before the database is opened.
+// The previous database is kept next to it with the time of the restore appended to its name.
+// It returns false if no backup was staged.
+func ApplyPendingRestore(dbPath string, now time.Time) (bool, error) {
+	pending := PendingRestorePath(dbPath)
+	if _, err := os.Stat(pending); errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	}
+
+	// The journal files belong to the previous database and would corrupt the restored one
+	previous := dbPath + ".before-restore-" + now.UTC().Format("20060102-150405")
+	for _, suffix := range []string{"", "-wal", "-shm"} {
+		if err := os.Rename(dbPath+suffix, previous+suffix); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return false, err
+		}
+	}
+	return true, os.Rename(pending, dbPath)
+}
@@ -0,0 +1,91 @@
+package services
+
+import (
+	"os"
+	"path/filepath"
+	"perema/models"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestBackupAndRestoreSQLite(t *testing.T) {
+	dir := t.TempDir()
+	dbPath := filepath.Join(dir, "perema.db")
+	db, err := gorm.Open(sqlite.Open(dbPath), &gorm.Config{})
+	assert.NoError(t, err)
+	db.AutoMigrate(&models.Contact{}, &models.User{})
+	db.Create(&models.Contact{Firstname: "Alice"})
+
+	backupPath := filepath.Join(dir, "backup.db")
+	assert.NoError(t, BackupSQLite(db, backupPath))
+	assert.NoError(t, ValidateSQLiteBackup(backupPath))
+
+	// Changes after the backup are not part of it
+	db.Create(&models.Contact{Firstname: "Bob"})
+	sqlDB, _ := db.DB()
+	sqlDB.Close()
+
+	// Other files and databases of other applications are rejected
+	textPath := filepath.Join(dir, "notes.txt")
+	os.WriteFile(textPath, []byte("not a database, just some text"), 0o600)
+	assert.ErrorIs(t, ValidateSQLiteBackup(textPath), ErrInvalidBackup)
+
+	otherPath := filepath.Join(dir, "other.db")
+	other, _ := gorm.Open(sqlite.Open(otherPath), &gorm.Config{})
+	other.Exec("CREATE TABLE things (id INTEGER)")
+	otherDB, _ := other.DB()
+	otherDB.Close()
+	assert.ErrorIs(t, ValidateSQLiteBackup(otherPath), ErrInvalidBackup)
+
+	// Nothing happens without a staged backup
+	restored, err := ApplyPendingRestore(dbPath, time.Now())
+	assert.NoError(t, err)
+	assert.False(t, restored)
+
+	// A staged backup replaces the database and the previous one is kept
+	assert.NoError(t, os.Rename(backupPath, PendingRestorePath(dbPath)))
+	now := time.Date(2024, 3, 1, 12, 30, 0, 0, time.UTC)
+	restored, err = ApplyPendingRestore(dbPath, now)
+	assert.NoError(t, err)
+	assert.True(t, restored)
+	assert.NoFileExists(t, PendingRestorePath(dbPath))
+	assert.FileExists(t, dbPath+".before-restore-20240301-123000")
+
+	db, _ = gorm.Open(sqlite.Open(dbPath), &gorm.Config{})
+	var names []string
+	db.Model(&models.Contact{}).Pluck("firstname", &names)
+	assert.Equal(t, []string{"Alice"}, names)
+	sqlDB, _ = db.DB()
+	sqlDB.Close()
+}
+
+func TestCreateScheduledBackup(t *testing.T) {
+	db := setupDB()
+	db.Create(&models.Contact{Firstname: "Alice"})
+	dir := filepath.Join(t.TempDir(), "backups")
+
+	// The directory is created on the first backup, files of others are never pruned
+	start := time.Date(2024, 3, 1, 2, 0, 0, 0, time.UTC)
+	path, size, err := CreateScheduledBackup(db, dir, 2, start)
+	assert.NoError(t, err)
+	assert.Equal(t, filepath.Join(dir, "perema-backup-20240301-020000.db"), path)
+	assert.Positive(t, size)
+	os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("keep me"), 0o600)
+
+	for day := 1; day <= 2; day++ {
+		_, _, err := CreateScheduledBackup(db, dir, 2, start.AddDate(0, 0, day))
+		assert.NoError(t, err)
+	}
+
+	entries, _ := os.ReadDir(dir)
+	var names []string
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	assert.Equal(t, []string{"notes.txt", "perema-backup-20240302-020000.db", "perema-backup-20240303-020000.db"}, names)
+	assert.NoError(t, ValidateSQLiteBackup(filepath.Join(dir, "perema-backup-20240303-020000.db")))
+}
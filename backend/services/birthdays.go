@@ -0,0 +1,45 @@
+package services
+
+import (
+	"perema/models"
+	"sort"
+	"time"
+)
+
+// UpcomingBirthday is a contact whose birthday falls within the requested window
+type UpcomingBirthday struct {
+	Contact    models.Contact `json:"contact"`
+	Date       string         `json:"date"` // Date of the next birthday in YYYY-MM-DD format
+	DaysUntil  int            `json:"days_until"`
+	TurningAge *int           `json:"turning_age,omitempty"` // Only set if the birth year is known
+}
+
+// UpcomingBirthdays returns the contacts whose birthday (month and day) is today or within the next days,
+// sorted by how soon the birthday is. The window continues into the next year, so early January birthdays
+// are part of a window starting in late December. Birthdays on Feb 29 fall on the day given by leapDay in years
+// without Feb 29. Contacts without birthday and deceased contacts are left out.
+func UpcomingBirthdays(contacts []models.Contact, now time.Time, days int, leapDay string) []UpcomingBirthday {
+	birthdays := []UpcomingBirthday{}
+	for _, contact := range contacts {
+		if contact.Birthday == nil || !contact.Birthday.Valid || contact.Birthday.Time.IsZero() || contact.Deceased {
+			continue
+		}
+
+		next := contact.Birthday.NextOccurrence(now, leapDay)
+		daysUntil := -daysBetween(next, now)
+		if daysUntil > days {
+			continue
+		}
+
+		birthday := UpcomingBirthday{Contact: contact, Date: next.Format(models.DateFormat), DaysUntil: daysUntil}
+		if age, ok := contact.AgeAtNextBirthday(now, leapDay); ok {
+			birthday.TurningAge = &age
+		}
+		birthdays = append(birthdays, birthday)
+	}
+
+	sort.SliceStable(birthdays, func(i, j int) bool {
+		return birthdays[i].DaysUntil < birthdays[j].DaysUntil
+	})
+	return birthdays
+}
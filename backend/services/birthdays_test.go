@@ -0,0 +1,74 @@
+package services
+
+import (
+	"perema/models"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUpcomingBirthdays(t *testing.T) {
+	birthday := func(year int, month time.Month, day int) *models.Date {
+		return &models.Date{Time: time.Date(year, month, day, 0, 0, 0, 0, time.UTC), Valid: true}
+	}
+
+	contacts := []models.Contact{
+		{Firstname: "Newyear", Birthday: birthday(1990, time.January, 3)},
+		{Firstname: "Today", Birthday: birthday(1985, time.December, 28)},
+		{Firstname: "Yearless", Birthday: birthday(1, time.December, 31)},
+		{Firstname: "Passed", Birthday: birthday(1990, time.December, 27)},
+		{Firstname: "Far", Birthday: birthday(1990, time.February, 15)},
+		{Firstname: "Deceased", Birthday: birthday(1950, time.December, 30), Deceased: true},
+		{Firstname: "Zero", Birthday: &models.Date{Valid: true}},
+		{Firstname: "Unknown"},
+	}
+
+	now := time.Date(2024, time.December, 28, 18, 30, 0, 0, time.UTC)
+	birthdays := UpcomingBirthdays(contacts, now, 30, "feb28")
+
+	assert.Len(t, birthdays, 3)
+
+	assert.Equal(t, "Today", birthdays[0].Contact.Firstname)
+	assert.Equal(t, 0, birthdays[0].DaysUntil)
+	assert.Equal(t, "2024-12-28", birthdays[0].Date)
+	assert.Equal(t, 39, *birthdays[0].TurningAge)
+
+	assert.Equal(t, "Yearless", birthdays[1].Contact.Firstname)
+	assert.Equal(t, 3, birthdays[1].DaysUntil)
+	assert.Nil(t, birthdays[1].TurningAge)
+
+	// The window wraps into the next year
+	assert.Equal(t, "Newyear", birthdays[2].Contact.Firstname)
+	assert.Equal(t, 6, birthdays[2].DaysUntil)
+	assert.Equal(t, "2025-01-03", birthdays[2].Date)
+	assert.Equal(t, 35, *birthdays[2].TurningAge)
+
+	// The last day of the window is included
+	assert.Len(t, UpcomingBirthdays(contacts, now, 6, "feb28"), 3)
+	assert.Len(t, UpcomingBirthdays(contacts, now, 5, "feb28"), 2)
+}
+
+func TestUpcomingLeapDayBirthdays(t *testing.T) {
+	contacts := []models.Contact{
+		{Firstname: "Leap", Birthday: &models.Date{Time: time.Date(2000, time.February, 29, 0, 0, 0, 0, time.UTC), Valid: true}},
+	}
+	feb28 := time.Date(2025, time.February, 28, 9, 0, 0, 0, time.UTC)
+	mar1 := time.Date(2025, time.March, 1, 9, 0, 0, 0, time.UTC)
+
+	// In years without Feb 29 the birthday falls on the same day the birthday mail is sent
+	birthdays := UpcomingBirthdays(contacts, feb28, 0, "feb28")
+	assert.Len(t, birthdays, 1)
+	assert.Equal(t, "2025-02-28", birthdays[0].Date)
+	assert.Equal(t, 25, *birthdays[0].TurningAge)
+	assert.Empty(t, UpcomingBirthdays(contacts, mar1, 0, "feb28"))
+
+	birthdays = UpcomingBirthdays(contacts, mar1, 0, "mar1")
+	assert.Len(t, birthdays, 1)
+	assert.Equal(t, "2025-03-01", birthdays[0].Date)
+	assert.Empty(t, UpcomingBirthdays(contacts, feb28, 0, "mar1"))
+
+	// Leap years keep Feb 29 with both settings
+	birthdays = UpcomingBirthdays(contacts, time.Date(2028, time.February, 28, 0, 0, 0, 0, time.UTC), 1, "mar1")
+	assert.Equal(t, "2028-02-29", birthdays[0].Date)
+}
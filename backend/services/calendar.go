@@ -0,0 +1,89 @@
+package services
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"perema/models"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Calendar dates have no time and no time zone
+const calendarDateFormat = "20060102"
+
+// Birthdays without a known year are stored in year 1, which calendar apps do not handle well, so their events start in this year
+const unknownBirthYear = 2000
+
+// GenerateCalendarToken returns a new random token for the calendar feed together with its hash to be stored
+func GenerateCalendarToken() (string, string, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", "", err
+	}
+	token := hex.EncodeToString(secret)
+	return token, HashAPIKey(token), nil
+}
+
+// CalendarFeed serializes the birthdays of the contacts as yearly all-day events and the reminders as
+// all-day events on their due date in iCalendar format (RFC 5545). Reminders must have their contact loaded.
+func CalendarFeed(contacts []models.Contact, reminders []models.Reminder, now time.Time, location *time.Location) string {
+	stamp := "DTSTAMP:" + now.UTC().Format("20060102T150405Z")
+	lines := []string{
+		"BEGIN:VCALENDAR",
+		"VERSION:2.0",
+		"PRODID:-//perema//perema//EN",
+		"CALSCALE:GREGORIAN",
+		"X-WR-CALNAME:perema",
+	}
+
+	for _, contact := range contacts {
+		if contact.Birthday == nil || !contact.Birthday.Valid || contact.Birthday.Time.IsZero() {
+			continue
+		}
+		birthday := contact.Birthday.Time
+		if !contact.Birthday.HasYear() {
+			birthday = time.Date(unknownBirthYear, birthday.Month(), birthday.Day(), 0, 0, 0, 0, time.UTC)
+		}
+		lines = append(lines,
+			"BEGIN:VEVENT",
+			"UID:birthday-"+strconv.Itoa(int(contact.ID))+"@perema",
+			stamp,
+			"DTSTART;VALUE=DATE:"+birthday.Format(calendarDateFormat),
+			"DTEND;VALUE=DATE:"+birthday.AddDate(0, 0, 1).Format(calendarDateFormat),
+			"RRULE:FREQ=YEARLY",
+			"SUMMARY:"+escapeVCard("Birthday of "+contactName(contact)),
+			"TRANSP:TRANSPARENT",
+			"END:VEVENT",
+		)
+	}
+
+	for _, reminder := range reminders {
+		due := reminder.RemindAt.In(location)
+		day := time.Date(due.Year(), due.Month(), due.Day(), 0, 0, 0, 0, time.UTC)
+		lines = append(lines,
+			"BEGIN:VEVENT",
+			"UID:reminder-"+strconv.Itoa(int(reminder.ID))+"@perema",
+			stamp,
+			"DTSTART;VALUE=DATE:"+day.Format(calendarDateFormat),
+			"DTEND;VALUE=DATE:"+day.AddDate(0, 0, 1).Format(calendarDateFormat),
+			"SUMMARY:"+escapeVCard(reminder.Message),
+			"DESCRIPTION:"+escapeVCard("Reminder for "+contactName(reminder.Contact)),
+			"TRANSP:TRANSPARENT",
+			"END:VEVENT",
+		)
+	}
+	lines = append(lines, "END:VCALENDAR")
+
+	// Text values are escaped and folded like in vCards
+	var calendar strings.Builder
+	for _, line := range lines {
+		calendar.WriteString(foldVCardLine(line))
+		calendar.WriteString("\r\n")
+	}
+	return calendar.String()
+}
+
+func contactName(contact models.Contact) string {
+	return strings.TrimSpace(contact.Firstname + " " + contact.Lastname)
+}
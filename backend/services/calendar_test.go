@@ -0,0 +1,42 @@
+package services
+
+import (
+	"perema/models"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCalendarFeed(t *testing.T) {
+	now := time.Date(2024, 3, 10, 9, 30, 0, 0, time.UTC)
+	berlin, _ := time.LoadLocation("Europe/Berlin")
+	contactID := uint(3)
+	contacts := []models.Contact{
+		{Firstname: "Alice", Lastname: "Doe", Birthday: &models.Date{Time: time.Date(1990, 5, 4, 0, 0, 0, 0, time.UTC), Valid: true}},
+		{Firstname: "Bob", Birthday: &models.Date{Time: time.Date(1, 12, 24, 0, 0, 0, 0, time.UTC), Valid: true}},
+		{Firstname: "Carol"},
+	}
+	contacts[0].ID, contacts[1].ID, contacts[2].ID = 1, 2, 3
+	reminder := models.Reminder{Message: "Call; ask about the trip, soon", RemindAt: time.Date(2024, 3, 11, 23, 30, 0, 0, time.UTC),
+		ContactID: &contactID, Contact: models.Contact{Firstname: "Carol"}}
+	reminder.ID = 7
+
+	feed := CalendarFeed(contacts, []models.Reminder{reminder}, now, berlin)
+	assert.True(t, strings.HasPrefix(feed, "BEGIN:VCALENDAR\r\nVERSION:2.0\r\n"))
+	assert.True(t, strings.HasSuffix(feed, "END:VCALENDAR\r\n"))
+	assert.Equal(t, 3, strings.Count(feed, "BEGIN:VEVENT"))
+	assert.Contains(t, feed, "DTSTAMP:20240310T093000Z")
+
+	// Birthdays recur yearly from the day of birth
+	assert.Contains(t, feed, "UID:birthday-1@perema\r\nDTSTAMP:20240310T093000Z\r\nDTSTART;VALUE=DATE:19900504\r\nDTEND;VALUE=DATE:19900505\r\nRRULE:FREQ=YEARLY\r\nSUMMARY:Birthday of Alice Doe\r\n")
+	// Birthdays without a year still recur
+	assert.Contains(t, feed, "DTSTART;VALUE=DATE:20001224\r\nDTEND;VALUE=DATE:20001225\r\nRRULE:FREQ=YEARLY\r\nSUMMARY:Birthday of Bob\r\n")
+	assert.NotContains(t, feed, "birthday-3@perema")
+
+	// Reminders are due on their day in the configured time zone, text is escaped
+	assert.Contains(t, feed, "UID:reminder-7@perema\r\nDTSTAMP:20240310T093000Z\r\nDTSTART;VALUE=DATE:20240312\r\nDTEND;VALUE=DATE:20240313\r\n")
+	assert.Contains(t, feed, `SUMMARY:Call\; ask about the trip\, soon`)
+	assert.Contains(t, feed, "DESCRIPTION:Reminder for Carol\r\n")
+}
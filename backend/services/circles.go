@@ -0,0 +1,57 @@
+package services
+
+import (
+	"perema/models"
+	"reflect"
+	"slices"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// NormalizeCircles trims the circles and removes empty ones and duplicates, keeping the first occurrence.
+// With foldCase the circles are lower cased, so circles differing only in case are merged.
+func NormalizeCircles(circles []string, foldCase bool) []string {
+	if circles == nil {
+		return nil
+	}
+	normalized := []string{}
+	for _, circle := range circles {
+		circle = strings.TrimSpace(circle)
+		if foldCase {
+			circle = strings.ToLower(circle)
+		}
+		if circle != "" && !slices.Contains(normalized, circle) {
+			normalized = append(normalized, circle)
+		}
+	}
+	return normalized
+}
+
+// RegisterCircleNormalization normalizes the circles of contacts whenever they are created or saved from the
+// model, so every way of saving a contact stores clean circles. Updates written as SQL expressions are not covered.
+func RegisterCircleNormalization(db *gorm.DB, foldCase bool) error {
+	normalize := func(tx *gorm.DB) {
+		normalizeContact := func(value reflect.Value) {
+			if value.Kind() == reflect.Struct && value.CanAddr() {
+				if contact, ok := value.Addr().Interface().(*models.Contact); ok {
+					contact.Circles = NormalizeCircles(contact.Circles, foldCase)
+				}
+			}
+		}
+
+		switch value := tx.Statement.ReflectValue; value.Kind() {
+		case reflect.Slice, reflect.Array:
+			for i := 0; i < value.Len(); i++ {
+				normalizeContact(reflect.Indirect(value.Index(i)))
+			}
+		default:
+			normalizeContact(value)
+		}
+	}
+
+	if err := db.Callback().Create().Before("gorm:create").Register("perema:normalize_circles", normalize); err != nil {
+		return err
+	}
+	return db.Callback().Update().Before("gorm:update").Register("perema:normalize_circles", normalize)
+}
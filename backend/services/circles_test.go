@@ -0,0 +1,53 @@
+package services
+
+import (
+	"perema/models"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalizeCircles(t *testing.T) {
+	assert.Equal(t, []string{"Friends", "Work", "friends"}, NormalizeCircles([]string{" Friends", "Work ", "", "Friends", "friends", "  "}, false))
+	assert.Equal(t, []string{"friends", "work"}, NormalizeCircles([]string{" Friends", "Work", "friends", "WORK"}, true))
+	assert.Equal(t, []string{}, NormalizeCircles([]string{" "}, false))
+	assert.Nil(t, NormalizeCircles(nil, false))
+}
+
+func TestRegisterCircleNormalization(t *testing.T) {
+	db := setupDB()
+	assert.NoError(t, RegisterCircleNormalization(db, false))
+
+	contact := models.Contact{Firstname: "Alice", Circles: []string{" Friends ", "Work", "Friends", ""}}
+	assert.NoError(t, db.Create(&contact).Error)
+	var saved models.Contact
+	db.First(&saved, contact.ID)
+	assert.Equal(t, []string{"Friends", "Work"}, saved.Circles)
+
+	contact.Circles = []string{"Family ", "Family", "family"}
+	assert.NoError(t, db.Model(&contact).Select("Circles").Updates(&contact).Error)
+	db.First(&saved, contact.ID)
+	assert.Equal(t, []string{"Family", "family"}, saved.Circles)
+
+	contact.Circles = []string{"Sports", " Sports"}
+	assert.NoError(t, db.Save(&contact).Error)
+	db.First(&saved, contact.ID)
+	assert.Equal(t, []string{"Sports"}, saved.Circles)
+
+	contacts := []models.Contact{{Firstname: "Bob", Circles: []string{"A", "A "}}, {Firstname: "Carol", Circles: []string{" B"}}}
+	assert.NoError(t, db.Create(&contacts).Error)
+	var bob, carol models.Contact
+	db.First(&bob, contacts[0].ID)
+	db.First(&carol, contacts[1].ID)
+	assert.Equal(t, []string{"A"}, bob.Circles)
+	assert.Equal(t, []string{"B"}, carol.Circles)
+
+	// With case folding circles differing only in case are the same
+	db = setupDB()
+	assert.NoError(t, RegisterCircleNormalization(db, true))
+	contact = models.Contact{Firstname: "Dave", Circles: []string{"Friends", "FRIENDS ", "Work"}}
+	assert.NoError(t, db.Create(&contact).Error)
+	var dave models.Contact
+	db.First(&dave, contact.ID)
+	assert.Equal(t, []string{"friends", "work"}, dave.Circles)
+}
@@ -0,0 +1,139 @@
+package services
+
+import (
+	"perema/models"
+	"strconv"
+	"strings"
+)
+
+// ContactToVCard serializes a contact as vCard 3.0
+func ContactToVCard(contact models.Contact) string {
+	var lines []string
+	lines = append(lines,
+		"BEGIN:VCARD",
+		"VERSION:3.0",
+		"FN:"+escapeVCard(strings.TrimSpace(contact.Firstname+" "+contact.Lastname)),
+		"N:"+escapeVCard(contact.Lastname)+";"+escapeVCard(contact.Firstname)+";;;",
+	)
+
+	// Address book clients identify cards by their UID
+	if contact.ID != 0 {
+		lines = append(lines, "UID:perema-contact-"+strconv.Itoa(int(contact.ID)))
+	}
+
+	if contact.Nickname != "" {
+		lines = append(lines, "NICKNAME:"+escapeVCard(contact.Nickname))
+	}
+	if contact.Email != "" {
+		lines = append(lines, "EMAIL;TYPE=INTERNET:"+escapeVCard(contact.Email))
+	}
+	if contact.Phone != "" {
+		lines = append(lines, "TEL:"+escapeVCard(contact.Phone))
+	}
+	if contact.Birthday != nil && contact.Birthday.Valid && !contact.Birthday.Time.IsZero() {
+		if contact.Birthday.HasYear() {
+			lines = append(lines, "BDAY:"+contact.Birthday.Time.Format(models.DateFormat))
+		} else {
+			lines = append(lines, "BDAY:"+contact.Birthday.Time.Format("--0102")) // Birthday without known year
+		}
+	}
+	if address := contact.Address; !address.IsEmpty() {
+		components := []string{"", "", address.Street, address.City, address.Region, address.PostalCode, address.Country}
+		for i, component := range components {
+			components[i] = escapeVCard(component)
+		}
+		lines = append(lines, "ADR:"+strings.Join(components, ";"))
+	}
+	if len(contact.Circles) > 0 {
+		categories := make([]string, len(contact.Circles))
+		for i, circle := range contact.Circles {
+			categories[i] = escapeVCard(circle)
+		}
+		lines = append(lines, "CATEGORIES:"+strings.Join(categories, ","))
+	}
+	lines = append(lines, "END:VCARD")
+
+	var vcard strings.Builder
+	for _, line := range lines {
+		vcard.WriteString(foldVCardLine(line))
+		vcard.WriteString("\r\n")
+	}
+	return vcard.String()
+}
+
+// escapeVCard escapes the characters with special meaning in vCard values
+func escapeVCard(value string) string {
+	return strings.NewReplacer(`\`, `\\`, ",", `\,`, ";", `\;`, "\r\n", `\n`, "\n", `\n`).Replace(value)
+}
+
+// foldVCardLine splits lines longer than 75 octets as required by RFC 6350
+func foldVCardLine(line string) string {
+	const maxLength = 75
+	if len(line) <= maxLength {
+		return line
+	}
+
+	var folded strings.Builder
+	length := 0
+	for _, r := range line {
+		runeLength := len(string(r))
+		if length+runeLength > maxLength {
+			folded.WriteString("\r\n ")
+			length = 1
+		}
+		folded.WriteRune(r)
+		length += runeLength
+	}
+	return folded.String()
+}
+
+// ContactCSVColumns are the columns used when exporting contacts as CSV, in a stable order
+var ContactCSVColumns = []string{"ID", "firstname", "lastname", "nickname", "gender", "email", "phone", "birthday", "street", "postal_code", "city", "region", "country", "how_we_met", "food_preference", "work_information", "contact_information", "circles"}
+
+// ContactCSVRecord returns the values of the given columns of a contact
+func ContactCSVRecord(contact models.Contact, columns []string) []string {
+	record := make([]string, len(columns))
+	for i, column := range columns {
+		switch column {
+		case "ID":
+			record[i] = strconv.FormatUint(uint64(contact.ID), 10)
+		case "firstname":
+			record[i] = contact.Firstname
+		case "lastname":
+			record[i] = contact.Lastname
+		case "nickname":
+			record[i] = contact.Nickname
+		case "gender":
+			record[i] = contact.Gender
+		case "email":
+			record[i] = contact.Email
+		case "phone":
+			record[i] = contact.Phone
+		case "birthday":
+			if contact.Birthday != nil && contact.Birthday.Valid {
+				record[i] = contact.Birthday.Time.Format(models.DateFormat)
+			}
+		case "street":
+			record[i] = contact.Address.Street
+		case "postal_code":
+			record[i] = contact.Address.PostalCode
+		case "city":
+			record[i] = contact.Address.City
+		case "region":
+			record[i] = contact.Address.Region
+		case "country":
+			record[i] = contact.Address.Country
+		case "how_we_met":
+			record[i] = contact.HowWeMet
+		case "food_preference":
+			record[i] = contact.FoodPreference
+		case "work_information":
+			record[i] = contact.WorkInformation
+		case "contact_information":
+			record[i] = contact.ContactInformation
+		case "circles":
+			record[i] = strings.Join(contact.Circles, ";")
+		}
+	}
+	return record
+}
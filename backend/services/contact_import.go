@@ -0,0 +1,278 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"perema/models"
+	"strings"
+	"time"
+)
+
+// ParsedVCard is a single card of a vCard file converted into a contact
+type ParsedVCard struct {
+	Contact models.Contact
+	Err     error
+}
+
+// ParseVCards splits a vCard file into its cards and maps each card to a contact.
+// Both CRLF and LF line endings as well as folded lines are supported. Cards that cannot be mapped carry an error.
+func ParseVCards(data string) []ParsedVCard {
+	data = strings.ReplaceAll(data, "\r\n", "\n")
+	data = strings.ReplaceAll(data, "\r", "\n")
+
+	// Unfold continuation lines starting with a space or tab
+	var lines []string
+	for _, line := range strings.Split(data, "\n") {
+		if (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) && len(lines) > 0 {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		lines = append(lines, line)
+	}
+
+	var cards []ParsedVCard
+	var properties []string
+	inCard := false
+	for _, line := range lines {
+		switch name, _ := splitVCardLine(line); {
+		case name == "BEGIN" && strings.EqualFold(vCardValue(line), "VCARD"):
+			if inCard {
+				cards = append(cards, ParsedVCard{Err: errors.New("card is not terminated by END:VCARD")})
+			}
+			inCard = true
+			properties = nil
+		case name == "END" && strings.EqualFold(vCardValue(line), "VCARD"):
+			if inCard {
+				contact, err := vCardToContact(properties)
+				cards = append(cards, ParsedVCard{Contact: contact, Err: err})
+			}
+			inCard = false
+		case inCard && strings.TrimSpace(line) != "":
+			properties = append(properties, line)
+		}
+	}
+	if inCard {
+		cards = append(cards, ParsedVCard{Err: errors.New("card is not terminated by END:VCARD")})
+	}
+
+	return cards
+}
+
+// vCardToContact maps the properties N, FN, NICKNAME, EMAIL, TEL, BDAY, ADR and CATEGORIES to a contact
+func vCardToContact(properties []string) (models.Contact, error) {
+	var contact models.Contact
+	var formattedName string
+
+	for _, property := range properties {
+		name, found := splitVCardLine(property)
+		if !found {
+			return contact, fmt.Errorf("invalid line %q", property)
+		}
+		value := vCardValue(property)
+
+		switch name {
+		case "N":
+			components := splitVCardValue(value, ';')
+			contact.Lastname = components[0]
+			if len(components) > 1 {
+				contact.Firstname = components[1]
+			}
+		case "FN":
+			formattedName = unescapeVCard(value)
+		case "NICKNAME":
+			contact.Nickname = strings.Join(splitVCardValue(value, ','), ", ")
+		case "EMAIL":
+			if contact.Email == "" {
+				contact.Email = unescapeVCard(value)
+			}
+		case "TEL":
+			if contact.Phone == "" {
+				contact.Phone = unescapeVCard(value)
+			}
+		case "BDAY":
+			birthday, err := parseVCardDate(value)
+			if err != nil {
+				return contact, err
+			}
+			contact.Birthday = birthday
+		case "ADR":
+			// Post office box, extended address, street, locality, region, postal code and country
+			components := make([]string, 7)
+			for i, component := range splitVCardValue(value, ';') {
+				if i < len(components) {
+					components[i] = strings.TrimSpace(unescapeVCard(component))
+				}
+			}
+			var street []string
+			for _, part := range components[:3] {
+				if part != "" {
+					street = append(street, part)
+				}
+			}
+			contact.Address = models.Address{
+				Street:     strings.Join(street, ", "),
+				City:       components[3],
+				Region:     components[4],
+				PostalCode: components[5],
+				Country:    components[6],
+			}
+		case "CATEGORIES":
+			for _, category := range splitVCardValue(value, ',') {
+				if category = strings.TrimSpace(category); category != "" {
+					contact.Circles = append(contact.Circles, category)
+				}
+			}
+		}
+	}
+
+	// Fall back to the formatted name if the structured name has no first name
+	if contact.Firstname == "" && formattedName != "" {
+		firstname, lastname, _ := strings.Cut(formattedName, " ")
+		contact.Firstname = firstname
+		if contact.Lastname == "" {
+			contact.Lastname = lastname
+		}
+	}
+	if contact.Firstname == "" {
+		return contact, errors.New("card has no name")
+	}
+
+	return contact, nil
+}
+
+// splitVCardLine returns the upper case property name of a content line without group and parameters
+func splitVCardLine(line string) (string, bool) {
+	nameAndParams, _, found := strings.Cut(line, ":")
+	name, _, _ := strings.Cut(nameAndParams, ";")
+	if index := strings.LastIndex(name, "."); index >= 0 {
+		name = name[index+1:]
+	}
+	return strings.ToUpper(strings.TrimSpace(name)), found
+}
+
+func vCardValue(line string) string {
+	_, value, _ := strings.Cut(line, ":")
+	return strings.TrimSpace(value)
+}
+
+// splitVCardValue splits a value at unescaped separators and unescapes the components
+func splitVCardValue(value string, separator rune) []string {
+	var components []string
+	var component strings.Builder
+	escaped := false
+	for _, r := range value {
+		switch {
+		case escaped:
+			component.WriteRune('\\')
+			component.WriteRune(r)
+			escaped = false
+		case r == '\\':
+			escaped = true
+		case r == separator:
+			components = append(components, unescapeVCard(component.String()))
+			component.Reset()
+		default:
+			component.WriteRune(r)
+		}
+	}
+	return append(components, unescapeVCard(component.String()))
+}
+
+// unescapeVCard reverts escapeVCard
+func unescapeVCard(value string) string {
+	return strings.NewReplacer(`\\`, `\`, `\,`, ",", `\;`, ";", `\n`, "\n", `\N`, "\n").Replace(value)
+}
+
+// parseVCardDate parses full dates in basic or extended format and dates without year like --0504
+func parseVCardDate(value string) (*models.Date, error) {
+	value, _, _ = strings.Cut(value, "T")
+	for _, layout := range []string{"2006-01-02", "20060102", "--0102", "--01-02"} {
+		if date, err := time.Parse(layout, value); err == nil {
+			if strings.HasPrefix(layout, "--") {
+				date = time.Date(1, date.Month(), date.Day(), 0, 0, 0, 0, time.UTC)
+			}
+			return &models.Date{Time: date, Valid: true}, nil
+		}
+	}
+	return nil, fmt.Errorf("invalid birthday %q", value)
+}
+
+// ContactFromCSVRecord maps a CSV row to a contact using the column names of the header row.
+// Circles are a semicolon separated list and birthdays may be given as 2006-01-02 or 02.01.2006.
+// Unknown columns are ignored.
+func ContactFromCSVRecord(header, record []string) (models.Contact, error) {
+	var contact models.Contact
+	for i, column := range header {
+		if i >= len(record) {
+			break
+		}
+		value := strings.TrimSpace(record[i])
+
+		switch strings.ToLower(strings.TrimSpace(column)) {
+		case "firstname":
+			contact.Firstname = value
+		case "lastname":
+			contact.Lastname = value
+		case "nickname":
+			contact.Nickname = value
+		case "gender":
+			// Genders outside the known set are kept as other instead of failing the whole row
+			gender, ok := models.NormalizeGender(value)
+			if !ok {
+				gender = models.GenderOther
+			}
+			contact.Gender = gender
+		case "email":
+			contact.Email = value
+		case "phone":
+			contact.Phone = value
+		case "birthday":
+			if value == "" {
+				continue
+			}
+			birthday, err := parseCSVDate(value)
+			if err != nil {
+				return contact, err
+			}
+			contact.Birthday = birthday
+		case "address", "street": // Exports before addresses were structured have a single address column
+			contact.Address.Street = value
+		case "postal_code":
+			contact.Address.PostalCode = value
+		case "city":
+			contact.Address.City = value
+		case "region":
+			contact.Address.Region = value
+		case "country":
+			contact.Address.Country = value
+		case "how_we_met":
+			contact.HowWeMet = value
+		case "food_preference":
+			contact.FoodPreference = value
+		case "work_information":
+			contact.WorkInformation = value
+		case "contact_information":
+			contact.ContactInformation = value
+		case "circles":
+			for _, circle := range strings.Split(value, ";") {
+				if circle = strings.TrimSpace(circle); circle != "" {
+					contact.Circles = append(contact.Circles, circle)
+				}
+			}
+		}
+	}
+
+	if contact.Firstname == "" {
+		return contact, errors.New("firstname is missing")
+	}
+	return contact, nil
+}
+
+func parseCSVDate(value string) (*models.Date, error) {
+	for _, layout := range []string{models.DateFormat, "02.01.2006"} {
+		if date, err := time.Parse(layout, value); err == nil {
+			return &models.Date{Time: date, Valid: true}, nil
+		}
+	}
+	return nil, fmt.Errorf("invalid birthday %q, use YYYY-MM-DD or DD.MM.YYYY", value)
+}
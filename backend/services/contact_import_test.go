@@ -0,0 +1,83 @@
+package services
+
+import (
+	"perema/models"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseVCardsRoundTrip(t *testing.T) {
+	contact := models.Contact{
+		Firstname: "Jane",
+		Lastname:  "Doe",
+		Nickname:  "JD",
+		Email:     "jane@example.com",
+		Phone:     "+49 123",
+		Birthday:  &models.Date{Time: time.Date(1990, time.May, 4, 0, 0, 0, 0, time.UTC), Valid: true},
+		Address:   models.Address{Street: "Main Street 1; Back, Left", City: "Berlin", PostalCode: "10115", Country: "Germany"},
+		Circles:   []string{"Friends", "Work, Old"},
+	}
+
+	cards := ParseVCards(ContactToVCard(contact))
+
+	assert.Len(t, cards, 1)
+	assert.NoError(t, cards[0].Err)
+	parsed := cards[0].Contact
+	assert.Equal(t, contact.Firstname, parsed.Firstname)
+	assert.Equal(t, contact.Lastname, parsed.Lastname)
+	assert.Equal(t, contact.Nickname, parsed.Nickname)
+	assert.Equal(t, contact.Email, parsed.Email)
+	assert.Equal(t, contact.Phone, parsed.Phone)
+	assert.Equal(t, contact.Birthday.Time, parsed.Birthday.Time)
+	assert.Equal(t, contact.Address, parsed.Address)
+	assert.Equal(t, contact.Circles, parsed.Circles)
+}
+
+func TestParseVCardsMultipleCards(t *testing.T) {
+	// LF line endings, a folded line, groups, parameters and a structured address
+	data := "BEGIN:VCARD\n" +
+		"VERSION:4.0\n" +
+		"FN:John Smith\n" +
+		"item1.EMAIL;TYPE=work:john@exa\n" +
+		" mple.com\n" +
+		"ADR;TYPE=home:;;Main Street 1;Berlin;;10115;Germany\n" +
+		"BDAY:--0504\n" +
+		"END:VCARD\n" +
+		"BEGIN:VCARD\r\n" +
+		"N:Doe;Jane;;;\r\n" +
+		"TEL;TYPE=cell:555\r\n" +
+		"BDAY:19900504\r\n" +
+		"END:VCARD\r\n" +
+		"BEGIN:VCARD\n" +
+		"EMAIL:nobody@example.com\n" +
+		"END:VCARD\n" +
+		"BEGIN:VCARD\n" +
+		"N:Broken;Bob\n" +
+		"BDAY:someday\n" +
+		"END:VCARD\n" +
+		"BEGIN:VCARD\n" +
+		"N:Open;Olivia\n"
+
+	cards := ParseVCards(data)
+
+	assert.Len(t, cards, 5)
+
+	assert.NoError(t, cards[0].Err)
+	assert.Equal(t, "John", cards[0].Contact.Firstname)
+	assert.Equal(t, "Smith", cards[0].Contact.Lastname)
+	assert.Equal(t, "john@example.com", cards[0].Contact.Email)
+	assert.Equal(t, models.Address{Street: "Main Street 1", City: "Berlin", PostalCode: "10115", Country: "Germany"}, cards[0].Contact.Address)
+	assert.False(t, cards[0].Contact.Birthday.HasYear())
+	assert.Equal(t, time.May, cards[0].Contact.Birthday.Time.Month())
+
+	assert.NoError(t, cards[1].Err)
+	assert.Equal(t, "Jane", cards[1].Contact.Firstname)
+	assert.Equal(t, "555", cards[1].Contact.Phone)
+	assert.Equal(t, 1990, cards[1].Contact.Birthday.Time.Year())
+
+	assert.EqualError(t, cards[2].Err, "card has no name")
+	assert.EqualError(t, cards[3].Err, `invalid birthday "someday"`)
+	assert.EqualError(t, cards[4].Err, "card is not terminated by END:VCARD")
+}
@@ -0,0 +1,111 @@
+package services
+
+import (
+	"perema/models"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Columns of the contact holding the value of the primary contact method of each type
+var legacyContactMethodColumns = map[string]string{
+	models.ContactMethodEmail: "email",
+	models.ContactMethodPhone: "phone",
+}
+
+// legacyContactMethodValue returns the value of the contact field which mirrors the primary method of the type
+func legacyContactMethodValue(contact models.Contact, methodType string) string {
+	if methodType == models.ContactMethodEmail {
+		return contact.Email
+	}
+	return contact.Phone
+}
+
+// EnsurePrimaryContactMethod makes exactly one contact method of the type primary, keeping the current primary one
+// or promoting the oldest method otherwise. The value of the primary method is copied into the email or phone field
+// of the contact, which is cleared once no method of the type is left.
+func EnsurePrimaryContactMethod(db *gorm.DB, contactID uint, methodType string) error {
+	var methods []models.ContactMethod
+	if err := db.Where("contact_id = ? AND type = ?", contactID, methodType).Order("is_primary DESC, id").Find(&methods).Error; err != nil {
+		return err
+	}
+
+	value := ""
+	if len(methods) > 0 {
+		primary := methods[0]
+		value = primary.Value
+		if err := db.Model(&models.ContactMethod{}).
+			Where("contact_id = ? AND type = ? AND id <> ? AND is_primary", contactID, methodType, primary.ID).
+			Update("is_primary", false).Error; err != nil {
+			return err
+		}
+		if !primary.IsPrimary {
+			if err := db.Model(&primary).Update("is_primary", true).Error; err != nil {
+				return err
+			}
+		}
+	}
+
+	column := legacyContactMethodColumns[methodType]
+	return db.Model(&models.Contact{}).
+		Where("id = ? AND ("+column+" IS NULL OR "+column+" <> ?)", contactID, value).
+		Updates(map[string]any{column: value, "version": gorm.Expr("version + 1")}).Error
+}
+
+// SetPrimaryContactMethod makes the method the primary one of its type
+func SetPrimaryContactMethod(db *gorm.DB, method models.ContactMethod) error {
+	if err := db.Model(&models.ContactMethod{}).
+		Where("contact_id = ? AND type = ?", method.ContactID, method.Type).
+		Update("is_primary", gorm.Expr("id = ?", method.ID)).Error; err != nil {
+		return err
+	}
+	return EnsurePrimaryContactMethod(db, method.ContactID, method.Type)
+}
+
+// SyncLegacyContactMethods applies the email and phone fields of a saved contact to its primary contact methods.
+// A new value creates or changes the primary method, an empty value removes it so the next method becomes primary.
+func SyncLegacyContactMethods(db *gorm.DB, contact models.Contact) error {
+	for methodType := range legacyContactMethodColumns {
+		value := legacyContactMethodValue(contact, methodType)
+
+		var primary models.ContactMethod
+		result := db.Where("contact_id = ? AND type = ? AND is_primary", contact.ID, methodType).Limit(1).Find(&primary)
+		if result.Error != nil {
+			return result.Error
+		}
+
+		var err error
+		switch {
+		case result.RowsAffected == 0 && value != "":
+			err = db.Create(&models.ContactMethod{ContactID: contact.ID, Type: methodType, Value: value, IsPrimary: true}).Error
+		case result.RowsAffected == 0:
+			continue
+		case value == "":
+			if err = db.Delete(&primary).Error; err == nil {
+				err = EnsurePrimaryContactMethod(db, contact.ID, methodType)
+			}
+		case primary.Value != value:
+			err = db.Model(&primary).Update("value", value).Error
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// BackfillContactMethods creates the primary contact methods of contacts whose email or phone was saved before
+// contacts could have several of them
+func BackfillContactMethods(db *gorm.DB) error {
+	for methodType, column := range legacyContactMethodColumns {
+		if err := db.Exec(`INSERT INTO contact_methods (created_at, updated_at, contact_id, type, value, is_primary)
+			SELECT ?, ?, id, ?, `+column+`, ? FROM contacts
+			WHERE `+column+` IS NOT NULL AND `+column+` <> ''
+			AND NOT EXISTS (SELECT 1 FROM contact_methods WHERE contact_methods.contact_id = contacts.id
+				AND contact_methods.type = ? AND contact_methods.deleted_at IS NULL)`,
+			time.Now(), time.Now(), methodType, true, methodType).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,58 @@
+package services
+
+import (
+	"perema/models"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBackfillContactMethods(t *testing.T) {
+	db := setupDB()
+	alice := models.Contact{Firstname: "Alice", Email: "alice@example.com", Phone: "0123"}
+	bob := models.Contact{Firstname: "Bob"}
+	db.Create(&alice)
+	db.Create(&bob)
+
+	assert.NoError(t, BackfillContactMethods(db))
+	// Running it again does not duplicate the methods
+	assert.NoError(t, BackfillContactMethods(db))
+
+	var methods []models.ContactMethod
+	db.Order("type").Find(&methods)
+	assert.Len(t, methods, 2)
+	for _, method := range methods {
+		assert.Equal(t, alice.ID, method.ContactID)
+		assert.True(t, method.IsPrimary)
+	}
+	assert.Equal(t, "alice@example.com", methods[0].Value)
+	assert.Equal(t, "0123", methods[1].Value)
+}
+
+func TestSyncLegacyContactMethods(t *testing.T) {
+	db := setupDB()
+	contact := models.Contact{Firstname: "Alice", Email: "alice@example.com"}
+	db.Create(&contact)
+	assert.NoError(t, SyncLegacyContactMethods(db, contact))
+	db.Create(&models.ContactMethod{ContactID: contact.ID, Type: models.ContactMethodEmail, Value: "alice@work.example.com"})
+
+	// Changing the email changes the primary method only
+	contact.Email = "alice@home.example.com"
+	assert.NoError(t, SyncLegacyContactMethods(db, contact))
+	var primary models.ContactMethod
+	db.Where("contact_id = ? AND is_primary", contact.ID).First(&primary)
+	assert.Equal(t, "alice@home.example.com", primary.Value)
+
+	// Clearing the email promotes the remaining method and stores it on the contact
+	db.Model(&contact).Update("email", "")
+	contact.Email = ""
+	assert.NoError(t, SyncLegacyContactMethods(db, contact))
+	var methods []models.ContactMethod
+	db.Where("contact_id = ?", contact.ID).Find(&methods)
+	assert.Len(t, methods, 1)
+	assert.True(t, methods[0].IsPrimary)
+
+	var stored models.Contact
+	db.First(&stored, contact.ID)
+	assert.Equal(t, "alice@work.example.com", stored.Email)
+}
@@ -0,0 +1,140 @@
+package services
+
+import (
+	"perema/models"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+var summaryPlaceholder = regexp.MustCompile(`\{(\w+)\}`)
+
+// ContactSummary assembles a one-line description of a contact from a template like
+// "{circles}, works at {work}, last seen {last_seen}". The template is split into parts at commas
+// and every part referencing an unknown value is left out.
+//
+// Supported placeholders: name, nickname, circles, address, city, work, how_we_met, last_seen, birthday and age.
+func ContactSummary(contact models.Contact, template string, now time.Time, leapDay string) string {
+	values := summaryValues(contact, now, leapDay)
+
+	var parts []string
+	for _, part := range strings.Split(template, ",") {
+		complete := true
+		part = summaryPlaceholder.ReplaceAllStringFunc(part, func(placeholder string) string {
+			value := values[strings.Trim(placeholder, "{}")]
+			if value == "" {
+				complete = false
+			}
+			return value
+		})
+		if part = strings.TrimSpace(part); complete && part != "" {
+			parts = append(parts, part)
+		}
+	}
+
+	summary := strings.Join(parts, ", ")
+	if summary == "" {
+		return ""
+	}
+	first, size := utf8.DecodeRuneInString(summary)
+	return string(unicode.ToUpper(first)) + summary[size:]
+}
+
+func summaryValues(contact models.Contact, now time.Time, leapDay string) map[string]string {
+	values := map[string]string{
+		"name":       strings.TrimSpace(contact.Firstname + " " + contact.Lastname),
+		"nickname":   contact.Nickname,
+		"circles":    joinList(contact.Circles),
+		"address":    contact.Address.String(),
+		"city":       contact.Address.City,
+		"work":       contact.WorkInformation,
+		"how_we_met": contact.HowWeMet,
+	}
+
+	if lastSeen, ok := LastSeen(contact); ok {
+		values["last_seen"] = relativeDays(daysBetween(lastSeen, now))
+	}
+
+	// Upcoming birthdays are of no interest for deceased contacts
+	if contact.Birthday != nil && contact.Birthday.Valid && !contact.Deceased {
+		untilBirthday := daysBetween(contact.Birthday.NextOccurrence(now, leapDay), now)
+		values["birthday"] = relativeDays(untilBirthday)
+		if age, ok := contact.AgeAtNextBirthday(now, leapDay); ok {
+			if untilBirthday != 0 {
+				age--
+			}
+			values["age"] = strconv.Itoa(age)
+		}
+	}
+
+	return values
+}
+
+// LastSeen returns the date of the latest loaded activity or note of the contact
+func LastSeen(contact models.Contact) (time.Time, bool) {
+	var latest time.Time
+	for _, activity := range contact.Activities {
+		if activity.Date.After(latest) {
+			latest = activity.Date
+		}
+	}
+	for _, note := range contact.Notes {
+		if note.Date.After(latest) {
+			latest = note.Date
+		}
+	}
+	return latest, !latest.IsZero()
+}
+
+// daysBetween counts the calendar days from one date to another in the location of "to"
+func daysBetween(from, to time.Time) int {
+	from = from.In(to.Location())
+	start := time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, time.UTC)
+	end := time.Date(to.Year(), to.Month(), to.Day(), 0, 0, 0, 0, time.UTC)
+	return int(end.Sub(start).Hours() / 24)
+}
+
+// relativeDays describes a distance in days like "3 weeks ago" or "in 5 days"
+func relativeDays(days int) string {
+	switch days {
+	case 0:
+		return "today"
+	case 1:
+		return "yesterday"
+	case -1:
+		return "tomorrow"
+	}
+
+	amount := days
+	if amount < 0 {
+		amount = -amount
+	}
+
+	var distance string
+	switch {
+	case amount < 14:
+		distance = strconv.Itoa(amount) + " days"
+	case amount < 60:
+		distance = strconv.Itoa(amount/7) + " weeks"
+	case amount < 730:
+		distance = strconv.Itoa(amount/30) + " months"
+	default:
+		distance = strconv.Itoa(amount/365) + " years"
+	}
+
+	if days < 0 {
+		return "in " + distance
+	}
+	return distance + " ago"
+}
+
+// joinList joins items like "friends, family and work"
+func joinList(items []string) string {
+	if len(items) < 2 {
+		return strings.Join(items, "")
+	}
+	return strings.Join(items[:len(items)-1], ", ") + " and " + items[len(items)-1]
+}
@@ -0,0 +1,86 @@
+package services
+
+import (
+	"perema/models"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestContactSummary(t *testing.T) {
+	now := time.Date(2024, time.March, 1, 15, 0, 0, 0, time.UTC)
+	template := "{circles} in {address}, works at {work}, last seen {last_seen}, birthday {birthday}"
+
+	tests := []struct {
+		name    string
+		contact models.Contact
+		summary string
+	}{
+		{
+			name:    "no data",
+			contact: models.Contact{Firstname: "Empty"},
+			summary: "",
+		},
+		{
+			name: "all data",
+			contact: models.Contact{
+				Firstname:       "Anna",
+				Circles:         []string{"close friend"},
+				Address:         models.Address{City: "Berlin"},
+				WorkInformation: "Acme",
+				Birthday:        &models.Date{Time: time.Date(1990, time.March, 22, 0, 0, 0, 0, time.UTC), Valid: true},
+				Notes:           []models.Note{{Date: now.AddDate(0, 0, -30)}},
+				Activities:      []models.Activity{{Date: now.AddDate(0, 0, -12)}},
+			},
+			summary: "Close friend in Berlin, works at Acme, last seen 12 days ago, birthday in 3 weeks",
+		},
+		{
+			name: "part with missing value is omitted",
+			contact: models.Contact{
+				Firstname:       "Ben",
+				Circles:         []string{"family", "sports", "work"},
+				WorkInformation: "Globex",
+			},
+			summary: "Works at Globex",
+		},
+		{
+			name: "yearless birthday today",
+			contact: models.Contact{
+				Firstname: "Cleo",
+				Birthday:  &models.Date{Time: time.Date(1, time.March, 1, 0, 0, 0, 0, time.UTC), Valid: true},
+				Notes:     []models.Note{{Date: now.AddDate(0, 0, -1)}},
+			},
+			summary: "Last seen yesterday, birthday today",
+		},
+		{
+			name: "no birthday for deceased contacts",
+			contact: models.Contact{
+				Firstname: "Dora",
+				Deceased:  true,
+				Birthday:  &models.Date{Time: time.Date(1930, time.March, 2, 0, 0, 0, 0, time.UTC), Valid: true},
+				Notes:     []models.Note{{Date: now.AddDate(-2, 0, 0)}},
+			},
+			summary: "Last seen 2 years ago",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.summary, ContactSummary(tt.contact, template, now, "feb28"))
+		})
+	}
+}
+
+func TestContactSummaryCustomTemplate(t *testing.T) {
+	now := time.Date(2024, time.March, 1, 0, 0, 0, 0, time.UTC)
+	contact := models.Contact{
+		Firstname: "Anna",
+		Lastname:  "Smith",
+		Circles:   []string{"family", "sports", "work"},
+		Birthday:  &models.Date{Time: time.Date(1990, time.March, 5, 0, 0, 0, 0, time.UTC), Valid: true},
+	}
+
+	summary := ContactSummary(contact, "{name} ({age}), {circles}, met {how_we_met}, birthday {birthday}", now, "feb28")
+	assert.Equal(t, "Anna Smith (33), family, sports and work, birthday in 4 days", summary)
+}
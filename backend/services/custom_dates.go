@@ -0,0 +1,118 @@
+package services
+
+import (
+	"fmt"
+	"perema/config"
+	"perema/models"
+	"sort"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// UpcomingCustomDate is a custom date of a contact which falls within the requested window
+type UpcomingCustomDate struct {
+	CustomDate models.CustomDate `json:"custom_date"` // Includes the contact
+	Date       string            `json:"date"`        // Date of the next occurrence in YYYY-MM-DD format
+	DaysUntil  int               `json:"days_until"`
+	Years      *int              `json:"years,omitempty"` // Years since the date, only set for recurring dates with a known year
+}
+
+// customDateOn reports whether the custom date falls on the day. Recurring dates fall on their month and day
+// every year, dates on Feb 29 on the day given by leapDay in years without Feb 29.
+func customDateOn(date models.CustomDate, day time.Time, leapDay string) bool {
+	if !date.Date.Valid || (!date.Recurring && date.Date.Time.Year() != day.Year()) {
+		return false
+	}
+	return sameMonthDay(date.Date.Time, day, leapDay)
+}
+
+// customDateYears returns how many years have passed since the date on the given day of a later year
+func customDateYears(date models.CustomDate, day time.Time) (int, bool) {
+	if !date.Recurring || !date.Date.HasYear() || day.Year() <= date.Date.Time.Year() {
+		return 0, false
+	}
+	return day.Year() - date.Date.Time.Year(), true
+}
+
+// customDateContacts returns the custom dates of living contacts, together with their contact, which are their
+// reminder lead days after the given day
+func customDateContacts(db *gorm.DB, day time.Time, leapDay string) ([]models.CustomDate, error) {
+	var candidates []models.CustomDate
+	dialect := Dialect(db)
+	monthDay := dialect.MonthDay("custom_dates.date")
+	if err := db.InnerJoins("Contact").
+		Where(`"Contact".deceased = ?`, false).
+		Where("("+monthDay+" = "+dialect.MonthDayAfter("custom_dates.reminder_lead_days")+" OR "+monthDay+" = ?)", day.Format(models.DateFormat), "02-29").
+		Order("custom_dates.id").
+		Find(&candidates).Error; err != nil {
+		return nil, err
+	}
+
+	// Dates which do not recur and leap days are decided for each date, as the lead days differ
+	dates := []models.CustomDate{}
+	for _, date := range candidates {
+		if customDateOn(date, day.AddDate(0, 0, date.ReminderLeadDays), leapDay) {
+			dates = append(dates, date)
+		}
+	}
+	return dates, nil
+}
+
+func sendCustomDateReminders(db *gorm.DB, cfg *config.Config, mailer *userMailer, today time.Time) error {
+	dates, err := customDateContacts(db, today, cfg.LeapDayBirthdays)
+	if err != nil {
+		return fmt.Errorf("failed to query custom dates: %w", err)
+	}
+
+	for _, date := range dates {
+		years := ""
+		if count, ok := customDateYears(date, today.AddDate(0, 0, date.ReminderLeadDays)); ok {
+			years = fmt.Sprintf(" (%d years)", count)
+		}
+		person := strings.TrimSpace(date.Contact.Firstname + " " + date.Contact.Lastname)
+		subject, body := customDateMail(person, date.Label, birthdayDistance(date.ReminderLeadDays), years)
+		if err := mailer.Send(date.Contact.UserID, subject, body); err != nil {
+			return fmt.Errorf("failed to send email for %s of %s: %w", date.Label, date.Contact.Firstname, err)
+		}
+	}
+	return nil
+}
+
+func customDateMail(person, label, when, years string) (string, string) {
+	subject := fmt.Sprintf("%s of %s %s", label, person, when)
+	body := fmt.Sprintf("Hi,\n\nthe %s of %s is %s%s.\n", label, person, when, years)
+	return subject, body
+}
+
+// UpcomingCustomDates returns the custom dates which occur today or within the next days, sorted by how soon
+// they are. Recurring dates continue into the next year like birthdays, other dates are only returned once.
+func UpcomingCustomDates(dates []models.CustomDate, now time.Time, days int, leapDay string) []UpcomingCustomDate {
+	upcoming := []UpcomingCustomDate{}
+	for _, date := range dates {
+		if !date.Date.Valid {
+			continue
+		}
+
+		next := date.Date.NextOccurrence(now, leapDay)
+		if !date.Recurring {
+			next = time.Date(date.Date.Time.Year(), date.Date.Time.Month(), date.Date.Time.Day(), 0, 0, 0, 0, now.Location())
+		}
+		daysUntil := -daysBetween(next, now)
+		if daysUntil < 0 || daysUntil > days {
+			continue
+		}
+
+		entry := UpcomingCustomDate{CustomDate: date, Date: next.Format(models.DateFormat), DaysUntil: daysUntil}
+		if years, ok := customDateYears(date, next); ok {
+			entry.Years = &years
+		}
+		upcoming = append(upcoming, entry)
+	}
+
+	sort.SliceStable(upcoming, func(i, j int) bool {
+		return upcoming[i].DaysUntil < upcoming[j].DaysUntil
+	})
+	return upcoming
+}
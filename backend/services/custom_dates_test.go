@@ -0,0 +1,90 @@
+package services
+
+import (
+	"perema/config"
+	"perema/models"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCustomDateContacts(t *testing.T) {
+	db := setupDB()
+
+	today := time.Date(2023, time.February, 28, 0, 0, 0, 0, time.UTC)
+	jane := models.Contact{Firstname: "Jane"}
+	grandpa := models.Contact{Firstname: "Grandpa", Deceased: true}
+	db.Create(&jane)
+	db.Create(&grandpa)
+
+	date := func(year int, month time.Month, day int) models.Date {
+		return models.Date{Time: time.Date(year, month, day, 0, 0, 0, 0, time.UTC), Valid: true}
+	}
+	db.Create(&models.CustomDate{ContactID: jane.ID, Label: "Work anniversary", Date: date(2010, time.February, 28), Recurring: true})
+	db.Create(&models.CustomDate{ContactID: jane.ID, Label: "Wedding", Date: date(2015, time.March, 7), Recurring: true, ReminderLeadDays: 7})
+	db.Create(&models.CustomDate{ContactID: jane.ID, Label: "Leap day", Date: date(2012, time.February, 29), Recurring: true})
+	db.Create(&models.CustomDate{ContactID: jane.ID, Label: "Last year", Date: date(2022, time.February, 28)})
+	db.Create(&models.CustomDate{ContactID: jane.ID, Label: "Moving", Date: date(2023, time.February, 28)})
+	db.Create(&models.CustomDate{ContactID: grandpa.ID, Label: "Wedding", Date: date(1960, time.February, 28), Recurring: true})
+
+	labels := func(leapDay string) []string {
+		dates, err := customDateContacts(db, today, leapDay)
+		assert.NoError(t, err)
+		result := []string{}
+		for _, date := range dates {
+			assert.Equal(t, "Jane", date.Contact.Firstname)
+			result = append(result, date.Label)
+		}
+		return result
+	}
+
+	// Dates which do not recur only match in their year, dates of deceased contacts never
+	assert.Equal(t, []string{"Work anniversary", "Wedding", "Leap day", "Moving"}, labels("feb28"))
+	assert.Equal(t, []string{"Work anniversary", "Wedding", "Moving"}, labels("mar1"))
+}
+
+func TestUpcomingCustomDates(t *testing.T) {
+	now := time.Date(2024, time.December, 28, 15, 0, 0, 0, time.UTC)
+	dates := []models.CustomDate{
+		{Label: "Wedding", Date: models.Date{Time: time.Date(2015, time.January, 2, 0, 0, 0, 0, time.UTC), Valid: true}, Recurring: true},
+		{Label: "Work anniversary", Date: models.Date{Time: time.Date(0, time.December, 28, 0, 0, 0, 0, time.UTC), Valid: true}, Recurring: true},
+		{Label: "Moving", Date: models.Date{Time: time.Date(2024, time.December, 30, 0, 0, 0, 0, time.UTC), Valid: true}},
+		{Label: "Retirement", Date: models.Date{Time: time.Date(2025, time.December, 27, 0, 0, 0, 0, time.UTC), Valid: true}},
+	}
+
+	upcoming := UpcomingCustomDates(dates, now, 7, "feb28")
+	assert.Len(t, upcoming, 3)
+
+	assert.Equal(t, "Work anniversary", upcoming[0].CustomDate.Label)
+	assert.Equal(t, 0, upcoming[0].DaysUntil)
+	assert.Nil(t, upcoming[0].Years)
+
+	assert.Equal(t, "Moving", upcoming[1].CustomDate.Label)
+	assert.Equal(t, "2024-12-30", upcoming[1].Date)
+	assert.Nil(t, upcoming[1].Years)
+
+	// Recurring dates continue into the next year
+	assert.Equal(t, "Wedding", upcoming[2].CustomDate.Label)
+	assert.Equal(t, "2025-01-02", upcoming[2].Date)
+	assert.Equal(t, 5, upcoming[2].DaysUntil)
+	assert.Equal(t, 10, *upcoming[2].Years)
+}
+
+func TestSendBirthdayReminders_CustomDates(t *testing.T) {
+	db := setupDB()
+
+	today := time.Now().UTC()
+	contact := models.Contact{Firstname: "Jane", Lastname: "Doe"}
+	db.Create(&contact)
+	db.Create(&models.CustomDate{
+		ContactID: contact.ID,
+		Label:     "Work anniversary",
+		Date:      models.Date{Time: time.Date(2010, today.Month(), today.Day(), 0, 0, 0, 0, time.UTC), Valid: true},
+		Recurring: true,
+	})
+
+	sender := &recordingSender{}
+	assert.NoError(t, SendBirthdayReminders(db, &config.Config{EmailTo: "me@example.com", Timezone: time.UTC}, sender))
+	assert.Equal(t, []string{"me@example.com: Work anniversary of Jane Doe today"}, sender.mails)
+}
@@ -0,0 +1,157 @@
+package services
+
+import (
+	"fmt"
+	"perema/config"
+	"perema/models"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// Supported database drivers
+const (
+	DriverSQLite   = "sqlite"
+	DriverPostgres = "postgres"
+)
+
+// OpenDatabase connects to the database selected by the driver in the configuration
+func OpenDatabase(cfg *config.Config) (*gorm.DB, error) {
+	switch cfg.DBDriver {
+	case DriverPostgres:
+		return gorm.Open(postgres.Open(cfg.DatabaseURL), &gorm.Config{})
+	case DriverSQLite:
+		return gorm.Open(sqlite.Open(cfg.DBPath), &gorm.Config{})
+	}
+	return nil, fmt.Errorf("unsupported database driver %q", cfg.DBDriver)
+}
+
+// MigrateAddresses moves the addresses stored as a single string before they were structured into the street
+// of the address and drops the old column
+func MigrateAddresses(db *gorm.DB) error {
+	migrator := db.Migrator()
+	if !migrator.HasColumn(&models.Contact{}, "address") {
+		return nil
+	}
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec(`UPDATE contacts SET address_street = address
+			WHERE address IS NOT NULL AND address <> '' AND (address_street IS NULL OR address_street = '')`).Error; err != nil {
+			return err
+		}
+		return tx.Migrator().DropColumn(&models.Contact{}, "address")
+	})
+}
+
+// BackfillReminderStatus marks the reminders completed before they had a status as done. The status column
+// was added with pending as default, completed reminders keep both fields in sync since then.
+func BackfillReminderStatus(db *gorm.DB) error {
+	return db.Model(&models.Reminder{}).
+		Where("completed = ? AND status <> ?", true, models.ReminderDone).
+		Update("status", models.ReminderDone).Error
+}
+
+// NormalizeGenders rewrites the genders stored before they were validated to the values of models.Genders.
+// Values which are not known are stored as other.
+func NormalizeGenders(db *gorm.DB) error {
+	var genders []string
+	if err := db.Unscoped().Model(&models.Contact{}).Where("gender IS NOT NULL").Distinct().Pluck("gender", &genders).Error; err != nil {
+		return err
+	}
+	for _, gender := range genders {
+		normalized, ok := models.NormalizeGender(gender)
+		if !ok {
+			normalized = models.GenderOther
+		}
+		if normalized == gender {
+			continue
+		}
+		if err := db.Unscoped().Model(&models.Contact{}).Where("gender = ?", gender).UpdateColumn("gender", normalized).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SQLDialect builds the SQL fragments which differ between SQLite and PostgreSQL.
+// JSON arrays are stored as text in both databases. Text columns have no collation, as NOCASE only exists in
+// SQLite, so names are compared and sorted with LOWER() or matched with Like.
+type SQLDialect struct {
+	Postgres bool
+}
+
+// Dialect returns the SQL dialect of the database
+func Dialect(db *gorm.DB) SQLDialect {
+	return SQLDialect{Postgres: db.Dialector.Name() == DriverPostgres}
+}
+
+// JSONEach returns a table expression with one row per element of the JSON array in the column.
+// In both databases the rows are available as json_each.value and their position as json_each.key.
+func (d SQLDialect) JSONEach(column string) string {
+	if d.Postgres {
+		return fmt.Sprintf(`jsonb_array_elements_text(CASE WHEN jsonb_typeof(%[1]s::jsonb) = 'array' THEN %[1]s::jsonb ELSE '[]'::jsonb END)
+			WITH ORDINALITY AS json_each(value, key)`, column)
+	}
+	return fmt.Sprintf("json_each(%s)", column)
+}
+
+// JSONContains returns a condition matching rows whose JSON array in the column contains the parameter
+func (d SQLDialect) JSONContains(column string) string {
+	return fmt.Sprintf("EXISTS (SELECT 1 FROM %s WHERE json_each.value = ?)", d.JSONEach(column))
+}
+
+// JSONGroupArray aggregates the expression into the text of a JSON array, which is empty if there are no rows
+func (d SQLDialect) JSONGroupArray(expression string) string {
+	if d.Postgres {
+		return fmt.Sprintf("COALESCE(jsonb_agg(%s), '[]'::jsonb)::text", expression)
+	}
+	return fmt.Sprintf("json_group_array(%s)", expression)
+}
+
+// MonthDay formats a date as MM-DD
+func (d SQLDialect) MonthDay(expression string) string {
+	if d.Postgres {
+		return fmt.Sprintf("to_char(%s, 'MM-DD')", expression)
+	}
+	return fmt.Sprintf("strftime('%%m-%%d', %s)", expression)
+}
+
+// MonthDayAfter formats the date given as parameter plus the number of days in the expression as MM-DD
+func (d SQLDialect) MonthDayAfter(days string) string {
+	if d.Postgres {
+		return fmt.Sprintf("to_char(CAST(? AS date) + %s, 'MM-DD')", days)
+	}
+	return fmt.Sprintf("strftime('%%m-%%d', ?, '+' || %s || ' days')", days)
+}
+
+// Date formats the date of a timestamp as YYYY-MM-DD
+func (d SQLDialect) Date(expression string) string {
+	if d.Postgres {
+		return fmt.Sprintf("to_char(%s, 'YYYY-MM-DD')", expression)
+	}
+	return fmt.Sprintf("date(%s)", expression)
+}
+
+// DaysSince returns the number of whole days from the timestamp in the expression until the given YYYY-MM-DD day
+func (d SQLDialect) DaysSince(day, expression string) string {
+	if d.Postgres {
+		return fmt.Sprintf("(DATE '%s' - CAST(%s AS date))", day, expression)
+	}
+	return fmt.Sprintf("CAST(julianday('%s') - julianday(date(%s)) AS INTEGER)", day, expression)
+}
+
+// Greatest returns the larger of two values
+func (d SQLDialect) Greatest(a, b string) string {
+	if d.Postgres {
+		return fmt.Sprintf("GREATEST(%s, %s)", a, b)
+	}
+	return fmt.Sprintf("MAX(%s, %s)", a, b)
+}
+
+// Like is the operator matching patterns case-insensitively, as LIKE does in SQLite
+func (d SQLDialect) Like() string {
+	if d.Postgres {
+		return "ILIKE"
+	}
+	return "LIKE"
+}
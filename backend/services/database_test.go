@@ -0,0 +1,123 @@
+package services
+
+import (
+	"context"
+	"perema/config"
+	"perema/models"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func TestOpenDatabase(t *testing.T) {
+	db, err := OpenDatabase(&config.Config{DBDriver: DriverSQLite, DBPath: ":memory:"})
+	assert.NoError(t, err)
+	assert.False(t, Dialect(db).Postgres)
+
+	_, err = OpenDatabase(&config.Config{DBDriver: "mysql"})
+	assert.Error(t, err)
+}
+
+func TestSQLDialect(t *testing.T) {
+	sqlite := SQLDialect{}
+	postgres := SQLDialect{Postgres: true}
+
+	assert.Equal(t, "json_each(contacts.circles)", sqlite.JSONEach("contacts.circles"))
+	assert.Contains(t, postgres.JSONEach("contacts.circles"), "jsonb_array_elements_text(")
+	assert.Contains(t, postgres.JSONEach("contacts.circles"), "WITH ORDINALITY AS json_each(value, key)")
+	assert.Equal(t, "EXISTS (SELECT 1 FROM json_each(notes.tags) WHERE json_each.value = ?)", sqlite.JSONContains("notes.tags"))
+
+	assert.Equal(t, "strftime('%m-%d', birthday)", sqlite.MonthDay("birthday"))
+	assert.Equal(t, "to_char(birthday, 'MM-DD')", postgres.MonthDay("birthday"))
+	assert.Equal(t, "to_char(CAST(? AS date) + reminder_lead_days, 'MM-DD')", postgres.MonthDayAfter("reminder_lead_days"))
+	assert.Equal(t, "(DATE '2024-03-01' - CAST(last AS date))", postgres.DaysSince("2024-03-01", "last"))
+	assert.Equal(t, "MAX(a, b)", sqlite.Greatest("a", "b"))
+	assert.Equal(t, "GREATEST(a, b)", postgres.Greatest("a", "b"))
+	assert.Equal(t, "ILIKE", postgres.Like())
+}
+
+func TestNormalizeGenders(t *testing.T) {
+	db := setupDB()
+	contacts := []models.Contact{{Firstname: "A", Gender: "Male"}, {Firstname: "B", Gender: "robot"}, {Firstname: "C", Gender: "female"}, {Firstname: "D"}}
+	db.Create(&contacts)
+
+	assert.NoError(t, NormalizeGenders(db))
+
+	var genders []string
+	db.Model(&models.Contact{}).Order("id").Pluck("gender", &genders)
+	assert.Equal(t, []string{"male", "other", "female", ""}, genders)
+}
+
+func TestMigrateAddresses(t *testing.T) {
+	db := setupDB()
+	db.Exec("ALTER TABLE contacts ADD COLUMN `address` text") // As created by versions with a single address field
+	db.Create(&models.Contact{Firstname: "A"})
+	db.Create(&models.Contact{Firstname: "B"})
+	db.Exec("UPDATE contacts SET address = ? WHERE firstname = ?", "Main Street 1, Berlin", "A")
+
+	assert.NoError(t, MigrateAddresses(db))
+	assert.False(t, db.Migrator().HasColumn(&models.Contact{}, "address"))
+
+	var contacts []models.Contact
+	db.Order("id").Find(&contacts)
+	assert.Equal(t, models.Address{Street: "Main Street 1, Berlin"}, contacts[0].Address)
+	assert.True(t, contacts[1].Address.IsEmpty())
+
+	// Nothing happens once the old column is gone
+	assert.NoError(t, MigrateAddresses(db))
+}
+
+func TestBackfillReminderStatus(t *testing.T) {
+	db := setupDB()
+	contact := models.Contact{Firstname: "A"}
+	db.Create(&contact)
+	// As stored before the status existed, the column default marks every reminder as pending
+	done := models.Reminder{Message: "Done", ContactID: &contact.ID, Completed: true}
+	pending := models.Reminder{Message: "Open", ContactID: &contact.ID}
+	db.Create(&done)
+	db.Create(&pending)
+	db.Exec("UPDATE reminders SET status = ?", models.ReminderPending)
+
+	assert.NoError(t, BackfillReminderStatus(db))
+
+	db.First(&done, done.ID)
+	db.First(&pending, pending.ID)
+	assert.Equal(t, models.ReminderDone, done.Status)
+	assert.Equal(t, models.ReminderPending, pending.Status)
+}
+
+// statementRecorder is a GORM logger collecting the SQL of all statements and their errors
+type statementRecorder struct {
+	logger.Interface
+	statements []string
+	errors     []error
+}
+
+func (r *statementRecorder) Trace(ctx context.Context, begin time.Time, fc func() (string, int64), err error) {
+	sql, _ := fc()
+	r.statements = append(r.statements, sql)
+	if err != nil {
+		r.errors = append(r.errors, err)
+	}
+}
+
+func TestPostgresSchema(t *testing.T) {
+	// The statements are only generated, no PostgreSQL server is needed
+	recorder := &statementRecorder{Interface: logger.Discard}
+	db, err := gorm.Open(postgres.Open("host=localhost dbname=perema"), &gorm.Config{DryRun: true, DisableAutomaticPing: true, Logger: recorder})
+	assert.NoError(t, err)
+	assert.True(t, Dialect(db).Postgres)
+
+	err = db.Migrator().CreateTable(&models.Contact{}, &models.Activity{}, &models.Note{}, models.Relationship{}, models.Reminder{}, models.User{}, models.NoteAttachment{}, models.APIKey{}, models.AuditLog{}, models.ContactMethod{}, models.ContactAttachment{}, models.Webhook{}, models.WebhookDeadLetter{}, models.CustomDate{})
+	assert.NoError(t, err)
+
+	ddl := strings.Join(recorder.statements, "\n")
+	assert.Contains(t, ddl, `CREATE TABLE "contacts"`)
+	assert.Contains(t, ddl, `"firstname" text not null`)
+	assert.NotContains(t, strings.ToUpper(ddl), "NOCASE")
+}
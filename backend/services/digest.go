@@ -0,0 +1,149 @@
+package services
+
+import (
+	"fmt"
+	"perema/config"
+	"perema/models"
+	"sort"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// DigestDay lists the birthdays and due reminders of a single day
+type DigestDay struct {
+	Date  time.Time
+	Items []string
+}
+
+// Digest is the weekly summary of a user
+type Digest struct {
+	Days    []DigestDay
+	Overdue []string // Open reminders which are overdue, taking the grace days into account
+}
+
+// SendWeeklyDigest sends each user a single mail with the birthdays and open reminders of their contacts in the
+// seven days starting today and the reminders which are overdue. No mail is sent to users with nothing to do.
+func SendWeeklyDigest(db *gorm.DB, cfg *config.Config, sender EmailSender) error {
+	today := time.Now().In(cfg.Timezone)
+	digests, err := weeklyDigests(db, cfg, today)
+	if err != nil {
+		return err
+	}
+	mailer, err := newUserMailer(db, cfg, sender)
+	if err != nil {
+		return err
+	}
+
+	userIDs := make([]uint, 0, len(digests))
+	for userID := range digests {
+		userIDs = append(userIDs, userID)
+	}
+	sort.Slice(userIDs, func(i, j int) bool {
+		return userIDs[i] < userIDs[j]
+	})
+	for _, userID := range userIDs {
+		subject, body := digestMail(today, digests[userID])
+		if err := mailer.Send(userID, subject, body); err != nil {
+			return fmt.Errorf("failed to send weekly digest: %w", err)
+		}
+	}
+	return nil
+}
+
+// weeklyDigests collects the birthdays and open reminders of the seven days starting at the given day, grouped by
+// the user owning the contact and by day, and the overdue reminders of each user. Reminders due before today
+// which are still within the grace days are neither. Users with nothing to do are left out.
+func weeklyDigests(db *gorm.DB, cfg *config.Config, today time.Time) (map[uint]Digest, error) {
+	weekStart := time.Date(today.Year(), today.Month(), today.Day(), 0, 0, 0, 0, today.Location())
+	weekEnd := weekStart.AddDate(0, 0, 7)
+
+	itemsByUser := map[uint]map[string][]string{}
+	add := func(userID uint, day time.Time, item string) {
+		if itemsByUser[userID] == nil {
+			itemsByUser[userID] = map[string][]string{}
+		}
+		key := day.Format(models.DateFormat)
+		itemsByUser[userID][key] = append(itemsByUser[userID][key], item)
+	}
+
+	var contacts []models.Contact
+	if err := db.Where("birthday IS NOT NULL").Find(&contacts).Error; err != nil {
+		return nil, fmt.Errorf("failed to query contacts: %w", err)
+	}
+	for _, birthday := range UpcomingBirthdays(contacts, weekStart, 6, cfg.LeapDayBirthdays) {
+		item := "Birthday of " + strings.TrimSpace(birthday.Contact.Firstname+" "+birthday.Contact.Lastname)
+		if birthday.TurningAge != nil {
+			item += fmt.Sprintf(" (turns %d)", *birthday.TurningAge)
+		}
+		add(birthday.Contact.UserID, weekStart.AddDate(0, 0, birthday.DaysUntil), item)
+	}
+
+	var reminders []models.Reminder
+	if err := db.InnerJoins("Contact").
+		Where("reminders.remind_at >= ? AND reminders.remind_at < ?", weekStart.UTC(), weekEnd.UTC()).
+		Where("reminders.completed = ?", false).
+		Order("reminders.remind_at ASC").
+		Find(&reminders).Error; err != nil {
+		return nil, fmt.Errorf("failed to query reminders: %w", err)
+	}
+	for _, reminder := range reminders {
+		add(reminder.Contact.UserID, reminder.RemindAt.In(today.Location()), reminderItem(reminder))
+	}
+
+	digests := map[uint]Digest{}
+	for userID, itemsByDay := range itemsByUser {
+		days := []DigestDay{}
+		for key, items := range itemsByDay {
+			date, _ := time.ParseInLocation(models.DateFormat, key, today.Location())
+			days = append(days, DigestDay{Date: date, Items: items})
+		}
+		sort.Slice(days, func(i, j int) bool {
+			return days[i].Date.Before(days[j].Date)
+		})
+		digests[userID] = Digest{Days: days}
+	}
+
+	var overdue []models.Reminder
+	if err := db.InnerJoins("Contact").
+		Where("reminders.remind_at < ?", models.OverdueCutoff(today, cfg.OverdueGraceDays).UTC()).
+		Where("reminders.completed = ?", false).
+		Order("reminders.remind_at ASC").
+		Find(&overdue).Error; err != nil {
+		return nil, fmt.Errorf("failed to query overdue reminders: %w", err)
+	}
+	for _, reminder := range overdue {
+		digest := digests[reminder.Contact.UserID]
+		digest.Overdue = append(digest.Overdue, reminderItem(reminder)+", due "+reminder.RemindAt.In(today.Location()).Format("2 January 2006"))
+		digests[reminder.Contact.UserID] = digest
+	}
+	return digests, nil
+}
+
+func reminderItem(reminder models.Reminder) string {
+	return fmt.Sprintf("%s: %s", strings.TrimSpace(reminder.Contact.Firstname+" "+reminder.Contact.Lastname), reminder.Message)
+}
+
+func digestMail(weekStart time.Time, digest Digest) (string, string) {
+	subject := "Your week from " + weekStart.Format("Monday, 2 January")
+
+	var body strings.Builder
+	body.WriteString("Hi,\n")
+	if len(digest.Days) > 0 {
+		body.WriteString("\nthis is what is coming up this week:\n")
+	}
+	for _, day := range digest.Days {
+		body.WriteString("\n" + day.Date.Format("Monday, 2 January") + "\n")
+		for _, item := range day.Items {
+			body.WriteString("- " + item + "\n")
+		}
+	}
+	if len(digest.Overdue) > 0 {
+		body.WriteString("\nOverdue reminders\n")
+		for _, item := range digest.Overdue {
+			body.WriteString("- " + item + "\n")
+		}
+	}
+	return subject, body.String()
+}
@@ -0,0 +1,135 @@
+package services
+
+import (
+	"perema/config"
+	"perema/models"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWeeklyDigest(t *testing.T) {
+	db := setupDB()
+
+	monday := time.Date(2024, time.December, 30, 8, 0, 0, 0, time.UTC)
+
+	jane := models.Contact{Firstname: "Jane", Lastname: "Doe", Birthday: &models.Date{Time: time.Date(1990, time.January, 2, 0, 0, 0, 0, time.UTC), Valid: true}}
+	john := models.Contact{Firstname: "John", Birthday: &models.Date{Time: time.Date(1, time.December, 30, 0, 0, 0, 0, time.UTC), Valid: true}}
+	late := models.Contact{Firstname: "Late", Birthday: &models.Date{Time: time.Date(1990, time.January, 6, 0, 0, 0, 0, time.UTC), Valid: true}}
+	db.Create(&jane)
+	db.Create(&john)
+	db.Create(&late)
+
+	db.Create(&models.Reminder{Message: "Send gift", RemindAt: monday.AddDate(0, 0, 3), Recurrence: "once", ContactID: &jane.ID})
+	db.Create(&models.Reminder{Message: "Done already", RemindAt: monday.AddDate(0, 0, 3), Recurrence: "once", ContactID: &jane.ID, Completed: true})
+	db.Create(&models.Reminder{Message: "Next week", RemindAt: monday.AddDate(0, 0, 7), Recurrence: "once", ContactID: &john.ID})
+
+	digests, err := weeklyDigests(db, &config.Config{LeapDayBirthdays: "feb28"}, monday)
+	assert.NoError(t, err)
+	assert.Len(t, digests, 1)
+	days := digests[0].Days
+	assert.Len(t, days, 2)
+
+	assert.Equal(t, "2024-12-30", days[0].Date.Format(models.DateFormat))
+	assert.Equal(t, []string{"Birthday of John"}, days[0].Items)
+
+	assert.Equal(t, "2025-01-02", days[1].Date.Format(models.DateFormat))
+	assert.Equal(t, []string{"Birthday of Jane Doe (turns 35)", "Jane Doe: Send gift"}, days[1].Items)
+
+	subject, body := digestMail(monday, digests[0])
+	assert.Equal(t, "Your week from Monday, 30 December", subject)
+	assert.Equal(t, "Hi,\n\nthis is what is coming up this week:\n\n"+
+		"Monday, 30 December\n- Birthday of John\n\n"+
+		"Thursday, 2 January\n- Birthday of Jane Doe (turns 35)\n- Jane Doe: Send gift\n", body)
+}
+
+func TestWeeklyDigestOverdueReminders(t *testing.T) {
+	db := setupDB()
+
+	monday := time.Date(2024, time.December, 30, 8, 0, 0, 0, time.UTC)
+	jane := models.Contact{Firstname: "Jane"}
+	db.Create(&jane)
+
+	// With two grace days reminders due before Dec 28 are overdue
+	db.Create(&models.Reminder{Message: "Overdue", RemindAt: time.Date(2024, time.December, 27, 23, 0, 0, 0, time.UTC), Recurrence: "once", ContactID: &jane.ID})
+	db.Create(&models.Reminder{Message: "In grace", RemindAt: time.Date(2024, time.December, 28, 0, 0, 0, 0, time.UTC), Recurrence: "once", ContactID: &jane.ID})
+	db.Create(&models.Reminder{Message: "Done", RemindAt: time.Date(2024, time.December, 1, 0, 0, 0, 0, time.UTC), Recurrence: "once", ContactID: &jane.ID, Completed: true})
+
+	digests, err := weeklyDigests(db, &config.Config{OverdueGraceDays: 2}, monday)
+	assert.NoError(t, err)
+	assert.Empty(t, digests[0].Days)
+	assert.Equal(t, []string{"Jane: Overdue, due 27 December 2024"}, digests[0].Overdue)
+
+	_, body := digestMail(monday, digests[0])
+	assert.Equal(t, "Hi,\n\nOverdue reminders\n- Jane: Overdue, due 27 December 2024\n", body)
+
+	// Without grace days every reminder due before today is overdue
+	digests, err = weeklyDigests(db, &config.Config{}, monday)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"Jane: Overdue, due 27 December 2024", "Jane: In grace, due 28 December 2024"}, digests[0].Overdue)
+
+	// Snoozing moves the due date, so the grace days start again from the snoozed date
+	db.Model(&models.Reminder{}).Where("message = ?", "Overdue").Update("remind_at", time.Date(2024, time.December, 29, 0, 0, 0, 0, time.UTC))
+	digests, err = weeklyDigests(db, &config.Config{OverdueGraceDays: 2}, monday)
+	assert.NoError(t, err)
+	assert.Empty(t, digests)
+}
+
+func TestWeeklyDigestLeapDayBirthdays(t *testing.T) {
+	db := setupDB()
+	db.Create(&models.Contact{Firstname: "Leap", Birthday: &models.Date{Time: time.Date(2000, time.February, 29, 0, 0, 0, 0, time.UTC), Valid: true}})
+
+	// The week ends on Feb 28 and starts on Mar 1, so only one of the two settings lists the birthday
+	for _, tc := range []struct {
+		leapDay string
+		start   time.Time
+		day     string
+	}{
+		{"feb28", time.Date(2025, time.February, 22, 8, 0, 0, 0, time.UTC), "2025-02-28"},
+		{"mar1", time.Date(2025, time.February, 22, 8, 0, 0, 0, time.UTC), ""},
+		{"feb28", time.Date(2025, time.March, 1, 8, 0, 0, 0, time.UTC), ""},
+		{"mar1", time.Date(2025, time.March, 1, 8, 0, 0, 0, time.UTC), "2025-03-01"},
+	} {
+		digests, err := weeklyDigests(db, &config.Config{LeapDayBirthdays: tc.leapDay}, tc.start)
+		assert.NoError(t, err)
+		if tc.day == "" {
+			assert.Empty(t, digests, tc.leapDay)
+			continue
+		}
+		assert.Len(t, digests[0].Days, 1, tc.leapDay)
+		assert.Equal(t, tc.day, digests[0].Days[0].Date.Format(models.DateFormat), tc.leapDay)
+	}
+}
+
+func TestSendWeeklyDigestPerUser(t *testing.T) {
+	db := setupDB()
+
+	today := time.Now().UTC()
+	birthday := &models.Date{Time: time.Date(1990, today.Month(), today.Day(), 0, 0, 0, 0, time.UTC), Valid: true}
+	jane := models.User{Username: "jane", Email: "jane@example.com"}
+	john := models.User{Username: "john", Email: "john@example.com"}
+	db.Create(&jane)
+	db.Create(&john)
+	db.Create(&models.Contact{Firstname: "Anna", Birthday: birthday, UserID: jane.ID})
+	db.Create(&models.Contact{Firstname: "Ben", Birthday: birthday, UserID: john.ID})
+	db.Create(&models.Contact{Firstname: "Carl", Birthday: birthday})
+
+	// Each user only gets the digest of their own contacts, contacts without a user go to EMAIL_TO
+	sender := &recordingSender{}
+	assert.NoError(t, SendWeeklyDigest(db, &config.Config{EmailTo: "me@example.com", Timezone: time.UTC}, sender))
+	assert.Len(t, sender.mails, 3)
+	assert.Equal(t, "me@example.com: ", sender.mails[0][:len("me@example.com: ")])
+	assert.Contains(t, sender.bodies[0], "Carl")
+	assert.Equal(t, "jane@example.com: ", sender.mails[1][:len("jane@example.com: ")])
+	assert.Contains(t, sender.bodies[1], "Anna")
+	assert.NotContains(t, sender.bodies[1], "Ben")
+	assert.Equal(t, "john@example.com: ", sender.mails[2][:len("john@example.com: ")])
+	assert.Contains(t, sender.bodies[2], "Ben")
+	assert.NotContains(t, sender.bodies[2], "Anna")
+
+	// Without EMAIL_TO the digest for contacts without a user is skipped
+	sender = &recordingSender{}
+	assert.NoError(t, SendWeeklyDigest(db, &config.Config{Timezone: time.UTC}, sender))
+	assert.Len(t, sender.mails, 2)
+}
@@ -0,0 +1,82 @@
+package services
+
+import (
+	"perema/models"
+	"slices"
+	"strings"
+	"unicode"
+)
+
+// Reasons why contacts are considered duplicates
+const (
+	DuplicateName  = "name"
+	DuplicateEmail = "email"
+	DuplicatePhone = "phone"
+)
+
+// DuplicateCluster is a group of contacts sharing the same normalized name, email or phone number
+type DuplicateCluster struct {
+	Reason   string           `json:"reason"`
+	Value    string           `json:"value"` // Normalized value shared by the contacts
+	Contacts []models.Contact `json:"contacts"`
+}
+
+// FindDuplicateContacts groups the contacts by normalized name, email and phone number and returns
+// all groups with at least two contacts. A contact can be part of several clusters.
+func FindDuplicateContacts(contacts []models.Contact) []DuplicateCluster {
+	clusters := []DuplicateCluster{}
+	for _, reason := range []string{DuplicateName, DuplicateEmail, DuplicatePhone} {
+		groups := map[string][]models.Contact{}
+		var keys []string
+		for _, contact := range contacts {
+			key := duplicateKey(contact, reason)
+			if key == "" {
+				continue
+			}
+			if _, exists := groups[key]; !exists {
+				keys = append(keys, key)
+			}
+			groups[key] = append(groups[key], contact)
+		}
+
+		slices.Sort(keys)
+		for _, key := range keys {
+			if len(groups[key]) > 1 {
+				clusters = append(clusters, DuplicateCluster{Reason: reason, Value: key, Contacts: groups[key]})
+			}
+		}
+	}
+	return clusters
+}
+
+func duplicateKey(contact models.Contact, reason string) string {
+	switch reason {
+	case DuplicateName:
+		return normalizeName(contact.Firstname + contact.Lastname)
+	case DuplicateEmail:
+		return strings.ToLower(strings.TrimSpace(contact.Email))
+	case DuplicatePhone:
+		return normalizePhone(contact.Phone)
+	}
+	return ""
+}
+
+// normalizeName lowercases the name and strips whitespace and punctuation
+func normalizeName(name string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsSpace(r) || unicode.IsPunct(r) {
+			return -1
+		}
+		return unicode.ToLower(r)
+	}, name)
+}
+
+// normalizePhone keeps only the digits of a phone number
+func normalizePhone(phone string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= '0' && r <= '9' {
+			return r
+		}
+		return -1
+	}, phone)
+}
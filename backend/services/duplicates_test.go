@@ -0,0 +1,39 @@
+package services
+
+import (
+	"perema/models"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFindDuplicateContacts(t *testing.T) {
+	contacts := []models.Contact{
+		{Firstname: "Anna-Lena", Lastname: "Meyer", Email: "anna@example.com"},
+		{Firstname: "anna lena", Lastname: "meyer ", Phone: "+49 (30) 123-456"},
+		{Firstname: "Bob", Email: " ANNA@example.com", Phone: "+4930123456"},
+		{Firstname: "Carol", Phone: "0171 555"},
+		{Firstname: "Dave"},
+		{Firstname: "Eve"},
+	}
+
+	clusters := FindDuplicateContacts(contacts)
+
+	assert.Len(t, clusters, 3)
+
+	assert.Equal(t, DuplicateName, clusters[0].Reason)
+	assert.Equal(t, "annalenameyer", clusters[0].Value)
+	assert.Len(t, clusters[0].Contacts, 2)
+
+	assert.Equal(t, DuplicateEmail, clusters[1].Reason)
+	assert.Equal(t, "anna@example.com", clusters[1].Value)
+	assert.Equal(t, "Anna-Lena", clusters[1].Contacts[0].Firstname)
+	assert.Equal(t, "Bob", clusters[1].Contacts[1].Firstname)
+
+	assert.Equal(t, DuplicatePhone, clusters[2].Reason)
+	assert.Equal(t, "4930123456", clusters[2].Value)
+	assert.Len(t, clusters[2].Contacts, 2)
+
+	// Contacts without email or phone are not grouped by these fields
+	assert.Empty(t, FindDuplicateContacts([]models.Contact{{Firstname: "Dave"}, {Firstname: "Eve"}}))
+}
@@ -0,0 +1,142 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"net/smtp"
+	"perema/config"
+	"perema/models"
+	"strings"
+
+	"github.com/sendgrid/sendgrid-go"
+	"github.com/sendgrid/sendgrid-go/helpers/mail"
+	"gorm.io/gorm"
+)
+
+// Supported email providers
+const (
+	EmailProviderSMTP     = "smtp"
+	EmailProviderSendgrid = "sendgrid"
+)
+
+// EmailSender delivers plain text mails
+type EmailSender interface {
+	Send(to, subject, body string) error
+}
+
+// NewEmailSender returns the sender of the configured email provider.
+// It returns an error naming the missing settings if the provider is not configured completely.
+func NewEmailSender(cfg *config.Config) (EmailSender, error) {
+	switch cfg.EmailProvider {
+	case EmailProviderSendgrid:
+		if cfg.SendgridAPIKey == "" {
+			return nil, errors.New("SENDGRID_API_KEY is not set")
+		}
+		// Mails were sent with Sendgrid templates before, which do not need a sender address in the configuration
+		if cfg.EmailFrom == "" {
+			return nil, errors.New("EMAIL_FROM is not set, Sendgrid templates are no longer used and the sender has to be a verified sender of your Sendgrid account")
+		}
+		return SendgridSender{APIKey: cfg.SendgridAPIKey, From: cfg.EmailFrom}, nil
+	case EmailProviderSMTP:
+		if cfg.SMTPHost == "" || cfg.EmailFrom == "" {
+			return nil, errors.New("SMTP_HOST and EMAIL_FROM have to be set")
+		}
+		return SMTPSender{Host: cfg.SMTPHost, Port: cfg.SMTPPort, Username: cfg.SMTPUsername, Password: cfg.SMTPPassword, From: cfg.EmailFrom}, nil
+	}
+	return nil, fmt.Errorf("unsupported email provider %q", cfg.EmailProvider)
+}
+
+// userMailer sends the mails about contacts to the user owning them, so that users of a shared instance only
+// get mails about their own contacts. EMAIL_TO is the fallback for users without an email address, like the
+// default user of single user installs. Mails without any recipient are skipped.
+type userMailer struct {
+	sender     EmailSender
+	recipients map[uint]string
+	fallback   string
+}
+
+// newUserMailer looks up the email addresses of all users
+func newUserMailer(db *gorm.DB, cfg *config.Config, sender EmailSender) (*userMailer, error) {
+	var users []models.User
+	if err := db.Select("ID", "Email").Find(&users).Error; err != nil {
+		return nil, fmt.Errorf("failed to query users: %w", err)
+	}
+
+	recipients := map[uint]string{}
+	for _, user := range users {
+		if user.Email != "" {
+			recipients[user.ID] = user.Email
+		}
+	}
+	return &userMailer{sender: sender, recipients: recipients, fallback: cfg.EmailTo}, nil
+}
+
+// recipient returns the address mails for the user are sent to, empty if there is none
+func (m *userMailer) recipient(userID uint) string {
+	if email, ok := m.recipients[userID]; ok {
+		return email
+	}
+	return m.fallback
+}
+
+// Send mails the user, nothing is sent if the user has no recipient
+func (m *userMailer) Send(userID uint, subject, body string) error {
+	to := m.recipient(userID)
+	if to == "" {
+		return nil
+	}
+	return m.sender.Send(to, subject, body)
+}
+
+// SMTPSender sends mails through an SMTP server. Authentication is only used if a username is set.
+type SMTPSender struct {
+	Host     string
+	Port     string
+	Username string
+	Password string
+	From     string
+}
+
+func (s SMTPSender) Send(to, subject, body string) error {
+	var auth smtp.Auth
+	if s.Username != "" {
+		auth = smtp.PlainAuth("", s.Username, s.Password, s.Host)
+	}
+	return smtp.SendMail(s.Host+":"+s.Port, auth, s.From, []string{to}, smtpMessage(s.From, to, subject, body))
+}
+
+// smtpMessage builds a plain text message with the required headers
+func smtpMessage(from, to, subject, body string) []byte {
+	// Line breaks in header values would allow injecting further headers
+	headerValue := strings.NewReplacer("\r", "", "\n", " ")
+
+	var message strings.Builder
+	message.WriteString("From: " + headerValue.Replace(from) + "\r\n")
+	message.WriteString("To: " + headerValue.Replace(to) + "\r\n")
+	message.WriteString("Subject: " + headerValue.Replace(subject) + "\r\n")
+	message.WriteString("MIME-Version: 1.0\r\n")
+	message.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	message.WriteString("\r\n")
+	message.WriteString(strings.ReplaceAll(strings.ReplaceAll(body, "\r\n", "\n"), "\n", "\r\n"))
+	return []byte(message.String())
+}
+
+// We are using Twillio Sendgrid to send e-mails. The free tier allows for up to 100 mails per day.
+type SendgridSender struct {
+	APIKey string
+	From   string
+}
+
+func (s SendgridSender) Send(to, subject, body string) error {
+	message := mail.NewSingleEmail(mail.NewEmail("", s.From), subject, mail.NewEmail("", to), body, "")
+
+	client := sendgrid.NewSendClient(s.APIKey)
+	response, err := client.Send(message)
+	if err != nil {
+		return err
+	}
+	if response.StatusCode >= 300 {
+		return fmt.Errorf("sendgrid responded with status %d: %s", response.StatusCode, response.Body)
+	}
+	return nil
+}
@@ -0,0 +1,46 @@
+package services
+
+import (
+	"perema/config"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewEmailSender(t *testing.T) {
+	tests := []struct {
+		name     string
+		cfg      config.Config
+		expected EmailSender
+	}{
+		{"smtp", config.Config{EmailProvider: "smtp", SMTPHost: "mail.example.com", SMTPPort: "25", EmailFrom: "perema@example.com"},
+			SMTPSender{Host: "mail.example.com", Port: "25", From: "perema@example.com"}},
+		{"smtp without host", config.Config{EmailProvider: "smtp", EmailFrom: "perema@example.com"}, nil},
+		{"sendgrid", config.Config{EmailProvider: "sendgrid", SendgridAPIKey: "key", EmailFrom: "perema@example.com"},
+			SendgridSender{APIKey: "key", From: "perema@example.com"}},
+		{"sendgrid without key", config.Config{EmailProvider: "sendgrid", EmailFrom: "perema@example.com"}, nil},
+		{"unknown provider", config.Config{EmailProvider: "pigeon"}, nil},
+	}
+
+	for _, test := range tests {
+		sender, err := NewEmailSender(&test.cfg)
+		assert.Equal(t, test.expected, sender, test.name)
+		assert.Equal(t, test.expected == nil, err != nil, test.name)
+	}
+
+	// Sendgrid installations from before the sender became configurable are told what is missing
+	_, err := NewEmailSender(&config.Config{EmailProvider: "sendgrid", SendgridAPIKey: "key"})
+	assert.ErrorContains(t, err, "EMAIL_FROM")
+}
+
+func TestSMTPMessage(t *testing.T) {
+	message := smtpMessage("perema@example.com", "me@example.com", "Birthday\r\nBcc: other@example.com", "Hi,\n\ncongratulate!")
+
+	assert.Equal(t, "From: perema@example.com\r\n"+
+		"To: me@example.com\r\n"+
+		"Subject: Birthday Bcc: other@example.com\r\n"+
+		"MIME-Version: 1.0\r\n"+
+		"Content-Type: text/plain; charset=UTF-8\r\n"+
+		"\r\n"+
+		"Hi,\r\n\r\ncongratulate!", string(message))
+}
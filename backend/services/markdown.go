@@ -0,0 +1,38 @@
+package services
+
+import (
+	"bytes"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/extension"
+	"github.com/yuin/goldmark/renderer/html"
+)
+
+// HTML within the Markdown is passed through unchecked, so notes written as HTML keep their formatting.
+// This is only safe because the rendered output is always sanitized with the safe policy afterwards.
+var markdown = goldmark.New(
+	goldmark.WithExtensions(extension.GFM),
+	goldmark.WithRendererOptions(html.WithUnsafe()),
+)
+
+// Without the unsafe option goldmark omits HTML within the Markdown, used in strict mode
+var strictMarkdown = goldmark.New(
+	goldmark.WithExtensions(extension.GFM),
+)
+
+// RenderMarkdown converts Markdown to HTML which is safe to display. Notes are sanitized with SanitizeHTML when
+// saved, which leaves Markdown without HTML unchanged, but older notes may not be. In strict mode HTML within the
+// Markdown is dropped, in any mode the result is sanitized with the safe policy, which removes scripts, event
+// handlers and javascript: URLs.
+func RenderMarkdown(source, mode string) (string, error) {
+	renderer := markdown
+	if mode == SanitizeStrict {
+		renderer = strictMarkdown
+	}
+
+	var rendered bytes.Buffer
+	if err := renderer.Convert([]byte(source), &rendered); err != nil {
+		return "", err
+	}
+	return safePolicy.Sanitize(rendered.String()), nil
+}
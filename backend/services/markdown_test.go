@@ -0,0 +1,52 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRenderMarkdown(t *testing.T) {
+	rendered, err := RenderMarkdown("# Gift ideas\n\n- **Books** by [her favorite author](https://example.com)\n- ~~Socks~~\n", SanitizeSafe)
+	assert.NoError(t, err)
+	assert.Contains(t, rendered, "<h1>Gift ideas</h1>")
+	assert.Contains(t, rendered, "<li><strong>Books</strong> by <a href=\"https://example.com\" rel=\"nofollow\">her favorite author</a></li>")
+	assert.Contains(t, rendered, "<del>Socks</del>")
+
+	// Scripts, event handlers and javascript: URLs are removed, in Markdown as well as in HTML
+	for _, mode := range []string{SanitizeSafe, SanitizeStrict} {
+		for _, source := range []string{
+			"<script>alert(1)</script>",
+			"[click](javascript:alert(1))",
+			"<a href=\"javascript:alert(1)\">click</a>",
+			"<img src=\"x.png\" onerror=\"alert(1)\">",
+			"<p onclick=\"alert(1)\">text</p>",
+		} {
+			rendered, err := RenderMarkdown(source, mode)
+			assert.NoError(t, err)
+			assert.NotContains(t, rendered, "alert", source)
+		}
+	}
+
+	// Notes written as HTML keep their formatting, unless HTML is stripped in strict mode
+	rendered, err = RenderMarkdown("<p>Met at <em>the lake</em></p>", SanitizeSafe)
+	assert.NoError(t, err)
+	assert.Equal(t, "<p>Met at <em>the lake</em></p>", rendered)
+	rendered, err = RenderMarkdown("Met at <em>the lake</em>", SanitizeStrict)
+	assert.NoError(t, err)
+	assert.Equal(t, "<p>Met at the lake</p>\n", rendered)
+}
+
+func TestRenderMarkdownSyntaxUsingHTMLCharacters(t *testing.T) {
+	rendered, err := RenderMarkdown("> Quote of the day", SanitizeSafe)
+	assert.NoError(t, err)
+	assert.Equal(t, "<blockquote>\n<p>Quote of the day</p>\n</blockquote>\n", rendered)
+
+	rendered, err = RenderMarkdown("See <https://example.com>", SanitizeSafe)
+	assert.NoError(t, err)
+	assert.Equal(t, "<p>See <a href=\"https://example.com\" rel=\"nofollow\">https://example.com</a></p>\n", rendered)
+
+	rendered, err = RenderMarkdown("Check `a < b && c`", SanitizeSafe)
+	assert.NoError(t, err)
+	assert.Equal(t, "<p>Check <code>a &lt; b &amp;&amp; c</code></p>\n", rendered)
+}
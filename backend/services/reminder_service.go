@@ -2,28 +2,31 @@ package services
 
 import (
 	"fmt"
-	"os"
+	"perema/config"
 	"perema/models"
+	"strings"
 	"time"
 
-	"github.com/sendgrid/sendgrid-go"
-	"github.com/sendgrid/sendgrid-go/helpers/mail"
 	"gorm.io/gorm"
 )
 
-func SendBirthdayReminders(db *gorm.DB) error {
-	var contacts []models.Contact
-	if err := db.Where("birthday = ?", time.Now().Format("2006-01-02")).Find(&contacts).Error; err != nil {
+func SendBirthdayReminders(db *gorm.DB, cfg *config.Config, sender EmailSender) error {
+	today := time.Now().In(cfg.Timezone)
+	contacts, err := birthdayContacts(db, today, cfg.LeapDayBirthdays)
+	if err != nil {
 		return fmt.Errorf("failed to query contacts: %w", err)
 	}
+	mailer, err := newUserMailer(db, cfg, sender)
+	if err != nil {
+		return err
+	}
 
 	for _, contact := range contacts {
-		age := "unknown age"
-		zeroTime := time.Time{}
+		when := birthdayDistance(contact.ReminderLeadDays)
 
-		contactBirthday, validBirthday := contact.Birthday.ToTime()
-		if validBirthday && !contactBirthday.Equal(zeroTime) {
-			age = fmt.Sprintf("%d years old", time.Now().Year()-contact.Birthday.Time.Year())
+		age := "unknown age"
+		if turningAge, known := contact.AgeAtNextBirthday(today, cfg.LeapDayBirthdays); known {
+			age = fmt.Sprintf("%d years old", turningAge)
 		}
 
 		nickname := contact.Nickname
@@ -31,37 +34,187 @@ func SendBirthdayReminders(db *gorm.DB) error {
 			nickname = contact.Firstname
 		}
 
-		if err := sendBirthdayMail(nickname, contact.Firstname+" "+contact.Lastname, age); err != nil {
+		subject, body := birthdayMail(nickname, contact.Firstname+" "+contact.Lastname, age, when)
+		if err := mailer.Send(contact.UserID, subject, body); err != nil {
 			return fmt.Errorf("failed to send email for %s: %w", contact.Firstname, err)
 		}
 	}
-	return nil
+	if err := sendCustomDateReminders(db, cfg, mailer, today); err != nil {
+		return err
+	}
+
+	// Remembrance mails for deceased contacts are optional
+	if !cfg.RemembranceMails {
+		return nil
+	}
+	return sendRemembranceReminders(db, cfg, mailer, today)
 }
 
-// We are using Twillio Sendgrid to send e-mails. The free tier allows for up to 100 mails per day.
-func sendBirthdayMail(birthday_person_nick, birthday_person, birthday_age string) error {
-	toEmail := mail.NewEmail("", os.Getenv("SENDGRID_TO_EMAIL"))
-	message := mail.NewV3Mail()
-	message.SetTemplateID(os.Getenv("SENDGRID_BIRTHDAY_TEMPLATE_ID"))
+// birthdayContacts returns all living contacts whose birthday (month and day) is their reminder lead days
+// after the given day. Contacts without lead days are returned on their birthday. Birthdays on Feb 29 are
+// celebrated on the day given by leapDay in years without Feb 29.
+func birthdayContacts(db *gorm.DB, day time.Time, leapDay string) ([]models.Contact, error) {
+	var contacts []models.Contact
+	dialect := Dialect(db)
+	if err := db.Where(dialect.MonthDay("birthday")+" = "+dialect.MonthDayAfter("reminder_lead_days"), day.Format(models.DateFormat)).
+		Where("deceased = ?", false).
+		Find(&contacts).Error; err != nil {
+		return nil, err
+	}
 
-	personalization := mail.NewPersonalization()
-	personalization.AddTos(toEmail)
+	// The lead days differ per contact, so whether their birthday is moved is decided for each of them
+	var leapDayContacts []models.Contact
+	if err := db.Where(dialect.MonthDay("birthday")+" = ?", "02-29").
+		Where("deceased = ?", false).
+		Find(&leapDayContacts).Error; err != nil {
+		return nil, err
+	}
+	for _, contact := range leapDayContacts {
+		if isLeapDaySubstitute(day.AddDate(0, 0, contact.ReminderLeadDays), leapDay) {
+			contacts = append(contacts, contact)
+		}
+	}
+	return contacts, nil
+}
+
+// isLeapDaySubstitute reports whether Feb 29 is celebrated on the day, which is Feb 28 or, with leapDay set to
+// "mar1", Mar 1 of years without Feb 29
+func isLeapDaySubstitute(day time.Time, leapDay string) bool {
+	if time.Date(day.Year(), time.February, 29, 0, 0, 0, 0, time.UTC).Day() == 29 {
+		return false
+	}
+	if leapDay == "mar1" {
+		return day.Month() == time.March && day.Day() == 1
+	}
+	return day.Month() == time.February && day.Day() == 28
+}
 
-	personalization.SetDynamicTemplateData("birthday_person_nick", birthday_person_nick)
-	personalization.SetDynamicTemplateData("birthday_person", birthday_person)
-	personalization.SetDynamicTemplateData("birthday_age", birthday_age)
+// sameMonthDay reports whether the date falls on the month and day of the given day, dates on Feb 29 also
+// match their substitute in years without Feb 29
+func sameMonthDay(date, day time.Time, leapDay string) bool {
+	if date.Month() == time.February && date.Day() == 29 && isLeapDaySubstitute(day, leapDay) {
+		return true
+	}
+	return date.Format("01-02") == day.Format("01-02")
+}
 
-	message.AddPersonalizations(personalization)
+// remembranceContacts returns all deceased contacts whose birthday or death anniversary is on the given day.
+// Dates on Feb 29 are remembered on the day given by leapDay in years without Feb 29.
+func remembranceContacts(db *gorm.DB, day time.Time, leapDay string) ([]models.Contact, error) {
+	var contacts []models.Contact
+	monthDays := []string{day.Format("01-02")}
+	if isLeapDaySubstitute(day, leapDay) {
+		monthDays = append(monthDays, "02-29")
+	}
+	dialect := Dialect(db)
+	err := db.Where("deceased = ?", true).
+		Where(dialect.MonthDay("birthday")+" IN ? OR "+dialect.MonthDay("deceased_date")+" IN ?", monthDays, monthDays).
+		Find(&contacts).Error
+	return contacts, err
+}
 
-	client := sendgrid.NewSendClient(os.Getenv("SENDGRID_API_KEY"))
-	response, err := client.Send(message)
+// birthdayDistance describes when the birthday is, e.g. "today" or "in 3 days"
+func birthdayDistance(days int) string {
+	switch days {
+	case 0:
+		return "today"
+	case 1:
+		return "in 1 day"
+	}
+	return fmt.Sprintf("in %d days", days)
+}
+
+func sendRemembranceReminders(db *gorm.DB, cfg *config.Config, mailer *userMailer, today time.Time) error {
+	contacts, err := remembranceContacts(db, today, cfg.LeapDayBirthdays)
+	if err != nil {
+		return fmt.Errorf("failed to query deceased contacts: %w", err)
+	}
+
+	for _, contact := range contacts {
+		occasion := "birthday"
+		if contact.DeceasedDate != nil {
+			if deceasedDate, valid := contact.DeceasedDate.ToTime(); valid && sameMonthDay(deceasedDate, today, cfg.LeapDayBirthdays) {
+				occasion = "anniversary of death"
+			}
+		}
+
+		subject, body := remembranceMail(contact.Firstname+" "+contact.Lastname, occasion)
+		if err := mailer.Send(contact.UserID, subject, body); err != nil {
+			return fmt.Errorf("failed to send remembrance email for %s: %w", contact.Firstname, err)
+		}
+	}
+	return nil
+}
+
+// SendDueReminders mails every open reminder flagged to be sent by mail that is due today or within the
+// configured lead days to the owner of its contact. Sent reminders are marked by their last sent time and recurring reminders which
+// do not recur from their completion are moved to their next due date.
+func SendDueReminders(db *gorm.DB, cfg *config.Config, sender EmailSender) error {
+	now := time.Now().In(cfg.Timezone)
+	reminders, err := dueReminders(db, now, cfg.ReminderLeadDays)
+	if err != nil {
+		return fmt.Errorf("failed to query reminders: %w", err)
+	}
+	mailer, err := newUserMailer(db, cfg, sender)
 	if err != nil {
 		return err
-	} else {
-		fmt.Println(response.StatusCode)
-		fmt.Println(response.Body)
-		fmt.Println(response.Headers)
 	}
 
+	for _, reminder := range reminders {
+		subject, body := reminderMail(strings.TrimSpace(reminder.Contact.Firstname+" "+reminder.Contact.Lastname), reminder.Message, reminder.RemindAt.In(cfg.Timezone))
+		if err := mailer.Send(reminder.Contact.UserID, subject, body); err != nil {
+			return fmt.Errorf("failed to send reminder %d: %w", reminder.ID, err)
+		}
+
+		updates := map[string]any{"last_sent": now}
+		if !reminder.ReocurrFromCompletion {
+			if next, recurs := reminder.NextRemindAt(reminder.RemindAt); recurs {
+				reminder.RemindAt = next
+				reminder.ScheduleNextDue()
+				updates["remind_at"] = reminder.RemindAt
+				updates["next_due"] = reminder.NextDue
+			}
+		}
+		if err := db.Model(&models.Reminder{}).Where("id = ?", reminder.ID).Updates(updates).Error; err != nil {
+			return fmt.Errorf("failed to update reminder %d: %w", reminder.ID, err)
+		}
+	}
 	return nil
 }
+
+// dueReminders returns the open mail reminders due between the start of today and the end of the lead window
+// which have not been sent since they became due
+func dueReminders(db *gorm.DB, now time.Time, leadDays int) ([]models.Reminder, error) {
+	windowStart := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	windowEnd := windowStart.AddDate(0, 0, leadDays+1)
+
+	var reminders []models.Reminder
+	err := db.InnerJoins("Contact").
+		Where("reminders.remind_at >= ? AND reminders.remind_at < ?", windowStart.UTC(), windowEnd.UTC()).
+		Where("reminders.completed = ? AND reminders.by_mail = ?", false, true).
+		Where("reminders.last_sent IS NULL OR reminders.last_sent < ?", windowStart.AddDate(0, 0, -leadDays).UTC()).
+		Order("reminders.remind_at ASC").
+		Find(&reminders).Error
+	return reminders, err
+}
+
+func reminderMail(person, message string, remindAt time.Time) (string, string) {
+	subject := fmt.Sprintf("Reminder for %s: %s", person, message)
+	body := fmt.Sprintf("Hi,\n\nyou wanted to be reminded on %s:\n\n%s\n\nContact: %s\n",
+		remindAt.Format("Monday, 2 January 2006"), message, person)
+	return subject, body
+}
+
+func birthdayMail(birthdayPersonNick, birthdayPerson, birthdayAge, birthdayWhen string) (string, string) {
+	subject := fmt.Sprintf("Birthday of %s %s", birthdayPerson, birthdayWhen)
+	body := fmt.Sprintf("Hi,\n\n%s (%s) has their birthday %s (%s).\nDon't forget to congratulate %s!\n",
+		birthdayPerson, birthdayPersonNick, birthdayWhen, birthdayAge, birthdayPersonNick)
+	return subject, body
+}
+
+// Remembrance mails use a gentler wording
+func remembranceMail(person, occasion string) (string, string) {
+	subject := fmt.Sprintf("Remembering %s", person)
+	body := fmt.Sprintf("Hi,\n\ntoday is the %s of %s. Take a moment to remember them.\n", occasion, person)
+	return subject, body
+}
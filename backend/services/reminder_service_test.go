@@ -0,0 +1,274 @@
+package services
+
+import (
+	"errors"
+	"perema/config"
+	"perema/models"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func setupDB() *gorm.DB {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		panic("failed to connect database")
+	}
+
+	db.AutoMigrate(&models.Contact{}, &models.Activity{}, &models.Note{}, models.Relationship{}, models.Reminder{}, models.User{}, models.NoteAttachment{}, models.APIKey{}, models.AuditLog{}, models.ContactMethod{}, models.ContactAttachment{}, models.Webhook{}, models.WebhookDeadLetter{}, models.CustomDate{})
+
+	return db
+}
+
+func TestBirthdayContacts_ExcludesDeceased(t *testing.T) {
+	db := setupDB()
+
+	today := time.Date(2024, time.June, 15, 0, 0, 0, 0, time.UTC)
+	birthday := &models.Date{Time: time.Date(1960, time.June, 15, 0, 0, 0, 0, time.UTC), Valid: true}
+	deceasedDate := &models.Date{Time: time.Date(2020, time.March, 2, 0, 0, 0, 0, time.UTC), Valid: true}
+
+	db.Create(&models.Contact{Firstname: "Alice", Birthday: birthday})
+	db.Create(&models.Contact{Firstname: "Grandpa", Birthday: birthday, Deceased: true, DeceasedDate: deceasedDate})
+
+	contacts, err := birthdayContacts(db, today, "feb28")
+	assert.NoError(t, err)
+	assert.Len(t, contacts, 1)
+	assert.Equal(t, "Alice", contacts[0].Firstname)
+
+	// Deceased contacts get a remembrance on their birthday and death anniversary instead
+	contacts, err = remembranceContacts(db, today, "feb28")
+	assert.NoError(t, err)
+	assert.Len(t, contacts, 1)
+	assert.Equal(t, "Grandpa", contacts[0].Firstname)
+
+	contacts, err = remembranceContacts(db, time.Date(2024, time.March, 2, 0, 0, 0, 0, time.UTC), "feb28")
+	assert.NoError(t, err)
+	assert.Len(t, contacts, 1)
+}
+
+func TestBirthdayContacts_LeadDays(t *testing.T) {
+	db := setupDB()
+
+	birthday := &models.Date{Time: time.Date(1990, time.January, 2, 0, 0, 0, 0, time.UTC), Valid: true}
+	db.Create(&models.Contact{Firstname: "Sameday", Birthday: birthday})
+	db.Create(&models.Contact{Firstname: "Ahead", Birthday: birthday, ReminderLeadDays: 3})
+
+	tests := []struct {
+		day      time.Time
+		expected []string
+	}{
+		{time.Date(2024, time.January, 2, 0, 0, 0, 0, time.UTC), []string{"Sameday"}},
+		{time.Date(2023, time.December, 30, 0, 0, 0, 0, time.UTC), []string{"Ahead"}}, // Across the turn of the year
+		{time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC), nil},
+	}
+
+	for _, test := range tests {
+		contacts, err := birthdayContacts(db, test.day, "feb28")
+		assert.NoError(t, err)
+
+		var names []string
+		for _, contact := range contacts {
+			names = append(names, contact.Firstname)
+		}
+		assert.Equal(t, test.expected, names, test.day.Format(models.DateFormat))
+	}
+
+	assert.Equal(t, "today", birthdayDistance(0))
+	assert.Equal(t, "in 1 day", birthdayDistance(1))
+	assert.Equal(t, "in 5 days", birthdayDistance(5))
+}
+
+func TestBirthdayContacts_LeapDay(t *testing.T) {
+	db := setupDB()
+
+	leapDay := &models.Date{Time: time.Date(1996, time.February, 29, 0, 0, 0, 0, time.UTC), Valid: true}
+	db.Create(&models.Contact{Firstname: "Leap", Birthday: leapDay})
+	db.Create(&models.Contact{Firstname: "Ahead", Birthday: leapDay, ReminderLeadDays: 2})
+	db.Create(&models.Contact{Firstname: "Feb28", Birthday: &models.Date{Time: time.Date(1980, time.February, 28, 0, 0, 0, 0, time.UTC), Valid: true}})
+	db.Create(&models.Contact{Firstname: "Grandpa", Birthday: leapDay, Deceased: true})
+
+	tests := []struct {
+		day      time.Time
+		leapDay  string
+		expected []string
+	}{
+		// Leap years celebrate on Feb 29 only, whatever the birth year
+		{time.Date(2024, time.February, 28, 0, 0, 0, 0, time.UTC), "feb28", []string{"Feb28"}},
+		{time.Date(2024, time.February, 29, 0, 0, 0, 0, time.UTC), "feb28", []string{"Leap"}},
+		{time.Date(2024, time.February, 27, 0, 0, 0, 0, time.UTC), "feb28", []string{"Ahead"}},
+		{time.Date(2024, time.March, 1, 0, 0, 0, 0, time.UTC), "mar1", nil},
+		// Other years move the birthday to Feb 28 or Mar 1
+		{time.Date(2025, time.February, 28, 0, 0, 0, 0, time.UTC), "feb28", []string{"Feb28", "Leap"}},
+		{time.Date(2025, time.February, 26, 0, 0, 0, 0, time.UTC), "feb28", []string{"Ahead"}},
+		{time.Date(2025, time.March, 1, 0, 0, 0, 0, time.UTC), "feb28", nil},
+		{time.Date(2025, time.February, 28, 0, 0, 0, 0, time.UTC), "mar1", []string{"Feb28"}},
+		{time.Date(2025, time.March, 1, 0, 0, 0, 0, time.UTC), "mar1", []string{"Leap"}},
+		{time.Date(2025, time.February, 27, 0, 0, 0, 0, time.UTC), "mar1", []string{"Ahead"}},
+	}
+
+	for _, test := range tests {
+		contacts, err := birthdayContacts(db, test.day, test.leapDay)
+		assert.NoError(t, err)
+
+		var names []string
+		for _, contact := range contacts {
+			names = append(names, contact.Firstname)
+		}
+		assert.Equal(t, test.expected, names, test.day.Format(models.DateFormat)+" "+test.leapDay)
+	}
+
+	// Deceased contacts born on Feb 29 are remembered on the same day
+	contacts, err := remembranceContacts(db, time.Date(2025, time.February, 28, 0, 0, 0, 0, time.UTC), "feb28")
+	assert.NoError(t, err)
+	assert.Len(t, contacts, 1)
+	contacts, err = remembranceContacts(db, time.Date(2025, time.February, 28, 0, 0, 0, 0, time.UTC), "mar1")
+	assert.NoError(t, err)
+	assert.Empty(t, contacts)
+}
+
+// recordingSender collects the mails instead of sending them
+type recordingSender struct {
+	mails  []string
+	bodies []string
+	err    error
+}
+
+func (s *recordingSender) Send(to, subject, body string) error {
+	s.mails = append(s.mails, to+": "+subject)
+	s.bodies = append(s.bodies, body)
+	return s.err
+}
+
+func TestSendBirthdayReminders(t *testing.T) {
+	db := setupDB()
+
+	today := time.Now().UTC()
+	birthday := &models.Date{Time: time.Date(1990, today.Month(), today.Day(), 0, 0, 0, 0, time.UTC), Valid: true}
+	db.Create(&models.Contact{Firstname: "Jane", Lastname: "Doe", Birthday: birthday})
+	db.Create(&models.Contact{Firstname: "Grandpa", Lastname: "Doe", Birthday: birthday, Deceased: true})
+
+	sender := &recordingSender{}
+	cfg := &config.Config{EmailTo: "me@example.com", Timezone: time.UTC}
+	assert.NoError(t, SendBirthdayReminders(db, cfg, sender))
+	assert.Equal(t, []string{"me@example.com: Birthday of Jane Doe today"}, sender.mails)
+
+	sender = &recordingSender{}
+	cfg.RemembranceMails = true
+	assert.NoError(t, SendBirthdayReminders(db, cfg, sender))
+	assert.Equal(t, []string{"me@example.com: Birthday of Jane Doe today", "me@example.com: Remembering Grandpa Doe"}, sender.mails)
+
+	// Errors of the provider are passed on
+	sender = &recordingSender{err: errors.New("connection refused")}
+	assert.ErrorContains(t, SendBirthdayReminders(db, cfg, sender), "connection refused")
+}
+
+func TestSendRemindersToOwner(t *testing.T) {
+	db := setupDB()
+
+	today := time.Now().UTC()
+	birthday := &models.Date{Time: time.Date(1990, today.Month(), today.Day(), 0, 0, 0, 0, time.UTC), Valid: true}
+	jane := models.User{Username: "jane", Email: "jane@example.com"}
+	john := models.User{Username: "john", Email: "john@example.com"}
+	db.Create(&jane)
+	db.Create(&john)
+	anna := models.Contact{Firstname: "Anna", Birthday: birthday, UserID: jane.ID}
+	ben := models.Contact{Firstname: "Ben", Birthday: birthday, UserID: john.ID}
+	db.Create(&anna)
+	db.Create(&ben)
+	db.Create(&models.Reminder{Message: "Call", ByMail: true, RemindAt: today.Add(time.Hour), Recurrence: "Once", ContactID: &ben.ID})
+
+	// Mails about contacts go to their owner instead of EMAIL_TO
+	cfg := &config.Config{EmailTo: "me@example.com", Timezone: time.UTC}
+	sender := &recordingSender{}
+	assert.NoError(t, SendBirthdayReminders(db, cfg, sender))
+	assert.Equal(t, []string{"jane@example.com: Birthday of Anna  today", "john@example.com: Birthday of Ben  today"}, sender.mails)
+
+	sender = &recordingSender{}
+	assert.NoError(t, SendDueReminders(db, cfg, sender))
+	assert.Equal(t, []string{"john@example.com: Reminder for Ben: Call"}, sender.mails)
+}
+
+func TestSendBirthdayRemindersTimezone(t *testing.T) {
+	db := setupDB()
+
+	// Both timezones are 25 hours apart, so they are never on the same day
+	kiritimati, err := time.LoadLocation("Pacific/Kiritimati")
+	assert.NoError(t, err)
+	pagoPago, err := time.LoadLocation("Pacific/Pago_Pago")
+	assert.NoError(t, err)
+
+	today := time.Now().In(kiritimati)
+	birthday := &models.Date{Time: time.Date(1990, today.Month(), today.Day(), 0, 0, 0, 0, time.UTC), Valid: true}
+	db.Create(&models.Contact{Firstname: "Jane", Lastname: "Doe", Birthday: birthday})
+
+	sender := &recordingSender{}
+	assert.NoError(t, SendBirthdayReminders(db, &config.Config{EmailTo: "me@example.com", Timezone: pagoPago}, sender))
+	assert.Empty(t, sender.mails)
+	assert.NoError(t, SendBirthdayReminders(db, &config.Config{EmailTo: "me@example.com", Timezone: kiritimati}, sender))
+	assert.Equal(t, []string{"me@example.com: Birthday of Jane Doe today"}, sender.mails)
+}
+
+func TestSendDueReminders(t *testing.T) {
+	db := setupDB()
+
+	contact := models.Contact{Firstname: "Jane", Lastname: "Doe"}
+	db.Create(&contact)
+
+	now := time.Now().UTC()
+	once := models.Reminder{Message: "Call", ByMail: true, RemindAt: now.Add(time.Hour), Recurrence: "Once", ContactID: &contact.ID}
+	monthly := models.Reminder{Message: "Visit", ByMail: true, RemindAt: now.AddDate(0, 0, 2), Recurrence: "Monthly", ContactID: &contact.ID}
+	notByMail := models.Reminder{Message: "Write", RemindAt: now.Add(time.Hour), Recurrence: "Once", ContactID: &contact.ID}
+	later := models.Reminder{Message: "Later", ByMail: true, RemindAt: now.AddDate(0, 0, 10), Recurrence: "Once", ContactID: &contact.ID}
+	db.Create(&once)
+	db.Create(&monthly)
+	db.Create(&notByMail)
+	db.Create(&later)
+	db.Model(&monthly).Update("reocurr_from_completion", false)
+
+	sender := &recordingSender{}
+	cfg := &config.Config{EmailTo: "me@example.com", Timezone: time.UTC, ReminderLeadDays: 3}
+	assert.NoError(t, SendDueReminders(db, cfg, sender))
+	assert.Equal(t, []string{"me@example.com: Reminder for Jane Doe: Call", "me@example.com: Reminder for Jane Doe: Visit"}, sender.mails)
+
+	// Sent reminders are not sent again
+	sender = &recordingSender{}
+	assert.NoError(t, SendDueReminders(db, cfg, sender))
+	assert.Empty(t, sender.mails)
+
+	db.First(&once, once.ID)
+	assert.NotNil(t, once.LastSent)
+
+	// Recurring reminders are moved to their next due date
+	db.First(&monthly, monthly.ID)
+	assert.NotNil(t, monthly.LastSent)
+	assert.Equal(t, now.AddDate(0, 1, 2).Unix(), monthly.RemindAt.Unix())
+	assert.Equal(t, now.AddDate(0, 2, 2).Unix(), monthly.NextDue.Unix())
+}
+
+func TestNextRemindAt(t *testing.T) {
+	from := time.Date(2024, time.January, 31, 10, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		recurrence string
+		interval   int
+		expected   time.Time
+		recurs     bool
+	}{
+		{"Once", 0, from, false},
+		{"No recurrence", 2, from, false},
+		{"Weekly", 0, time.Date(2024, time.February, 7, 10, 0, 0, 0, time.UTC), true},
+		{"weekly", 2, time.Date(2024, time.February, 14, 10, 0, 0, 0, time.UTC), true},
+		{"Quarterly", 1, time.Date(2024, time.May, 1, 10, 0, 0, 0, time.UTC), true},
+		{"Halbjährlich", 1, time.Date(2024, time.July, 31, 10, 0, 0, 0, time.UTC), true},
+		{"yearly", 3, time.Date(2027, time.January, 31, 10, 0, 0, 0, time.UTC), true},
+	}
+
+	for _, test := range tests {
+		next, recurs := models.Reminder{Recurrence: test.recurrence, RecurrenceInterval: test.interval}.NextRemindAt(from)
+		assert.Equal(t, test.recurs, recurs, test.recurrence)
+		assert.Equal(t, test.expected, next, test.recurrence)
+	}
+}
@@ -0,0 +1,42 @@
+package services
+
+import (
+	"html"
+
+	"github.com/microcosm-cc/bluemonday"
+)
+
+// Sanitization modes for user supplied HTML
+const (
+	SanitizeStrict = "strict" // Strip all HTML
+	SanitizeSafe   = "safe"   // Allow a safe subset of formatting tags
+)
+
+var (
+	strictPolicy = bluemonday.StrictPolicy()
+	safePolicy   = bluemonday.UGCPolicy()
+)
+
+// SanitizeHTML removes dangerous tags and attributes from the input.
+// In strict mode all HTML is removed and the result is plain text, so characters like & and < are kept as typed
+// instead of being stored as entities. Otherwise safe formatting like bold, lists and links is kept, input without
+// any markup is returned unchanged.
+func SanitizeHTML(input, mode string) string {
+	text := plainText(input)
+	if mode == SanitizeStrict || text == input {
+		return text
+	}
+	return safePolicy.Sanitize(input)
+}
+
+// plainText strips all HTML and decodes the entities of the remaining text. Decoding can turn escaped markup
+// like &lt;script&gt; into tags, so the text is stripped again until it no longer changes.
+func plainText(input string) string {
+	for {
+		text := html.UnescapeString(strictPolicy.Sanitize(input))
+		if text == input {
+			return text
+		}
+		input = text
+	}
+}
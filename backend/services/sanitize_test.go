@@ -0,0 +1,52 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSanitizeHTML(t *testing.T) {
+	tests := []struct {
+		name   string
+		input  string
+		mode   string
+		output string
+	}{
+		{"plain text", "Lunch with Anna", SanitizeSafe, "Lunch with Anna"},
+		{"formatting kept", "<p>Talked about <strong>the trip</strong></p><ul><li>Rome</li></ul>", SanitizeSafe, "<p>Talked about <strong>the trip</strong></p><ul><li>Rome</li></ul>"},
+		{"script removed", `Hello<script>alert("xss")</script>`, SanitizeSafe, "Hello"},
+		{"event handler removed", `<a href="https://example.com" onclick="steal()">link</a>`, SanitizeSafe, `<a href="https://example.com" rel="nofollow">link</a>`},
+		{"javascript url removed", `<a href="javascript:alert(1)">link</a>`, SanitizeSafe, "link"},
+		{"iframe removed", `<iframe src="https://evil.example"></iframe>Note`, SanitizeSafe, "Note"},
+		{"strict strips formatting", "<p>Talked about <strong>the trip</strong></p>", SanitizeStrict, "Talked about the trip"},
+		{"strict strips scripts", `<img src=x onerror="alert(1)">Hi`, SanitizeStrict, "Hi"},
+		{"ampersand kept", "Tom & Jerry", SanitizeSafe, "Tom & Jerry"},
+		{"less than kept", "a < b", SanitizeSafe, "a < b"},
+		{"strict ampersand kept", "Tom & Jerry", SanitizeStrict, "Tom & Jerry"},
+		{"strict less than kept", "a < b", SanitizeStrict, "a < b"},
+		{"strict text around tags kept", "<b>Tom & Jerry</b>", SanitizeStrict, "Tom & Jerry"},
+		{"escaped script in safe mode", "&lt;script&gt;", SanitizeSafe, "&lt;script&gt;"},
+		{"strict escaped script not decoded into markup", "&lt;script&gt;alert(1)&lt;/script&gt;", SanitizeStrict, ""},
+		{"strict escaped image not decoded into markup", "&lt;img src=x onerror=alert(1)&gt;", SanitizeStrict, ""},
+		{"escaped script stays escaped", "&lt;script&gt;alert(1)&lt;/script&gt;", SanitizeSafe, "&lt;script&gt;alert(1)&lt;/script&gt;"},
+		{"escaped image stays escaped", "&lt;img src=x onerror=alert(1)&gt;", SanitizeSafe, "&lt;img src=x onerror=alert(1)&gt;"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.output, SanitizeHTML(tt.input, tt.mode))
+		})
+	}
+}
+
+func TestSanitizeHTMLRoundTrip(t *testing.T) {
+	// Saving plain text again and again must not change it
+	for _, mode := range []string{SanitizeSafe, SanitizeStrict} {
+		for _, input := range []string{"Tom & Jerry", "a < b", "x > y && y < z", `"quoted" and 'single'`} {
+			once := SanitizeHTML(input, mode)
+			assert.Equal(t, input, once, mode)
+			assert.Equal(t, once, SanitizeHTML(once, mode), mode)
+		}
+	}
+}
@@ -8,6 +8,7 @@ import (
 
 	"github.com/golang-jwt/jwt/v4"
 	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
 )
 
 func HashPassword(password string) (string, error) {
@@ -22,6 +23,53 @@ func HashPassword(password string) (string, error) {
 	return string(hashedPassword), nil
 }
 
+// SeedAdminUser creates the admin user configured by email and password. If the user already exists
+// its password is replaced when it no longer matches the configured one. Nothing happens without configuration.
+func SeedAdminUser(db *gorm.DB, cfg *config.Config) error {
+	if cfg.AdminEmail == "" || cfg.AdminPassword == "" {
+		return nil
+	}
+
+	var admin models.User
+	err := db.Where("email = ?", cfg.AdminEmail).First(&admin).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+	if err == nil && bcrypt.CompareHashAndPassword([]byte(admin.Password), []byte(cfg.AdminPassword)) == nil {
+		return nil
+	}
+
+	hashedPassword, err := HashPassword(cfg.AdminPassword)
+	if err != nil {
+		return err
+	}
+
+	if admin.ID == 0 {
+		admin = models.User{Username: cfg.AdminUsername, Email: cfg.AdminEmail, Password: hashedPassword}
+		return db.Create(&admin).Error
+	}
+	return db.Model(&admin).Update("password", hashedPassword).Error
+}
+
+// AssignOwnerlessRecords hands contacts, notes and activities created before users owned their data to the
+// first user. It does nothing as long as no user exists.
+func AssignOwnerlessRecords(db *gorm.DB) error {
+	var owner models.User
+	// Find instead of First, so the first start without users does not log a missing record
+	if err := db.Order("id").Limit(1).Find(&owner).Error; err != nil || owner.ID == 0 {
+		return err
+	}
+
+	for _, model := range []any{&models.Contact{}, &models.Note{}, &models.Activity{}} {
+		if err := db.Unscoped().Model(model).
+			Where("user_id = 0 OR user_id IS NULL").
+			Update("user_id", owner.ID).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func GenerateToken(user models.User, cfg *config.Config) (string, error) {
 	JWTSecretKey := cfg.JWTSecretKey
 	if JWTSecretKey == "" {
@@ -36,6 +84,7 @@ func GenerateToken(user models.User, cfg *config.Config) (string, error) {
 	claims := jwt.MapClaims{
 		"authorized": true,
 		"username":   user.Username,
+		"user_id":    user.ID,
 		"exp":        time.Now().Add(time.Hour * time.Duration(JWTExpiryHours)).Unix(),
 	}
 
@@ -8,6 +8,8 @@ import (
 	"github.com/golang-jwt/jwt/v4"
 	"github.com/stretchr/testify/assert"
 	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
 )
 
 func TestHashPassword(t *testing.T) {
@@ -70,3 +72,60 @@ func TestGenerateToken_Error(t *testing.T) {
 
 	assert.Error(t, err)
 }
+
+func TestSeedAdminUser(t *testing.T) {
+	db := setupDB()
+	cfg := &config.Config{AdminUsername: "admin", AdminEmail: "admin@example.com", AdminPassword: "first"}
+
+	assert.NoError(t, SeedAdminUser(db, cfg))
+	assert.NoError(t, SeedAdminUser(db, cfg))
+
+	var users []models.User
+	db.Find(&users)
+	assert.Len(t, users, 1)
+	assert.Equal(t, "admin", users[0].Username)
+	assert.NoError(t, bcrypt.CompareHashAndPassword([]byte(users[0].Password), []byte("first")))
+
+	// A changed password replaces the stored one
+	cfg.AdminPassword = "second"
+	assert.NoError(t, SeedAdminUser(db, cfg))
+	db.Find(&users)
+	assert.Len(t, users, 1)
+	assert.NoError(t, bcrypt.CompareHashAndPassword([]byte(users[0].Password), []byte("second")))
+
+	// Without configuration no user is created
+	assert.NoError(t, SeedAdminUser(db, &config.Config{AdminEmail: "other@example.com"}))
+	db.Find(&users)
+	assert.Len(t, users, 1)
+}
+
+func TestAssignOwnerlessRecords(t *testing.T) {
+	db := setupDB()
+
+	contact := models.Contact{Firstname: "Old"}
+	db.Create(&contact)
+	db.Create(&models.Note{Content: "Old note", ContactID: &contact.ID})
+
+	// Without users the data stays ownerless and no failed query is logged
+	recorder := &statementRecorder{Interface: logger.Discard}
+	assert.NoError(t, AssignOwnerlessRecords(db.Session(&gorm.Session{Logger: recorder})))
+	assert.Empty(t, recorder.errors)
+	db.First(&contact, contact.ID)
+	assert.Equal(t, uint(0), contact.UserID)
+
+	first := models.User{Username: "first", Email: "first@example.com", Password: "x"}
+	second := models.User{Username: "second", Email: "second@example.com", Password: "x"}
+	db.Create(&first)
+	db.Create(&second)
+	owned := models.Contact{Firstname: "Owned", UserID: second.ID}
+	db.Create(&owned)
+
+	assert.NoError(t, AssignOwnerlessRecords(db))
+	db.First(&contact, contact.ID)
+	assert.Equal(t, first.ID, contact.UserID)
+	var note models.Note
+	db.First(&note)
+	assert.Equal(t, first.ID, note.UserID)
+	db.First(&owned, owned.ID)
+	assert.Equal(t, second.ID, owned.UserID)
+}
@@ -0,0 +1,270 @@
+package services
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"perema/config"
+	"perema/models"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Headers sent with every webhook delivery
+const (
+	WebhookEventHeader     = "X-Perema-Event"
+	WebhookDeliveryHeader  = "X-Perema-Delivery"
+	WebhookSignatureHeader = "X-Perema-Signature" // sha256= followed by the hex encoded HMAC of the body
+)
+
+// Number of deliveries waiting for a worker before further events are dropped
+const webhookQueueSize = 256
+
+// WebhookPayload is the JSON body posted to webhooks
+type WebhookPayload struct {
+	ID         string    `json:"id"` // Same for all retries of a delivery, so receivers can skip duplicates
+	Event      string    `json:"event"`
+	OccurredAt time.Time `json:"occurred_at"`
+	Data       any       `json:"data"`
+}
+
+type webhookDelivery struct {
+	webhook models.Webhook
+	event   string
+	id      string
+	body    []byte
+	attempt int
+}
+
+// ErrPrivateWebhookTarget is returned for webhooks to loopback, private and link-local addresses, which would
+// let users reach services in the network of the server like the cloud metadata endpoint 169.254.169.254
+var ErrPrivateWebhookTarget = errors.New("webhooks must not target loopback, private or link-local addresses")
+
+// publicAddress reports whether webhooks may be delivered to the IP address
+func publicAddress(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	return !addr.IsLoopback() && !addr.IsPrivate() && !addr.IsLinkLocalUnicast() && !addr.IsLinkLocalMulticast() &&
+		!addr.IsInterfaceLocalMulticast() && !addr.IsMulticast() && !addr.IsUnspecified()
+}
+
+// CheckWebhookTarget rejects webhook URLs whose host is a non-public IP address or localhost. Host names are
+// resolved by the dispatcher on every delivery, which checks the addresses it connects to again.
+func CheckWebhookTarget(target *url.URL) error {
+	host := strings.ToLower(strings.TrimSuffix(target.Hostname(), "."))
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return ErrPrivateWebhookTarget
+	}
+	if addr, err := netip.ParseAddr(host); err == nil && !publicAddress(addr) {
+		return ErrPrivateWebhookTarget
+	}
+	return nil
+}
+
+// publicDialControl refuses connections to addresses which are not public. It runs after the host name was
+// resolved, so host names resolving to private addresses cannot bypass CheckWebhookTarget.
+func publicDialControl(network, address string, _ syscall.RawConn) error {
+	addrPort, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return err
+	}
+	if !publicAddress(addrPort.Addr()) {
+		return ErrPrivateWebhookTarget
+	}
+	return nil
+}
+
+// WebhookDispatcher delivers events to the webhooks of a user in background workers, so slow receivers never
+// delay requests. Failed deliveries are retried with increasing delays and stored as dead letters at last.
+type WebhookDispatcher struct {
+	db          *gorm.DB
+	client      *http.Client
+	retryDelays []time.Duration // Delay before each retry, the number of retries is its length
+
+	mu      sync.Mutex
+	stopped bool
+	queue   chan webhookDelivery
+	workers sync.WaitGroup
+}
+
+// NewWebhookDispatcher starts the given number of workers delivering webhooks. Unless private targets are
+// allowed, deliveries to loopback, private and link-local addresses fail.
+func NewWebhookDispatcher(db *gorm.DB, workers int, allowPrivateTargets bool) *WebhookDispatcher {
+	dialer := &net.Dialer{Timeout: 10 * time.Second}
+	if !allowPrivateTargets {
+		dialer.Control = publicDialControl
+	}
+	d := &WebhookDispatcher{
+		db: db,
+		client: &http.Client{
+			Timeout: 10 * time.Second,
+			// Without a proxy, so the checked address is the address of the receiver
+			Transport: &http.Transport{DialContext: dialer.DialContext, TLSHandshakeTimeout: 10 * time.Second},
+			// Redirects are not followed, a receiver must not forward the signed payload to another address
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+		retryDelays: []time.Duration{10 * time.Second, time.Minute, 10 * time.Minute},
+		queue:       make(chan webhookDelivery, webhookQueueSize),
+	}
+	for range workers {
+		d.workers.Add(1)
+		go func() {
+			defer d.workers.Done()
+			for delivery := range d.queue {
+				d.deliver(delivery)
+			}
+		}()
+	}
+	return d
+}
+
+// Dispatch queues the event for all webhooks of the user subscribed to it
+func (d *WebhookDispatcher) Dispatch(userID uint, event string, data any) {
+	var webhooks []models.Webhook
+	if err := d.db.Where("user_id = ?", userID).Find(&webhooks).Error; err != nil {
+		slog.Error("Failed to retrieve webhooks", "user_id", userID, "event", event, "error", err)
+		return
+	}
+
+	for _, webhook := range webhooks {
+		if !webhook.Subscribes(event) {
+			continue
+		}
+		id := uuid.New().String()
+		body, err := json.Marshal(WebhookPayload{ID: id, Event: event, OccurredAt: time.Now().UTC(), Data: data})
+		if err != nil {
+			slog.Error("Failed to encode webhook payload", "webhook_id", webhook.ID, "event", event, "error", err)
+			continue
+		}
+		d.enqueue(webhookDelivery{webhook: webhook, event: event, id: id, body: body, attempt: 1})
+	}
+}
+
+// Stop waits for the running deliveries to finish. Retries which are still pending are stored as dead letters.
+func (d *WebhookDispatcher) Stop() {
+	d.mu.Lock()
+	d.stopped = true
+	close(d.queue)
+	d.mu.Unlock()
+	d.workers.Wait()
+}
+
+func (d *WebhookDispatcher) enqueue(delivery webhookDelivery) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.stopped {
+		d.deadLetter(delivery, errors.New("server stopped before the delivery"))
+		return
+	}
+	select {
+	case d.queue <- delivery:
+	default:
+		d.deadLetter(delivery, errors.New("too many pending deliveries"))
+	}
+}
+
+func (d *WebhookDispatcher) deliver(delivery webhookDelivery) {
+	err := d.post(delivery)
+	if err == nil {
+		return
+	}
+	if delivery.attempt > len(d.retryDelays) {
+		d.deadLetter(delivery, err)
+		return
+	}
+
+	// Retry without blocking the worker
+	delay := d.retryDelays[delivery.attempt-1]
+	slog.Warn("Webhook delivery failed, retrying", "webhook_id", delivery.webhook.ID, "event", delivery.event, "attempt", delivery.attempt, "retry_in", delay, "error", err)
+	delivery.attempt++
+	time.AfterFunc(delay, func() { d.enqueue(delivery) })
+}
+
+func (d *WebhookDispatcher) post(delivery webhookDelivery) error {
+	req, err := http.NewRequest(http.MethodPost, delivery.webhook.URL, bytes.NewReader(delivery.body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "perema-webhooks")
+	req.Header.Set(WebhookEventHeader, delivery.event)
+	req.Header.Set(WebhookDeliveryHeader, delivery.id)
+	req.Header.Set(WebhookSignatureHeader, "sha256="+SignWebhookPayload(delivery.webhook.Secret, delivery.body))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+func (d *WebhookDispatcher) deadLetter(delivery webhookDelivery, err error) {
+	slog.Error("Webhook delivery failed", "webhook_id", delivery.webhook.ID, "event", delivery.event, "attempts", delivery.attempt, "error", err)
+	if err := d.db.Create(&models.WebhookDeadLetter{
+		WebhookID: delivery.webhook.ID,
+		Event:     delivery.event,
+		Payload:   string(delivery.body),
+		Attempts:  delivery.attempt,
+		Error:     err.Error(),
+	}).Error; err != nil {
+		slog.Error("Failed to store webhook dead letter", "webhook_id", delivery.webhook.ID, "error", err)
+	}
+}
+
+// SignWebhookPayload returns the hex encoded HMAC-SHA256 of the body, receivers compute it with their copy of
+// the secret to verify that a delivery comes from perema
+func SignWebhookPayload(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// GenerateWebhookSecret returns a new random secret for signing the payloads of a webhook
+func GenerateWebhookSecret() (string, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(secret), nil
+}
+
+// DispatchDueReminders sends the reminder.due event for every open reminder due today
+func DispatchDueReminders(db *gorm.DB, cfg *config.Config, dispatcher *WebhookDispatcher) error {
+	now := time.Now().In(cfg.Timezone)
+	startOfToday := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+
+	var reminders []models.Reminder
+	if err := db.InnerJoins("Contact").
+		Where("reminders.remind_at >= ? AND reminders.remind_at < ?", startOfToday.UTC(), startOfToday.AddDate(0, 0, 1).UTC()).
+		Where("reminders.completed = ?", false).
+		Where(`"Contact".user_id IN (SELECT user_id FROM webhooks WHERE deleted_at IS NULL)`).
+		Order("reminders.remind_at ASC").
+		Find(&reminders).Error; err != nil {
+		return fmt.Errorf("failed to query reminders: %w", err)
+	}
+
+	for _, reminder := range reminders {
+		dispatcher.Dispatch(reminder.Contact.UserID, models.WebhookReminderDue, reminder)
+	}
+	return nil
+}
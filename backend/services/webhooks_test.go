@@ -0,0 +1,193 @@
+package services
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"perema/config"
+	"perema/models"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWebhookDispatcher(t *testing.T) {
+	db := setupDB()
+	sqlDB, _ := db.DB()
+	sqlDB.SetMaxOpenConns(1) // Every connection to an in-memory database has its own database
+
+	type received struct {
+		header  http.Header
+		payload WebhookPayload
+		body    []byte
+	}
+	var mu sync.Mutex
+	var deliveries []received
+	failures := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		defer mu.Unlock()
+		if r.URL.Path == "/failing" {
+			failures++
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		var payload WebhookPayload
+		json.Unmarshal(body, &payload)
+		deliveries = append(deliveries, received{header: r.Header, payload: payload, body: body})
+	}))
+	defer server.Close()
+
+	subscribed := models.Webhook{UserID: 1, URL: server.URL + "/hook", Secret: "secret", Events: []string{models.WebhookContactCreated}}
+	failing := models.Webhook{UserID: 1, URL: server.URL + "/failing", Secret: "secret", Events: []string{models.WebhookContactCreated}}
+	db.Create(&subscribed)
+	db.Create(&failing)
+	db.Create(&models.Webhook{UserID: 1, URL: server.URL + "/other", Secret: "secret", Events: []string{models.WebhookContactDeleted}})
+	db.Create(&models.Webhook{UserID: 2, URL: server.URL + "/foreign", Secret: "secret", Events: []string{models.WebhookContactCreated}})
+
+	dispatcher := NewWebhookDispatcher(db, 2, true) // The test server listens on the loopback address
+	dispatcher.retryDelays = []time.Duration{time.Millisecond, time.Millisecond}
+	dispatcher.Dispatch(1, models.WebhookContactCreated, models.Contact{Firstname: "Alice"})
+
+	// Only the subscribed webhook of the user receives the event, signed with its secret
+	assert.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(deliveries) == 1
+	}, time.Second, 5*time.Millisecond)
+	delivery := deliveries[0]
+	assert.Equal(t, models.WebhookContactCreated, delivery.payload.Event)
+	assert.Equal(t, "Alice", delivery.payload.Data.(map[string]any)["firstname"])
+	assert.Equal(t, models.WebhookContactCreated, delivery.header.Get(WebhookEventHeader))
+	assert.Equal(t, delivery.payload.ID, delivery.header.Get(WebhookDeliveryHeader))
+	assert.Equal(t, "sha256="+SignWebhookPayload("secret", delivery.body), delivery.header.Get(WebhookSignatureHeader))
+
+	// Failed deliveries are retried and then kept as dead letter
+	var deadLetter models.WebhookDeadLetter
+	assert.Eventually(t, func() bool {
+		return db.Where("webhook_id = ?", failing.ID).First(&deadLetter).Error == nil
+	}, time.Second, 5*time.Millisecond)
+	assert.Equal(t, 3, deadLetter.Attempts)
+	assert.Equal(t, models.WebhookContactCreated, deadLetter.Event)
+	assert.Equal(t, "unexpected status 500", deadLetter.Error)
+	mu.Lock()
+	assert.Equal(t, 3, failures)
+	assert.Len(t, deliveries, 1)
+	mu.Unlock()
+
+	// Events after stopping are not lost silently
+	dispatcher.Stop()
+	dispatcher.Dispatch(1, models.WebhookContactCreated, models.Contact{Firstname: "Bob"})
+	var count int64
+	db.Model(&models.WebhookDeadLetter{}).Where("webhook_id = ?", subscribed.ID).Count(&count)
+	assert.Equal(t, int64(1), count)
+}
+
+func TestDispatchDueReminders(t *testing.T) {
+	db := setupDB()
+	sqlDB, _ := db.DB()
+	sqlDB.SetMaxOpenConns(1)
+
+	var mu sync.Mutex
+	var messages []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload struct {
+			Data models.Reminder `json:"data"`
+		}
+		json.NewDecoder(r.Body).Decode(&payload)
+		mu.Lock()
+		messages = append(messages, payload.Data.Message)
+		mu.Unlock()
+	}))
+	defer server.Close()
+
+	db.Create(&models.Webhook{UserID: 1, URL: server.URL, Secret: "secret", Events: []string{models.WebhookReminderDue}})
+	contact := models.Contact{Firstname: "Alice", UserID: 1}
+	db.Create(&contact)
+	now := time.Now()
+	db.Create(&models.Reminder{Message: "Due today", RemindAt: now, Recurrence: "once", ContactID: &contact.ID})
+	db.Create(&models.Reminder{Message: "Done", RemindAt: now, Recurrence: "once", Completed: true, ContactID: &contact.ID})
+	db.Create(&models.Reminder{Message: "Tomorrow", RemindAt: now.AddDate(0, 0, 1), Recurrence: "once", ContactID: &contact.ID})
+
+	dispatcher := NewWebhookDispatcher(db, 1, true)
+	assert.NoError(t, DispatchDueReminders(db, &config.Config{Timezone: time.Local}, dispatcher))
+	dispatcher.Stop()
+
+	assert.Equal(t, []string{"Due today"}, messages)
+}
+
+func TestWebhookDispatcherPrivateTargets(t *testing.T) {
+	db := setupDB()
+	sqlDB, _ := db.DB()
+	sqlDB.SetMaxOpenConns(1)
+
+	var mu sync.Mutex
+	var paths []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		paths = append(paths, r.URL.Path)
+		mu.Unlock()
+		if r.URL.Path == "/redirect" {
+			http.Redirect(w, r, "/hook", http.StatusTemporaryRedirect)
+		}
+	}))
+	defer server.Close()
+
+	// The loopback address of the test server is refused when connecting
+	webhook := models.Webhook{UserID: 1, URL: server.URL + "/hook", Secret: "secret", Events: []string{models.WebhookContactCreated}}
+	db.Create(&webhook)
+	dispatcher := NewWebhookDispatcher(db, 1, false)
+	dispatcher.retryDelays = nil
+	dispatcher.Dispatch(1, models.WebhookContactCreated, models.Contact{Firstname: "Alice"})
+	dispatcher.Stop()
+
+	var deadLetter models.WebhookDeadLetter
+	assert.NoError(t, db.Where("webhook_id = ?", webhook.ID).First(&deadLetter).Error)
+	assert.Contains(t, deadLetter.Error, ErrPrivateWebhookTarget.Error())
+
+	// Redirects are not followed, even if private targets are allowed
+	db.Model(&webhook).Update("url", server.URL+"/redirect")
+	dispatcher = NewWebhookDispatcher(db, 1, true)
+	dispatcher.retryDelays = nil
+	dispatcher.Dispatch(1, models.WebhookContactCreated, models.Contact{Firstname: "Bob"})
+	dispatcher.Stop()
+
+	var count int64
+	db.Model(&models.WebhookDeadLetter{}).Where("error = ?", "unexpected status 307").Count(&count)
+	assert.Equal(t, int64(1), count)
+	mu.Lock()
+	assert.Equal(t, []string{"/redirect"}, paths)
+	mu.Unlock()
+}
+
+func TestCheckWebhookTarget(t *testing.T) {
+	for target, allowed := range map[string]bool{
+		"https://example.com/hook":             true,
+		"https://93.184.216.34/hook":           true,
+		"http://localhost:8080":                false,
+		"http://api.localhost":                 false,
+		"http://127.0.0.1:8080":                false,
+		"http://[::1]/hook":                    false,
+		"http://10.0.0.5":                      false,
+		"http://192.168.1.10":                  false,
+		"http://172.16.0.1":                    false,
+		"http://169.254.169.254/latest/meta":   false,
+		"http://0.0.0.0":                       false,
+		"http://[::ffff:127.0.0.1]/hook":       false,
+		"http://[fd00::1]/hook":                false,
+		"http://[fe80::1%25eth0]:8080/webhook": false,
+	} {
+		parsed, err := url.Parse(target)
+		assert.NoError(t, err, target)
+		if allowed {
+			assert.NoError(t, CheckWebhookTarget(parsed), target)
+		} else {
+			assert.ErrorIs(t, CheckWebhookTarget(parsed), ErrPrivateWebhookTarget, target)
+		}
+	}
+}
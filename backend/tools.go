@@ -0,0 +1,8 @@
+//go:build tools
+
+// Tools used by go generate, imported so that their versions are pinned in go.mod
+package main
+
+import (
+	_ "github.com/swaggo/swag/v2/cmd/swag"
+)
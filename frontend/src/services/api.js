@@ -1,6 +1,6 @@
 import axios from "axios";
 
-const backendURL = process.env.VUE_APP_BACKEND_URL || "http://localhost:8080";
+const backendURL = `${process.env.VUE_APP_BACKEND_URL || "http://localhost:8080"}/api`;
 
 const apiClient = axios.create({
   baseURL: backendURL, // Go server URL
@@ -86,9 +86,27 @@ export default {
       throw error;
     }
   },
+  // Replaces all fields, contactData must contain the version of the contact it is based on
   async updateContact(contactId, contactData) {
     try {
-      await apiClient.put(`${API_URL}/${contactId}`, contactData);
+      const response = await apiClient.put(
+        `${API_URL}/${contactId}`,
+        contactData
+      );
+      return response.data;
+    } catch (error) {
+      console.error("Error updating contact:", error);
+      throw error;
+    }
+  },
+  // Changes only the given fields, the update is rejected with 409 if the version is outdated
+  async patchContact(contactId, fields, version) {
+    try {
+      const response = await apiClient.patch(`${API_URL}/${contactId}`, {
+        ...fields,
+        version,
+      });
+      return response.data;
     } catch (error) {
       console.error("Error updating contact:", error);
       throw error;